@@ -79,6 +79,23 @@ func (ak *AccountKey) publicKey() PublicKey {
 	return ak.pubKey
 }
 
+// CheckContentSignature checks that signature, as returned by
+// SignContent, is a signature of content made with the account key
+// while it was valid.
+func CheckContentSignature(content, signature []byte, ak *AccountKey) error {
+	sig, err := decodeSignature(signature)
+	if err != nil {
+		return err
+	}
+	if !ak.isKeyValidAt(sig.CreationTime) {
+		return fmt.Errorf("signature time %s is outside of the validity of key %q", sig.CreationTime.Format(time.RFC3339), ak.PublicKeyID())
+	}
+	if err := ak.pubKey.verify(content, sig); err != nil {
+		return fmt.Errorf("failed signature verification: %v", err)
+	}
+	return nil
+}
+
 func checkPublicKey(ab *assertionBase, keyIDName string) (PublicKey, error) {
 	pubKey, err := DecodePublicKey(ab.Body())
 	if err != nil {
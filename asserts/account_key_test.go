@@ -106,6 +106,42 @@ func (aks *accountKeySuite) TestDecodeNoName(c *C) {
 	c.Check(accKey.Since(), Equals, aks.since)
 }
 
+func (aks *accountKeySuite) TestCheckContentSignature(c *C) {
+	decodeKey := func(untilLine string) *asserts.AccountKey {
+		encoded := "type: account-key\n" +
+			"authority-id: canonical\n" +
+			"account-id: acc-id1\n" +
+			"name: default\n" +
+			"public-key-sha3-384: " + aks.keyID + "\n" +
+			aks.sinceLine +
+			untilLine +
+			fmt.Sprintf("body-length: %v", len(aks.pubKeyBody)) + "\n" +
+			"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" + "\n\n" +
+			aks.pubKeyBody + "\n\n" +
+			"AXNpZw=="
+		a, err := asserts.Decode([]byte(encoded))
+		c.Assert(err, IsNil)
+		return a.(*asserts.AccountKey)
+	}
+
+	content := []byte("some content")
+	sig, err := asserts.SignContent(content, aks.privKey)
+	c.Assert(err, IsNil)
+
+	accKey := decodeKey("")
+	c.Check(asserts.CheckContentSignature(content, sig, accKey), IsNil)
+
+	err = asserts.CheckContentSignature([]byte("other content"), sig, accKey)
+	c.Check(err, ErrorMatches, "failed signature verification: .*")
+
+	err = asserts.CheckContentSignature(content, []byte("AXNpZw=="), accKey)
+	c.Check(err, ErrorMatches, "cannot decode signature: .*")
+
+	// the key expired before the content was signed
+	err = asserts.CheckContentSignature(content, sig, decodeKey(aks.untilLine))
+	c.Check(err, ErrorMatches, `signature time .* is outside of the validity of key ".*"`)
+}
+
 func (aks *accountKeySuite) TestUntil(c *C) {
 
 	untilSinceLine := "until: " + aks.since.Format(time.RFC3339) + "\n"
@@ -111,6 +111,13 @@ func signContent(content []byte, privateKey PrivateKey) ([]byte, error) {
 	return encodeV1(buf.Bytes()), nil
 }
 
+// SignContent signs content that is not an assertion with the private
+// key, returning the signature encoded as in assertions. The signature
+// is checked with CheckContentSignature.
+func SignContent(content []byte, privateKey PrivateKey) ([]byte, error) {
+	return signContent(content, privateKey)
+}
+
 func decodeV1(b []byte, kind string) (packet.Packet, error) {
 	if len(b) == 0 {
 		return nil, fmt.Errorf("cannot decode %s: no data", kind)
@@ -44,6 +44,10 @@ type BootableSet struct {
 
 	UnpackedGadgetDir string
 
+	// KernelCmdlineExtra is appended to the kernel command line of the
+	// run system.
+	KernelCmdlineExtra string
+
 	// Recover is set when making the recovery partition bootable.
 	Recovery bool
 }
@@ -300,6 +304,9 @@ func makeBootable20RunMode(model *asserts.Model, rootdir string, bootWith *Boota
 	blVars := map[string]string{
 		"kernel_status": "",
 	}
+	if bootWith.KernelCmdlineExtra != "" {
+		blVars["snapd_extra_cmdline_args"] = bootWith.KernelCmdlineExtra
+	}
 
 	ebl, ok := bl.(bootloader.ExtractedRunKernelImageBootloader)
 	if ok {
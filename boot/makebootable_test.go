@@ -400,6 +400,8 @@ version: 5.0
 		Kernel:            kernelInfo,
 		Recovery:          false,
 		UnpackedGadgetDir: unpackedGadgetDir,
+
+		KernelCmdlineExtra: "quiet splash",
 	}
 
 	err = boot.MakeBootable(model, rootdir, bootWith)
@@ -428,6 +430,8 @@ version: 5.0
 	// use (?m) to match multi-line file in the regex here, because the file is
 	// a grubenv with padding #### blocks
 	c.Check(mockBootGrubenv, testutil.FileMatches, `(?m)^kernel_status=$`)
+	// the extra kernel command line arguments are picked up by grub.cfg
+	c.Check(mockBootGrubenv, testutil.FileMatches, `(?m)^snapd_extra_cmdline_args=quiet splash$`)
 
 	// check that we have the extracted kernel in the right places, both in the
 	// old uc16/uc18 location and the new ubuntu-boot partition grub dir
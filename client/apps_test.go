@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -209,6 +210,20 @@ func (cs *clientSuite) TestClientLogsOpts(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientLogsSinceUntil(c *check.C) {
+	since := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	until := since.Add(time.Hour)
+	ch, err := cs.cli.Logs([]string{"foo"}, client.LogOptions{N: -1, Since: since, Until: until})
+	c.Assert(err, check.IsNil)
+	for range ch {
+	}
+
+	query := cs.req.URL.Query()
+	c.Check(query, check.HasLen, 4)
+	c.Check(query.Get("since"), check.Equals, "2020-09-13T12:26:40Z")
+	c.Check(query.Get("until"), check.Equals, "2020-09-13T13:26:40Z")
+}
+
 func (cs *clientSuite) TestClientLogsNotFound(c *check.C) {
 	cs.rsp = `{"type":"error","status-code":404,"status":"Not Found","result":{"message":"snap \"foo\" not found","kind":"snap-not-found","value":"foo"}}`
 	cs.status = 404
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"golang.org/x/xerrors"
 )
@@ -30,6 +31,28 @@ import (
 type CohortAction struct {
 	Action string   `json:"action"`
 	Snaps  []string `json:"snaps"`
+	// Name and RefreshPolicy are about named cohorts.
+	Name          string `json:"name,omitempty"`
+	RefreshPolicy string `json:"refresh-policy,omitempty"`
+}
+
+// Cohort is a named cohort of snaps.
+type Cohort struct {
+	Name          string       `json:"name"`
+	Created       time.Time    `json:"created"`
+	RefreshPolicy string       `json:"refresh-policy"`
+	Snaps         []CohortSnap `json:"snaps"`
+}
+
+// CohortSnap is a snap of a named cohort, as seen by this device.
+type CohortSnap struct {
+	Name      string `json:"name"`
+	CohortKey string `json:"cohort-key"`
+	// Installed is whether the snap is installed on this device.
+	Installed bool `json:"installed"`
+	// Following is whether the snap follows the cohort key on this
+	// device.
+	Following bool `json:"following"`
 }
 
 func (client *Client) CreateCohorts(snaps []string) (map[string]string, error) {
@@ -48,3 +71,51 @@ func (client *Client) CreateCohorts(snaps []string) (map[string]string, error) {
 	return cohorts, nil
 
 }
+
+// Cohorts lists the named cohorts.
+func (client *Client) Cohorts() ([]*Cohort, error) {
+	var cohorts []*Cohort
+	if _, err := client.doSync("GET", "/v2/cohorts", nil, nil, nil, &cohorts); err != nil {
+		return nil, fmt.Errorf("cannot list cohorts: %v", err)
+	}
+	return cohorts, nil
+}
+
+// CreateNamedCohort creates cohort keys for the given snaps and records
+// them as the named cohort, refreshed according to the given policy.
+func (client *Client) CreateNamedCohort(name string, snaps []string, refreshPolicy string) (*Cohort, error) {
+	data, err := json.Marshal(&CohortAction{Action: "create", Snaps: snaps, Name: name, RefreshPolicy: refreshPolicy})
+	if err != nil {
+		return nil, fmt.Errorf("cannot request cohort: %v", err)
+	}
+
+	var cohort Cohort
+	if _, err := client.doSync("POST", "/v2/cohorts", nil, nil, bytes.NewReader(data), &cohort); err != nil {
+		return nil, xerrors.Errorf("cannot create cohort %q: %w", name, err)
+	}
+	return &cohort, nil
+}
+
+// RemoveCohort forgets about the named cohort.
+func (client *Client) RemoveCohort(name string) error {
+	data, err := json.Marshal(&CohortAction{Action: "remove", Name: name})
+	if err != nil {
+		return fmt.Errorf("cannot request cohort removal: %v", err)
+	}
+
+	if _, err := client.doSync("POST", "/v2/cohorts", nil, nil, bytes.NewReader(data), nil); err != nil {
+		return xerrors.Errorf("cannot remove cohort %q: %w", name, err)
+	}
+	return nil
+}
+
+// RefreshCohort refreshes all the snaps of the named cohort into their
+// cohort key, together.
+func (client *Client) RefreshCohort(name string) (changeID string, err error) {
+	data, err := json.Marshal(&CohortAction{Action: "refresh", Name: name})
+	if err != nil {
+		return "", fmt.Errorf("cannot request cohort refresh: %v", err)
+	}
+
+	return client.doAsync("POST", "/v2/cohorts", nil, nil, bytes.NewReader(data))
+}
@@ -23,10 +23,13 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"time"
 
 	"golang.org/x/xerrors"
 
 	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
 )
 
 func (cs *clientSuite) TestClientCreateCohortsEndpoint(c *check.C) {
@@ -75,3 +78,76 @@ func (cs *clientSuite) TestClientCreateCohortsErrIsWrapped(c *check.C) {
 	var e xerrors.Wrapper
 	c.Assert(err, check.Implements, &e)
 }
+
+func (cs *clientSuite) TestClientCohorts(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{
+			"name": "fleet-a",
+			"created": "2020-06-01T10:00:00Z",
+			"refresh-policy": "pinned",
+			"snaps": [{"name": "foo", "cohort-key": "xyzzy", "installed": true, "following": true}]
+		}]
+	}`
+	cohorts, err := cs.cli.Cohorts()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/cohorts")
+	c.Check(cohorts, check.DeepEquals, []*client.Cohort{{
+		Name:          "fleet-a",
+		Created:       time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+		RefreshPolicy: "pinned",
+		Snaps:         []client.CohortSnap{{Name: "foo", CohortKey: "xyzzy", Installed: true, Following: true}},
+	}})
+}
+
+func (cs *clientSuite) TestClientCreateNamedCohort(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"name": "fleet-a", "refresh-policy": "auto", "snaps": [{"name": "foo", "cohort-key": "xyzzy"}]}
+	}`
+	cohort, err := cs.cli.CreateNamedCohort("fleet-a", []string{"foo"}, "auto")
+	c.Assert(err, check.IsNil)
+	c.Check(cohort.Name, check.Equals, "fleet-a")
+	c.Check(cohort.Snaps, check.DeepEquals, []client.CohortSnap{{Name: "foo", CohortKey: "xyzzy"}})
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var jsonBody map[string]interface{}
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":         "create",
+		"snaps":          []interface{}{"foo"},
+		"name":           "fleet-a",
+		"refresh-policy": "auto",
+	})
+}
+
+func (cs *clientSuite) TestClientRemoveCohort(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": null}`
+	err := cs.cli.RemoveCohort("fleet-a")
+	c.Assert(err, check.IsNil)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"remove","snaps":null,"name":"fleet-a"}`)
+}
+
+func (cs *clientSuite) TestClientRefreshCohort(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"status-code": 202,
+		"type": "async",
+		"change": "42"
+	}`
+	id, err := cs.cli.RefreshCohort("fleet-a")
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "42")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"refresh","snaps":null,"name":"fleet-a"}`)
+}
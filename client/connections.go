@@ -62,10 +62,7 @@ type ConnectionOptions struct {
 	All bool
 }
 
-// Connections returns matching plugs, slots and their connections. Unless
-// specified by matching options, returns established connections.
-func (client *Client) Connections(opts *ConnectionOptions) (Connections, error) {
-	var conns Connections
+func (opts *ConnectionOptions) query() url.Values {
 	query := url.Values{}
 	if opts != nil && opts.Snap != "" {
 		query.Set("snap", opts.Snap)
@@ -76,6 +73,57 @@ func (client *Client) Connections(opts *ConnectionOptions) (Connections, error)
 	if opts != nil && opts.All {
 		query.Set("select", "all")
 	}
-	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &conns)
+	return query
+}
+
+// Connections returns matching plugs, slots and their connections. Unless
+// specified by matching options, returns established connections.
+func (client *Client) Connections(opts *ConnectionOptions) (Connections, error) {
+	var conns Connections
+	_, err := client.doSync("GET", "/v2/connections", opts.query(), nil, nil, &conns)
 	return conns, err
 }
+
+// ConnectionGraphNode describes a snap in the connection graph together with
+// the names of its plugs and slots.
+type ConnectionGraphNode struct {
+	Snap  string   `json:"snap"`
+	Plugs []string `json:"plugs,omitempty"`
+	Slots []string `json:"slots,omitempty"`
+}
+
+// ConnectionGraphEdge describes a connection in the connection graph.
+type ConnectionGraphEdge struct {
+	Slot      SlotRef `json:"slot"`
+	Plug      PlugRef `json:"plug"`
+	Interface string  `json:"interface"`
+	// Auto is set for connections that were established automatically.
+	Auto bool `json:"auto,omitempty"`
+	// Gadget is set for connections that were enabled by the gadget snap.
+	Gadget bool `json:"gadget,omitempty"`
+	// Undesired is set for connections that were manually denied.
+	Undesired bool `json:"undesired,omitempty"`
+	// HotplugKey is the key of the device backing a hotplug slot.
+	HotplugKey       string                 `json:"hotplug-key,omitempty"`
+	PlugStaticAttrs  map[string]interface{} `json:"plug-static,omitempty"`
+	PlugDynamicAttrs map[string]interface{} `json:"plug-dynamic,omitempty"`
+	SlotStaticAttrs  map[string]interface{} `json:"slot-static,omitempty"`
+	SlotDynamicAttrs map[string]interface{} `json:"slot-dynamic,omitempty"`
+}
+
+// ConnectionsGraph contains the interface connections of the system as a
+// graph, with the snaps as nodes and their connections as edges.
+type ConnectionsGraph struct {
+	Nodes []ConnectionGraphNode `json:"nodes"`
+	Edges []ConnectionGraphEdge `json:"edges"`
+}
+
+// ConnectionsGraph returns the graph of matching snaps and their
+// connections. The options are interpreted the same way as for Connections.
+func (client *Client) ConnectionsGraph(opts *ConnectionOptions) (ConnectionsGraph, error) {
+	var graph ConnectionsGraph
+	query := opts.query()
+	query.Set("view", "graph")
+	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &graph)
+	return graph, err
+}
@@ -268,3 +268,48 @@ func (cs *clientSuite) TestClientConnectionsFilter(c *check.C) {
 		"snap":      []string{"foo"},
 	})
 }
+
+func (cs *clientSuite) TestClientConnectionsGraph(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"nodes": [
+				{"snap": "canonical-pi2", "plugs": ["pin-13"]},
+				{"snap": "keyboard-lights", "slots": ["capslock-led"]}
+			],
+			"edges": [
+				{
+					"slot": {"snap": "keyboard-lights", "slot": "capslock-led"},
+					"plug": {"snap": "canonical-pi2", "plug": "pin-13"},
+					"interface": "bool-file",
+					"auto": true,
+					"hotplug-key": "1234",
+					"plug-dynamic": {"path": "/dev/foo"}
+				}
+			]
+		}
+	}`
+	graph, err := cs.cli.ConnectionsGraph(&client.ConnectionOptions{Snap: "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"snap": []string{"foo"},
+		"view": []string{"graph"},
+	})
+	c.Check(graph, check.DeepEquals, client.ConnectionsGraph{
+		Nodes: []client.ConnectionGraphNode{
+			{Snap: "canonical-pi2", Plugs: []string{"pin-13"}},
+			{Snap: "keyboard-lights", Slots: []string{"capslock-led"}},
+		},
+		Edges: []client.ConnectionGraphEdge{
+			{
+				Plug:             client.PlugRef{Snap: "canonical-pi2", Name: "pin-13"},
+				Slot:             client.SlotRef{Snap: "keyboard-lights", Name: "capslock-led"},
+				Interface:        "bool-file",
+				Auto:             true,
+				HotplugKey:       "1234",
+				PlugDynamicAttrs: map[string]interface{}{"path": "/dev/foo"},
+			},
+		},
+	})
+}
@@ -125,6 +125,9 @@ const (
 
 type ResultInfo struct {
 	SuggestedCurrency string `json:"suggested-currency"`
+	// CachedAt is set when the store could not be reached and the
+	// results come from the responses it cached at that time
+	CachedAt *time.Time `json:"cached-at,omitempty"`
 }
 
 // FindOptions supports exactly one of the following options:
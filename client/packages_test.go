@@ -215,6 +215,20 @@ func (cs *clientSuite) TestClientFindOne(c *check.C) {
 	c.Check(cs.req.URL.RawQuery, check.Equals, "name=foo")
 }
 
+func (cs *clientSuite) TestClientFindCachedAt(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{"name": "foo"}],
+		"sources": ["store"],
+		"cached-at": "2020-04-01T10:00:00Z"
+	}`
+	snaps, resInfo, err := cs.cli.Find(&client.FindOptions{Query: "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(snaps, check.HasLen, 1)
+	c.Assert(resInfo.CachedAt, check.NotNil)
+	c.Check(resInfo.CachedAt.Equal(time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+}
+
 const (
 	pkgName = "chatroom"
 )
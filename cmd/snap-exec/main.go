@@ -32,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapenv"
+	"github.com/snapcore/snapd/strutil"
 )
 
 // for the tests
@@ -190,9 +191,28 @@ func execApp(snapApp, revision, command string, args []string) error {
 	if err != nil {
 		return err
 	}
+	// the values of the configurable environment variables of a
+	// service are set in its unit from the configuration of the snap
+	// and take precedence over the defaults from the yaml, the unit
+	// lists the ones it sets as others might just be inherited
+	configured := make(map[string]string)
+	if app.IsService() {
+		for _, name := range strings.Split(env[snapenv.ConfiguredEnv], ",") {
+			if !strutil.ListContains(app.ConfigurableEnv, name) {
+				continue
+			}
+			if v, ok := env[name]; ok {
+				configured[name] = v
+			}
+		}
+	}
+	delete(env, snapenv.ConfiguredEnv)
 	for _, eenv := range app.EnvChain() {
 		env.ExtendWithExpanded(eenv)
 	}
+	for name, v := range configured {
+		env[name] = v
+	}
 
 	// strings.Split() is ok here because we validate all app fields and the
 	// whitelist is pretty strict (see snap/validate.go:appContentWhitelist)
@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	. "gopkg.in/check.v1"
@@ -451,6 +452,58 @@ func (s *snapExecSuite) TestSnapExecAppIntegrationWithVars(c *C) {
 	c.Check(execEnv, testutil.Contains, fmt.Sprintf("MY_PATH=%s", os.Getenv("PATH")))
 }
 
+func (s *snapExecSuite) TestSnapExecServiceConfigurableEnv(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, `name: snapname
+version: 1.0
+apps:
+ svc:
+  command: run-svc
+  daemon: simple
+  configurable-env: [LOG_LEVEL]
+  environment:
+   LOG_LEVEL: info
+   OTHER: yaml
+`, &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+
+	execEnv := []string{}
+	restore := snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		execEnv = env
+		return nil
+	})
+	defer restore()
+
+	err := snapExec.ExecApp("snapname.svc", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(execEnv, testutil.Contains, "LOG_LEVEL=info")
+
+	// inherited, e.g. from /etc/environment
+	os.Setenv("LOG_LEVEL", "debug")
+	defer os.Unsetenv("LOG_LEVEL")
+	os.Setenv("OTHER", "unit")
+	defer os.Unsetenv("OTHER")
+
+	err = snapExec.ExecApp("snapname.svc", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(execEnv, testutil.Contains, "LOG_LEVEL=info")
+	c.Check(execEnv, testutil.Contains, "OTHER=yaml")
+
+	// set from the unit of the service
+	os.Setenv("SNAP_CONFIGURED_ENV", "LOG_LEVEL,OTHER")
+	defer os.Unsetenv("SNAP_CONFIGURED_ENV")
+
+	err = snapExec.ExecApp("snapname.svc", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(execEnv, testutil.Contains, "LOG_LEVEL=debug")
+	// variables that are not configurable come from the yaml
+	c.Check(execEnv, testutil.Contains, "OTHER=yaml")
+	for _, e := range execEnv {
+		c.Check(strings.HasPrefix(e, "SNAP_CONFIGURED_ENV="), Equals, false)
+	}
+}
+
 func (s *snapExecSuite) TestSnapExecExpandEnvCmdArgs(c *C) {
 	for _, t := range []struct {
 		args     []string
@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var shortCohortsHelp = i18n.G("List the named cohorts")
+var longCohortsHelp = i18n.G(`
+The cohorts command lists the cohorts created with 'snap create-cohort --name',
+with the cohort key of each of their snaps and whether the snap follows that
+cohort key on this system.
+`)
+
+var shortRemoveCohortHelp = i18n.G("Remove a named cohort")
+var longRemoveCohortHelp = i18n.G(`
+The remove-cohort command forgets about the given named cohort. The snaps of
+the cohort are left in their cohort until they are refreshed out of it.
+`)
+
+type cmdCohorts struct {
+	clientMixin
+}
+
+type cmdRemoveCohort struct {
+	clientMixin
+	Positional struct {
+		Name string `positional-arg-name:"<cohort>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	addCommand("cohorts", shortCohortsHelp, longCohortsHelp, func() flags.Commander { return &cmdCohorts{} }, nil, nil)
+	addCommand("remove-cohort", shortRemoveCohortHelp, longRemoveCohortHelp, func() flags.Commander { return &cmdRemoveCohort{} }, nil, nil)
+}
+
+func (x *cmdCohorts) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	cohorts, err := x.client.Cohorts()
+	if err != nil {
+		return err
+	}
+	if len(cohorts) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No named cohorts."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Cohort\tRefresh policy\tSnap\tCohort key\tFollowing"))
+	for _, cohort := range cohorts {
+		for _, sn := range cohort.Snaps {
+			following := "-"
+			if sn.Installed {
+				following = i18n.G("no")
+				if sn.Following {
+					following = i18n.G("yes")
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cohort.Name, cohort.RefreshPolicy, sn.Name, strutil.ElliptLeft(sn.CohortKey, 10), following)
+		}
+	}
+
+	return nil
+}
+
+func (x *cmdRemoveCohort) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	if err := x.client.RemoveCohort(x.Positional.Name); err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.G("Cohort %q removed.\n"), x.Positional.Name)
+	return nil
+}
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestCohorts(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/cohorts")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+{"name": "fleet-a", "refresh-policy": "pinned", "snaps": [
+  {"name": "bar", "cohort-key": "MSBzaUIzcGFkbmZhMG5YeFRXOWtSbXFxWjRDSUlBRjk", "installed": true, "following": false},
+  {"name": "foo", "cohort-key": "MSBlaW5zdGFsbGVkLXNuYXAta2V5", "installed": true, "following": true},
+  {"name": "baz", "cohort-key": "short", "installed": false}]}]}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"cohorts"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+Cohort   Refresh policy  Snap  Cohort key  Following
+fleet-a  pinned          bar   …RDSUlBRjk  no
+fleet-a  pinned          foo   …uYXAta2V5  yes
+fleet-a  pinned          baz   short       -
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestCohortsEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"cohorts"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No named cohorts.\n")
+}
+
+func (s *SnapSuite) TestRemoveCohort(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "remove",
+			"name":   "fleet-a",
+			"snaps":  nil,
+		})
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": null}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-cohort", "fleet-a"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Cohort \"fleet-a\" removed.\n")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestRemoveCohortNotFound(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "cannot find cohort \"fleet-a\""}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-cohort", "fleet-a"})
+	c.Check(err, check.ErrorMatches, `cannot remove cohort "fleet-a": cannot find cohort "fleet-a"`)
+}
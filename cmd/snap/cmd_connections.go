@@ -20,7 +20,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
@@ -28,11 +30,13 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
 )
 
 type cmdConnections struct {
 	clientMixin
-	All         bool `long:"all"`
+	All         bool   `long:"all"`
+	Format      string `long:"format" default:"table" choice:"table" choice:"json" choice:"dot"`
 	Positionals struct {
 		Snap installedSnapName
 	} `positional-args:"true"`
@@ -51,6 +55,11 @@ $ snap connections <snap>
 
 Lists connected and unconnected plugs and slots for the specified
 snap.
+
+With --format=json or --format=dot the connections are printed as a
+graph of snaps and their connections, including whether a connection
+was established automatically, its hotplug key and its dynamic
+attributes.
 `)
 
 func init() {
@@ -58,6 +67,8 @@ func init() {
 		return &cmdConnections{}
 	}, map[string]string{
 		"all": i18n.G("Show connected and unconnected plugs and slots"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"format": i18n.G("Output format: table (default), json or dot"),
 	}, []argDesc{{
 		// TRANSLATORS: This needs to be wrapped in <>s.
 		name: "<snap>",
@@ -154,6 +165,19 @@ func (x *cmdConnections) Execute(args []string) error {
 		x.All = true
 	}
 
+	if x.Format != "table" {
+		graph, err := x.client.ConnectionsGraph(&opts)
+		if err != nil {
+			return err
+		}
+		if x.Format == "json" {
+			enc := json.NewEncoder(Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(graph)
+		}
+		return printConnectionsDot(Stdout, &graph)
+	}
+
 	connections, err := x.client.Connections(&opts)
 	if err != nil {
 		return err
@@ -212,3 +236,54 @@ func (x *cmdConnections) Execute(args []string) error {
 	}
 	return nil
 }
+
+func connectionsDotEdgeAttrs(edge *client.ConnectionGraphEdge) string {
+	notes := []string{edge.Interface}
+	style := "solid"
+	color := "black"
+	if edge.Auto {
+		notes = append(notes, "auto")
+		style = "dashed"
+	} else {
+		notes = append(notes, "manual")
+	}
+	if edge.Gadget {
+		notes = append(notes, "gadget")
+	}
+	if edge.HotplugKey != "" {
+		notes = append(notes, "hotplug "+snap.HotplugKey(edge.HotplugKey).ShortString())
+	}
+	if edge.Undesired {
+		notes = append(notes, "undesired")
+		style = "dotted"
+		color = "gray"
+	}
+	// %q renders the newlines as \n, which is what DOT expects
+	return fmt.Sprintf("label=%q, style=%s, color=%s", strings.Join(notes, "\n"), style, color)
+}
+
+// printConnectionsDot writes the connection graph in the graphviz DOT
+// language, with a cluster for each snap holding its plugs and slots.
+func printConnectionsDot(w io.Writer, graph *client.ConnectionsGraph) error {
+	fmt.Fprintln(w, "digraph connections {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	for i, node := range graph.Nodes {
+		fmt.Fprintf(w, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(w, "\t\tlabel=%q;\n", node.Snap)
+		for _, plug := range node.Plugs {
+			fmt.Fprintf(w, "\t\t%q [label=%q, shape=box];\n", node.Snap+":"+plug, plug)
+		}
+		for _, slot := range node.Slots {
+			fmt.Fprintf(w, "\t\t%q [label=%q, shape=ellipse];\n", node.Snap+":"+slot, slot)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	for i := range graph.Edges {
+		edge := &graph.Edges[i]
+		plug := edge.Plug.Snap + ":" + edge.Plug.Name
+		slot := edge.Slot.Snap + ":" + edge.Slot.Name
+		fmt.Fprintf(w, "\t%q -> %q [%s];\n", plug, slot, connectionsDotEdgeAttrs(edge))
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -851,3 +852,98 @@ func (s *SnapSuite) TestConnectionsDefiningAttribute(c *C) {
 	c.Assert(s.Stdout(), Equals, expectedStdout)
 	c.Assert(s.Stderr(), Equals, "")
 }
+
+var connectionsGraphResult = client.ConnectionsGraph{
+	Nodes: []client.ConnectionGraphNode{
+		{Snap: "keyboard-lights", Plugs: []string{"capslock"}},
+		{Snap: "leds-provider", Slots: []string{"capslock-led", "numlock-led"}},
+	},
+	Edges: []client.ConnectionGraphEdge{
+		{
+			Plug:             client.PlugRef{Snap: "keyboard-lights", Name: "capslock"},
+			Slot:             client.SlotRef{Snap: "leds-provider", Name: "capslock-led"},
+			Interface:        "leds",
+			Auto:             true,
+			HotplugKey:       "a5bdd4d4dc5b2d1f0aa8e1a8f3d9c4e4f2e7e0b1",
+			PlugDynamicAttrs: map[string]interface{}{"path": "/dev/capslock"},
+		}, {
+			Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "capslock"},
+			Slot:      client.SlotRef{Snap: "leds-provider", Name: "numlock-led"},
+			Interface: "leds",
+			Auto:      true,
+			Gadget:    true,
+			Undesired: true,
+		},
+	},
+}
+
+func (s *SnapSuite) TestConnectionsFormatJSON(c *C) {
+	query := url.Values{
+		"select": []string{"all"},
+		"view":   []string{"graph"},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, query)
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": connectionsGraphResult,
+		})
+	})
+
+	rest, err := Parser(Client()).ParseArgs([]string{"connections", "--all", "--format=json"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Assert(s.Stderr(), Equals, "")
+
+	var graph client.ConnectionsGraph
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &graph), IsNil)
+	c.Check(graph, DeepEquals, connectionsGraphResult)
+}
+
+func (s *SnapSuite) TestConnectionsFormatDot(c *C) {
+	query := url.Values{
+		"snap":   []string{"keyboard-lights"},
+		"select": []string{"all"},
+		"view":   []string{"graph"},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, query)
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": connectionsGraphResult,
+		})
+	})
+
+	rest, err := Parser(Client()).ParseArgs([]string{"connections", "--format=dot", "keyboard-lights"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := `digraph connections {
+	rankdir=LR;
+	subgraph cluster_0 {
+		label="keyboard-lights";
+		"keyboard-lights:capslock" [label="capslock", shape=box];
+	}
+	subgraph cluster_1 {
+		label="leds-provider";
+		"leds-provider:capslock-led" [label="capslock-led", shape=ellipse];
+		"leds-provider:numlock-led" [label="numlock-led", shape=ellipse];
+	}
+	"keyboard-lights:capslock" -> "leds-provider:capslock-led" [label="leds\nauto\nhotplug a5bdd4d4dc5b…", style=dashed, color=black];
+	"keyboard-lights:capslock" -> "leds-provider:numlock-led" [label="leds\nauto\ngadget\nundesired", style=dotted, color=gray];
+}
+`
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestConnectionsFormatInvalid(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--format=yaml"})
+	c.Assert(err, ErrorMatches, `Invalid value .yaml. for option .*--format.*`)
+}
@@ -21,12 +21,14 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 )
 
 type cmdConnectivityCheck struct {
 	clientMixin
+	timeMixin
 }
 
 func init() {
@@ -35,7 +37,7 @@ func init() {
 		"The connectivity command checks the network connectivity of snapd.",
 		func() flags.Commander {
 			return &cmdConnectivityCheck{}
-		}, nil, nil)
+		}, timeDescs, nil)
 }
 
 func (x *cmdConnectivityCheck) Execute(args []string) error {
@@ -45,6 +47,14 @@ func (x *cmdConnectivityCheck) Execute(args []string) error {
 
 	var status struct {
 		Unreachable []string
+		Endpoints   []struct {
+			URL          string     `json:"url"`
+			Service      string     `json:"service"`
+			Status       string     `json:"status"`
+			Failures     int        `json:"failures"`
+			LastError    string     `json:"last-error"`
+			BackoffUntil *time.Time `json:"backoff-until"`
+		}
 	}
 	if err := x.client.DebugGet("connectivity", &status, nil); err != nil {
 		return err
@@ -53,11 +63,33 @@ func (x *cmdConnectivityCheck) Execute(args []string) error {
 	fmt.Fprintf(Stdout, "Connectivity status:\n")
 	if len(status.Unreachable) == 0 {
 		fmt.Fprintf(Stdout, " * PASS\n")
-		return nil
 	}
-
 	for _, uri := range status.Unreachable {
 		fmt.Fprintf(Stdout, " * %s: unreachable\n", uri)
 	}
-	return fmt.Errorf("%v servers unreachable", len(status.Unreachable))
+
+	service := "-"
+	for _, ep := range status.Endpoints {
+		if ep.Service != service {
+			service = ep.Service
+			if service == "assertions" {
+				fmt.Fprintf(Stdout, "Assertions endpoints:\n")
+			} else {
+				fmt.Fprintf(Stdout, "Store endpoints:\n")
+			}
+		}
+		switch ep.Status {
+		case "ok":
+			fmt.Fprintf(Stdout, " * %s: ok\n", ep.URL)
+		case "backoff":
+			fmt.Fprintf(Stdout, " * %s: backing off until %s after %d failures (%s)\n", ep.URL, x.fmtTime(*ep.BackoffUntil), ep.Failures, ep.LastError)
+		default:
+			fmt.Fprintf(Stdout, " * %s: %s after %d failures (%s)\n", ep.URL, ep.Status, ep.Failures, ep.LastError)
+		}
+	}
+
+	if len(status.Unreachable) > 0 {
+		return fmt.Errorf("%v servers unreachable", len(status.Unreachable))
+	}
+	return nil
 }
@@ -82,3 +82,30 @@ func (s *SnapSuite) TestConnectivityUnhappy(c *check.C) {
 `)
 	c.Check(s.Stderr(), check.Equals, "")
 }
+
+func (s *SnapSuite) TestConnectivityEndpoints(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/debug")
+		c.Check(r.URL.RawQuery, check.Equals, "aspect=connectivity")
+		fmt.Fprintln(w, `{"type": "sync", "result": {"connectivity":false,"unreachable":["eu.proxy.example"],"endpoints":[
+{"url":"https://api.snapcraft.io/","status":"ok"},
+{"url":"https://eu.proxy.example/","status":"backoff","failures":4,"last-error":"503 Service Unavailable","backoff-until":"2020-06-01T12:00:00Z"},
+{"url":"https://us.proxy.example/","status":"failing","failures":1,"last-error":"connection refused"},
+{"url":"https://assertions.example/","service":"assertions","status":"failing","failures":2,"last-error":"502 Bad Gateway"},
+{"url":"https://assertions.proxy.example/","service":"assertions","status":"ok"}]}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "connectivity", "--abs-time"})
+	c.Assert(err, check.ErrorMatches, "1 servers unreachable")
+	c.Check(s.Stdout(), check.Equals, `Connectivity status:
+ * eu.proxy.example: unreachable
+Store endpoints:
+ * https://api.snapcraft.io/: ok
+ * https://eu.proxy.example/: backing off until 2020-06-01T12:00:00Z after 4 failures (503 Service Unavailable)
+ * https://us.proxy.example/: failing after 1 failures (connection refused)
+Assertions endpoints:
+ * https://assertions.example/: failing after 2 failures (502 Bad Gateway)
+ * https://assertions.proxy.example/: ok
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
@@ -20,6 +20,8 @@
 package main
 
 import (
+	"errors"
+
 	"github.com/jessevdk/go-flags"
 	"gopkg.in/yaml.v2"
 
@@ -37,17 +39,29 @@ per-snap key that works across systems. Installations or refreshes of the snap
 using a given cohort key would use a fixed revision for up to 90 days, after
 which a new set of revisions would be fixed under that same cohort key and a
 new 90 days window started.
+
+With --name the cohort keys are also recorded under the given name, so that
+all the snaps of the cohort can be refreshed into it together with
+'snap refresh --cohort=<name>'. With --refresh-policy=pinned the snaps of
+the cohort are then only refreshed that way, and not by auto-refreshes.
 `)
 
 type cmdCreateCohort struct {
 	clientMixin
-	Positional struct {
+	Name          string `long:"name"`
+	RefreshPolicy string `long:"refresh-policy" choice:"auto" choice:"pinned"`
+	Positional    struct {
 		Snaps []anySnapName `positional-arg-name:"<snap>" required:"1"`
 	} `positional-args:"yes" required:"yes"`
 }
 
 func init() {
-	addCommand("create-cohort", shortCreateCohortHelp, longCreateCohortHelp, func() flags.Commander { return &cmdCreateCohort{} }, nil, nil)
+	addCommand("create-cohort", shortCreateCohortHelp, longCreateCohortHelp, func() flags.Commander { return &cmdCreateCohort{} }, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"name": i18n.G("Record the cohort keys under the given cohort name"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"refresh-policy": i18n.G("How the snaps of the named cohort are refreshed (auto or pinned)"),
+	}, nil)
 }
 
 // output should be YAML, so we use these two as helpers to get that done easy
@@ -68,9 +82,26 @@ func (x *cmdCreateCohort) Execute(args []string) error {
 		snaps[i] = string(s)
 	}
 
-	cohorts, err := x.client.CreateCohorts(snaps)
-	if len(cohorts) == 0 || err != nil {
-		return err
+	if x.Name == "" && x.RefreshPolicy != "" {
+		return errors.New(i18n.G("--refresh-policy can only be used with --name"))
+	}
+
+	var cohorts map[string]string
+	if x.Name != "" {
+		cohort, err := x.client.CreateNamedCohort(x.Name, snaps, x.RefreshPolicy)
+		if err != nil {
+			return err
+		}
+		cohorts = make(map[string]string, len(cohort.Snaps))
+		for _, sn := range cohort.Snaps {
+			cohorts[sn.Name] = sn.CohortKey
+		}
+	} else {
+		var err error
+		cohorts, err = x.client.CreateCohorts(snaps)
+		if len(cohorts) == 0 || err != nil {
+			return err
+		}
 	}
 
 	var out cohortOutYAML
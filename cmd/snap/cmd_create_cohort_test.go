@@ -85,3 +85,42 @@ func (s *SnapSuite) TestCreateCohortError(c *check.C) {
 	c.Check(err, check.ErrorMatches, "cannot create cohorts: something went wrong")
 	c.Check(n, check.Equals, 1)
 }
+
+func (s *SnapSuite) TestCreateNamedCohort(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":         "create",
+			"snaps":          []interface{}{"foo", "bar"},
+			"name":           "fleet-a",
+			"refresh-policy": "pinned",
+		})
+		fmt.Fprintln(w, `{
+"type": "sync",
+"status-code": 200,
+"status": "OK",
+"result": {"name": "fleet-a", "refresh-policy": "pinned", "snaps": [{"name": "bar", "cohort-key": "this"}, {"name": "foo", "cohort-key": "what"}]}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"create-cohort", "--name=fleet-a", "--refresh-policy=pinned", "foo", "bar"})
+	c.Assert(err, check.IsNil)
+
+	var v map[string]map[string]map[string]string
+	c.Assert(yaml.Unmarshal(s.stdout.Bytes(), &v), check.IsNil)
+	c.Check(v, check.DeepEquals, map[string]map[string]map[string]string{
+		"cohorts": {
+			"foo": {"cohort-key": "what"},
+			"bar": {"cohort-key": "this"},
+		},
+	})
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestCreateCohortRefreshPolicyNeedsName(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		panic("shouldn't be called")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"create-cohort", "--refresh-policy=pinned", "foo"})
+	c.Check(err, check.ErrorMatches, "--refresh-policy can only be used with --name")
+}
@@ -0,0 +1,117 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
+)
+
+type cmdDebugCache struct {
+	clientMixin
+	timeMixin
+	Positional struct {
+		Action string `positional-arg-name:"<action>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+var shortDebugCacheHelp = i18n.G("Inspect and clean the store download cache")
+var longDebugCacheHelp = i18n.G(`
+The cache command lists the snaps kept in the store download cache with
+'snap debug cache list', most recently used first, or empties it with
+'snap debug cache clean'.
+
+Cached snaps that are also installed take no space of their own.
+`)
+
+func init() {
+	addDebugCommand("cache", shortDebugCacheHelp, longDebugCacheHelp,
+		func() flags.Commander {
+			return &cmdDebugCache{}
+		}, timeDescs, []argDesc{{
+			// TRANSLATORS: This needs to be wrapped in <>s.
+			name: "<action>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Either list or clean"),
+		}})
+}
+
+type cacheEntry struct {
+	Digest   string    `json:"digest"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last-used"`
+	InUse    bool      `json:"in-use"`
+}
+
+func (x *cmdDebugCache) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	switch x.Positional.Action {
+	case "list":
+		return x.list()
+	case "clean":
+		return x.clean()
+	default:
+		return fmt.Errorf(i18n.G("unknown cache action %q, expected list or clean"), x.Positional.Action)
+	}
+}
+
+func (x *cmdDebugCache) list() error {
+	var entries []cacheEntry
+	if err := x.client.DebugGet("cache", &entries, nil); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("The download cache is empty."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Digest\tSize\tLast used\tIn use"))
+	for _, entry := range entries {
+		// TRANSLATORS: whether a cached snap is also installed
+		inUse := i18n.G("no")
+		if entry.InUse {
+			// TRANSLATORS: whether a cached snap is also installed
+			inUse = i18n.G("yes")
+		}
+		fmt.Fprintf(w, "%.12s…\t%s\t%s\t%s\n", entry.Digest, strutil.SizeToStr(entry.Size), x.fmtTime(entry.LastUsed), inUse)
+	}
+	return nil
+}
+
+func (x *cmdDebugCache) clean() error {
+	var result struct {
+		Freed int64 `json:"freed"`
+	}
+	if err := x.client.Debug("clean-cache", nil, &result); err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.G("Cleaned the download cache, freeing %s.\n"), strutil.SizeToStr(result.Freed))
+	return nil
+}
@@ -0,0 +1,119 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugDenials struct {
+	clientMixin
+	Positional struct {
+		Snap installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+var shortDebugDenialsHelp = i18n.G("Summarise the sandbox denials of snaps")
+var longDebugDenialsHelp = i18n.G(`
+The denials command summarises the apparmor and seccomp denials logged
+by the kernel since boot, for the given snap or for all snaps. Accesses
+that were only logged because the snap runs in devmode are reported in
+complain mode.
+
+Where known, the interface that would grant the access is suggested.
+`)
+
+func init() {
+	addDebugCommand("denials", shortDebugDenialsHelp, longDebugDenialsHelp,
+		func() flags.Commander {
+			return &cmdDebugDenials{}
+		}, nil, []argDesc{{
+			// TRANSLATORS: This needs to be wrapped in <>s.
+			name: "<snap>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Constrain the summary to a specific snap"),
+		}})
+}
+
+type denialSummary struct {
+	Snap      string    `json:"snap"`
+	App       string    `json:"app,omitempty"`
+	Hook      string    `json:"hook,omitempty"`
+	Backend   string    `json:"backend"`
+	Operation string    `json:"operation"`
+	Target    string    `json:"target,omitempty"`
+	Allowed   bool      `json:"allowed,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	Count     int       `json:"count"`
+	LastSeen  time.Time `json:"last-seen"`
+}
+
+func (d *denialSummary) origin() string {
+	if d.Hook != "" {
+		return fmt.Sprintf("%s (hook %s)", d.Snap, d.Hook)
+	}
+	return d.Snap + "." + d.App
+}
+
+func (x *cmdDebugDenials) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	var params map[string]string
+	if snapName := string(x.Positional.Snap); snapName != "" {
+		params = map[string]string{"snap": snapName}
+	}
+	var summaries []*denialSummary
+	if err := x.client.DebugGet("denials", &summaries, params); err != nil {
+		return err
+	}
+	if len(summaries) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No denials found."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("App\tBackend\tMode\tOperation\tTarget\tCount\tSuggested interface"))
+	for _, d := range summaries {
+		// TRANSLATORS: the mode of a sandbox denial, as in enforce or complain mode
+		mode := i18n.G("enforce")
+		if d.Allowed {
+			// TRANSLATORS: the mode of a sandbox denial, as in enforce or complain mode
+			mode = i18n.G("complain")
+		}
+		target := d.Target
+		if target == "" {
+			target = "-"
+		}
+		iface := d.Interface
+		if iface == "" {
+			iface = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", d.origin(), d.Backend, mode, d.Operation, target, d.Count, iface)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+)
+
+type cmdSandboxProfile struct {
+	clientMixin
+	Positional struct {
+		App string `positional-arg-name:"<snap.app>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+var shortSandboxProfileHelp = i18n.G("Show the sandbox rules of an app")
+var longSandboxProfileHelp = i18n.G(`
+The sandbox-profile command shows the effective rules each security
+backend (apparmor, seccomp, mount, udev, kmod and dbus) applies to the
+given app. Every group of rules is annotated with the plug, slot or
+connection that contributed it.
+`)
+
+func init() {
+	addDebugCommand("sandbox-profile", shortSandboxProfileHelp, longSandboxProfileHelp,
+		func() flags.Commander {
+			return &cmdSandboxProfile{}
+		}, nil, []argDesc{{
+			// TRANSLATORS: This needs to be wrapped in <>s.
+			name: "<snap.app>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The app to show the sandbox rules of"),
+		}})
+}
+
+type sandboxRules struct {
+	Backend   string              `json:"backend"`
+	Interface string              `json:"interface"`
+	Plug      *interfaces.PlugRef `json:"plug,omitempty"`
+	Slot      *interfaces.SlotRef `json:"slot,omitempty"`
+	Connected bool                `json:"connected,omitempty"`
+	Rules     []string            `json:"rules"`
+}
+
+type sandboxProfile struct {
+	Snap        string         `json:"snap"`
+	App         string         `json:"app"`
+	SecurityTag string         `json:"security-tag"`
+	Confinement string         `json:"confinement"`
+	Rules       []sandboxRules `json:"rules"`
+}
+
+// origin describes the plug, slot or connection that contributed the rules.
+func (r *sandboxRules) origin() string {
+	switch {
+	case r.Connected:
+		return fmt.Sprintf("connected plug %s to %s (%s)",
+			endpoint(r.Plug.Snap, r.Plug.Name), endpoint(r.Slot.Snap, r.Slot.Name), r.Interface)
+	case r.Plug != nil:
+		return fmt.Sprintf("plug %s (%s)", endpoint(r.Plug.Snap, r.Plug.Name), r.Interface)
+	case r.Slot != nil:
+		return fmt.Sprintf("slot %s (%s)", endpoint(r.Slot.Snap, r.Slot.Name), r.Interface)
+	}
+	return r.Interface
+}
+
+func printSandboxRules(w io.Writer, rules []sandboxRules) {
+	backend := ""
+	for _, r := range rules {
+		if r.Backend != backend {
+			backend = r.Backend
+			fmt.Fprintf(w, "\n%s:\n", backend)
+		}
+		fmt.Fprintf(w, "  # %s\n", r.origin())
+		for _, rule := range r.Rules {
+			for _, line := range strings.Split(strings.TrimRight(rule, "\n"), "\n") {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+	}
+}
+
+func (x *cmdSandboxProfile) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	var resp sandboxProfile
+	if err := x.client.DebugGet("sandbox-profile", &resp, map[string]string{"app": x.Positional.App}); err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	fmt.Fprintf(w, "snap:\t%s\n", resp.Snap)
+	fmt.Fprintf(w, "app:\t%s\n", resp.App)
+	fmt.Fprintf(w, "security-tag:\t%s\n", resp.SecurityTag)
+	fmt.Fprintf(w, "confinement:\t%s\n", resp.Confinement)
+	w.Flush()
+
+	if len(resp.Rules) == 0 {
+		fmt.Fprintf(Stdout, "\n%s\n", i18n.G("No interface contributes rules to this app."))
+		return nil
+	}
+	printSandboxRules(Stdout, resp.Rules)
+	return nil
+}
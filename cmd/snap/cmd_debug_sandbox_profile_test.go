@@ -0,0 +1,99 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugSandboxProfile(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "sandbox-profile")
+			c.Check(r.URL.Query().Get("app"), check.Equals, "consumer.app")
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+"snap": "consumer", "app": "app", "security-tag": "snap.consumer.app", "confinement": "strict",
+"rules": [
+ {"backend": "apparmor", "interface": "network", "plug": {"snap": "consumer", "plug": "network"}, "slot": {"snap": "core", "slot": "network"}, "connected": true, "rules": ["#include <abstractions/nameservice>\n/run/systemd/resolve/stub-resolv.conf r,\n"]},
+ {"backend": "apparmor", "interface": "home", "plug": {"snap": "consumer", "plug": "home"}, "rules": ["owner @{HOME}/ r,"]},
+ {"backend": "seccomp", "interface": "network", "plug": {"snap": "consumer", "plug": "network"}, "slot": {"snap": "core", "slot": "network"}, "connected": true, "rules": ["bind", "listen"]},
+ {"backend": "kmod", "interface": "test", "slot": {"snap": "consumer", "slot": "test"}, "rules": ["tun"]}
+]}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-profile", "consumer.app"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `snap:          consumer
+app:           app
+security-tag:  snap.consumer.app
+confinement:   strict
+
+apparmor:
+  # connected plug consumer:network to :network (network)
+  #include <abstractions/nameservice>
+  /run/systemd/resolve/stub-resolv.conf r,
+  # plug consumer:home (home)
+  owner @{HOME}/ r,
+
+seccomp:
+  # connected plug consumer:network to :network (network)
+  bind
+  listen
+
+kmod:
+  # slot consumer:test (test)
+  tun
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugSandboxProfileNoRules(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {"snap": "foo", "app": "bar", "security-tag": "snap.foo.bar", "confinement": "devmode", "rules": []}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-profile", "foo.bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `snap:          foo
+app:           bar
+security-tag:  snap.foo.bar
+confinement:   devmode
+
+No interface contributes rules to this app.
+`)
+}
+
+func (s *SnapSuite) TestDebugSandboxProfileMissingApp(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-profile"})
+	c.Assert(err, check.ErrorMatches, "the required argument `<snap.app>` was not provided")
+}
@@ -26,6 +26,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
@@ -241,6 +242,7 @@ func (x *cmdFind) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
+	maybePrintCachedNotice(resInfo, timeutilHuman)
 	if len(snaps) == 0 {
 		if x.Section == "" {
 			// TRANSLATORS: the %q is the (quoted) query the user entered
@@ -272,3 +274,13 @@ func (x *cmdFind) Execute(args []string) error {
 	}
 	return nil
 }
+
+// maybePrintCachedNotice tells the user when the store results come
+// from the cache as the store could not be reached.
+func maybePrintCachedNotice(resInfo *client.ResultInfo, fmtTime func(time.Time) string) {
+	if resInfo == nil || resInfo.CachedAt == nil {
+		return
+	}
+	// TRANSLATORS: the %s is a time, like "today at 10:04 UTC"
+	fmt.Fprintf(Stderr, i18n.G("Cannot reach the store, showing results cached %s.\n"), fmtTime(*resInfo.CachedAt))
+}
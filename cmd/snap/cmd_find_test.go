@@ -25,6 +25,8 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"gopkg.in/check.v1"
@@ -240,6 +242,25 @@ hello-huge +1.0 +noise +- +a really big snap
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapSuite) TestFindHelloCached(c *check.C) {
+	restore := snap.MockTimeutilHuman(func(t time.Time) string {
+		c.Check(t.Equal(time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+		return "today at 10:00 UTC"
+	})
+	defer restore()
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, strings.Replace(findHelloJSON, `"suggested-currency": "GBP"`, `"suggested-currency": "GBP", "cached-at": "2020-04-01T10:00:00Z"`, 1))
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"find", "hello"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `Name +Version +Publisher +Notes +Summary
+hello +2.10 +canonical\* +- +GNU Hello, the "hello world" snap
+hello-huge +1.0 +noise +- +a really big snap
+`)
+	c.Check(s.Stderr(), check.Equals, "Cannot reach the store, showing results cached today at 10:00 UTC.\n")
+}
+
 func (s *SnapSuite) TestFindHelloNarrow(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -710,6 +710,7 @@ func (x *infoCmd) Execute([]string) error {
 			iw.setupDiskSnap(norm(snapName), diskSnap)
 		} else {
 			remoteSnap, resInfo, _ := x.client.FindOne(snap.InstanceSnap(snapName))
+			maybePrintCachedNotice(resInfo, x.fmtTime)
 			localSnap, _, _ := x.client.Snap(snapName)
 			iw.setupSnap(localSnap, remoteSnap, resInfo)
 		}
@@ -42,6 +42,8 @@ type cmdPrepareImage struct {
 	// TODO: introduce SnapWithChannel?
 	Snaps      []string `long:"snap" value-name:"<snap>[=<channel>]"`
 	ExtraSnaps []string `long:"extra-snaps" hidden:"yes"` // DEPRECATED
+
+	Revisions string `long:"revisions" value-name:"<manifest>"`
 }
 
 func init() {
@@ -54,7 +56,10 @@ creating device images.
 For core images it is not invoked directly but usually via
 ubuntu-image.
 
-For preparing classic images it supports a --classic mode`),
+For preparing classic images it supports a --classic mode.
+
+The exact revisions of the snaps put into the image are recorded in a
+seed.manifest file, passing it back via --revisions reproduces them.`),
 		func() flags.Commander { return &cmdPrepareImage{} },
 		map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -67,6 +72,8 @@ For preparing classic images it supports a --classic mode`),
 			"extra-snaps": i18n.G("Extra snaps to be installed (DEPRECATED)"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"channel": i18n.G("The channel to use"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"revisions": i18n.G("Use the exact snap revisions listed in the given seed manifest"),
 		}, []argDesc{
 			{
 				// TRANSLATORS: This needs to begin with < and end with >
@@ -86,10 +93,11 @@ var imagePrepare = image.Prepare
 
 func (x *cmdPrepareImage) Execute(args []string) error {
 	opts := &image.Options{
-		Snaps:        x.ExtraSnaps,
-		ModelFile:    x.Positional.ModelAssertionFn,
-		Channel:      x.Channel,
-		Architecture: x.Architecture,
+		Snaps:         x.ExtraSnaps,
+		ModelFile:     x.Positional.ModelAssertionFn,
+		Channel:       x.Channel,
+		Architecture:  x.Architecture,
+		RevisionsFile: x.Revisions,
 	}
 
 	snaps := make([]string, 0, len(x.Snaps)+len(x.ExtraSnaps))
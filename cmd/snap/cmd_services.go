@@ -20,8 +20,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
@@ -41,6 +43,9 @@ type svcLogs struct {
 	clientMixin
 	N          string `short:"n" default:"10"`
 	Follow     bool   `short:"f"`
+	Since      string `long:"since"`
+	Until      string `long:"until"`
+	Output     string `long:"output" default:"short" choice:"short" choice:"json"`
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -56,6 +61,11 @@ the services in all currently installed snaps.
 	longLogsHelp  = i18n.G(`
 The logs command fetches logs of the given services and displays them in
 chronological order.
+
+The --since and --until options restrict the logs to a time range, given
+either as a timestamp like 2020-09-13T12:26:40Z or as a duration back
+from now like 2h30m. With --output=json each log line is printed as a
+JSON object, suitable for exporting the logs.
 `)
 	shortStartHelp = i18n.G("Start services")
 	longStartHelp  = i18n.G(`
@@ -88,6 +98,12 @@ func init() {
 			"n": i18n.G("Show only the given number of lines, or 'all'."),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"f": i18n.G("Wait for new lines and print them as they come in."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"since": i18n.G("Show only lines logged at or after the given time."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"until": i18n.G("Show only lines logged at or before the given time."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"output": i18n.G("Output format: short (default) or json"),
 		}, argdescs)
 
 	addCommand("start", shortStartHelp, longStartHelp, func() flags.Commander { return &svcStart{} },
@@ -163,19 +179,54 @@ func (s *svcLogs) Execute(args []string) error {
 		}
 		sN = int(n)
 	}
+	since, err := parseLogTime("--since", s.Since)
+	if err != nil {
+		return err
+	}
+	until, err := parseLogTime("--until", s.Until)
+	if err != nil {
+		return err
+	}
 
-	logs, err := s.client.Logs(svcNames(s.Positional.ServiceNames), client.LogOptions{N: sN, Follow: s.Follow})
+	logs, err := s.client.Logs(svcNames(s.Positional.ServiceNames), client.LogOptions{
+		N:      sN,
+		Follow: s.Follow,
+		Since:  since,
+		Until:  until,
+	})
 	if err != nil {
 		return err
 	}
 
+	enc := json.NewEncoder(Stdout)
 	for log := range logs {
+		if s.Output == "json" {
+			if err := enc.Encode(log); err != nil {
+				return err
+			}
+			continue
+		}
 		fmt.Fprintln(Stdout, log)
 	}
 
 	return nil
 }
 
+// parseLogTime parses the value of a time option of snap logs, either a
+// timestamp or a duration back from now.
+func parseLogTime(option, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return timeNow().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf(i18n.G("invalid argument for flag ‘%s’: expected a timestamp like 2020-09-13T12:26:40Z or a duration like 2h30m"), option)
+}
+
 type svcStart struct {
 	waitMixin
 	Positional struct {
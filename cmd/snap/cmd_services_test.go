@@ -294,3 +294,44 @@ func (s *appOpSuite) TestAppStatusNoServices(c *check.C) {
 	// ensure that the fake server api was actually hit
 	c.Check(n, check.Equals, 1)
 }
+
+func (s *appOpSuite) TestLogsSinceUntilJSON(c *check.C) {
+	restore := snap.MockTimeNow(func() time.Time {
+		return time.Date(2020, 9, 13, 14, 26, 40, 0, time.UTC)
+	})
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/logs")
+			q := r.URL.Query()
+			c.Check(q.Get("names"), check.Equals, "foo")
+			c.Check(q.Get("since"), check.Equals, "2020-09-13T12:26:40Z")
+			c.Check(q.Get("until"), check.Equals, "2020-09-13T13:00:00Z")
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e{\"timestamp\":\"2020-09-13T12:30:00Z\",\"message\":\"hello\",\"sid\":\"foo.svc\",\"pid\":\"42\"}\n")
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--since=2h", "--until=2020-09-13T13:00:00Z", "--output=json", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, `{"timestamp":"2020-09-13T12:30:00Z","message":"hello","sid":"foo.svc","pid":"42"}`+"\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *appOpSuite) TestLogsBadSince(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--since=yesterday", "foo"})
+	c.Assert(err, check.ErrorMatches, `invalid argument for flag ‘--since’: expected a timestamp like 2020-09-13T12:26:40Z or a duration like 2h30m`)
+}
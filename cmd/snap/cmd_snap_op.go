@@ -92,6 +92,10 @@ have developer access to the snap, either directly or through the
 store's collaboration feature, and to be logged in (see 'snap help login').
 
 Note a later refresh will typically undo a revision override.
+
+When --cohort is given the name of a cohort created with
+'snap create-cohort --name' and no snaps, all the installed snaps of the
+cohort are refreshed into their cohort together.
 `)
 
 var longTryHelp = i18n.G(`
@@ -670,6 +674,34 @@ func (x *cmdRefresh) refreshMany(snaps []string, opts *client.SnapOptions) error
 	return nil
 }
 
+func (x *cmdRefresh) refreshCohort(name string) error {
+	changeID, err := x.client.RefreshCohort(name)
+	if err != nil {
+		return err
+	}
+
+	chg, err := x.wait(changeID)
+	if err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	var refreshed []string
+	if err := chg.Get("snap-names", &refreshed); err != nil && err != client.ErrNoData {
+		return err
+	}
+
+	if len(refreshed) > 0 {
+		return showDone(x.client, refreshed, "refresh", nil, x.getEscapes())
+	}
+
+	fmt.Fprintf(Stderr, i18n.G("All snaps of cohort %q up to date.\n"), name)
+
+	return nil
+}
+
 func (x *cmdRefresh) refreshOne(name string, opts *client.SnapOptions) error {
 	changeID, err := x.client.Refresh(name, opts)
 	if err != nil {
@@ -796,6 +828,13 @@ func (x *cmdRefresh) Execute([]string) error {
 		return nil
 	}
 
+	if len(x.Positional.Snaps) == 0 && x.Cohort != "" {
+		if x.asksForMode() || x.asksForChannel() || x.LeaveCohort || x.IgnoreValidation || x.Amend || x.Revision != "" {
+			return errors.New(i18n.G("refreshing a named cohort does not take additional flags"))
+		}
+		return x.refreshCohort(x.Cohort)
+	}
+
 	names := installedSnapNames(x.Positional.Snaps)
 	if len(names) == 1 {
 		opts := &client.SnapOptions{
@@ -1109,7 +1148,7 @@ func init() {
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-validation": i18n.G("Ignore validation by other snaps blocking the refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
-			"cohort": i18n.G("Refresh the snap into the given cohort"),
+			"cohort": i18n.G("Refresh the snap into the given cohort, or all the snaps of the given named cohort"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"leave-cohort": i18n.G("Refresh the snap out of its cohort"),
 		}), nil)
@@ -1369,6 +1369,56 @@ func (s *SnapOpSuite) TestRefreshAllModeFlags(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `a single snap name is needed to specify mode or channel flags`)
 }
 
+func (s *SnapOpSuite) TestRefreshNamedCohortNoUpdates(c *check.C) {
+	s.srv.total = 3
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/cohorts")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "refresh",
+			"name":   "fleet-a",
+			"snaps":  nil,
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--cohort=fleet-a"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "All snaps of cohort \"fleet-a\" up to date.\n")
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestRefreshNamedCohort(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/cohorts")
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"snap-names": ["foo"]}}}`)
+		case 2:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo", "status": "active", "version": "1.0", "developer": "bar", "publisher": {"id": "bar-id", "username": "bar", "display-name": "Bar", "validation": "unproven"}, "revision":42, "channel":"stable"}]}`)
+		default:
+			c.Fatalf("expected to get 3 requests, now on %d", n+1)
+		}
+		n++
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--cohort=fleet-a"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo 1.0 from Bar refreshed`)
+	c.Check(n, check.Equals, 3)
+}
+
+func (s *SnapOpSuite) TestRefreshNamedCohortFlagsErr(c *check.C) {
+	for _, flag := range []string{"--channel=edge", "--leave-cohort", "--devmode", "--amend"} {
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--cohort=fleet-a", flag})
+		c.Check(err, check.ErrorMatches, "refreshing a named cohort does not take additional flags", check.Commentf(flag))
+	}
+}
+
 func (s *SnapOpSuite) TestRefreshOneAmend(c *check.C) {
 	s.RedirectClientToTestServer(s.srv.handle)
 	s.srv.checker = func(r *http.Request) {
@@ -50,7 +50,7 @@ func ClientSnapFromSnapInfo(snapInfo *snap.Info) (*client.Snap, error) {
 	}
 	sort.Sort(BySnapApp(snapapps))
 
-	apps, err := ClientAppInfosFromSnapAppInfos(snapapps)
+	apps, err := ClientAppInfosFromSnapAppInfos(snapapps, nil)
 	result := &client.Snap{
 		Description: snapInfo.Description(),
 		Developer:   snapInfo.Publisher.Username,
@@ -125,7 +125,14 @@ func (a BySnapApp) Less(i, j int) bool {
 	return iName < jName
 }
 
-func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) ([]client.AppInfo, error) {
+// UserServicesStatusFunc returns the status of the given user service
+// units, keyed by unit name, as reported by the running user sessions.
+type UserServicesStatusFunc func(units []string) (map[string]*systemd.UnitStatus, error)
+
+// ClientAppInfosFromSnapAppInfos converts the given apps for the client,
+// filling in the status of their services. The status of user daemons is
+// only filled in if userStatus is not nil.
+func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo, userStatus UserServicesStatusFunc) ([]client.AppInfo, error) {
 	// TODO: pass in an actual notifier here instead of null
 	//       (Status doesn't _need_ it, but benefits from it)
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, progress.Null)
@@ -162,9 +169,20 @@ func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) ([]client.AppInfo, err
 			serviceNames = append(serviceNames, timerUnit)
 		}
 
-		// sysd.Status() makes sure that we get only the units we asked
-		// for and raises an error otherwise
-		sts, err := sysd.Status(serviceNames...)
+		var sts []*systemd.UnitStatus
+		var err error
+		switch app.DaemonScope {
+		case snap.UserDaemon:
+			if userStatus == nil {
+				out = append(out, appInfo)
+				continue
+			}
+			sts, err = userUnitsStatus(userStatus, serviceNames)
+		default:
+			// sysd.Status() makes sure that we get only the units we asked
+			// for and raises an error otherwise
+			sts, err = sysd.Status(serviceNames...)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("cannot get status of services of app %q: %v", app.Name, err)
 		}
@@ -197,3 +215,22 @@ func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) ([]client.AppInfo, err
 
 	return out, nil
 }
+
+// userUnitsStatus returns the status of the given user units in order. A
+// unit that no session reports on (e.g. nobody is logged in) is inactive
+// and disabled.
+func userUnitsStatus(userStatus UserServicesStatusFunc, units []string) ([]*systemd.UnitStatus, error) {
+	status, err := userStatus(units)
+	if err != nil {
+		return nil, err
+	}
+	sts := make([]*systemd.UnitStatus, len(units))
+	for i, unit := range units {
+		if st := status[unit]; st != nil {
+			sts[i] = st
+		} else {
+			sts[i] = &systemd.UnitStatus{UnitName: unit}
+		}
+	}
+	return sts, nil
+}
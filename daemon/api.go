@@ -74,6 +74,7 @@ import (
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
+	userclient "github.com/snapcore/snapd/usersession/client"
 )
 
 var api = []*Command{
@@ -527,7 +528,7 @@ func searchStore(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	theStore := getStore(c)
-	ctx := store.WithClientUserAgent(r.Context(), r)
+	ctx, cacheStatus := store.WithResponseCacheStatus(store.WithClientUserAgent(r.Context(), r))
 	found, err := theStore.Find(ctx, &store.Search{
 		Query:    q,
 		Prefix:   prefix,
@@ -581,6 +582,9 @@ func searchStore(c *Command, r *http.Request, user *auth.UserState) Response {
 		SuggestedCurrency: theStore.SuggestedCurrency(),
 		Sources:           []string{"store"},
 	}
+	if cacheStatus.Stale {
+		meta.CachedAt = &cacheStatus.StoredAt
+	}
 
 	return sendStorePackages(route, meta, found)
 }
@@ -594,7 +598,7 @@ func findOne(c *Command, r *http.Request, user *auth.UserState, name string) Res
 	spec := store.SnapSpec{
 		Name: name,
 	}
-	ctx := store.WithClientUserAgent(r.Context(), r)
+	ctx, cacheStatus := store.WithResponseCacheStatus(store.WithClientUserAgent(r.Context(), r))
 	snapInfo, err := theStore.SnapInfo(ctx, spec, user)
 	switch err {
 	case nil:
@@ -611,6 +615,9 @@ func findOne(c *Command, r *http.Request, user *auth.UserState, name string) Res
 		SuggestedCurrency: theStore.SuggestedCurrency(),
 		Sources:           []string{"store"},
 	}
+	if cacheStatus.Stale {
+		meta.CachedAt = &cacheStatus.StoredAt
+	}
 
 	results := make([]*json.RawMessage, 1)
 	data, err := json.Marshal(webify(mapRemote(snapInfo), r.URL.String()))
@@ -1737,7 +1744,7 @@ func getInterfaces(c *Command, r *http.Request, user *auth.UserState) Response {
 }
 
 func getLegacyConnections(c *Command, r *http.Request, user *auth.UserState) Response {
-	connsjson, err := collectConnections(c.d.overlord.InterfaceManager(), collectFilter{})
+	connsjson, _, err := collectConnections(c.d.overlord.InterfaceManager(), collectFilter{})
 	if err != nil {
 		return InternalError("collecting connection information failed: %v", err)
 	}
@@ -2346,7 +2353,7 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		return rsp
 	}
 
-	clientAppInfos, err := cmd.ClientAppInfosFromSnapAppInfos(appInfos)
+	clientAppInfos, err := cmd.ClientAppInfosFromSnapAppInfos(appInfos, userServicesStatus)
 	if err != nil {
 		return InternalError("%v", err)
 	}
@@ -2354,6 +2361,37 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(clientAppInfos, nil)
 }
 
+// userSessionQueryTimeout bounds how long the session agents get to report
+// the status of user services.
+var userSessionQueryTimeout = 5 * time.Second
+
+// userServicesStatus asks the session agents of the logged in users for
+// the status of the given user units. A unit is reported active or enabled
+// if it is so in any of the sessions; sessions that fail to answer are
+// skipped.
+var userServicesStatus = func(units []string) (map[string]*systemd.UnitStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), userSessionQueryTimeout)
+	defer cancel()
+	perSession, err := userclient.New().ServicesStatus(ctx, units)
+	if err != nil {
+		logger.Noticef("cannot get status of user services from all sessions: %v", err)
+	}
+
+	status := make(map[string]*systemd.UnitStatus, len(units))
+	for _, sts := range perSession {
+		for _, st := range sts {
+			cur := status[st.Unit]
+			if cur == nil {
+				cur = &systemd.UnitStatus{UnitName: st.Unit}
+				status[st.Unit] = cur
+			}
+			cur.Enabled = cur.Enabled || st.Enabled
+			cur.Active = cur.Active || st.Active
+		}
+	}
+	return status, nil
+}
+
 func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	n := 10
@@ -2372,10 +2410,26 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 		follow = f
 	}
+	var since, until time.Time
+	if s := query.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequest(`invalid value for since: %q: %v`, s, err)
+		}
+		since = t
+	}
+	if s := query.Get("until"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequest(`invalid value for until: %q: %v`, s, err)
+		}
+		until = t
+	}
 
 	// only services have logs for now
 	opts := appInfoOptions{service: true}
-	appInfos, rsp := appInfosFor(c.d.overlord.State(), strutil.CommaSeparatedList(query.Get("names")), opts)
+	st := c.d.overlord.State()
+	appInfos, rsp := appInfosFor(st, strutil.CommaSeparatedList(query.Get("names")), opts)
 	if rsp != nil {
 		return rsp
 	}
@@ -2388,8 +2442,30 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		serviceNames[i] = appInfo.ServiceName()
 	}
 
+	// services of snaps with a size capped journal log into a
+	// namespace of their own, on systemd supporting namespaces
+	namespaces := false
+	st.Lock()
+	for _, appInfo := range appInfos {
+		settings := snapstate.JournalSettings(st, appInfo.Snap.InstanceName())
+		if settings != nil && settings.MaxSize > 0 {
+			namespaces = true
+			break
+		}
+	}
+	st.Unlock()
+	if namespaces && systemd.EnsureAtLeast(systemd.LogNamespaceMinVersion) != nil {
+		namespaces = false
+	}
+
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, progress.Null)
-	reader, err := sysd.LogReader(serviceNames, n, follow)
+	reader, err := sysd.LogReader(serviceNames, systemd.LogReaderOptions{
+		N:          n,
+		Follow:     follow,
+		Since:      since,
+		Until:      until,
+		Namespaces: namespaces,
+	})
 	if err != nil {
 		return InternalError("cannot get logs: %v", err)
 	}
@@ -22,15 +22,67 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
 var cohortsCmd = &Command{
-	Path: "/v2/cohorts",
-	POST: postCohorts,
+	Path:   "/v2/cohorts",
+	UserOK: true,
+	GET:    getCohorts,
+	POST:   postCohorts,
+}
+
+var snapstateRefreshCohort = snapstate.RefreshCohort
+
+// cohortInfo reports a named cohort and whether the snaps of this
+// device follow its cohort keys.
+func cohortInfo(st *state.State, cohort *snapstate.Cohort) (*client.Cohort, error) {
+	res := &client.Cohort{
+		Name:          cohort.Name,
+		Created:       cohort.Created,
+		RefreshPolicy: string(cohort.RefreshPolicy),
+		Snaps:         make([]client.CohortSnap, 0, len(cohort.Keys)),
+	}
+	for _, name := range cohort.Snaps() {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, name, &snapst); err != nil && err != state.ErrNoState {
+			return nil, err
+		}
+		res.Snaps = append(res.Snaps, client.CohortSnap{
+			Name:      name,
+			CohortKey: cohort.Keys[name],
+			Installed: snapst.IsInstalled(),
+			Following: snapst.IsInstalled() && snapst.CohortKey == cohort.Keys[name],
+		})
+	}
+	return res, nil
+}
+
+func getCohorts(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	cohorts, err := snapstate.Cohorts(st)
+	if err != nil {
+		return InternalError("cannot list cohorts: %v", err)
+	}
+	res := make([]*client.Cohort, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		info, err := cohortInfo(st, cohort)
+		if err != nil {
+			return InternalError("cannot list cohorts: %v", err)
+		}
+		res = append(res, info)
+	}
+	return SyncResponse(res, nil)
 }
 
 func postCohorts(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -43,7 +95,16 @@ func postCohorts(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("spurious content after cohort instruction")
 	}
 
-	if inst.Action != "create" {
+	switch inst.Action {
+	case "create":
+		if inst.Name != "" {
+			return createNamedCohort(c, &inst)
+		}
+	case "remove":
+		return removeNamedCohort(c, &inst)
+	case "refresh":
+		return refreshNamedCohort(c, &inst, user)
+	default:
 		return BadRequest("unknown cohort action %q", inst.Action)
 	}
 
@@ -58,3 +119,62 @@ func postCohorts(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 	return SyncResponse(cohorts, nil)
 }
+
+func createNamedCohort(c *Command, inst *client.CohortAction) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	cohort, err := snapstate.CreateCohort(context.TODO(), st, inst.Name, inst.Snaps, snapstate.CohortRefreshPolicy(inst.RefreshPolicy))
+	if err != nil {
+		return BadRequest("cannot create cohort: %v", err)
+	}
+	info, err := cohortInfo(st, cohort)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	return SyncResponse(info, nil)
+}
+
+func removeNamedCohort(c *Command, inst *client.CohortAction) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := snapstate.RemoveCohort(st, inst.Name); err != nil {
+		if _, ok := err.(*snapstate.CohortNotFoundError); ok {
+			return NotFound("%v", err)
+		}
+		return InternalError("%v", err)
+	}
+	return SyncResponse(nil, nil)
+}
+
+func refreshNamedCohort(c *Command, inst *client.CohortAction, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var userID int
+	if user != nil {
+		userID = user.ID
+	}
+	updated, tasksets, err := snapstateRefreshCohort(st, inst.Name, userID, nil)
+	if err != nil {
+		if _, ok := err.(*snapstate.CohortNotFoundError); ok {
+			return NotFound("%v", err)
+		}
+		return errToResponse(err, nil, BadRequest, "cannot refresh cohort: %v")
+	}
+
+	msg := fmt.Sprintf(i18n.G("Refresh snaps of cohort %q: no updates"), inst.Name)
+	if len(updated) > 0 {
+		msg = fmt.Sprintf(i18n.G("Refresh snaps of cohort %q"), inst.Name)
+	}
+	chg := newChange(st, "refresh-cohort", msg, tasksets, updated)
+	chg.Set("api-data", map[string]interface{}{"snap-names": updated, "cohort": inst.Name})
+
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
@@ -27,10 +27,13 @@ import (
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store/storetest"
 )
 
@@ -38,6 +41,7 @@ var _ = check.Suite(&cohortSuite{})
 
 type cohortSuite struct {
 	storetest.Store
+	o *overlord.Overlord
 	d *daemon.Daemon
 
 	snaps []string
@@ -55,10 +59,10 @@ func (s *cohortSuite) SetUpTest(c *check.C) {
 	s.coh = nil
 	s.err = nil
 
-	o := overlord.Mock()
-	s.d = daemon.NewWithOverlord(o)
+	s.o = overlord.Mock()
+	s.d = daemon.NewWithOverlord(s.o)
 
-	st := o.State()
+	st := s.o.State()
 	st.Lock()
 	defer st.Unlock()
 	snapstate.ReplaceStore(st, s)
@@ -143,3 +147,125 @@ func (s *cohortSuite) TestCreateBadBody2(c *check.C) {
 		Result: &daemon.ErrorResult{Message: `spurious content after cohort instruction`},
 	})
 }
+
+func (s *cohortSuite) TestCreateNamedCohort(c *check.C) {
+	s.coh = map[string]string{
+		"foo": "cohort for foo",
+		"bar": "cohort for bar",
+	}
+
+	req, err := http.NewRequest("POST", "/v2/cohorts", strings.NewReader(`{"action": "create", "name": "fleet-a", "refresh-policy": "pinned", "snaps": ["foo","bar"]}`))
+	c.Assert(err, check.IsNil)
+
+	rsp := daemon.CohortsCmd.POST(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Assert(rsp.Status, check.Equals, 200, check.Commentf("%v", rsp.Result))
+	cohort := rsp.Result.(*client.Cohort)
+	c.Check(cohort.Name, check.Equals, "fleet-a")
+	c.Check(cohort.RefreshPolicy, check.Equals, "pinned")
+	c.Check(cohort.Snaps, check.DeepEquals, []client.CohortSnap{
+		{Name: "bar", CohortKey: "cohort for bar"},
+		{Name: "foo", CohortKey: "cohort for foo"},
+	})
+
+	// names are unique
+	req, err = http.NewRequest("POST", "/v2/cohorts", strings.NewReader(`{"action": "create", "name": "fleet-a", "snaps": ["foo"]}`))
+	c.Assert(err, check.IsNil)
+	rsp = daemon.CohortsCmd.POST(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result, check.DeepEquals, &daemon.ErrorResult{Message: `cannot create cohort: cohort "fleet-a" already exists`})
+}
+
+func (s *cohortSuite) TestGetCohorts(c *check.C) {
+	s.coh = map[string]string{
+		"foo": "cohort for foo",
+		"bar": "cohort for bar",
+	}
+	st := s.o.State()
+	st.Lock()
+	_, err := snapstate.CreateCohort(context.Background(), st, "fleet-a", []string{"foo", "bar"}, "")
+	c.Assert(err, check.IsNil)
+	for name, key := range map[string]string{"foo": "cohort for foo", "bar": "some other cohort"} {
+		snapstate.Set(st, name, &snapstate.SnapState{
+			Active:    true,
+			Sequence:  []*snap.SideInfo{{RealName: name, Revision: snap.R(1)}},
+			Current:   snap.R(1),
+			CohortKey: key,
+		})
+	}
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/cohorts", nil)
+	c.Assert(err, check.IsNil)
+	rsp := daemon.CohortsCmd.GET(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	cohorts := rsp.Result.([]*client.Cohort)
+	c.Assert(cohorts, check.HasLen, 1)
+	c.Check(cohorts[0].Name, check.Equals, "fleet-a")
+	c.Check(cohorts[0].RefreshPolicy, check.Equals, "auto")
+	c.Check(cohorts[0].Snaps, check.DeepEquals, []client.CohortSnap{
+		{Name: "bar", CohortKey: "cohort for bar", Installed: true, Following: false},
+		{Name: "foo", CohortKey: "cohort for foo", Installed: true, Following: true},
+	})
+}
+
+func (s *cohortSuite) TestRemoveCohort(c *check.C) {
+	s.coh = map[string]string{"foo": "cohort for foo"}
+	st := s.o.State()
+	st.Lock()
+	_, err := snapstate.CreateCohort(context.Background(), st, "fleet-a", []string{"foo"}, "")
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/cohorts", strings.NewReader(`{"action": "remove", "name": "fleet-a"}`))
+	c.Assert(err, check.IsNil)
+	rsp := daemon.CohortsCmd.POST(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Check(rsp.Status, check.Equals, 200)
+
+	st.Lock()
+	cohorts, err := snapstate.Cohorts(st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(cohorts, check.HasLen, 0)
+
+	req, err = http.NewRequest("POST", "/v2/cohorts", strings.NewReader(`{"action": "remove", "name": "fleet-a"}`))
+	c.Assert(err, check.IsNil)
+	rsp = daemon.CohortsCmd.POST(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result, check.DeepEquals, &daemon.ErrorResult{Message: `cannot find cohort "fleet-a"`})
+}
+
+func (s *cohortSuite) TestRefreshCohort(c *check.C) {
+	restore := daemon.MockSnapstateRefreshCohort(func(st *state.State, name string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		c.Check(name, check.Equals, "fleet-a")
+		if flags != nil {
+			c.Check(*flags, check.DeepEquals, snapstate.Flags{})
+		}
+		t := st.NewTask("fake-refresh", "...")
+		return []string{"bar", "foo"}, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/cohorts", strings.NewReader(`{"action": "refresh", "name": "fleet-a"}`))
+	c.Assert(err, check.IsNil)
+	rsp := daemon.CohortsCmd.POST(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Assert(rsp.Status, check.Equals, 202)
+
+	st := s.o.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Meta.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "refresh-cohort")
+	c.Check(chg.Summary(), check.Equals, `Refresh snaps of cohort "fleet-a"`)
+	var snapNames []string
+	c.Assert(chg.Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"bar", "foo"})
+}
+
+func (s *cohortSuite) TestRefreshCohortNotFound(c *check.C) {
+	req, err := http.NewRequest("POST", "/v2/cohorts", strings.NewReader(`{"action": "refresh", "name": "fleet-a"}`))
+	c.Assert(err, check.IsNil)
+	rsp := daemon.CohortsCmd.POST(daemon.CohortsCmd, req, nil).(*daemon.Resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result, check.DeepEquals, &daemon.ErrorResult{Message: `cannot find cohort "fleet-a"`})
+}
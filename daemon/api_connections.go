@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var connectionsCmd = &Command{
@@ -102,7 +103,9 @@ func mergeAttrs(one map[string]interface{}, other map[string]interface{}) map[st
 	return merged
 }
 
-func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFilter) (*connectionsJSON, error) {
+// collectConnections returns the plugs, slots and connections matching the
+// filter, together with the connection states they were built from.
+func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFilter) (*connectionsJSON, map[string]ifacestate.ConnectionState, error) {
 	repo := ifaceMgr.Repository()
 	ifaces := repo.Interfaces()
 
@@ -114,7 +117,7 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 	var err error
 	connStates, err = ifaceMgr.ConnectionStates()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	connsjson.Established = make([]connectionJSON, 0, len(connStates))
@@ -132,7 +135,7 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 
 		cref, err := interfaces.ParseConnRef(crefStr)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// plug or slot not in the repository, e.g. cref is referring to an
@@ -228,7 +231,96 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 		}
 		connsjson.Slots = append(connsjson.Slots, sj)
 	}
-	return &connsjson, nil
+	return &connsjson, connStates, nil
+}
+
+// collectConnectionsGraph turns the plugs, slots and connections collected by
+// collectConnections into a graph with a node for each snap and an edge for
+// each connection. The connection states must be the ones returned by
+// collectConnections along with connsjson.
+func collectConnectionsGraph(repo *interfaces.Repository, connsjson *connectionsJSON, connStates map[string]ifacestate.ConnectionState) *connectionsGraphJSON {
+	nodes := make(map[string]*connectionGraphNodeJSON)
+	node := func(snapName string) *connectionGraphNodeJSON {
+		n := nodes[snapName]
+		if n == nil {
+			n = &connectionGraphNodeJSON{Snap: snapName}
+			nodes[snapName] = n
+		}
+		return n
+	}
+	addPlug := func(ref interfaces.PlugRef) {
+		n := node(ref.Snap)
+		if !strutil.ListContains(n.Plugs, ref.Name) {
+			n.Plugs = append(n.Plugs, ref.Name)
+		}
+	}
+	addSlot := func(ref interfaces.SlotRef) {
+		n := node(ref.Snap)
+		if !strutil.ListContains(n.Slots, ref.Name) {
+			n.Slots = append(n.Slots, ref.Name)
+		}
+	}
+	for _, plug := range connsjson.Plugs {
+		addPlug(interfaces.PlugRef{Snap: plug.Snap, Name: plug.Name})
+	}
+	for _, slot := range connsjson.Slots {
+		addSlot(interfaces.SlotRef{Snap: slot.Snap, Name: slot.Name})
+	}
+
+	graph := &connectionsGraphJSON{
+		Edges: make([]connectionGraphEdgeJSON, 0, len(connsjson.Established)+len(connsjson.Undesired)),
+	}
+	addEdges := func(conns []connectionJSON) {
+		for _, cj := range conns {
+			cref := interfaces.ConnRef{PlugRef: cj.Plug, SlotRef: cj.Slot}
+			cstate := connStates[cref.ID()]
+			edge := connectionGraphEdgeJSON{
+				Plug:             cj.Plug,
+				Slot:             cj.Slot,
+				Interface:        cj.Interface,
+				Auto:             cstate.Auto,
+				Gadget:           cstate.ByGadget,
+				Undesired:        cstate.Undesired,
+				PlugStaticAttrs:  cstate.StaticPlugAttrs,
+				PlugDynamicAttrs: cstate.DynamicPlugAttrs,
+				SlotStaticAttrs:  cstate.StaticSlotAttrs,
+				SlotDynamicAttrs: cstate.DynamicSlotAttrs,
+			}
+			if slot := repo.Slot(cj.Slot.Snap, cj.Slot.Name); slot != nil {
+				edge.HotplugKey = string(slot.HotplugKey)
+			}
+			// the repository carries the dynamic attributes that are in
+			// effect for established connections
+			if !cstate.Undesired {
+				if conn, err := repo.Connection(&cref); err == nil {
+					edge.PlugDynamicAttrs = conn.Plug.DynamicAttrs()
+					edge.SlotDynamicAttrs = conn.Slot.DynamicAttrs()
+				}
+			}
+			// with a snap filter the other end of a connection may
+			// not have been collected, make sure both ends are
+			// declared
+			addPlug(cj.Plug)
+			addSlot(cj.Slot)
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	addEdges(connsjson.Established)
+	addEdges(connsjson.Undesired)
+
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	graph.Nodes = make([]connectionGraphNodeJSON, 0, len(names))
+	for _, name := range names {
+		n := nodes[name]
+		sort.Strings(n.Plugs)
+		sort.Strings(n.Slots)
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+	return graph
 }
 
 type byCrefConnJSON []connectionJSON
@@ -261,6 +353,10 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 		return BadRequest("unsupported select qualifier")
 	}
 	onlyConnected := qselect == ""
+	view := query.Get("view")
+	if view != "graph" && view != "" {
+		return BadRequest("unsupported view %q", view)
+	}
 
 	snapName = ifacestate.RemapSnapFromRequest(snapName)
 	if snapName != "" {
@@ -272,7 +368,8 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 		}
 	}
 
-	connsjson, err := collectConnections(c.d.overlord.InterfaceManager(), collectFilter{
+	ifaceMgr := c.d.overlord.InterfaceManager()
+	connsjson, connStates, err := collectConnections(ifaceMgr, collectFilter{
 		snapName:  snapName,
 		ifaceName: ifaceName,
 		connected: onlyConnected,
@@ -283,5 +380,10 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 	sort.Sort(byCrefConnJSON(connsjson.Established))
 	sort.Sort(byCrefConnJSON(connsjson.Undesired))
 
+	if view == "graph" {
+		graph := collectConnectionsGraph(ifaceMgr.Repository(), connsjson, connStates)
+		return SyncResponse(graph, nil)
+	}
+
 	return SyncResponse(connsjson, nil)
 }
@@ -1043,3 +1043,208 @@ slots:
 		"type":        "sync",
 	})
 }
+
+func (s *apiSuite) TestConnectionsUnsupportedView(c *check.C) {
+	s.daemon(c)
+	req, err := http.NewRequest("GET", "/v2/connections?view=tree", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	connectionsCmd.GET(connectionsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 400)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, map[string]interface{}{
+		"message": `unsupported view "tree"`,
+	})
+}
+
+func (s *apiSuite) TestConnectionsGraphEmpty(c *check.C) {
+	s.daemon(c)
+	s.testConnections(c, "/v2/connections?view=graph", map[string]interface{}{
+		"result": map[string]interface{}{
+			"nodes": []interface{}{},
+			"edges": []interface{}{},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	})
+}
+
+func (s *apiSuite) TestConnectionsGraph(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	repo := s.d.overlord.InterfaceManager().Repository()
+	slot := repo.Slot("producer", "slot")
+	c.Assert(slot, check.NotNil)
+	slot.HotplugKey = "1234"
+
+	// the repository has newer dynamic attributes than the state
+	cref := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	_, err := repo.Connect(cref,
+		map[string]interface{}{"key": "value"},
+		map[string]interface{}{"foo-plug-dynamic": "repo-dynamic"},
+		map[string]interface{}{"key": "value"},
+		map[string]interface{}{"foo-slot-dynamic": "repo-dynamic"},
+		nil)
+	c.Assert(err, check.IsNil)
+
+	st := s.d.overlord.State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":   "test",
+			"auto":        true,
+			"hotplug-key": "1234",
+			"plug-static": map[string]interface{}{
+				"key": "value",
+			},
+			"plug-dynamic": map[string]interface{}{
+				"foo-plug-dynamic": "state-dynamic",
+			},
+			"slot-static": map[string]interface{}{
+				"key": "value",
+			},
+		},
+	})
+	st.Unlock()
+
+	s.testConnections(c, "/v2/connections?view=graph", map[string]interface{}{
+		"result": map[string]interface{}{
+			"nodes": []interface{}{
+				map[string]interface{}{
+					"snap":  "consumer",
+					"plugs": []interface{}{"plug"},
+				},
+				map[string]interface{}{
+					"snap":  "producer",
+					"slots": []interface{}{"slot"},
+				},
+			},
+			"edges": []interface{}{
+				map[string]interface{}{
+					"plug":        map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"slot":        map[string]interface{}{"snap": "producer", "slot": "slot"},
+					"interface":   "test",
+					"auto":        true,
+					"hotplug-key": "1234",
+					"plug-static": map[string]interface{}{
+						"key": "value",
+					},
+					"plug-dynamic": map[string]interface{}{
+						"foo-plug-dynamic": "repo-dynamic",
+					},
+					"slot-static": map[string]interface{}{
+						"key": "value",
+					},
+					"slot-dynamic": map[string]interface{}{
+						"foo-slot-dynamic": "repo-dynamic",
+					},
+				},
+			},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	})
+}
+
+func (s *apiSuite) TestConnectionsGraphUndesired(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.testConnectionsConnected(c, "/v2/connections?select=all&view=graph", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"by-gadget": true,
+			"auto":      true,
+			"undesired": true,
+		},
+	}, nil, map[string]interface{}{
+		"result": map[string]interface{}{
+			"nodes": []interface{}{
+				map[string]interface{}{
+					"snap":  "consumer",
+					"plugs": []interface{}{"plug"},
+				},
+				map[string]interface{}{
+					"snap":  "producer",
+					"slots": []interface{}{"slot"},
+				},
+			},
+			"edges": []interface{}{
+				map[string]interface{}{
+					"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+					"interface": "test",
+					"auto":      true,
+					"gadget":    true,
+					"undesired": true,
+				},
+			},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	})
+}
+
+func (s *apiSuite) TestConnectionsGraphBySnapUndesiredPeer(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	// the producer slot is not collected when filtering by the consumer
+	// snap, as undesired connections do not pull in their peers
+	s.testConnectionsConnected(c, "/v2/connections?snap=consumer&select=all&view=graph", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"auto":      true,
+			"undesired": true,
+		},
+	}, nil, map[string]interface{}{
+		"result": map[string]interface{}{
+			"nodes": []interface{}{
+				map[string]interface{}{
+					"snap":  "consumer",
+					"plugs": []interface{}{"plug"},
+				},
+				map[string]interface{}{
+					"snap":  "producer",
+					"slots": []interface{}{"slot"},
+				},
+			},
+			"edges": []interface{}{
+				map[string]interface{}{
+					"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+					"interface": "test",
+					"auto":      true,
+					"undesired": true,
+				},
+			},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	})
+}
@@ -51,8 +51,22 @@ type debugAction struct {
 }
 
 type ConnectivityStatus struct {
-	Connectivity bool     `json:"connectivity"`
-	Unreachable  []string `json:"unreachable,omitempty"`
+	Connectivity bool             `json:"connectivity"`
+	Unreachable  []string         `json:"unreachable,omitempty"`
+	Endpoints    []EndpointStatus `json:"endpoints,omitempty"`
+}
+
+// EndpointStatus describes the health of a store endpoint.
+type EndpointStatus struct {
+	URL string `json:"url"`
+	// Service is "assertions" for the endpoints of a separate
+	// assertions service
+	Service string `json:"service,omitempty"`
+	// Status is one of "ok", "failing" or "backoff"
+	Status       string     `json:"status"`
+	Failures     int        `json:"failures,omitempty"`
+	LastError    string     `json:"last-error,omitempty"`
+	BackoffUntil *time.Time `json:"backoff-until,omitempty"`
 }
 
 func getBaseDeclaration(st *state.State) Response {
@@ -82,6 +96,25 @@ func checkConnectivity(st *state.State) Response {
 	}
 	sort.Strings(status.Unreachable)
 
+	for _, ep := range theStore.EndpointsStatus() {
+		epStatus := EndpointStatus{
+			URL:       ep.URL,
+			Service:   ep.Service,
+			Status:    "ok",
+			Failures:  ep.Failures,
+			LastError: ep.LastError,
+		}
+		if ep.Failures > 0 {
+			epStatus.Status = "failing"
+		}
+		if !ep.BackoffUntil.IsZero() {
+			backoffUntil := ep.BackoffUntil
+			epStatus.Status = "backoff"
+			epStatus.BackoffUntil = &backoffUntil
+		}
+		status.Endpoints = append(status.Endpoints, epStatus)
+	}
+
 	return SyncResponse(status, nil)
 }
 
@@ -263,9 +296,29 @@ func getChangeTimings(st *state.State, changeID, ensureTag, startupTag string, a
 	return SyncResponse(responseData, nil)
 }
 
+// rootOnlyDebugAspects are the debug aspects exposing information about
+// all the snaps of the system that unprivileged users cannot read
+// otherwise, the debug endpoint itself being readable by all users.
+var rootOnlyDebugAspects = map[string]bool{
+	"denials": true,
+}
+
 func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	aspect := query.Get("aspect")
+	if rootOnlyDebugAspects[aspect] {
+		if _, uid, _, err := ucrednetGet(r.RemoteAddr); err != nil || uid != 0 {
+			return Forbidden("access denied")
+		}
+	}
+	if aspect == "denials" {
+		// reading the journal can take a while and doesn't need the state
+		return getDenials(query.Get("snap"))
+	}
+	if aspect == "cache" {
+		// the download cache is not tracked in the state
+		return getDownloadCache()
+	}
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
@@ -290,6 +343,12 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getChangeTimings(st, chgID, ensureTag, startupTag, all == "true")
 	case "seeding":
 		return getSeedingInfo(st)
+	case "sandbox-profile":
+		app := query.Get("app")
+		if app == "" {
+			return BadRequest("sandbox-profile requires an app")
+		}
+		return getSandboxProfile(st, c.d.overlord.InterfaceManager().Repository(), app)
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
@@ -322,6 +381,8 @@ func postDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return SyncResponse(devicestate.CanManageRefreshes(st), nil)
 	case "connectivity":
 		return checkConnectivity(st)
+	case "clean-cache":
+		return cleanDownloadCache()
 	case "prune":
 		opTime, err := c.d.overlord.DeviceManager().StartOfOperationTime()
 		if err != nil {
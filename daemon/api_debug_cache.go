@@ -0,0 +1,70 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/store"
+)
+
+// CacheEntry describes an entry of the store download cache.
+type CacheEntry struct {
+	Digest   string    `json:"digest"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last-used"`
+	InUse    bool      `json:"in-use,omitempty"`
+}
+
+func downloadCache() *store.CacheManager {
+	// the limits do not matter for inspecting and cleaning
+	return store.NewCacheManager(dirs.SnapDownloadCacheDir, 0)
+}
+
+// getDownloadCache lists the entries of the download cache, most
+// recently used first.
+func getDownloadCache() Response {
+	entries, err := downloadCache().Entries()
+	if err != nil {
+		return InternalError("cannot list download cache: %v", err)
+	}
+	result := make([]CacheEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = CacheEntry{
+			Digest:   entry.Digest,
+			Size:     entry.Size,
+			LastUsed: entry.LastUsed,
+			InUse:    entry.InUse,
+		}
+	}
+	return SyncResponse(result, nil)
+}
+
+// cleanDownloadCache empties the download cache.
+func cleanDownloadCache() Response {
+	freed, err := downloadCache().Clean()
+	if err != nil {
+		return InternalError("cannot clean download cache: %v", err)
+	}
+	return SyncResponse(map[string]interface{}{
+		"freed": freed,
+	}, nil)
+}
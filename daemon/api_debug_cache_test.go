@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+func (s *apiSuite) TestGetDebugCache(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=cache", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []CacheEntry{})
+
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), check.IsNil)
+	older := filepath.Join(dirs.SnapDownloadCacheDir, "digest-older")
+	c.Assert(ioutil.WriteFile(older, []byte("older"), 0600), check.IsNil)
+	newer := filepath.Join(dirs.SnapDownloadCacheDir, "digest-newer")
+	c.Assert(ioutil.WriteFile(newer, []byte("newer content"), 0600), check.IsNil)
+	// the newer one is an installed snap too
+	c.Assert(os.Link(newer, filepath.Join(c.MkDir(), "installed.snap")), check.IsNil)
+	t0 := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	c.Assert(os.Chtimes(older, t0, t0), check.IsNil)
+	c.Assert(os.Chtimes(newer, t0.Add(time.Hour), t0.Add(time.Hour)), check.IsNil)
+
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Assert(rsp.Result, check.HasLen, 2)
+	entries := rsp.Result.([]CacheEntry)
+	c.Check(entries[0].Digest, check.Equals, "digest-newer")
+	c.Check(entries[0].Size, check.Equals, int64(13))
+	c.Check(entries[0].LastUsed.Equal(t0.Add(time.Hour)), check.Equals, true)
+	c.Check(entries[0].InUse, check.Equals, true)
+	c.Check(entries[1].Digest, check.Equals, "digest-older")
+	c.Check(entries[1].InUse, check.Equals, false)
+}
+
+func (s *apiSuite) TestPostDebugCleanCache(c *check.C) {
+	s.daemon(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), check.IsNil)
+	for _, name := range []string{"digest-1", "digest-2"} {
+		c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapDownloadCacheDir, name), []byte("content"), 0600), check.IsNil)
+	}
+
+	req, err := http.NewRequest("POST", "/v2/debug", bytes.NewBufferString(`{"action": "clean-cache"}`))
+	c.Assert(err, check.IsNil)
+	rsp := postDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, map[string]interface{}{"freed": int64(14)})
+
+	files, err := ioutil.ReadDir(dirs.SnapDownloadCacheDir)
+	c.Assert(err, check.IsNil)
+	c.Check(files, check.HasLen, 0)
+}
@@ -0,0 +1,50 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"github.com/snapcore/snapd/sandbox/denials"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+)
+
+var systemdKernelLogReader = systemd.KernelLogReader
+
+// getDenials summarises the apparmor and seccomp denials logged since boot
+// for the given snap, or for all snaps if snapName is empty.
+func getDenials(snapName string) Response {
+	if snapName != "" {
+		if err := snap.ValidateInstanceName(snapName); err != nil {
+			return BadRequest("invalid snap name: %v", err)
+		}
+	}
+
+	rc, err := systemdKernelLogReader(-1)
+	if err != nil {
+		return InternalError("cannot read kernel log: %v", err)
+	}
+	defer rc.Close()
+
+	summaries, err := denials.Collect(rc, snapName)
+	if err != nil {
+		return InternalError("cannot collect denials: %v", err)
+	}
+	return SyncResponse(summaries, nil)
+}
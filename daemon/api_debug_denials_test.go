@@ -0,0 +1,102 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+func (s *apiSuite) mockKernelLog(c *check.C, log string, err error) {
+	old := systemdKernelLogReader
+	systemdKernelLogReader = func(n int) (io.ReadCloser, error) {
+		c.Check(n, check.Equals, -1)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(strings.NewReader(log)), nil
+	}
+	s.AddCleanup(func() { systemdKernelLogReader = old })
+}
+
+func (s *apiSuite) getDenials(c *check.C, query string) *resp {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=denials"+query, nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+	return getDebug(debugCmd, req, nil).(*resp)
+}
+
+func (s *apiSuite) TestGetDebugDenials(c *check.C) {
+	s.daemon(c)
+	s.mockKernelLog(c, `{"__REALTIME_TIMESTAMP":"1593452237000000","MESSAGE":"apparmor=\"DENIED\" operation=\"open\" profile=\"snap.foo.bar\" name=\"/dev/video0\" pid=1 comm=\"bar\""}
+{"__REALTIME_TIMESTAMP":"1593452238000000","MESSAGE":"apparmor=\"ALLOWED\" operation=\"open\" profile=\"snap.other.app\" name=\"/var/log/syslog\" pid=2 comm=\"app\""}
+`, nil)
+
+	rsp := s.getDenials(c, "&snap=foo")
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*denials.Summary{{
+		Snap:      "foo",
+		App:       "bar",
+		Backend:   "apparmor",
+		Operation: "open",
+		Target:    "/dev/video0",
+		Interface: "camera",
+		Count:     1,
+		LastSeen:  time.Unix(1593452237, 0).UTC(),
+	}})
+
+	rsp = s.getDenials(c, "")
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.HasLen, 2)
+}
+
+func (s *apiSuite) TestGetDebugDenialsErrors(c *check.C) {
+	s.daemon(c)
+	s.mockKernelLog(c, "", errors.New("boom"))
+
+	rsp := s.getDenials(c, "&snap=Foo")
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, `invalid snap name: .*`)
+
+	rsp = s.getDenials(c, "&snap=foo")
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot read kernel log: boom")
+}
+
+func (s *apiSuite) TestGetDebugDenialsRootOnly(c *check.C) {
+	s.daemon(c)
+	s.mockKernelLog(c, "", errors.New("should not be read"))
+
+	for _, remoteAddr := range []string{"pid=100;uid=1000;socket=;", ""} {
+		req, err := http.NewRequest("GET", "/v2/debug?aspect=denials", nil)
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = remoteAddr
+		rsp := getDebug(debugCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 403, check.Commentf("%q", remoteAddr))
+	}
+}
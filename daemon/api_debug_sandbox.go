@@ -0,0 +1,161 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/dbus"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// sandboxProfileBackends lists the security backends, in display order,
+// whose rules are reported by the sandbox-profile debug aspect.
+var sandboxProfileBackends = []interfaces.SecuritySystem{
+	interfaces.SecurityAppArmor,
+	interfaces.SecuritySecComp,
+	interfaces.SecurityMount,
+	interfaces.SecurityUDev,
+	interfaces.SecurityKMod,
+	interfaces.SecurityDBus,
+}
+
+// sandboxRulesJSON describes the rules a single plug, slot or connection
+// contributes to the profile of an app in a given security backend.
+type sandboxRulesJSON struct {
+	Backend   string              `json:"backend"`
+	Interface string              `json:"interface"`
+	Plug      *interfaces.PlugRef `json:"plug,omitempty"`
+	Slot      *interfaces.SlotRef `json:"slot,omitempty"`
+	Connected bool                `json:"connected,omitempty"`
+	Rules     []string            `json:"rules"`
+}
+
+type sandboxProfileJSON struct {
+	Snap        string             `json:"snap"`
+	App         string             `json:"app"`
+	SecurityTag string             `json:"security-tag"`
+	Confinement string             `json:"confinement"`
+	Rules       []sandboxRulesJSON `json:"rules"`
+}
+
+// udevTagged returns whether the udev rule tags devices for any app or hook.
+func udevTagged(rule string) bool {
+	return strings.Contains(rule, `TAG+="snap_`) || strings.Contains(rule, `TAG=="snap_`)
+}
+
+// sandboxRules extracts the rules that apply to the given security tag from a
+// backend specification.
+func sandboxRules(spec interfaces.Specification, securityTag string) []string {
+	switch spec := spec.(type) {
+	case *apparmor.Specification:
+		return spec.Snippets()[securityTag]
+	case *seccomp.Specification:
+		return spec.Snippets()[securityTag]
+	case *dbus.Specification:
+		return spec.Snippets()[securityTag]
+	case *udev.Specification:
+		// udev tags use underscores instead of dots
+		tag := strings.Replace(securityTag, ".", "_", -1)
+		var rules []string
+		for _, rule := range spec.Snippets() {
+			if !udevTagged(rule) || strings.Contains(rule, `"`+tag+`"`) {
+				rules = append(rules, rule)
+			}
+		}
+		return rules
+	case *kmod.Specification:
+		var modules []string
+		for module := range spec.Modules() {
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+		return modules
+	case *mount.Specification:
+		// mount entries apply to the whole snap
+		var entries []string
+		for _, entry := range spec.MountEntries() {
+			entries = append(entries, entry.String())
+		}
+		for _, entry := range spec.UserMountEntries() {
+			entries = append(entries, entry.String())
+		}
+		return entries
+	}
+	return nil
+}
+
+func getSandboxProfile(st *state.State, repo *interfaces.Repository, snapApp string) Response {
+	snapName, appName := snap.SplitSnapApp(snapApp)
+	info, err := snapstate.CurrentInfo(st, snapName)
+	if err != nil {
+		return SnapNotFound(snapName, err)
+	}
+	app, ok := info.Apps[appName]
+	if !ok {
+		return AppNotFound("snap %q has no app %q", snapName, appName)
+	}
+
+	known := make(map[interfaces.SecuritySystem]bool)
+	for _, backend := range repo.Backends() {
+		known[backend.Name()] = true
+	}
+
+	profile := &sandboxProfileJSON{
+		Snap:        info.InstanceName(),
+		App:         app.Name,
+		SecurityTag: app.SecurityTag(),
+		Confinement: string(info.Confinement),
+		Rules:       []sandboxRulesJSON{},
+	}
+	for _, system := range sandboxProfileBackends {
+		if !known[system] {
+			continue
+		}
+		fragments, err := repo.SnapSpecificationFragments(system, info.InstanceName())
+		if err != nil {
+			return InternalError("cannot compute %s profile of %q: %v", system, snapApp, err)
+		}
+		for _, frag := range fragments {
+			rules := sandboxRules(frag.Spec, profile.SecurityTag)
+			if len(rules) == 0 {
+				continue
+			}
+			profile.Rules = append(profile.Rules, sandboxRulesJSON{
+				Backend:   string(system),
+				Interface: frag.Interface,
+				Plug:      frag.Plug,
+				Slot:      frag.Slot,
+				Connected: frag.Connected,
+				Rules:     rules,
+			})
+		}
+	}
+	return SyncResponse(profile, nil)
+}
@@ -0,0 +1,133 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *apiSuite) mockSandboxProfile(c *check.C) {
+	d := s.daemon(c)
+	repo := d.overlord.InterfaceManager().Repository()
+	c.Assert(repo.AddBackend(&apparmor.Backend{}), check.IsNil)
+	c.Assert(repo.AddBackend(&seccomp.Backend{}), check.IsNil)
+
+	s.mockIface(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("/dev/test rw,")
+			return nil
+		},
+		AppArmorPermanentSlotCallback: func(spec *apparmor.Specification, slot *snap.SlotInfo) error {
+			spec.AddSnippet("capability test,")
+			return nil
+		},
+		SecCompConnectedPlugCallback: func(spec *seccomp.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("testcall")
+			return nil
+		},
+	})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *apiSuite) getSandboxProfile(c *check.C, app string) *resp {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=sandbox-profile&app="+app, nil)
+	c.Assert(err, check.IsNil)
+	return getDebug(debugCmd, req, nil).(*resp)
+}
+
+func (s *apiSuite) TestGetDebugSandboxProfileConnectedPlug(c *check.C) {
+	s.mockSandboxProfile(c)
+
+	rsp := s.getSandboxProfile(c, "consumer.app")
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &sandboxProfileJSON{
+		Snap:        "consumer",
+		App:         "app",
+		SecurityTag: "snap.consumer.app",
+		Confinement: "strict",
+		Rules: []sandboxRulesJSON{{
+			Backend:   "apparmor",
+			Interface: "test",
+			Plug:      &interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+			Slot:      &interfaces.SlotRef{Snap: "producer", Name: "slot"},
+			Connected: true,
+			Rules:     []string{"/dev/test rw,"},
+		}, {
+			Backend:   "seccomp",
+			Interface: "test",
+			Plug:      &interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+			Slot:      &interfaces.SlotRef{Snap: "producer", Name: "slot"},
+			Connected: true,
+			Rules:     []string{"testcall"},
+		}},
+	})
+}
+
+func (s *apiSuite) TestGetDebugSandboxProfilePermanentSlot(c *check.C) {
+	s.mockSandboxProfile(c)
+
+	rsp := s.getSandboxProfile(c, "producer.app")
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &sandboxProfileJSON{
+		Snap:        "producer",
+		App:         "app",
+		SecurityTag: "snap.producer.app",
+		Confinement: "strict",
+		Rules: []sandboxRulesJSON{{
+			Backend:   "apparmor",
+			Interface: "test",
+			Slot:      &interfaces.SlotRef{Snap: "producer", Name: "slot"},
+			Rules:     []string{"capability test,"},
+		}},
+	})
+}
+
+func (s *apiSuite) TestGetDebugSandboxProfileErrors(c *check.C) {
+	s.mockSandboxProfile(c)
+
+	rsp := s.getSandboxProfile(c, "")
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "sandbox-profile requires an app")
+
+	rsp = s.getSandboxProfile(c, "unknown.app")
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindSnapNotFound)
+
+	rsp = s.getSandboxProfile(c, "consumer.unknown")
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `snap "consumer" has no app "unknown"`)
+}
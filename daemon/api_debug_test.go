@@ -23,10 +23,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -144,6 +146,38 @@ func (s *postDebugSuite) TestGetDebugConnectivityUnhappy(c *check.C) {
 	s.testDebugConnectivityUnhappy(c, false)
 }
 
+func (s *postDebugSuite) TestGetDebugConnectivityEndpoints(c *check.C) {
+	_ = s.daemon(c)
+
+	backoffUntil := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.connectivityResult = map[string]bool{
+		"api.snapcraft.io": true,
+		"eu.proxy.example": false,
+	}
+	s.endpointsStatus = []store.EndpointStatus{
+		{URL: "https://api.snapcraft.io/"},
+		{URL: "https://eu.proxy.example/", Failures: 4, LastError: "503 Service Unavailable", BackoffUntil: backoffUntil},
+		{URL: "https://us.proxy.example/", Failures: 1, LastError: "connection refused"},
+		{URL: "https://assertions.example/", Service: "assertions"},
+	}
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=connectivity", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, ConnectivityStatus{
+		Connectivity: false,
+		Unreachable:  []string{"eu.proxy.example"},
+		Endpoints: []EndpointStatus{
+			{URL: "https://api.snapcraft.io/", Status: "ok"},
+			{URL: "https://eu.proxy.example/", Status: "backoff", Failures: 4, LastError: "503 Service Unavailable", BackoffUntil: &backoffUntil},
+			{URL: "https://us.proxy.example/", Status: "failing", Failures: 1, LastError: "connection refused"},
+			{URL: "https://assertions.example/", Service: "assertions", Status: "ok"},
+		},
+	})
+}
+
 func (s *postDebugSuite) TestGetDebugBaseDeclaration(c *check.C) {
 	_ = s.daemon(c)
 
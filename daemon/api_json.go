@@ -89,3 +89,34 @@ type connectionsJSON struct {
 	Plugs       []*plugJSON      `json:"plugs"`
 	Slots       []*slotJSON      `json:"slots"`
 }
+
+// connectionGraphNodeJSON aids in marshaling a single snap of the connection
+// graph, together with its plugs and slots, into JSON.
+type connectionGraphNodeJSON struct {
+	Snap  string   `json:"snap"`
+	Plugs []string `json:"plugs,omitempty"`
+	Slots []string `json:"slots,omitempty"`
+}
+
+// connectionGraphEdgeJSON aids in marshaling a single connection of the
+// connection graph into JSON. Unlike connectionJSON, the static and dynamic
+// attributes are kept apart.
+type connectionGraphEdgeJSON struct {
+	Slot             interfaces.SlotRef     `json:"slot"`
+	Plug             interfaces.PlugRef     `json:"plug"`
+	Interface        string                 `json:"interface"`
+	Auto             bool                   `json:"auto,omitempty"`
+	Gadget           bool                   `json:"gadget,omitempty"`
+	Undesired        bool                   `json:"undesired,omitempty"`
+	HotplugKey       string                 `json:"hotplug-key,omitempty"`
+	PlugStaticAttrs  map[string]interface{} `json:"plug-static,omitempty"`
+	PlugDynamicAttrs map[string]interface{} `json:"plug-dynamic,omitempty"`
+	SlotStaticAttrs  map[string]interface{} `json:"slot-static,omitempty"`
+	SlotDynamicAttrs map[string]interface{} `json:"slot-dynamic,omitempty"`
+}
+
+// connectionsGraphJSON aids in marshaling the connection graph into JSON.
+type connectionsGraphJSON struct {
+	Nodes []connectionGraphNodeJSON `json:"nodes"`
+	Edges []connectionGraphEdgeJSON `json:"edges"`
+}
@@ -30,6 +30,7 @@ import (
 	"io/ioutil"
 	"math"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -90,6 +91,7 @@ type apiBaseSuite struct {
 	vars              map[string]string
 	storeSearch       store.Search
 	suggestedCurrency string
+	storeCachedAt     time.Time
 	d                 *Daemon
 	user              *auth.UserState
 	ctx               context.Context
@@ -110,6 +112,7 @@ type apiBaseSuite struct {
 	jctlSvcses         [][]string
 	jctlNs             []int
 	jctlFollows        []bool
+	jctlOpts           []systemd.LogReaderOptions
 	jctlRCs            []io.ReadCloser
 	jctlErrs           []error
 
@@ -117,6 +120,7 @@ type apiBaseSuite struct {
 	serviceControlCalls []serviceControlArgs
 
 	connectivityResult     map[string]bool
+	endpointsStatus        []store.EndpointStatus
 	loginUserStoreMacaroon string
 	loginUserDischarge     string
 	userInfoResult         *store.User
@@ -145,6 +149,9 @@ func (s *apiBaseSuite) SnapInfo(ctx context.Context, spec store.SnapSpec, user *
 	s.pokeStateLock()
 	s.user = user
 	s.ctx = ctx
+	if !s.storeCachedAt.IsZero() {
+		store.MarkStaleResponse(ctx, s.storeCachedAt)
+	}
 	if len(s.rsnaps) > 0 {
 		return s.rsnaps[0], s.err
 	}
@@ -157,6 +164,9 @@ func (s *apiBaseSuite) Find(ctx context.Context, search *store.Search, user *aut
 	s.storeSearch = *search
 	s.user = user
 	s.ctx = ctx
+	if !s.storeCachedAt.IsZero() {
+		store.MarkStaleResponse(ctx, s.storeCachedAt)
+	}
 
 	return s.rsnaps, s.err
 }
@@ -208,6 +218,12 @@ func (s *apiBaseSuite) ConnectivityCheck() (map[string]bool, error) {
 	return s.connectivityResult, s.err
 }
 
+func (s *apiBaseSuite) EndpointsStatus() []store.EndpointStatus {
+	s.pokeStateLock()
+
+	return s.endpointsStatus
+}
+
 func (s *apiBaseSuite) LoginUser(username, password, otp string) (string, string, error) {
 	s.pokeStateLock()
 
@@ -251,10 +267,11 @@ func (s *apiBaseSuite) systemctl(args ...string) (buf []byte, err error) {
 	return buf, err
 }
 
-func (s *apiBaseSuite) journalctl(svcs []string, n int, follow bool) (rc io.ReadCloser, err error) {
+func (s *apiBaseSuite) journalctl(svcs []string, opts systemd.LogReaderOptions) (rc io.ReadCloser, err error) {
 	s.jctlSvcses = append(s.jctlSvcses, svcs)
-	s.jctlNs = append(s.jctlNs, n)
-	s.jctlFollows = append(s.jctlFollows, follow)
+	s.jctlNs = append(s.jctlNs, opts.N)
+	s.jctlFollows = append(s.jctlFollows, opts.Follow)
+	s.jctlOpts = append(s.jctlOpts, opts)
 
 	if len(s.jctlErrs) > 0 {
 		err, s.jctlErrs = s.jctlErrs[0], s.jctlErrs[1:]
@@ -271,6 +288,7 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 	s.jctlSvcses = nil
 	s.jctlNs = nil
 	s.jctlFollows = nil
+	s.jctlOpts = nil
 	s.jctlRCs = nil
 	s.jctlErrs = nil
 
@@ -285,8 +303,10 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 
 	s.rsnaps = nil
 	s.suggestedCurrency = ""
+	s.storeCachedAt = time.Time{}
 	s.storeSearch = store.Search{}
 	s.err = nil
+	s.endpointsStatus = nil
 	s.vars = nil
 	s.user = nil
 	s.d = nil
@@ -1920,6 +1940,49 @@ func (s *apiSuite) TestFind(c *check.C) {
 	c.Check(s.actions, check.HasLen, 0)
 }
 
+func (s *apiSuite) TestFindStaleCached(c *check.C) {
+	s.daemon(c)
+
+	s.storeCachedAt = time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)
+	s.rsnaps = []*snap.Info{{
+		SideInfo: snap.SideInfo{
+			RealName: "store",
+		},
+	}}
+
+	req, err := http.NewRequest("GET", "/v2/find?q=hi", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := searchStore(findCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Assert(rsp.CachedAt, check.NotNil)
+	c.Check(rsp.CachedAt.Equal(s.storeCachedAt), check.Equals, true)
+
+	// not set for fresh results
+	s.storeCachedAt = time.Time{}
+	rsp = searchStore(findCmd, req, nil).(*resp)
+	c.Check(rsp.CachedAt, check.IsNil)
+}
+
+func (s *apiSuite) TestFindOneStaleCached(c *check.C) {
+	s.daemon(c)
+
+	s.storeCachedAt = time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)
+	s.rsnaps = []*snap.Info{{
+		SideInfo: snap.SideInfo{
+			RealName: "store",
+		},
+	}}
+
+	req, err := http.NewRequest("GET", "/v2/find?name=store", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := searchStore(findCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Assert(rsp.CachedAt, check.NotNil)
+	c.Check(rsp.CachedAt.Equal(s.storeCachedAt), check.Equals, true)
+}
+
 func (s *apiSuite) TestFindRefreshes(c *check.C) {
 	snapstateRefreshCandidates = snapstate.RefreshCandidates
 	s.daemon(c)
@@ -6968,6 +7031,47 @@ UnitFileState=enabled
 	c.Check(sort.StringsAreSorted(appNames), check.Equals, true)
 }
 
+func (s *appSuite) TestGetAppsInfoUserDaemons(c *check.C) {
+	s.mkInstalledInState(c, s.d, "snap-e", "dev", "v1", snap.R(1), true, "apps: {svc4: {daemon: simple, daemon-scope: user, sockets: {sock: {listen-stream: $XDG_RUNTIME_DIR/sock}}}}")
+
+	// two sessions, the service only runs in one of them
+	for uid, active := range map[int]bool{1000: true, 1001: false} {
+		sock := fmt.Sprintf("%s/%d/snapd-session-agent.socket", dirs.XdgRuntimeDirBase, uid)
+		c.Assert(os.MkdirAll(filepath.Dir(sock), 0700), check.IsNil)
+		l, err := net.Listen("unix", sock)
+		c.Assert(err, check.IsNil)
+		active := active
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.URL.Path, check.Equals, "/v1/service-status")
+			c.Check(r.URL.Query().Get("services"), check.Equals, "snap.snap-e.svc4.service,snap.snap-e.svc4.sock.socket")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"type": "sync", "result": [
+  {"unit": "snap.snap-e.svc4.service", "enabled": false, "active": %v},
+  {"unit": "snap.snap-e.svc4.sock.socket", "enabled": true, "active": true}
+]}`, active)
+		})}
+		go srv.Serve(l)
+		defer srv.Close()
+	}
+
+	req, err := http.NewRequest("GET", "/v2/apps?names=snap-e", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Assert(rsp.Result, check.FitsTypeOf, []client.AppInfo{})
+	c.Check(rsp.Result.([]client.AppInfo), check.DeepEquals, []client.AppInfo{{
+		Snap:    "snap-e",
+		Name:    "svc4",
+		Daemon:  "simple",
+		Active:  true,
+		Enabled: false,
+		Activators: []client.AppActivator{
+			{Name: "sock", Type: "socket", Active: true, Enabled: true},
+		},
+	}})
+}
+
 func (s *appSuite) TestGetAppsInfoBadSelect(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/apps?select=potato", nil)
 	c.Assert(err, check.IsNil)
@@ -7223,6 +7327,84 @@ func (s *appSuite) TestLogsBadFollow(c *check.C) {
 	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
 }
 
+func (s *appSuite) TestLogsSinceUntil(c *check.C) {
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a.svc1&since=2020-09-13T12:26:40Z&until=2020-09-13T13:26:40Z", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	c.Assert(s.jctlOpts, check.HasLen, 1)
+	c.Check(s.jctlOpts[0].Since.Equal(time.Unix(1600000000, 0)), check.Equals, true)
+	c.Check(s.jctlOpts[0].Until.Equal(time.Unix(1600003600, 0)), check.Equals, true)
+	c.Check(s.jctlOpts[0].Namespaces, check.Equals, false)
+}
+
+func (s *appSuite) TestLogsBadSinceUntil(c *check.C) {
+	for _, q := range []string{"since=yesterday", "until=1h"} {
+		req, err := http.NewRequest("GET", "/v2/logs?"+q, nil)
+		c.Assert(err, check.IsNil)
+
+		rsp := getLogs(logsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	}
+}
+
+func (s *appSuite) TestLogsNamespaces(c *check.C) {
+	restore := systemd.MockSystemdVersion(245, nil)
+	defer restore()
+
+	st := s.d.overlord.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("snap-a", "logs.max-size", "10MB"), check.IsNil)
+	tr.Commit()
+	st.Unlock()
+
+	s.jctlRCs = []io.ReadCloser{
+		ioutil.NopCloser(strings.NewReader("")),
+		ioutil.NopCloser(strings.NewReader("")),
+	}
+
+	rec := httptest.NewRecorder()
+	for _, names := range []string{"snap-a", "snap-b"} {
+		req, err := http.NewRequest("GET", "/v2/logs?names="+names, nil)
+		c.Assert(err, check.IsNil)
+		getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+	}
+
+	c.Assert(s.jctlOpts, check.HasLen, 2)
+	c.Check(s.jctlOpts[0].Namespaces, check.Equals, true)
+	c.Check(s.jctlOpts[1].Namespaces, check.Equals, false)
+}
+
+func (s *appSuite) TestLogsNamespacesOldSystemd(c *check.C) {
+	restore := systemd.MockSystemdVersion(244, nil)
+	defer restore()
+
+	st := s.d.overlord.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("snap-a", "logs.max-size", "10MB"), check.IsNil)
+	tr.Commit()
+	st.Unlock()
+
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	c.Assert(s.jctlOpts, check.HasLen, 1)
+	c.Check(s.jctlOpts[0].Namespaces, check.Equals, false)
+}
+
 func (s *appSuite) TestLogsBadName(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/logs?names=hello", nil)
 	c.Assert(err, check.IsNil)
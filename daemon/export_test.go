@@ -26,6 +26,7 @@ import (
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -65,6 +66,14 @@ func MockShutdownTimeout(tm time.Duration) (restore func()) {
 	}
 }
 
+func MockSnapstateRefreshCohort(f func(st *state.State, name string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error)) (restore func()) {
+	old := snapstateRefreshCohort
+	snapstateRefreshCohort = f
+	return func() {
+		snapstateRefreshCohort = old
+	}
+}
+
 func MockServicestateControl(f func(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, context *hookstate.Context) ([]*state.TaskSet, error)) (restore func()) {
 	old := servicestateControl
 	servicestateControl = f
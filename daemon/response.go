@@ -98,6 +98,9 @@ type Meta struct {
 	Change            string     `json:"change,omitempty"`
 	WarningTimestamp  *time.Time `json:"warning-timestamp,omitempty"`
 	WarningCount      int        `json:"warning-count,omitempty"`
+	// CachedAt is set when the store could not be reached and
+	// the results come from its cached responses of that time
+	CachedAt *time.Time `json:"cached-at,omitempty"`
 }
 
 type respJSON struct {
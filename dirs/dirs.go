@@ -85,10 +85,13 @@ var (
 	SnapCommandsDB      string
 	SnapAuxStoreInfoDir string
 
+	SnapStoreResponseCacheDir string
+
 	SnapBinariesDir     string
 	SnapServicesDir     string
 	SnapUserServicesDir string
 	SnapSystemdConfDir  string
+	SnapJournaldConfDir string
 	SnapDesktopFilesDir string
 	SnapDesktopIconsDir string
 
@@ -316,6 +319,7 @@ func SetRootDir(rootdir string) {
 	SnapSectionsFile = filepath.Join(SnapCacheDir, "sections")
 	SnapCommandsDB = filepath.Join(SnapCacheDir, "commands.db")
 	SnapAuxStoreInfoDir = filepath.Join(SnapCacheDir, "aux")
+	SnapStoreResponseCacheDir = filepath.Join(SnapCacheDir, "store-responses")
 
 	SnapSeedDir = SnapSeedDirUnder(rootdir)
 	SnapDeviceDir = filepath.Join(rootdir, snappyDir, "device")
@@ -334,6 +338,7 @@ func SetRootDir(rootdir string) {
 	SnapServicesDir = filepath.Join(rootdir, "/etc/systemd/system")
 	SnapUserServicesDir = filepath.Join(rootdir, "/etc/systemd/user")
 	SnapSystemdConfDir = SnapSystemdConfDirUnder(rootdir)
+	SnapJournaldConfDir = filepath.Join(rootdir, "/etc/systemd")
 
 	SnapDBusSystemPolicyDir = filepath.Join(rootdir, "/etc/dbus-1/system.d")
 	SnapDBusSessionPolicyDir = filepath.Join(rootdir, "/etc/dbus-1/session.d")
@@ -22,11 +22,13 @@ package install
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/secboot"
 )
 
@@ -34,6 +36,8 @@ const (
 	ubuntuDataLabel = "ubuntu-data"
 )
 
+var secbootSealKey = secboot.SealKey
+
 func deviceFromRole(lv *gadget.LaidOutVolume, role string) (device string, err error) {
 	for _, vs := range lv.LaidOutStructure {
 		// XXX: this part of the finding maybe should be a
@@ -52,8 +56,8 @@ func deviceFromRole(lv *gadget.LaidOutVolume, role string) (device string, err e
 // Run bootstraps the partitions of a device, by either creating
 // missing ones or recreating installed ones.
 func Run(gadgetRoot, device string, options Options) error {
-	if options.Encrypt && (options.KeyFile == "" || options.RecoveryKeyFile == "") {
-		return fmt.Errorf("key file and recovery key file must be specified when encrypting")
+	if options.Encrypt && (options.KeyFile == "" || options.RecoveryKeyFile == "" || options.UnsealedKeyFile == "") {
+		return fmt.Errorf("key file, recovery key file and unsealed key file must be specified when encrypting")
 	}
 
 	if gadgetRoot == "" {
@@ -162,6 +166,36 @@ func Run(gadgetRoot, device string, options Options) error {
 		return fmt.Errorf("cannot store recovery key: %v", err)
 	}
 
+	// keep the encryption key around until Seal is called, once the
+	// boot configuration of the run system is known
+	if err := os.MkdirAll(filepath.Dir(options.UnsealedKeyFile), 0700); err != nil {
+		return fmt.Errorf("cannot store encryption key: %v", err)
+	}
+	if err := osutil.AtomicWriteFile(options.UnsealedKeyFile, key[:], 0600, 0); err != nil {
+		return fmt.Errorf("cannot store encryption key: %v", err)
+	}
+
+	return nil
+}
+
+// Seal seals the encryption key of a device bootstrapped by Run to the
+// boot chain and kernel command lines of the run and recover systems.
+// The unsealed copy of the key is removed afterwards.
+func Seal(options Options) error {
+	if options.KeyFile == "" || options.UnsealedKeyFile == "" {
+		return fmt.Errorf("key file and unsealed key file must be specified when sealing")
+	}
+
+	buf, err := ioutil.ReadFile(options.UnsealedKeyFile)
+	if err != nil {
+		return fmt.Errorf("cannot read encryption key: %v", err)
+	}
+	var key secboot.EncryptionKey
+	if len(buf) != len(key) {
+		return fmt.Errorf("cannot use encryption key: unexpected size %d", len(buf))
+	}
+	copy(key[:], buf)
+
 	// TODO:UC20: binaries are EFI/bootloader-specific, hardcoded for now
 	loadChain := []string{
 		// the path to the shim EFI binary
@@ -177,9 +211,13 @@ func Run(gadgetRoot, device string, options Options) error {
 	}
 
 	// TODO:UC20: get cmdline definition from bootloaders
+	runCmdline := "snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1"
+	if options.KernelCmdlineExtra != "" {
+		runCmdline += " " + options.KernelCmdlineExtra
+	}
 	kernelCmdlines := []string{
 		// run mode
-		"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1",
+		runCmdline,
 		// recover mode
 		fmt.Sprintf("snapd_recovery_mode=recover snapd_recovery_system=%s console=ttyS0 console=tty1 panic=-1", options.SystemLabel),
 	}
@@ -197,10 +235,14 @@ func Run(gadgetRoot, device string, options Options) error {
 		TPMLockoutAuthFile:      options.TPMLockoutAuthFile,
 	}
 
-	if err := secboot.SealKey(key, &sealKeyParams); err != nil {
+	if err := secbootSealKey(key, &sealKeyParams); err != nil {
 		return fmt.Errorf("cannot seal the encryption key: %v", err)
 	}
 
+	if err := os.Remove(options.UnsealedKeyFile); err != nil {
+		return fmt.Errorf("cannot remove unsealed encryption key: %v", err)
+	}
+
 	return nil
 }
 
@@ -27,3 +27,7 @@ import (
 func Run(gadgetRoot, device string, options Options) error {
 	return fmt.Errorf("build without secboot support")
 }
+
+func Seal(options Options) error {
+	return fmt.Errorf("build without secboot support")
+}
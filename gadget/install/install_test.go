@@ -21,6 +21,7 @@
 package install_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -31,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/gadget/install"
+	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -59,6 +61,76 @@ func (s *installSuite) SetUpTest(c *C) {
 func (s *installSuite) TestInstallRunError(c *C) {
 	err := install.Run("", "", install.Options{})
 	c.Assert(err, ErrorMatches, "cannot use empty gadget root directory")
+
+	err = install.Run("", "", install.Options{Encrypt: true, KeyFile: "key", RecoveryKeyFile: "recovery-key"})
+	c.Assert(err, ErrorMatches, "key file, recovery key file and unsealed key file must be specified when encrypting")
+}
+
+func (s *installSuite) TestSeal(c *C) {
+	unsealedKeyFile := filepath.Join(s.dir, "run/unsealed-key")
+	c.Assert(os.MkdirAll(filepath.Dir(unsealedKeyFile), 0700), IsNil)
+	myKey := secboot.EncryptionKey{}
+	for i := range myKey {
+		myKey[i] = byte(i)
+	}
+	c.Assert(ioutil.WriteFile(unsealedKeyFile, myKey[:], 0600), IsNil)
+
+	sealCalls := 0
+	restore := install.MockSecbootSealKey(func(key secboot.EncryptionKey, params *secboot.SealKeyParams) error {
+		sealCalls++
+		c.Check(key, DeepEquals, myKey)
+		c.Check(params.KeyFile, Equals, "sealed-key")
+		c.Assert(params.ModelParams, HasLen, 1)
+		c.Check(params.ModelParams[0].KernelCmdlines, DeepEquals, []string{
+			"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 quiet splash",
+			"snapd_recovery_mode=recover snapd_recovery_system=20200901 console=ttyS0 console=tty1 panic=-1",
+		})
+		c.Check(params.ModelParams[0].EFILoadChains, DeepEquals, [][]string{{
+			filepath.Join(s.dir, "run/mnt/ubuntu-seed/EFI/boot/bootx64.efi"),
+			filepath.Join(s.dir, "run/mnt/ubuntu-seed/EFI/boot/grubx64.efi"),
+			filepath.Join(s.dir, "run/mnt/ubuntu-boot/EFI/boot/grubx64.efi"),
+			"kernel.efi",
+		}})
+		return nil
+	})
+	defer restore()
+
+	err := install.Seal(install.Options{
+		Encrypt:            true,
+		KeyFile:            "sealed-key",
+		UnsealedKeyFile:    unsealedKeyFile,
+		KernelPath:         "kernel.efi",
+		SystemLabel:        "20200901",
+		KernelCmdlineExtra: "quiet splash",
+	})
+	c.Assert(err, IsNil)
+	c.Check(sealCalls, Equals, 1)
+	// the unsealed key is gone
+	c.Check(unsealedKeyFile, testutil.FileAbsent)
+}
+
+func (s *installSuite) TestSealErrors(c *C) {
+	err := install.Seal(install.Options{KeyFile: "sealed-key"})
+	c.Assert(err, ErrorMatches, "key file and unsealed key file must be specified when sealing")
+
+	unsealedKeyFile := filepath.Join(s.dir, "unsealed-key")
+	err = install.Seal(install.Options{KeyFile: "sealed-key", UnsealedKeyFile: unsealedKeyFile})
+	c.Assert(err, ErrorMatches, "cannot read encryption key: .*")
+
+	c.Assert(ioutil.WriteFile(unsealedKeyFile, []byte("short"), 0600), IsNil)
+	err = install.Seal(install.Options{KeyFile: "sealed-key", UnsealedKeyFile: unsealedKeyFile})
+	c.Assert(err, ErrorMatches, "cannot use encryption key: unexpected size 5")
+
+	myKey := secboot.EncryptionKey{}
+	c.Assert(ioutil.WriteFile(unsealedKeyFile, myKey[:], 0600), IsNil)
+	restore := install.MockSecbootSealKey(func(key secboot.EncryptionKey, params *secboot.SealKeyParams) error {
+		return fmt.Errorf("seal error")
+	})
+	defer restore()
+	err = install.Seal(install.Options{KeyFile: "sealed-key", UnsealedKeyFile: unsealedKeyFile})
+	c.Assert(err, ErrorMatches, "cannot seal the encryption key: seal error")
+	// the unsealed key is kept to retry
+	c.Check(unsealedKeyFile, testutil.FilePresent)
 }
 
 const mockGadgetYaml = `volumes:
@@ -30,6 +30,9 @@ type Options struct {
 	Encrypt bool
 	// KeyFile is the location where the encryption key is written to
 	KeyFile string
+	// UnsealedKeyFile is the location where the encryption key is kept
+	// between Run and Seal, it should be on a tmpfs
+	UnsealedKeyFile string
 	// RecoveryKeyFile is the location where the recovery key is written to
 	RecoveryKeyFile string
 	// TPMLockoutAuthFile is the location where the TPM lockout authorization is written to
@@ -42,4 +45,7 @@ type Options struct {
 	Model *asserts.Model
 	// SystemLabel is the recover system label to seal the keyfile to
 	SystemLabel string
+	// KernelCmdlineExtra is appended to the run mode kernel command line
+	// the keyfile is sealed to
+	KernelCmdlineExtra string
 }
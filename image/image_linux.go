@@ -158,11 +158,24 @@ func makeLabel(now time.Time) string {
 	return now.UTC().Format("20060102")
 }
 
+// SeedManifestName is the name of the file recording the exact snap
+// revisions that went into a seed, see seedwriter.Manifest.
+const SeedManifestName = "seed.manifest"
+
 func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options) error {
 	if model.Classic() != opts.Classic {
 		return fmt.Errorf("internal error: classic model but classic mode not set")
 	}
 
+	var revisions *seedwriter.Manifest
+	if opts.RevisionsFile != "" {
+		var err error
+		revisions, err = seedwriter.ReadManifest(opts.RevisionsFile)
+		if err != nil {
+			return err
+		}
+	}
+
 	core20 := model.Grade() != asserts.ModelGradeUnset
 	var rootDir string
 	var bootRootDir string
@@ -307,6 +320,14 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options) error {
 				Channel:        sn.Channel,
 				CohortKey:      opts.WideCohortKey,
 			}
+			if revisions != nil {
+				pinned := revisions.Snap(sn.SnapName())
+				if pinned == nil {
+					return fmt.Errorf("cannot find snap %q in revisions manifest %q", sn.SnapName(), opts.RevisionsFile)
+				}
+				dlOpts.Revision = pinned.Revision
+				dlOpts.CohortKey = ""
+			}
 			fn, info, redirectChannel, err := tsto.DownloadSnap(sn.SnapName(), dlOpts) // TODO|XXX make this take the SnapRef really
 			if err != nil {
 				return err
@@ -361,6 +382,25 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options) error {
 		return err
 	}
 
+	manifest, err := w.Manifest()
+	if err != nil {
+		return err
+	}
+	if revisions != nil {
+		if err := revisions.Check(manifest); err != nil {
+			return fmt.Errorf("cannot reproduce revisions manifest %q: %v", opts.RevisionsFile, err)
+		}
+	}
+	// for classic PrepareDir is the root dir itself, keep the
+	// manifest next to the seed there
+	manifestDir := opts.PrepareDir
+	if opts.Classic {
+		manifestDir = seedDir
+	}
+	if err := manifest.Write(filepath.Join(manifestDir, SeedManifestName)); err != nil {
+		return err
+	}
+
 	if opts.Classic {
 		// TODO:UC20: consider Core 20 extended models vs classic
 		seedFn := filepath.Join(seedDir, "seed.yaml")
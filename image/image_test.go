@@ -22,6 +22,7 @@ package image_test
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"fmt"
 	"io/ioutil"
 	"net/url"
@@ -45,6 +46,7 @@ import (
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
+	"github.com/snapcore/snapd/seed/seedwriter"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
@@ -690,6 +692,106 @@ func (s *imageSuite) TestSetupSeed(c *C) {
 		InstanceName: "pc",
 		Channel:      stableChannel,
 	})
+
+	// check the seed manifest
+	manifest, err := seedwriter.ReadManifest(filepath.Join(opts.PrepareDir, "seed.manifest"))
+	c.Assert(err, IsNil)
+	c.Assert(manifest.Snaps, HasLen, 4)
+	for i, name := range []string{"core", "pc-kernel", "pc", "required-snap1"} {
+		info := s.AssertedSnapInfo(name)
+		dgst, _, err := osutil.FileDigest(filepath.Join(seedsnapsdir, info.Filename()), crypto.SHA3_384)
+		c.Assert(err, IsNil)
+		c.Check(manifest.Snaps[i], DeepEquals, &seedwriter.ManifestSnap{
+			Name:     name,
+			Revision: info.Revision,
+			Channel:  stableChannel,
+			Sha3_384: fmt.Sprintf("%x", dgst),
+		})
+	}
+}
+
+func (s *imageSuite) TestSetupSeedRevisions(c *C) {
+	restore := image.MockTrusted(s.StoreSigning.Trusted)
+	defer restore()
+
+	s.setupSnaps(c, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	}, "")
+
+	// a first run records the revisions
+	prepareDir := c.MkDir()
+	opts := &image.Options{
+		PrepareDir: prepareDir,
+	}
+	err := image.SetupSeed(s.tsto, s.model, opts)
+	c.Assert(err, IsNil)
+	revisionsFn := filepath.Join(prepareDir, "seed.manifest")
+	c.Check(revisionsFn, testutil.FilePresent)
+
+	// which a second one can reproduce
+	s.storeActions = nil
+	prepareDir2 := c.MkDir()
+	opts = &image.Options{
+		PrepareDir:    prepareDir2,
+		RevisionsFile: revisionsFn,
+		// the pinned revisions take precedence
+		WideCohortKey: "wide-cohort",
+	}
+	err = image.SetupSeed(s.tsto, s.model, opts)
+	c.Assert(err, IsNil)
+
+	c.Check(s.storeActions, HasLen, 4)
+	for i, name := range []string{"core", "pc-kernel", "pc", "required-snap1"} {
+		c.Check(s.storeActions[i], DeepEquals, &store.SnapAction{
+			Action:       "download",
+			InstanceName: name,
+			Revision:     s.AssertedSnapInfo(name).Revision,
+		})
+	}
+	recorded, err := ioutil.ReadFile(revisionsFn)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(prepareDir2, "seed.manifest"), testutil.FileEquals, string(recorded))
+}
+
+func (s *imageSuite) TestSetupSeedRevisionsMismatch(c *C) {
+	restore := image.MockTrusted(s.StoreSigning.Trusted)
+	defer restore()
+
+	s.setupSnaps(c, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	}, "")
+
+	prepareDir := c.MkDir()
+	revisionsFn := filepath.Join(c.MkDir(), "seed.manifest")
+	manifest := &seedwriter.Manifest{
+		Snaps: []*seedwriter.ManifestSnap{
+			{Name: "core", Revision: snap.R(3), Sha3_384: "aaaa"},
+		},
+	}
+	c.Assert(manifest.Write(revisionsFn), IsNil)
+
+	opts := &image.Options{
+		PrepareDir:    prepareDir,
+		RevisionsFile: revisionsFn,
+	}
+	err := image.SetupSeed(s.tsto, s.model, opts)
+	c.Assert(err, ErrorMatches, `cannot find snap "pc-kernel" in revisions manifest ".*/seed.manifest"`)
+
+	manifest.Snaps = []*seedwriter.ManifestSnap{
+		{Name: "core", Revision: snap.R(3), Sha3_384: "aaaa"},
+		{Name: "pc-kernel", Revision: snap.R(2), Sha3_384: "bbbb"},
+		{Name: "pc", Revision: snap.R(1), Sha3_384: "cccc"},
+		{Name: "required-snap1", Revision: snap.R(3), Sha3_384: "dddd"},
+	}
+	c.Assert(manifest.Write(revisionsFn), IsNil)
+
+	prepareDir = c.MkDir()
+	opts.PrepareDir = prepareDir
+	err = image.SetupSeed(s.tsto, s.model, opts)
+	c.Assert(err, ErrorMatches, `cannot reproduce revisions manifest ".*/seed.manifest": snap "core" \(3\) has digest [0-9a-f]+ instead of aaaa`)
+	c.Check(filepath.Join(prepareDir, "seed.manifest"), testutil.FileAbsent)
 }
 
 func (s *imageSuite) TestSetupSeedLocalCoreBrandKernel(c *C) {
@@ -2283,6 +2385,12 @@ func (s *imageSuite) TestSetupSeedClassic(c *C) {
 	// no blob dir created
 	blobdir := filepath.Join(rootdir, "var/lib/snapd/snaps")
 	c.Check(osutil.FileExists(blobdir), Equals, false)
+
+	// the seed manifest is kept next to the seed
+	c.Check(filepath.Join(rootdir, "seed.manifest"), testutil.FileAbsent)
+	manifest, err := seedwriter.ReadManifest(filepath.Join(seeddir, "seed.manifest"))
+	c.Assert(err, IsNil)
+	c.Check(manifest.Snaps, HasLen, 3)
 }
 
 func (s *imageSuite) TestSetupSeedClassicWithLocalClassicSnap(c *C) {
@@ -36,6 +36,11 @@ type Options struct {
 
 	PrepareDir string
 
+	// RevisionsFile can point to a seed manifest written by a
+	// previous Prepare, the snaps in the image are then pinned to
+	// the exact revisions (and digests) listed in it.
+	RevisionsFile string
+
 	// Architecture to use if none is specified by the model,
 	// useful only for classic mode. If set must match the model otherwise.
 	Architecture string
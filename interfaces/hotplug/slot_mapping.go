@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+// SlotMapping is declared by the administrator to give a fixed name to the
+// slot created for a matching device, and to connect it to the given plugs.
+// Since the hotplug key of a device is derived from its vendor, model and
+// serial, the slot keeps its name across reboots and port changes.
+type SlotMapping struct {
+	// Interface is the hotplug interface that must propose the slot.
+	Interface string `json:"interface"`
+	// Match lists the udev properties, such as ID_VENDOR_ID, ID_MODEL_ID
+	// or ID_SERIAL, that the device must have with the exact values.
+	Match map[string]string `json:"match"`
+	// Connect lists the plugs, in the "snap:plug" form, to connect to
+	// the slot when it is created.
+	Connect []string `json:"connect,omitempty"`
+}
+
+// Validate checks that the mapping for the given slot name is well formed.
+func (m *SlotMapping) Validate(slotName string) error {
+	if err := snap.ValidateSlotName(slotName); err != nil {
+		return err
+	}
+	if m.Interface == "" {
+		return fmt.Errorf("hotplug slot %q must specify an interface", slotName)
+	}
+	if len(m.Match) == 0 {
+		return fmt.Errorf("hotplug slot %q must match at least one device property", slotName)
+	}
+	for _, plug := range m.Connect {
+		if _, _, err := ParsePlug(plug); err != nil {
+			return fmt.Errorf("hotplug slot %q: %v", slotName, err)
+		}
+	}
+	return nil
+}
+
+// Matches returns whether the device has all the properties of the mapping.
+func (m *SlotMapping) Matches(di *HotplugDeviceInfo) bool {
+	if len(m.Match) == 0 {
+		return false
+	}
+	for attr, expected := range m.Match {
+		if val, ok := di.Attribute(attr); !ok || val != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePlug parses a "snap:plug" reference to a plug to connect.
+func ParsePlug(plug string) (snapName, plugName string, err error) {
+	parts := strings.Split(plug, ":")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid plug %q, expected <snap>:<plug>", plug)
+	}
+	if err := snap.ValidateInstanceName(parts[0]); err != nil {
+		return "", "", err
+	}
+	if err := snap.ValidatePlugName(parts[1]); err != nil {
+		return "", "", err
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,96 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type slotMappingSuite struct{}
+
+var _ = Suite(&slotMappingSuite{})
+
+func (s *slotMappingSuite) TestMatches(c *C) {
+	di, err := NewHotplugDeviceInfo(map[string]string{
+		"DEVPATH":      "/devices/pci0000:00/0000:00:14.0/usb1/1-2",
+		"DEVNAME":      "/dev/ttyACM0",
+		"SUBSYSTEM":    "tty",
+		"ID_VENDOR_ID": "05e0",
+		"ID_MODEL_ID":  "1200",
+		"ID_SERIAL":    "Symbol_Bar_Code_Scanner_S/N:1234",
+	})
+	c.Assert(err, IsNil)
+
+	m := &SlotMapping{Interface: "serial-port", Match: map[string]string{
+		"ID_VENDOR_ID": "05e0",
+		"ID_MODEL_ID":  "1200",
+	}}
+	c.Check(m.Matches(di), Equals, true)
+
+	m.Match["ID_SERIAL"] = "Symbol_Bar_Code_Scanner_S/N:1234"
+	c.Check(m.Matches(di), Equals, true)
+
+	m.Match["ID_SERIAL"] = "Symbol_Bar_Code_Scanner_S/N:9999"
+	c.Check(m.Matches(di), Equals, false)
+
+	m.Match = map[string]string{"ID_MODEL_FROM_DATABASE": "Scanner"}
+	c.Check(m.Matches(di), Equals, false)
+
+	// an empty mapping doesn't match every device
+	m.Match = nil
+	c.Check(m.Matches(di), Equals, false)
+}
+
+func (s *slotMappingSuite) TestValidate(c *C) {
+	m := &SlotMapping{
+		Interface: "serial-port",
+		Match:     map[string]string{"ID_VENDOR_ID": "05e0"},
+		Connect:   []string{"kiosk:scanner", "kiosk_foo:serial"},
+	}
+	c.Check(m.Validate("scanner"), IsNil)
+
+	for _, tc := range []struct {
+		name    string
+		mapping SlotMapping
+		err     string
+	}{
+		{"Scanner", *m, `invalid slot name: "Scanner"`},
+		{"scanner", SlotMapping{Match: m.Match}, `hotplug slot "scanner" must specify an interface`},
+		{"scanner", SlotMapping{Interface: "serial-port"}, `hotplug slot "scanner" must match at least one device property`},
+		{"scanner", SlotMapping{Interface: "serial-port", Match: m.Match, Connect: []string{"kiosk"}},
+			`hotplug slot "scanner": invalid plug "kiosk", expected <snap>:<plug>`},
+		{"scanner", SlotMapping{Interface: "serial-port", Match: m.Match, Connect: []string{"kiosk:Scanner"}},
+			`hotplug slot "scanner": invalid plug name: "Scanner"`},
+	} {
+		c.Check(tc.mapping.Validate(tc.name), ErrorMatches, tc.err, Commentf("%v", tc))
+	}
+}
+
+func (s *slotMappingSuite) TestParsePlug(c *C) {
+	snapName, plugName, err := ParsePlug("kiosk:scanner")
+	c.Assert(err, IsNil)
+	c.Check(snapName, Equals, "kiosk")
+	c.Check(plugName, Equals, "scanner")
+
+	_, _, err = ParsePlug("kiosk:scanner:extra")
+	c.Check(err, ErrorMatches, `invalid plug "kiosk:scanner:extra", expected <snap>:<plug>`)
+	_, _, err = ParsePlug("Kiosk:scanner")
+	c.Check(err, ErrorMatches, `invalid snap name: "Kiosk"`)
+}
@@ -956,6 +956,122 @@ func (r *Repository) SnapSpecification(securitySystem SecuritySystem, snapName s
 	return spec, nil
 }
 
+// SpecificationFragment is a security specification built from a single
+// plug, slot or connection of a snap.
+type SpecificationFragment struct {
+	// Interface is the name of the interface that contributed the
+	// specification.
+	Interface string
+	// Plug is set for fragments contributed by a plug of the snap or by a
+	// connection.
+	Plug *PlugRef
+	// Slot is set for fragments contributed by a slot of the snap or by a
+	// connection.
+	Slot *SlotRef
+	// Connected is set when the fragment was contributed by the connection
+	// between Plug and Slot, as opposed to the plug or slot alone.
+	Connected bool
+	Spec      Specification
+}
+
+// SnapSpecificationFragments returns the specification of a given snap in a
+// given security system split into the fragments contributed by each plug,
+// slot and connection of the snap. Combining all the fragments yields the
+// same result as SnapSpecification.
+func (r *Repository) SnapSpecificationFragments(securitySystem SecuritySystem, snapName string) ([]*SpecificationFragment, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var backend SecurityBackend
+	for _, b := range r.backends {
+		if b.Name() == securitySystem {
+			backend = b
+			break
+		}
+	}
+	if backend == nil {
+		return nil, fmt.Errorf("cannot handle interfaces of snap %q, security system %q is not known", snapName, securitySystem)
+	}
+
+	var fragments []*SpecificationFragment
+	addFragment := func(frag *SpecificationFragment, add func(spec Specification) error) error {
+		frag.Spec = backend.NewSpecification()
+		if err := add(frag.Spec); err != nil {
+			return err
+		}
+		fragments = append(fragments, frag)
+		return nil
+	}
+
+	// slot side
+	slots := make([]*snap.SlotInfo, 0, len(r.slots[snapName]))
+	for _, slotInfo := range r.slots[snapName] {
+		slots = append(slots, slotInfo)
+	}
+	sort.Sort(bySlotSnapAndName(slots))
+	for _, slotInfo := range slots {
+		iface := r.ifaces[slotInfo.Interface]
+		slotRef := &SlotRef{Snap: snapName, Name: slotInfo.Name}
+		err := addFragment(&SpecificationFragment{Interface: iface.Name(), Slot: slotRef}, func(spec Specification) error {
+			return spec.AddPermanentSlot(iface, slotInfo)
+		})
+		if err != nil {
+			return nil, err
+		}
+		conns := make([]*Connection, 0, len(r.slotPlugs[slotInfo]))
+		for _, conn := range r.slotPlugs[slotInfo] {
+			conns = append(conns, conn)
+		}
+		sort.Slice(conns, func(i, j int) bool {
+			return conns[i].Plug.Ref().SortsBefore(*conns[j].Plug.Ref())
+		})
+		for _, conn := range conns {
+			conn := conn
+			frag := &SpecificationFragment{Interface: iface.Name(), Plug: conn.Plug.Ref(), Slot: slotRef, Connected: true}
+			err := addFragment(frag, func(spec Specification) error {
+				return spec.AddConnectedSlot(iface, conn.Plug, conn.Slot)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	// plug side
+	plugs := make([]*snap.PlugInfo, 0, len(r.plugs[snapName]))
+	for _, plugInfo := range r.plugs[snapName] {
+		plugs = append(plugs, plugInfo)
+	}
+	sort.Sort(byPlugSnapAndName(plugs))
+	for _, plugInfo := range plugs {
+		iface := r.ifaces[plugInfo.Interface]
+		plugRef := &PlugRef{Snap: snapName, Name: plugInfo.Name}
+		err := addFragment(&SpecificationFragment{Interface: iface.Name(), Plug: plugRef}, func(spec Specification) error {
+			return spec.AddPermanentPlug(iface, plugInfo)
+		})
+		if err != nil {
+			return nil, err
+		}
+		conns := make([]*Connection, 0, len(r.plugSlots[plugInfo]))
+		for _, conn := range r.plugSlots[plugInfo] {
+			conns = append(conns, conn)
+		}
+		sort.Slice(conns, func(i, j int) bool {
+			return conns[i].Slot.Ref().SortsBefore(*conns[j].Slot.Ref())
+		})
+		for _, conn := range conns {
+			conn := conn
+			frag := &SpecificationFragment{Interface: iface.Name(), Plug: plugRef, Slot: conn.Slot.Ref(), Connected: true}
+			err := addFragment(frag, func(spec Specification) error {
+				return spec.AddConnectedPlug(iface, conn.Plug, conn.Slot)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return fragments, nil
+}
+
 // AddSnap adds plugs and slots declared by the given snap to the repository.
 //
 // This function can be used to implement snap install or, when used along with
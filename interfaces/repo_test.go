@@ -1260,6 +1260,48 @@ func (s *RepositorySuite) TestSnapSpecification(c *C) {
 	})
 }
 
+func (s *RepositorySuite) TestSnapSpecificationFragments(c *C) {
+	repo := s.emptyRepo
+	backend := &ifacetest.TestSecurityBackend{BackendName: testSecurity}
+	c.Assert(repo.AddBackend(backend), IsNil)
+	c.Assert(repo.AddInterface(testInterface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+
+	frags, err := repo.SnapSpecificationFragments(testSecurity, s.plug.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(frags, HasLen, 1)
+	c.Check(frags[0].Interface, Equals, "interface")
+	c.Check(frags[0].Plug, DeepEquals, &PlugRef{Snap: "consumer", Name: "plug"})
+	c.Check(frags[0].Slot, IsNil)
+	c.Check(frags[0].Connected, Equals, false)
+	c.Check(frags[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static plug snippet"})
+
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err = repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	frags, err = repo.SnapSpecificationFragments(testSecurity, s.plug.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(frags, HasLen, 2)
+	c.Check(frags[1].Plug, DeepEquals, &PlugRef{Snap: "consumer", Name: "plug"})
+	c.Check(frags[1].Slot, DeepEquals, &SlotRef{Snap: "producer", Name: "slot"})
+	c.Check(frags[1].Connected, Equals, true)
+	c.Check(frags[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific plug snippet"})
+
+	frags, err = repo.SnapSpecificationFragments(testSecurity, s.slot.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(frags, HasLen, 2)
+	c.Check(frags[0].Slot, DeepEquals, &SlotRef{Snap: "producer", Name: "slot"})
+	c.Check(frags[0].Plug, IsNil)
+	c.Check(frags[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static slot snippet"})
+	c.Check(frags[1].Connected, Equals, true)
+	c.Check(frags[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific slot snippet"})
+
+	_, err = repo.SnapSpecificationFragments("unknown", s.slot.Snap.InstanceName())
+	c.Assert(err, ErrorMatches, `cannot handle interfaces of snap "producer", security system "unknown" is not known`)
+}
+
 func (s *RepositorySuite) TestSnapSpecificationFailureWithConnectionSnippets(c *C) {
 	var testSecurity SecuritySystem = "security"
 	backend := &ifacetest.TestSecurityBackend{BackendName: testSecurity}
@@ -19,7 +19,11 @@
 
 package configcore
 
-import "github.com/snapcore/snapd/osutil/sys"
+import (
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil/sys"
+	"github.com/snapcore/snapd/overlord/state"
+)
 
 var (
 	UpdatePiConfig       = updatePiConfig
@@ -48,3 +52,11 @@ func MockChownPath(f func(string, sys.UserID, sys.GroupID) error) func() {
 		sysChownPath = old
 	}
 }
+
+func MockOfflineStoreKeys(f func(*state.State) ([]*asserts.AccountKey, error)) func() {
+	old := offlineStoreKeys
+	offlineStoreKeys = f
+	return func() {
+		offlineStoreKeys = old
+	}
+}
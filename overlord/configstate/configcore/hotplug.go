@@ -0,0 +1,56 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module, the individual
+	// hotplug.slots.<slot> options are accepted by Run
+	supportedConfigurations["core.hotplug.slots"] = true
+}
+
+func validateHotplugSlots(tr config.Conf) error {
+	var slots map[string]*hotplug.SlotMapping
+	if err := tr.Get("core", "hotplug.slots", &slots); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	// validate in a stable order so that the reported error is predictable
+	names := make([]string, 0, len(slots))
+	for name := range slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if slots[name] == nil {
+			// unset
+			continue
+		}
+		if err := slots[name].Validate(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type hotplugSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&hotplugSuite{})
+
+func (s *hotplugSuite) TestConfigureHotplugSlotsHappy(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"hotplug.slots": map[string]*hotplug.SlotMapping{
+				"scanner": {
+					Interface: "serial-port",
+					Match:     map[string]string{"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200"},
+					Connect:   []string{"kiosk:scanner"},
+				},
+			},
+		},
+		changes: map[string]interface{}{
+			"hotplug.slots.scanner.interface": "serial-port",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *hotplugSuite) TestConfigureHotplugSlotsUnset(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"hotplug.slots": map[string]*hotplug.SlotMapping{"scanner": nil},
+		},
+		changes: map[string]interface{}{
+			"hotplug.slots": nil,
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *hotplugSuite) TestConfigureHotplugSlotsInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"hotplug.slots": map[string]*hotplug.SlotMapping{
+				"scanner": {
+					Interface: "serial-port",
+					Match:     map[string]string{"ID_VENDOR_ID": "05e0"},
+				},
+				"printer": {
+					Interface: "serial-port",
+				},
+			},
+		},
+	})
+	c.Assert(err, ErrorMatches, `hotplug slot "printer" must match at least one device property`)
+}
+
+func (s *hotplugSuite) TestConfigureHotplugUnsupportedOption(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"hotplug.foo": "bar",
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot set "core.hotplug.foo": unsupported system option`)
+}
@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/store/offline"
+)
+
+var offlineStoreKeys = devicestate.OfflineStoreKeys
+
+// newOfflineStore returns a store for the directory, trusting the
+// manifests signed with the keys of the device brand.
+func newOfflineStore(tr config.Conf, dir string) (*offline.Store, error) {
+	st := tr.State()
+	st.Lock()
+	keys, err := offlineStoreKeys(st)
+	st.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return offline.New(dir, keys)
+}
+
+func validateOfflineSource(tr config.Conf) error {
+	dir, err := coreCfg(tr, offlineSourceOpt)
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("%s must be an absolute path, not %q", offlineSourceOpt, dir)
+	}
+	if _, err := newOfflineStore(tr, dir); err != nil {
+		return fmt.Errorf("cannot use %q as offline store: %v", dir, err)
+	}
+	return nil
+}
+
+func handleOfflineSource(tr config.Conf, opts *fsOnlyContext) error {
+	var pristineDir, dir string
+	if err := tr.GetPristine("core", offlineSourceOpt, &pristineDir); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if err := tr.Get("core", offlineSourceOpt, &dir); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if pristineDir == dir {
+		return nil
+	}
+
+	var sto snapstate.StoreService
+	if dir != "" {
+		offlineSto, err := newOfflineStore(tr, dir)
+		if err != nil {
+			return fmt.Errorf("cannot use %q as offline store: %v", dir, err)
+		}
+		sto = offlineSto
+	}
+
+	st := tr.State()
+	st.Lock()
+	defer st.Unlock()
+	snapstate.ReplaceOfflineStore(st, sto)
+	return nil
+}
@@ -38,6 +38,7 @@ func init() {
 	supportedConfigurations["core.refresh.metered"] = true
 	supportedConfigurations["core.refresh.retain"] = true
 	supportedConfigurations["core.refresh.rate-limit"] = true
+	supportedConfigurations["core.refresh.download-concurrency"] = true
 }
 
 func validateRefreshSchedule(tr config.Conf) error {
@@ -133,3 +134,17 @@ func validateRefreshRateLimit(tr config.Conf) error {
 	}
 	return nil
 }
+
+func validateRefreshDownloadConcurrency(tr config.Conf) error {
+	concurrency, err := coreCfg(tr, "refresh.download-concurrency")
+	if err != nil {
+		return err
+	}
+	if concurrency == "" {
+		return nil
+	}
+	if n, err := strconv.ParseUint(concurrency, 10, 8); err != nil || n < 1 {
+		return fmt.Errorf("download-concurrency must be a number between 1 and 255, not %q", concurrency)
+	}
+	return nil
+}
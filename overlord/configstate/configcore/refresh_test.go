@@ -167,3 +167,25 @@ func (s *refreshSuite) TestConfigureRefreshRetainInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `retain must be a number between 2 and 20, not "invalid"`)
 }
+
+func (s *refreshSuite) TestConfigureRefreshDownloadConcurrencyHappy(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.download-concurrency": 4,
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *refreshSuite) TestConfigureRefreshDownloadConcurrencyInvalid(c *C) {
+	for _, value := range []interface{}{"0", 256, "-1", "many"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.download-concurrency": value,
+			},
+		})
+		c.Check(err, ErrorMatches, `download-concurrency must be a number between 1 and 255, not ".*"`, Commentf("%v", value))
+	}
+}
@@ -49,10 +49,25 @@ func init() {
 	// store-certs.*
 	addWithStateHandler(validateCertSettings, handleCertConfiguration, nil)
 
+	// store.offline-source
+	addWithStateHandler(validateOfflineSource, handleOfflineSource, nil)
+
+	// store.cache.max-size
+	addWithStateHandler(validateCacheMaxSize, handleCacheMaxSize, nil)
+
 	validateOnly := &flags{validatedOnlyStateConfig: true}
 	addWithStateHandler(validateRefreshSchedule, nil, validateOnly)
 	addWithStateHandler(validateRefreshRateLimit, nil, validateOnly)
+	addWithStateHandler(validateRefreshDownloadConcurrency, nil, validateOnly)
+	// store.endpoints, store.assertions-endpoints
+	addWithStateHandler(validateStoreEndpoints, nil, validateOnly)
+	// store.peer-cache.*
+	addWithStateHandler(validatePeerCache, nil, validateOnly)
 	addWithStateHandler(validateAutomaticSnapshotsExpiration, nil, validateOnly)
+	// services.failure-{limit,window}
+	addWithStateHandler(validateServiceFailureSettings, nil, validateOnly)
+	// hotplug.slots.*
+	addWithStateHandler(validateHotplugSlots, nil, validateOnly)
 }
 
 type withStateHandler struct {
@@ -109,6 +124,8 @@ func Run(cfg config.Conf) error {
 			if !validCertOption(k) {
 				return fmt.Errorf("cannot set store ssl certificate under name %q: name must only contain word characters or a dash", k)
 			}
+		case strings.HasPrefix(k, "core.hotplug.slots."):
+			// validated by validateHotplugSlots
 		case !supportedConfigurations[k]:
 			return fmt.Errorf("cannot set %q: unsupported system option", k)
 		}
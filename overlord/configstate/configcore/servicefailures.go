@@ -0,0 +1,62 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.services.failure-limit"] = true
+	supportedConfigurations["core.services.failure-window"] = true
+}
+
+func validateServiceFailureSettings(tr config.Conf) error {
+	limitStr, err := coreCfg(tr, "services.failure-limit")
+	if err != nil {
+		return err
+	}
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return fmt.Errorf("services.failure-limit must be a positive integer: %q", limitStr)
+		}
+	}
+
+	windowStr, err := coreCfg(tr, "services.failure-window")
+	if err != nil {
+		return err
+	}
+	if windowStr != "" {
+		window, err := time.ParseDuration(windowStr)
+		if err != nil {
+			return fmt.Errorf("services.failure-window cannot be parsed: %v", err)
+		}
+		if window < time.Minute {
+			return fmt.Errorf("services.failure-window must be at least one minute")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type serviceFailuresSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&serviceFailuresSuite{})
+
+func (s *serviceFailuresSuite) TestConfigureServiceFailuresHappy(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"services.failure-limit":  "5",
+			"services.failure-window": "30m",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *serviceFailuresSuite) TestConfigureServiceFailuresInvalid(c *C) {
+	for _, t := range []struct {
+		conf map[string]interface{}
+		err  string
+	}{
+		{map[string]interface{}{"services.failure-limit": "0"}, `services.failure-limit must be a positive integer: "0"`},
+		{map[string]interface{}{"services.failure-limit": "many"}, `services.failure-limit must be a positive integer: "many"`},
+		{map[string]interface{}{"services.failure-window": "soon"}, `services.failure-window cannot be parsed: .*`},
+		{map[string]interface{}{"services.failure-window": "10s"}, `services.failure-window must be at least one minute`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf:  t.conf,
+		})
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.conf))
+	}
+}
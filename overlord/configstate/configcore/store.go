@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
+)
+
+const (
+	offlineSourceOpt    = "store.offline-source"
+	storeEndpointsOpt   = "store.endpoints"
+	assertsEndpointsOpt = "store.assertions-endpoints"
+	peerCacheListenOpt  = "store.peer-cache.listen"
+	peerCachePeersOpt   = "store.peer-cache.peers"
+	cacheMaxSizeOpt     = "store.cache.max-size"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core."+offlineSourceOpt] = true
+	supportedConfigurations["core."+storeEndpointsOpt] = true
+	supportedConfigurations["core."+assertsEndpointsOpt] = true
+	supportedConfigurations["core."+peerCacheListenOpt] = true
+	supportedConfigurations["core."+peerCachePeersOpt] = true
+	supportedConfigurations["core."+cacheMaxSizeOpt] = true
+}
+
+// validateCacheMaxSize checks the maximum size of the download cache,
+// the store reads it whenever it adds to the cache and snapd applies it
+// on startup.
+func validateCacheMaxSize(tr config.Conf) error {
+	maxSize, err := coreCfg(tr, cacheMaxSizeOpt)
+	if err != nil {
+		return err
+	}
+	if maxSize == "" {
+		return nil
+	}
+	if _, err := strutil.ParseByteSize(maxSize); err != nil {
+		return fmt.Errorf("%s is invalid: %v", cacheMaxSizeOpt, err)
+	}
+	return nil
+}
+
+// handleCacheMaxSize trims the download cache right away when its
+// maximum size changes.
+func handleCacheMaxSize(tr config.Conf, opts *fsOnlyContext) error {
+	var pristine, maxSize string
+	if err := tr.GetPristine("core", cacheMaxSizeOpt, &pristine); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if err := tr.Get("core", cacheMaxSizeOpt, &maxSize); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if pristine == maxSize || maxSize == "" {
+		return nil
+	}
+	size, err := strutil.ParseByteSize(maxSize)
+	if err != nil {
+		return err
+	}
+	return store.TrimDownloadCache(dirs.SnapDownloadCacheDir, size)
+}
+
+// validateStoreEndpoints checks the comma separated lists of store and
+// assertions service endpoints to fail over to, the store reads them on
+// each request.
+func validateStoreEndpoints(tr config.Conf) error {
+	for _, opt := range []string{storeEndpointsOpt, assertsEndpointsOpt} {
+		endpoints, err := coreCfg(tr, opt)
+		if err != nil {
+			return err
+		}
+		if _, err := store.ParseEndpoints(endpoints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePeerCache checks the address to serve the download cache on
+// and the peers to serve it to and download from, the overlord and the
+// store pick them up as needed.
+func validatePeerCache(tr config.Conf) error {
+	listen, err := coreCfg(tr, peerCacheListenOpt)
+	if err != nil {
+		return err
+	}
+	if listen != "" {
+		if _, port, err := net.SplitHostPort(listen); err != nil || port == "" {
+			return fmt.Errorf("%s must be an address like \":8738\" or \"10.0.0.1:8738\", not %q", peerCacheListenOpt, listen)
+		}
+	}
+	peers, err := coreCfg(tr, peerCachePeersOpt)
+	if err != nil {
+		return err
+	}
+	_, err = store.ParsePeers(peers)
+	return err
+}
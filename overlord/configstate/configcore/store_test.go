@@ -0,0 +1,263 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store/offline"
+	"github.com/snapcore/snapd/store/storetest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type fakeStore struct {
+	storetest.Store
+}
+
+type storeSuite struct {
+	configcoreSuite
+
+	brandKey asserts.PrivateKey
+}
+
+var _ = Suite(&storeSuite{})
+
+func (s *storeSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.ReplaceStore(s.state, &fakeStore{})
+
+	storeStack := assertstest.NewStoreStack("canonical", nil)
+	brandAcct := assertstest.NewAccount(storeStack, "my-brand", map[string]interface{}{
+		"account-id": "my-brand",
+	}, "")
+	s.brandKey, _ = assertstest.GenerateKey(752)
+	brandAccKey := assertstest.NewAccountKey(storeStack, brandAcct, map[string]interface{}{
+		"since": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}, s.brandKey.PublicKey(), "")
+	s.AddCleanup(configcore.MockOfflineStoreKeys(func(*state.State) ([]*asserts.AccountKey, error) {
+		return []*asserts.AccountKey{brandAccKey}, nil
+	}))
+}
+
+func (s *storeSuite) mockOfflineDir(c *C) string {
+	dir := c.MkDir()
+	manifest := []byte("snaps: []\n")
+	err := ioutil.WriteFile(filepath.Join(dir, offline.ManifestFile), manifest, 0644)
+	c.Assert(err, IsNil)
+	sig, err := asserts.SignContent(manifest, s.brandKey)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, offline.ManifestSignatureFile), sig, 0644)
+	c.Assert(err, IsNil)
+	return dir
+}
+
+func (s *storeSuite) currentStore() snapstate.StoreService {
+	s.state.Lock()
+	defer s.state.Unlock()
+	return snapstate.Store(s.state, nil)
+}
+
+func (s *storeSuite) TestConfigureOfflineSource(c *C) {
+	dir := s.mockOfflineDir(c)
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"store.offline-source": dir,
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.currentStore(), FitsTypeOf, &offline.Store{})
+
+	// unsetting the option goes back to the regular store
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"store.offline-source": dir,
+		},
+		changes: map[string]interface{}{
+			"store.offline-source": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.currentStore(), FitsTypeOf, &fakeStore{})
+}
+
+func (s *storeSuite) TestConfigureOfflineSourceUnchanged(c *C) {
+	dir := s.mockOfflineDir(c)
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"store.offline-source": dir,
+		},
+	})
+	c.Assert(err, IsNil)
+	// the store was set up when the option was changed or at startup
+	c.Check(s.currentStore(), FitsTypeOf, &fakeStore{})
+}
+
+func (s *storeSuite) TestConfigureOfflineSourceErrors(c *C) {
+	otherKey, _ := assertstest.GenerateKey(752)
+	untrustedDir := s.mockOfflineDir(c)
+	sig, err := asserts.SignContent([]byte("snaps: []\n"), otherKey)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(untrustedDir, offline.ManifestSignatureFile), sig, 0644)
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		dir string
+		err string
+	}{
+		{"relative/dir", `store.offline-source must be an absolute path, not "relative/dir"`},
+		{c.MkDir(), `cannot use ".*" as offline store: cannot read offline store manifest: .*`},
+		{untrustedDir, `cannot use ".*" as offline store: offline store manifest is not signed with a trusted key`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			changes: map[string]interface{}{
+				"store.offline-source": t.dir,
+			},
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+	c.Check(s.currentStore(), FitsTypeOf, &fakeStore{})
+}
+
+func (s *storeSuite) TestConfigureStoreEndpoints(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"store.endpoints":            "https://eu.proxy.example/, http://10.0.0.1:8080/api/",
+			"store.assertions-endpoints": "https://assertions.proxy.example/",
+		},
+	})
+	c.Check(err, IsNil)
+}
+
+func (s *storeSuite) TestConfigureStoreEndpointsErrors(c *C) {
+	for _, t := range []struct {
+		endpoints string
+		err       string
+	}{
+		{"eu.proxy.example", `invalid store endpoint "eu.proxy.example": must be an http or https URL`},
+		{"https://ok.example/,ftp://proxy.example/", `invalid store endpoint "ftp://proxy.example/": must be an http or https URL`},
+		{"https://proxy.example/?q=1", `invalid store endpoint "https://proxy.example/\?q=1": cannot have a query or fragment`},
+		{"https://proxy.example:port/", `invalid store endpoint "https://proxy.example:port/": .*invalid port.*`},
+	} {
+		for _, opt := range []string{"store.endpoints", "store.assertions-endpoints"} {
+			err := configcore.Run(&mockConf{
+				state: s.state,
+				changes: map[string]interface{}{
+					opt: t.endpoints,
+				},
+			})
+			c.Check(err, ErrorMatches, t.err)
+		}
+	}
+}
+
+func (s *storeSuite) TestConfigurePeerCache(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"store.peer-cache.listen": ":8738",
+			"store.peer-cache.peers":  "10.0.0.2:8738, peer.lan:8738",
+		},
+	})
+	c.Check(err, IsNil)
+}
+
+func (s *storeSuite) TestConfigurePeerCacheErrors(c *C) {
+	for _, t := range []struct {
+		opt, value string
+		err        string
+	}{
+		{"store.peer-cache.listen", "8738", `store.peer-cache.listen must be an address like ":8738" or "10.0.0.1:8738", not "8738"`},
+		{"store.peer-cache.listen", "10.0.0.1:", `store.peer-cache.listen must be an address like .*, not "10.0.0.1:"`},
+		{"store.peer-cache.peers", "10.0.0.2", `invalid peer "10.0.0.2": .*missing port in address`},
+		{"store.peer-cache.peers", ":8738", `invalid peer ":8738": missing host`},
+		{"store.peer-cache.peers", "peer.lan:http", `invalid peer "peer.lan:http": invalid port "http"`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			changes: map[string]interface{}{
+				t.opt: t.value,
+			},
+		})
+		c.Check(err, ErrorMatches, t.err, Commentf("%s=%s", t.opt, t.value))
+	}
+}
+
+func (s *storeSuite) TestConfigureCacheMaxSize(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"store.cache.max-size": "512MB",
+		},
+	})
+	c.Check(err, IsNil)
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"store.cache.max-size": "lots",
+		},
+	})
+	c.Check(err, ErrorMatches, `store.cache.max-size is invalid: cannot parse "lots": .*`)
+}
+
+func (s *storeSuite) TestConfigureCacheMaxSizeTrimsCache(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), IsNil)
+	then := time.Now().Add(-time.Hour)
+	for i, name := range []string{"oldest", "older", "newest"} {
+		p := filepath.Join(dirs.SnapDownloadCacheDir, name)
+		c.Assert(ioutil.WriteFile(p, []byte("0123456789"), 0644), IsNil)
+		mtime := then.Add(time.Duration(i) * time.Minute)
+		c.Assert(os.Chtimes(p, mtime, mtime), IsNil)
+	}
+
+	// lowering the bound applies it right away
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"store.cache.max-size": "15B",
+		},
+	})
+	c.Assert(err, IsNil)
+	for _, name := range []string{"oldest", "older"} {
+		c.Check(filepath.Join(dirs.SnapDownloadCacheDir, name), testutil.FileAbsent)
+	}
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, "newest"), testutil.FilePresent)
+}
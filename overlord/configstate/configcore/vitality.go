@@ -103,13 +103,24 @@ func handleVitalityConfiguration(tr config.Conf, opts *fsOnlyContext) error {
 			return err
 		}
 
+		providers, err := snapstate.ServiceProviders(st, info)
+		if err != nil {
+			return err
+		}
+
 		// rank changed, rewrite/restart services
 		for _, app := range info.Apps {
 			if !app.IsService() {
 				continue
 			}
 
-			opts := &wrappers.AddSnapServicesOptions{VitalityRank: rank}
+			opts := &wrappers.AddSnapServicesOptions{
+				VitalityRank:     rank,
+				ServiceProviders: providers,
+				Journal:          snapstate.JournalSettings(st, instanceName),
+				Overrides:        snapstate.ServiceOverrides(st, info),
+				ScheduledTasks:   snapstate.ScheduledTasks(st, info),
+			}
 			if err := wrappers.AddSnapServices(info, disabledSvcs, opts, progress.Null); err != nil {
 				return err
 			}
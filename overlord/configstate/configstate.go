@@ -100,11 +100,23 @@ func ConfigureInstalled(st *state.State, snapName string, patch map[string]inter
 // Configure returns a taskset to apply the given configuration patch.
 func Configure(st *state.State, snapName string, patch map[string]interface{}, flags int) *state.TaskSet {
 	summary := fmt.Sprintf(i18n.G("Run configure hook of %q snap"), snapName)
+	// journal and service override options are handled by snapd
+	// itself, so they can be set even when the snap has no configure
+	// hook
+	var snapdOpts, otherOpts bool
+	for key := range patch {
+		if snapstate.IsJournalOption(key) || snapstate.IsServiceOverrideOption(key) {
+			snapdOpts = true
+		} else {
+			otherOpts = true
+		}
+	}
 	// regular configuration hook
 	hooksup := &hookstate.HookSetup{
 		Snap:        snapName,
 		Hook:        "configure",
-		Optional:    len(patch) == 0,
+		Optional:    !otherOpts,
+		Always:      snapdOpts && snapName != "core",
 		IgnoreError: flags&snapstate.IgnoreHookError != 0,
 		TrackError:  flags&snapstate.TrackHookError != 0,
 		// all configure hooks must finish within this timeout
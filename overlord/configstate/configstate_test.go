@@ -51,6 +51,7 @@ var configureTests = []struct {
 	optional    bool
 	ignoreError bool
 	useDefaults bool
+	always      bool
 }{{
 	patch:       nil,
 	optional:    true,
@@ -72,6 +73,22 @@ var configureTests = []struct {
 	optional:    true,
 	ignoreError: true,
 	useDefaults: true,
+}, {
+	patch:    map[string]interface{}{"logs.max-size": "10MB"},
+	optional: true,
+	always:   true,
+}, {
+	patch:    map[string]interface{}{"logs.max-size": "10MB", "foo": "bar"},
+	optional: false,
+	always:   true,
+}, {
+	patch:    map[string]interface{}{"services.svc.env": map[string]interface{}{"LOG_LEVEL": "debug"}},
+	optional: true,
+	always:   true,
+}, {
+	patch:    map[string]interface{}{"services.svc.enabled": true},
+	optional: false,
+	always:   false,
 }}
 
 func (s *tasksetsSuite) TestConfigureInstalled(c *C) {
@@ -121,6 +138,7 @@ func (s *tasksetsSuite) TestConfigureInstalled(c *C) {
 		c.Assert(hooksup.Hook, Equals, "configure")
 		c.Assert(hooksup.Optional, Equals, test.optional)
 		c.Assert(hooksup.IgnoreError, Equals, test.ignoreError)
+		c.Assert(hooksup.Always, Equals, test.always)
 		c.Assert(hooksup.Timeout, Equals, 5*time.Minute)
 
 		context, err := hookstate.NewContext(task, task.State(), &hooksup, nil, "")
@@ -41,6 +41,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -115,6 +116,113 @@ func (s *configureHandlerSuite) TestBeforeInitializesTransaction(c *C) {
 	c.Check(value, Equals, "bar")
 }
 
+func (s *configureHandlerSuite) TestDoneValidatesJournalOptions(c *C) {
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"logs.rate-limit": "lots",
+	})
+	s.context.Unlock()
+
+	c.Assert(s.handler.Before(), IsNil)
+	c.Check(s.handler.Done(), ErrorMatches, `cannot set "logs.rate-limit": expected <messages>/<interval>, e.g. 1000/30s, got "lots"`)
+}
+
+func (s *configureHandlerSuite) TestDoneRefusesJournalOptionsOnOldSystemd(c *C) {
+	restore := systemd.MockSystemdVersion(244, nil)
+	defer restore()
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"logs.max-size": "10MB",
+	})
+	s.context.Unlock()
+
+	c.Assert(s.handler.Before(), IsNil)
+	c.Check(s.handler.Done(), ErrorMatches, `cannot set "logs.max-size": systemd version 244 is too old \(expected at least 245\)`)
+}
+
+func (s *configureHandlerSuite) TestDoneAppliesJournalOptions(c *C) {
+	restore := systemd.MockSystemdVersion(245, nil)
+	defer restore()
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"logs.max-size": "10MB",
+	})
+	s.context.Unlock()
+
+	c.Assert(s.handler.Before(), IsNil)
+	c.Assert(s.handler.Done(), IsNil)
+
+	s.context.Lock()
+	c.Assert(s.context.Done(), IsNil)
+	s.context.Unlock()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var maxSize string
+	c.Assert(config.NewTransaction(s.state).Get("test-snap", "logs.max-size", &maxSize), IsNil)
+	c.Check(maxSize, Equals, "10MB")
+}
+
+func (s *configureHandlerSuite) mockServiceSnap(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snaptest.MockSnap(c, `name: test-snap
+version: 1.0
+apps:
+ svc:
+  command: bin/svc
+  daemon: simple
+  configurable-env: [LOG_LEVEL]
+  configurable-args: [--verbose, --log-format=]
+`, &snap.SideInfo{Revision: snap.R(1)})
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "test-snap", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+}
+
+func (s *configureHandlerSuite) TestDoneValidatesServiceOverrides(c *C) {
+	s.mockServiceSnap(c)
+
+	for _, t := range []struct {
+		patch map[string]interface{}
+		err   string
+	}{
+		{map[string]interface{}{"services.svc.env": map[string]interface{}{"LOG_LEVEL": "debug"}}, ""},
+		{map[string]interface{}{"services.svc.args": "--verbose --log-format=json"}, ""},
+		{map[string]interface{}{"services.svc.env": map[string]interface{}{"PATH": "/tmp"}}, `cannot set "services.svc.env": service "svc" does not declare "PATH" in configurable-env`},
+		{map[string]interface{}{"services.svc.args": "--debug"}, `cannot set "services.svc.args": service "svc" does not declare "--debug" in configurable-args`},
+		{map[string]interface{}{"services.other.args": "--verbose"}, `cannot set "services.other": snap "test-snap" has no service "other"`},
+		// other options under services are left to the snap
+		{map[string]interface{}{"services.other.enabled": true}, ""},
+	} {
+		s.state.Lock()
+		task := s.state.NewTask("test-task", "my test task")
+		setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "configure"}
+		context, err := hookstate.NewContext(task, s.state, setup, hooktest.NewMockHandler(), "")
+		c.Assert(err, IsNil)
+		s.state.Unlock()
+
+		context.Lock()
+		context.Set("patch", t.patch)
+		context.Unlock()
+
+		handler := configstate.NewConfigureHandler(context)
+		c.Assert(handler.Before(), IsNil)
+		err = handler.Done()
+		if t.err == "" {
+			c.Check(err, IsNil, Commentf("%v", t.patch))
+		} else {
+			c.Check(err, ErrorMatches, t.err, Commentf("%v", t.patch))
+		}
+	}
+}
+
 func makeModel(override map[string]interface{}) *asserts.Model {
 	model := map[string]interface{}{
 		"type":         "model",
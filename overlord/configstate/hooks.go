@@ -127,7 +127,14 @@ func (h *configureHandler) Before() error {
 // Done is called by the HookManager after the configure hook has exited
 // successfully.
 func (h *configureHandler) Done() error {
-	return nil
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	tr := ContextTransaction(h.context)
+	if err := handleJournalConfiguration(h.context, tr); err != nil {
+		return err
+	}
+	return handleServiceOverridesConfiguration(h.context, tr)
 }
 
 // Error is called by the HookManager after the configure hook has exited
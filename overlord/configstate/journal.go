@@ -0,0 +1,65 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configstate
+
+import (
+	"reflect"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+// handleJournalConfiguration validates changes to the journal options of
+// the snap being configured and arranges for its services to be updated
+// once they are committed. The context must be locked.
+func handleJournalConfiguration(context *hookstate.Context, tr *config.Transaction) error {
+	instanceName := context.InstanceName()
+	// the system configuration has no services of its own
+	if instanceName == "core" {
+		return nil
+	}
+
+	var pristine, current interface{}
+	if err := tr.GetPristineMaybe(instanceName, "logs", &pristine); err != nil {
+		return err
+	}
+	if err := tr.GetMaybe(instanceName, "logs", &current); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(pristine, current) {
+		return nil
+	}
+
+	settings, err := snapstate.JournalSettingsFromConfig(tr, instanceName)
+	if err != nil {
+		return err
+	}
+	if err := snapstate.CheckJournalSettings(settings); err != nil {
+		return err
+	}
+
+	st := context.State()
+	// run after the transaction got committed
+	context.OnDone(func() error {
+		return snapstate.UpdateJournalSettings(st, instanceName)
+	})
+	return nil
+}
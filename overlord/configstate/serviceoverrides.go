@@ -0,0 +1,81 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configstate
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+// handleServiceOverridesConfiguration validates changes to the
+// environment and arguments overrides of the services of the snap being
+// configured and arranges for the affected services to be updated and
+// restarted once they are committed. The context must be locked.
+func handleServiceOverridesConfiguration(context *hookstate.Context, tr *config.Transaction) error {
+	instanceName := context.InstanceName()
+	// the system configuration has no services of its own
+	if instanceName == "core" {
+		return nil
+	}
+
+	var pristine, current map[string]interface{}
+	if err := tr.GetPristineMaybe(instanceName, "services", &pristine); err != nil {
+		return err
+	}
+	if err := tr.GetMaybe(instanceName, "services", &current); err != nil {
+		return err
+	}
+	appNames := make(map[string]bool)
+	for appName := range pristine {
+		appNames[appName] = true
+	}
+	for appName := range current {
+		appNames[appName] = true
+	}
+	var changed []string
+	for appName := range appNames {
+		if !reflect.DeepEqual(snapstate.ServiceOverrideSubtree(pristine, appName), snapstate.ServiceOverrideSubtree(current, appName)) {
+			changed = append(changed, appName)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	st := context.State()
+	info, err := snapstate.CurrentInfo(st, instanceName)
+	if err != nil {
+		return err
+	}
+	if _, err := snapstate.ServiceOverridesFromConfig(tr, info); err != nil {
+		return err
+	}
+
+	// run after the transaction got committed
+	context.OnDone(func() error {
+		return snapstate.UpdateServiceOverrides(st, instanceName, changed)
+	})
+	return nil
+}
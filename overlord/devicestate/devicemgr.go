@@ -117,12 +117,14 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	}
 
 	hookManager.Register(regexp.MustCompile("^prepare-device$"), newPrepareDeviceHandler)
+	hookManager.Register(regexp.MustCompile("^install-device$"), newInstallDeviceHandler)
 
 	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
 	runner.AddHandler("request-serial", m.doRequestSerial, nil)
 	runner.AddHandler("mark-preseeded", m.doMarkPreseeded, nil)
 	runner.AddHandler("mark-seeded", m.doMarkSeeded, nil)
-	runner.AddHandler("setup-run-system", m.doSetupRunSystem, nil)
+	runner.AddHandler("setup-run-system", m.doSetupRunSystem, m.undoSetupRunSystem)
+	runner.AddHandler("make-run-system-bootable", m.doMakeRunSystemBootable, nil)
 	runner.AddHandler("prepare-remodeling", m.doPrepareRemodeling, nil)
 	runner.AddCleanup("prepare-remodeling", m.cleanupRemodel)
 	// this *must* always run last and finalizes a remodel
@@ -737,11 +739,35 @@ func (m *DeviceManager) ensureInstalled() error {
 		return nil
 	}
 
+	gadget, err := gadgetWithInstallDeviceHook(m.state)
+	if err != nil {
+		return err
+	}
+
 	m.ensureInstalledRan = true
 
-	tasks := []*state.Task{}
 	setupRunSystem := m.state.NewTask("setup-run-system", i18n.G("Setup system for run mode"))
-	tasks = append(tasks, setupRunSystem)
+	tasks := []*state.Task{setupRunSystem}
+	prev := setupRunSystem
+
+	// the gadget gets to choose the encryption policy, system options
+	// and boot configuration once the partitions are created, before
+	// the encryption key is sealed and the system made bootable
+	if gadget != "" {
+		summary := i18n.G("Run install-device hook")
+		hooksup := &hookstate.HookSetup{
+			Snap: gadget,
+			Hook: "install-device",
+		}
+		installDevice := hookstate.HookTask(m.state, summary, hooksup, nil)
+		installDevice.WaitFor(prev)
+		tasks = append(tasks, installDevice)
+		prev = installDevice
+	}
+
+	makeBootable := m.state.NewTask("make-run-system-bootable", i18n.G("Make run system bootable"))
+	makeBootable.WaitFor(prev)
+	tasks = append(tasks, makeBootable)
 
 	chg := m.state.NewChange("install-system", i18n.G("Install the system"))
 	chg.AddAll(state.NewTaskSet(tasks...))
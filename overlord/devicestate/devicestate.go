@@ -103,6 +103,12 @@ func findSerial(st *state.State, device *auth.DeviceState) (*asserts.Serial, err
 	return a.(*asserts.Serial), nil
 }
 
+// SerialFromState returns the device serial assertion, or state.ErrNoState
+// if the device is not registered yet.
+func SerialFromState(st *state.State) (*asserts.Serial, error) {
+	return findSerial(st, nil)
+}
+
 // auto-refresh
 func canAutoRefresh(st *state.State) (bool, error) {
 	// we need to be seeded first
@@ -309,6 +315,32 @@ func CanManageRefreshes(st *state.State) bool {
 	return false
 }
 
+// OfflineStoreKeys returns the keys an offline store manifest can be
+// signed with, the ones of the brand of the device model.
+func OfflineStoreKeys(st *state.State) ([]*asserts.AccountKey, error) {
+	model, err := findModel(st)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	as, err := assertstate.DB(st).FindMany(asserts.AccountKeyType, map[string]string{
+		"account-id": model.BrandID(),
+	})
+	if asserts.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*asserts.AccountKey, len(as))
+	for i, a := range as {
+		keys[i] = a.(*asserts.AccountKey)
+	}
+	return keys, nil
+}
+
 func getAllRequiredSnapsForModel(model *asserts.Model) *naming.SnapSet {
 	reqSnaps := model.RequiredWithEssentialSnaps()
 	return naming.NewSnapSet(reqSnaps)
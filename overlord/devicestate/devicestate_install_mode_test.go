@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/boot"
@@ -36,6 +37,8 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -50,6 +53,10 @@ type deviceMgrInstallModeSuite struct {
 
 	configureRunSystemOptsPassed []*sysconfig.Options
 	configureRunSystemErr        error
+
+	installSealErr error
+
+	pcSnapYaml string
 }
 
 var _ = Suite(&deviceMgrInstallModeSuite{})
@@ -68,6 +75,8 @@ func (s *deviceMgrInstallModeSuite) SetUpTest(c *C) {
 
 	s.configureRunSystemOptsPassed = nil
 	s.configureRunSystemErr = nil
+	s.installSealErr = nil
+	s.pcSnapYaml = "name: pc\ntype: gadget"
 	restore := devicestate.MockSysconfigConfigureRunSystem(func(opts *sysconfig.Options) error {
 		s.configureRunSystemOptsPassed = append(s.configureRunSystemOptsPassed, opts)
 		return s.configureRunSystemErr
@@ -117,7 +126,7 @@ func (s *deviceMgrInstallModeSuite) makeMockInstalledPcGadget(c *C, grade, gadge
 		Current:  si.Revision,
 		Active:   true,
 	})
-	snaptest.MockSnapWithFiles(c, "name: pc\ntype: gadget", si, [][]string{
+	snaptest.MockSnapWithFiles(c, s.pcSnapYaml, si, [][]string{
 		{"meta/gadget.yaml", gadgetYaml + gadgetDefaultsYaml},
 	})
 
@@ -188,6 +197,19 @@ func (s *deviceMgrInstallModeSuite) doRunChangeTestWithEncryption(c *C, grade st
 	})
 	defer restore()
 
+	var sealOpts install.Options
+	var installSealCalled int
+	restore = devicestate.MockInstallSeal(func(options install.Options) error {
+		// ensure we can grab the lock here, i.e. that it's not taken
+		s.state.Lock()
+		s.state.Unlock()
+
+		sealOpts = options
+		installSealCalled++
+		return nil
+	})
+	defer restore()
+
 	restore = devicestate.MockSecbootCheckKeySealingSupported(func() error {
 		if tc.tpm {
 			return nil
@@ -256,17 +278,22 @@ func (s *deviceMgrInstallModeSuite) doRunChangeTestWithEncryption(c *C, grade st
 	if tc.encrypt {
 		c.Assert(brGadgetRoot, Equals, filepath.Join(dirs.SnapMountDir, "/pc/1"))
 		c.Assert(brDevice, Equals, "")
-		c.Assert(brOpts, DeepEquals, install.Options{
-			Mount:                   true,
+		expectedOpts := install.Options{
 			Encrypt:                 true,
 			KeyFile:                 filepath.Join(boot.InitramfsEncryptionKeyDir, "ubuntu-data.sealed-key"),
+			UnsealedKeyFile:         filepath.Join(dirs.SnapRunDir, "install/ubuntu-data.key"),
 			RecoveryKeyFile:         filepath.Join(boot.InstallHostWritableDir, "var/lib/snapd/device/fde/recovery.key"),
 			TPMLockoutAuthFile:      filepath.Join(boot.InstallHostWritableDir, "var/lib/snapd/device/fde/tpm-lockout-auth"),
 			TPMPolicyUpdateDataFile: filepath.Join(boot.InstallHostWritableDir, "var/lib/snapd/device/fde/policy-update-data"),
 			KernelPath:              filepath.Join(dirs.SnapMountDir, "pc-kernel/1/kernel.efi"),
 			Model:                   mockModel,
 			SystemLabel:             "20191218",
-		})
+		}
+		// the key is sealed by a separate step
+		c.Assert(installSealCalled, Equals, 1)
+		c.Assert(sealOpts, DeepEquals, expectedOpts)
+		expectedOpts.Mount = true
+		c.Assert(brOpts, DeepEquals, expectedOpts)
 
 		// directories were ensured
 		c.Assert(osutil.IsDirectory(boot.InitramfsEncryptionKeyDir), Equals, true)
@@ -277,6 +304,7 @@ func (s *deviceMgrInstallModeSuite) doRunChangeTestWithEncryption(c *C, grade st
 		c.Assert(brOpts, DeepEquals, install.Options{
 			Mount: true,
 		})
+		c.Assert(installSealCalled, Equals, 0)
 	}
 	c.Assert(installRunCalled, Equals, 1)
 	c.Assert(bootMakeBootableCalled, Equals, 1)
@@ -573,3 +601,159 @@ func (s *deviceMgrInstallModeSuite) TestInstallModeWritesModel(c *C) {
 
 	c.Check(filepath.Join(boot.InitramfsUbuntuBootDir, "model"), testutil.FileEquals, buf.String())
 }
+
+func (s *deviceMgrInstallModeSuite) runInstallDeviceHook(c *C, grade string, tpm bool, snapctlCmds ...[]string) (brOpts, sealOpts *install.Options, bootWith *boot.BootableSet, err error) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	restore = devicestate.MockInstallRun(func(gadgetRoot, device string, options install.Options) error {
+		brOpts = &options
+		if options.Encrypt {
+			// the key is kept until it is sealed
+			c.Assert(os.MkdirAll(filepath.Dir(options.UnsealedKeyFile), 0700), IsNil)
+			c.Assert(ioutil.WriteFile(options.UnsealedKeyFile, []byte("key"), 0600), IsNil)
+		}
+		return nil
+	})
+	defer restore()
+
+	restore = devicestate.MockInstallSeal(func(options install.Options) error {
+		// the hook ran in between
+		c.Check(brOpts, NotNil)
+		sealOpts = &options
+		if s.installSealErr != nil {
+			return s.installSealErr
+		}
+		return os.Remove(options.UnsealedKeyFile)
+	})
+	defer restore()
+
+	restore = devicestate.MockSecbootCheckKeySealingSupported(func() error {
+		if tpm {
+			return nil
+		}
+		return fmt.Errorf("TPM not available")
+	})
+	defer restore()
+
+	restore = devicestate.MockBootMakeBootable(func(model *asserts.Model, rootdir string, bw *boot.BootableSet) error {
+		bootWith = bw
+		return nil
+	})
+	defer restore()
+
+	hookCalled := 0
+	restore = hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		c.Assert(ctx.HookName(), Equals, "install-device")
+		c.Assert(ctx.InstanceName(), Equals, "pc")
+		// the partitions are created, but the key is not sealed yet
+		c.Check(brOpts, NotNil)
+		c.Check(sealOpts, IsNil)
+		c.Check(bootWith, IsNil)
+		hookCalled++
+		for _, cmd := range snapctlCmds {
+			if _, _, err := ctlcmd.Run(ctx, cmd, 0); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	defer restore()
+
+	s.pcSnapYaml = "name: pc\ntype: gadget\nhooks:\n  install-device:"
+	s.state.Lock()
+	s.makeMockInstalledPcGadget(c, grade, "")
+	s.state.Unlock()
+
+	modeenv := boot.Modeenv{
+		Mode:           "install",
+		RecoverySystem: "20191218",
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	devicestate.SetSystemMode(s.mgr, "install")
+
+	// normally done by snap-bootstrap
+	err = os.MkdirAll(boot.InitramfsUbuntuBootDir, 0755)
+	c.Assert(err, IsNil)
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	installSystem := s.findInstallSystem()
+	c.Assert(installSystem, NotNil)
+	c.Check(hookCalled, Equals, 1)
+
+	// the hook runs between partitioning and making the system bootable
+	tasks := installSystem.Tasks()
+	c.Assert(tasks, HasLen, 3)
+	c.Check(tasks[0].Kind(), Equals, "setup-run-system")
+	c.Check(tasks[1].Kind(), Equals, "run-hook")
+	c.Check(tasks[1].WaitTasks(), DeepEquals, []*state.Task{tasks[0]})
+	c.Check(tasks[2].Kind(), Equals, "make-run-system-bootable")
+	c.Check(tasks[2].WaitTasks(), DeepEquals, []*state.Task{tasks[1]})
+
+	if err := installSystem.Err(); err != nil {
+		return brOpts, sealOpts, bootWith, err
+	}
+	c.Assert(installSystem.Status(), Equals, state.DoneStatus)
+	return brOpts, sealOpts, bootWith, nil
+}
+
+func (s *deviceMgrInstallModeSuite) TestInstallDeviceHookNoOptions(c *C) {
+	brOpts, sealOpts, bootWith, err := s.runInstallDeviceHook(c, "signed", true)
+	c.Assert(err, IsNil)
+
+	// same as without the hook
+	c.Check(brOpts.Encrypt, Equals, true)
+	c.Assert(sealOpts, NotNil)
+	c.Check(sealOpts.KernelCmdlineExtra, Equals, "")
+	c.Check(bootWith.KernelCmdlineExtra, Equals, "")
+	c.Assert(s.configureRunSystemOptsPassed, HasLen, 1)
+	c.Check(s.configureRunSystemOptsPassed[0].SystemOptions, IsNil)
+}
+
+func (s *deviceMgrInstallModeSuite) TestInstallDeviceHookOptions(c *C) {
+	brOpts, sealOpts, bootWith, err := s.runInstallDeviceHook(c, "signed", true,
+		[]string{"install-device", "system-option", "service.ssh.disable=true", "system.timezone=Europe/Berlin"},
+		[]string{"install-device", "kernel-cmdline", "quiet", "splash"},
+		[]string{"install-device", "kernel-cmdline", "console=ttyS0"},
+	)
+	c.Assert(err, IsNil)
+
+	c.Check(brOpts.Encrypt, Equals, true)
+	// the key is sealed to the extended kernel command line
+	c.Assert(sealOpts, NotNil)
+	c.Check(sealOpts.KernelCmdlineExtra, Equals, "quiet splash console=ttyS0")
+	c.Check(bootWith.KernelCmdlineExtra, Equals, "quiet splash console=ttyS0")
+	c.Assert(s.configureRunSystemOptsPassed, HasLen, 1)
+	c.Check(s.configureRunSystemOptsPassed[0].SystemOptions, DeepEquals, map[string]interface{}{
+		"service.ssh.disable": true,
+		"system.timezone":     "Europe/Berlin",
+	})
+}
+
+func (s *deviceMgrInstallModeSuite) TestInstallDeviceHookInvalidOption(c *C) {
+	brOpts, sealOpts, bootWith, err := s.runInstallDeviceHook(c, "signed", true,
+		[]string{"install-device", "kernel-cmdline", "$(reboot)"},
+	)
+	c.Assert(err, ErrorMatches, `(?s).*invalid character '\$' in kernel command line arguments.*`)
+	// the run system was not configured nor made bootable
+	c.Check(s.configureRunSystemOptsPassed, HasLen, 0)
+	c.Check(sealOpts, IsNil)
+	c.Check(bootWith, IsNil)
+	// and the encryption key was not left around unsealed
+	c.Assert(brOpts, NotNil)
+	c.Check(brOpts.UnsealedKeyFile, testutil.FileAbsent)
+}
+
+func (s *deviceMgrInstallModeSuite) TestInstallDeviceSealErrorRemovesUnsealedKey(c *C) {
+	s.installSealErr = fmt.Errorf("cannot seal the encryption key: boom")
+	brOpts, _, bootWith, err := s.runInstallDeviceHook(c, "signed", true)
+	c.Assert(err, ErrorMatches, `(?s).*cannot seal the encryption key: boom.*`)
+	c.Check(bootWith, IsNil)
+	c.Assert(brOpts, NotNil)
+	c.Check(brOpts.UnsealedKeyFile, Equals, filepath.Join(dirs.SnapRunDir, "install/ubuntu-data.key"))
+	c.Check(brOpts.UnsealedKeyFile, testutil.FileAbsent)
+}
@@ -1006,6 +1006,34 @@ func (s *deviceMgrSuite) TestCanManageRefreshes(c *C) {
 	c.Check(devicestate.CanManageRefreshes(st), Equals, true)
 }
 
+func (s *deviceMgrSuite) TestOfflineStoreKeys(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// no model, no keys
+	keys, err := devicestate.OfflineStoreKeys(st)
+	c.Assert(err, IsNil)
+	c.Check(keys, HasLen, 0)
+
+	s.makeModelAssertionInState(c, "my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	devicestatetest.SetDevice(st, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "my-model",
+	})
+
+	// only the keys of the brand
+	keys, err = devicestate.OfflineStoreKeys(st)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 1)
+	c.Check(keys[0].AccountID(), Equals, "my-brand")
+	c.Check(keys[0].PublicKeyID(), Equals, s.brands.AccountKey("my-brand").PublicKeyID())
+}
+
 func (s *deviceMgrSuite) TestCanManageRefreshesNoRefreshScheduleManaged(c *C) {
 	st := s.state
 	st.Lock()
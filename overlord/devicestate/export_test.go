@@ -256,6 +256,14 @@ func MockInstallRun(f func(gadgetRoot, device string, options install.Options) e
 	}
 }
 
+func MockInstallSeal(f func(options install.Options) error) (restore func()) {
+	old := installSeal
+	installSeal = f
+	return func() {
+		installSeal = old
+	}
+}
+
 func MockCloudInitStatus(f func() (sysconfig.CloudInitState, error)) (restore func()) {
 	old := cloudInitStatus
 	cloudInitStatus = f
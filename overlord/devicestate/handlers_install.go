@@ -42,6 +42,7 @@ var (
 	bootMakeBootable            = boot.MakeBootable
 	sysconfigConfigureRunSystem = sysconfig.ConfigureRunSystem
 	installRun                  = install.Run
+	installSeal                 = install.Seal
 )
 
 func setSysconfigCloudOptions(opts *sysconfig.Options, gadgetDir string, model *asserts.Model) {
@@ -70,6 +71,33 @@ func writeModel(model *asserts.Model, where string) error {
 	return asserts.NewEncoder(f).Encode(model)
 }
 
+// encryptedDeviceKey records in the install-system change whether
+// setup-run-system encrypted the data partition.
+const encryptedDeviceKey = "encrypted-device"
+
+// unsealedKeyFile is where setup-run-system keeps the encryption key of
+// the data partition until make-run-system-bootable seals it.
+func unsealedKeyFile() string {
+	return filepath.Join(dirs.SnapRunDir, "install/ubuntu-data.key")
+}
+
+// encryptionOptions returns the install options locating the keys of the
+// encrypted data partition of the run system.
+func encryptionOptions(model *asserts.Model, kernelDir, systemLabel string) install.Options {
+	fdeDir := filepath.Join(boot.InstallHostWritableDir, "var/lib/snapd/device/fde")
+	return install.Options{
+		Encrypt:                 true,
+		KeyFile:                 filepath.Join(boot.InitramfsEncryptionKeyDir, "ubuntu-data.sealed-key"),
+		UnsealedKeyFile:         unsealedKeyFile(),
+		RecoveryKeyFile:         filepath.Join(fdeDir, "recovery.key"),
+		TPMLockoutAuthFile:      filepath.Join(fdeDir, "tpm-lockout-auth"),
+		TPMPolicyUpdateDataFile: filepath.Join(fdeDir, "policy-update-data"),
+		KernelPath:              filepath.Join(kernelDir, "kernel.efi"),
+		Model:                   model,
+		SystemLabel:             systemLabel,
+	}
+}
+
 func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 	st.Lock()
@@ -112,25 +140,18 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 	if useEncryption {
-		fdeDir := "var/lib/snapd/device/fde"
+		bopts = encryptionOptions(deviceCtx.Model(), kernelDir, modeEnv.RecoverySystem)
+		bopts.Mount = true
 		// ensure directories
-		for _, p := range []string{boot.InitramfsEncryptionKeyDir, filepath.Join(boot.InstallHostWritableDir, fdeDir)} {
+		for _, p := range []string{boot.InitramfsEncryptionKeyDir, filepath.Dir(bopts.RecoveryKeyFile)} {
 			if err := os.MkdirAll(p, 0755); err != nil {
 				return err
 			}
 		}
-
-		bopts.Encrypt = true
-		bopts.KeyFile = filepath.Join(boot.InitramfsEncryptionKeyDir, "ubuntu-data.sealed-key")
-		bopts.RecoveryKeyFile = filepath.Join(boot.InstallHostWritableDir, fdeDir, "recovery.key")
-		bopts.TPMLockoutAuthFile = filepath.Join(boot.InstallHostWritableDir, fdeDir, "tpm-lockout-auth")
-		bopts.TPMPolicyUpdateDataFile = filepath.Join(boot.InstallHostWritableDir, fdeDir, "policy-update-data")
-		bopts.KernelPath = filepath.Join(kernelDir, "kernel.efi")
-		bopts.Model = deviceCtx.Model()
-		bopts.SystemLabel = modeEnv.RecoverySystem
 	}
 
-	// run the create partition code
+	// run the create partition code, the encryption key is sealed
+	// later by make-run-system-bootable
 	logger.Noticef("create and deploy partitions")
 	func() {
 		st.Unlock()
@@ -140,6 +161,7 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return fmt.Errorf("cannot create partitions: %v", err)
 	}
+	t.Change().Set(encryptedDeviceKey, useEncryption)
 
 	// keep track of the model we installed
 	err = writeModel(deviceCtx.Model(), filepath.Join(boot.InitramfsUbuntuBootDir, "model"))
@@ -147,14 +169,89 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("cannot store the model: %v", err)
 	}
 
+	return nil
+}
+
+// undoSetupRunSystem removes the encryption key left unsealed when the
+// install fails before make-run-system-bootable sealed it, be it in the
+// install-device hook of the gadget or while sealing.
+func (m *DeviceManager) undoSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
+	if err := os.Remove(unsealedKeyFile()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove unsealed encryption key: %v", err)
+	}
+	return nil
+}
+
+func (m *DeviceManager) doMakeRunSystemBootable(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(t)
+	defer perfTimings.Save(st)
+
+	// get gadget dir
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return fmt.Errorf("cannot get device context: %v", err)
+	}
+	gadgetInfo, err := snapstate.GadgetInfo(st, deviceCtx)
+	if err != nil {
+		return fmt.Errorf("cannot get gadget info: %v", err)
+	}
+	gadgetDir := gadgetInfo.MountDir()
+
+	kernelInfo, err := snapstate.KernelInfo(st, deviceCtx)
+	if err != nil {
+		return fmt.Errorf("cannot get kernel info: %v", err)
+	}
+
+	modeEnv, err := maybeReadModeenv()
+	if err != nil {
+		return err
+	}
+	if modeEnv == nil {
+		return fmt.Errorf("missing modeenv, cannot proceed")
+	}
+
+	// choices made by the install-device hook of the gadget, if any
+	installOpts, err := installDeviceOptions(t)
+	if err != nil {
+		return fmt.Errorf("cannot get install-device options: %v", err)
+	}
+
+	var encrypted bool
+	if err := t.Change().Get(encryptedDeviceKey, &encrypted); err != nil && err != state.ErrNoState {
+		return err
+	}
+
 	// configure the run system
-	opts := &sysconfig.Options{TargetRootDir: boot.InstallHostWritableDir, GadgetDir: gadgetDir}
+	opts := &sysconfig.Options{
+		TargetRootDir: boot.InstallHostWritableDir,
+		GadgetDir:     gadgetDir,
+		SystemOptions: installOpts.SystemOptions,
+	}
 	// configure cloud init
 	setSysconfigCloudOptions(opts, gadgetDir, deviceCtx.Model())
 	if err := sysconfigConfigureRunSystem(opts); err != nil {
 		return err
 	}
 
+	// seal the encryption key now that the kernel command line is known
+	if encrypted {
+		logger.Noticef("seal encryption key")
+		sopts := encryptionOptions(deviceCtx.Model(), kernelInfo.MountDir(), modeEnv.RecoverySystem)
+		sopts.KernelCmdlineExtra = installOpts.KernelCmdlineExtra
+		func() {
+			st.Unlock()
+			defer st.Lock()
+			err = installSeal(sopts)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
 	// make it bootable
 	logger.Noticef("make system bootable")
 	bootBaseInfo, err := snapstate.BootBaseInfo(st, deviceCtx)
@@ -169,6 +266,8 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 		KernelPath:        kernelInfo.MountFile(),
 		RecoverySystemDir: recoverySystemDir,
 		UnpackedGadgetDir: gadgetDir,
+
+		KernelCmdlineExtra: installOpts.KernelCmdlineExtra,
 	}
 	rootdir := dirs.GlobalRootDir
 	if err := bootMakeBootable(deviceCtx.Model(), rootdir, bootWith); err != nil {
@@ -0,0 +1,174 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// installDeviceKey is where the install-device options are kept, both in
+// the context of the hook and in the install-system change.
+const installDeviceKey = "install-device"
+
+// InstallDeviceOptions carries the provisioning choices the gadget made in
+// its install-device hook, they are applied by make-run-system-bootable.
+// The hook runs once the partitions are created, so whether the data
+// partition is encrypted is not among them.
+type InstallDeviceOptions struct {
+	// SystemOptions are core configuration options applied to the run
+	// system on top of the gadget defaults.
+	SystemOptions map[string]interface{} `json:"system-options,omitempty"`
+	// KernelCmdlineExtra is appended to the kernel command line of the
+	// run system.
+	KernelCmdlineExtra string `json:"kernel-cmdline-extra,omitempty"`
+}
+
+// Validate checks the options for consistency.
+func (opts *InstallDeviceOptions) Validate() error {
+	for key := range opts.SystemOptions {
+		if !strings.Contains(key, ".") || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+			return fmt.Errorf("invalid system option %q", key)
+		}
+	}
+	for _, r := range opts.KernelCmdlineExtra {
+		// the arguments end up in the bootloader environment
+		if !unicode.IsPrint(r) || r == '\'' || r == '"' || r == '\\' || r == '$' {
+			return fmt.Errorf("invalid character %q in kernel command line arguments", r)
+		}
+	}
+	return nil
+}
+
+func checkInstallDeviceContext(context *hookstate.Context) error {
+	if context.IsEphemeral() || context.HookName() != "install-device" {
+		return fmt.Errorf("cannot use install-device options outside of the install-device hook")
+	}
+	return nil
+}
+
+// InstallDeviceOptionsFromContext returns the options set so far by the
+// install-device hook running in the given context. The context must be
+// locked.
+func InstallDeviceOptionsFromContext(context *hookstate.Context) (*InstallDeviceOptions, error) {
+	if err := checkInstallDeviceContext(context); err != nil {
+		return nil, err
+	}
+	var opts InstallDeviceOptions
+	if err := context.Get(installDeviceKey, &opts); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// SetInstallDeviceOptions validates and records the options of the
+// install-device hook running in the given context. The context must be
+// locked.
+func SetInstallDeviceOptions(context *hookstate.Context, opts *InstallDeviceOptions) error {
+	if err := checkInstallDeviceContext(context); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	context.Set(installDeviceKey, opts)
+	return nil
+}
+
+// gadgetWithInstallDeviceHook returns the name of the gadget snap if it
+// has an install-device hook, the empty string otherwise.
+func gadgetWithInstallDeviceHook(st *state.State) (string, error) {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err == state.ErrNoState {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot get device context: %v", err)
+	}
+	gadgetInfo, err := snapstate.GadgetInfo(st, deviceCtx)
+	if err == state.ErrNoState {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot get gadget info: %v", err)
+	}
+	if gadgetInfo.Hooks["install-device"] == nil {
+		return "", nil
+	}
+	return gadgetInfo.InstanceName(), nil
+}
+
+// installDeviceOptions returns the options recorded by the install-device
+// hook of the change of the task, if any.
+func installDeviceOptions(t *state.Task) (*InstallDeviceOptions, error) {
+	var opts InstallDeviceOptions
+	chg := t.Change()
+	if chg == nil {
+		return &opts, nil
+	}
+	if err := chg.Get(installDeviceKey, &opts); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+type installDeviceHandler struct {
+	context *hookstate.Context
+}
+
+func newInstallDeviceHandler(context *hookstate.Context) hookstate.Handler {
+	return installDeviceHandler{context: context}
+}
+
+func (h installDeviceHandler) Before() error {
+	return nil
+}
+
+// Done hands the options set by the hook over to the install-system change.
+func (h installDeviceHandler) Done() error {
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	task, ok := h.context.Task()
+	if !ok {
+		return fmt.Errorf("internal error: install-device hook without a task")
+	}
+	var opts InstallDeviceOptions
+	if err := h.context.Get(installDeviceKey, &opts); err != nil {
+		if err == state.ErrNoState {
+			return nil
+		}
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	task.Change().Set(installDeviceKey, &opts)
+	return nil
+}
+
+func (h installDeviceHandler) Error(err error) error {
+	return nil
+}
@@ -20,8 +20,10 @@
 package overlord
 
 import (
+	"net"
 	"time"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -83,6 +85,16 @@ func MockStoreNew(new func(*store.Config, store.DeviceAndAuthContext) *store.Sto
 	}
 }
 
+// MockOfflineStoreKeys mocks devicestate.OfflineStoreKeys as called by
+// overlord.New.
+func MockOfflineStoreKeys(f func(*state.State) ([]*asserts.AccountKey, error)) (restore func()) {
+	old := offlineStoreKeys
+	offlineStoreKeys = f
+	return func() {
+		offlineStoreKeys = old
+	}
+}
+
 func MockConfigstateInit(new func(*state.State, *hookstate.HookManager) error) (restore func()) {
 	configstateInit = new
 	return func() {
@@ -97,3 +109,13 @@ func MockPreseedExitWithError(f func(err error)) (restore func()) {
 		preseedExitWithError = old
 	}
 }
+
+// PeerCacheManager exposes the manager serving the peer cache.
+func (o *Overlord) PeerCacheManager() StateManager {
+	return o.peerCacheMgr
+}
+
+// PeerCacheAddr returns the address the peer cache is served on.
+func (o *Overlord) PeerCacheAddr() net.Addr {
+	return o.peerCacheMgr.server.Addr()
+}
@@ -22,6 +22,7 @@ package ctlcmd
 import (
 	"fmt"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -88,3 +89,21 @@ func (c *MockCommand) Execute(args []string) error {
 
 	return nil
 }
+
+func MockDevicestateSerialFromState(f func(*state.State) (*asserts.Serial, error)) (restore func()) {
+	old := devicestateSerialFromState
+	devicestateSerialFromState = f
+	return func() { devicestateSerialFromState = old }
+}
+
+func MockSnapstateAddSchedule(f func(st *state.State, instanceName, name, schedule, appName string) error) (restore func()) {
+	old := snapstateAddSchedule
+	snapstateAddSchedule = f
+	return func() { snapstateAddSchedule = old }
+}
+
+func MockSnapstateRemoveSchedule(f func(st *state.State, instanceName, name string) error) (restore func()) {
+	old := snapstateRemoveSchedule
+	snapstateRemoveSchedule = f
+	return func() { snapstateRemoveSchedule = old }
+}
@@ -26,8 +26,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
@@ -230,3 +232,41 @@ func getAttribute(snapName string, subkeys []string, pos int, attrs map[string]i
 	}
 	return getAttribute(snapName, subkeys, pos+1, attrsm, result)
 }
+
+// publishedByBrand returns whether the given snap is published by the
+// brand of the model. Snaps without a snap-id, like sideloaded ones, are
+// never considered as published by the brand.
+func publishedByBrand(st *state.State, instanceName string, model *asserts.Model) (bool, error) {
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(st, instanceName, &snapst); err != nil {
+		return false, err
+	}
+	snapID := snapst.CurrentSideInfo().SnapID
+	if snapID == "" {
+		return false, nil
+	}
+	decl, err := assertstate.SnapDeclaration(st, snapID)
+	if err != nil {
+		return false, fmt.Errorf("cannot find snap-declaration for %q: %v", instanceName, err)
+	}
+	return decl.PublisherID() == model.BrandID(), nil
+}
+
+// canAccessDeviceIdentity returns an error unless the snap is one of the
+// given snaps of the model or is published by the brand of the model.
+func canAccessDeviceIdentity(st *state.State, instanceName string, model *asserts.Model, modelSnaps ...string) error {
+	snapName := snap.InstanceSnap(instanceName)
+	for _, name := range modelSnaps {
+		if name != "" && name == snapName {
+			return nil
+		}
+	}
+	ok, err := publishedByBrand(st, instanceName, model)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("snap %q is not published by the brand of the model %q", instanceName, model.BrandID())
+	}
+	return nil
+}
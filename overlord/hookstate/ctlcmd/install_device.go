@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/devicestate"
+)
+
+var (
+	shortInstallDeviceHelp = i18n.G("Provision the device being installed")
+	longInstallDeviceHelp  = i18n.G(`
+The install-device command can only be used by the install-device hook of
+the gadget, which runs in install mode once the partitions are created and
+before the encryption key is sealed and the run system made bootable. It
+chooses how the run system is set up:
+
+$ snapctl install-device system-option service.ssh.disable=true
+$ snapctl install-device kernel-cmdline quiet splash
+
+System options are applied on top of the gadget defaults, with the same
+subset of options supported at install time. The kernel command line
+arguments are appended to the ones of the run system, and to the ones given
+by earlier calls.
+`)
+)
+
+func init() {
+	addCommand("install-device", shortInstallDeviceHelp, longInstallDeviceHelp, func() command { return &installDeviceCommand{} })
+}
+
+type installDeviceCommand struct {
+	baseCommand
+
+	Positional struct {
+		Action string   `positional-arg-name:"<system-option|kernel-cmdline>" required:"yes"`
+		Values []string `positional-arg-name:"<value>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *installDeviceCommand) Execute(args []string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf("cannot provision the device without a context")
+	}
+
+	context.Lock()
+	defer context.Unlock()
+
+	opts, err := devicestate.InstallDeviceOptionsFromContext(context)
+	if err != nil {
+		return err
+	}
+
+	values := c.Positional.Values
+	switch c.Positional.Action {
+	case "system-option":
+		if opts.SystemOptions == nil {
+			opts.SystemOptions = make(map[string]interface{}, len(values))
+		}
+		for _, keyValue := range values {
+			parts := strings.SplitN(keyValue, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf(i18n.G("invalid parameter: %q (want key=value)"), keyValue)
+			}
+			var value interface{}
+			if err := jsonutil.DecodeWithNumber(strings.NewReader(parts[1]), &value); err != nil {
+				// Not valid JSON-- just save the string as-is.
+				value = parts[1]
+			}
+			opts.SystemOptions[parts[0]] = value
+		}
+	case "kernel-cmdline":
+		if opts.KernelCmdlineExtra != "" {
+			opts.KernelCmdlineExtra += " "
+		}
+		opts.KernelCmdlineExtra += strings.Join(values, " ")
+	default:
+		return fmt.Errorf(i18n.G("unknown install-device action %q, expected system-option or kernel-cmdline"), c.Positional.Action)
+	}
+
+	return devicestate.SetInstallDeviceOptions(context, opts)
+}
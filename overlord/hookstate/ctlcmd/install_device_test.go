@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type installDeviceSuite struct {
+	testutil.BaseTest
+	state       *state.State
+	mockContext *hookstate.Context
+}
+
+var _ = Suite(&installDeviceSuite{})
+
+func (s *installDeviceSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.state = state.New(nil)
+	s.mockContext = s.hookContext(c, "install-device")
+}
+
+func (s *installDeviceSuite) hookContext(c *C, hook string) *hookstate.Context {
+	s.state.Lock()
+	task := s.state.NewTask("test-task", "my test task")
+	s.state.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "pc", Revision: snap.R(1), Hook: hook}
+	ctx, err := hookstate.NewContext(task, s.state, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+	return ctx
+}
+
+func (s *installDeviceSuite) options(c *C) *devicestate.InstallDeviceOptions {
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	opts, err := devicestate.InstallDeviceOptionsFromContext(s.mockContext)
+	c.Assert(err, IsNil)
+	return opts
+}
+
+func (s *installDeviceSuite) TestInstallDevice(c *C) {
+	for _, cmd := range [][]string{
+		{"install-device", "system-option", "service.ssh.disable=true", "system.timezone=UTC"},
+		{"install-device", "system-option", "system.hostname=foo"},
+		{"install-device", "kernel-cmdline", "quiet", "splash"},
+		{"install-device", "kernel-cmdline", "console=ttyS0"},
+	} {
+		stdout, stderr, err := ctlcmd.Run(s.mockContext, cmd, 0)
+		c.Assert(err, IsNil, Commentf("%v", cmd))
+		c.Check(string(stdout), Equals, "")
+		c.Check(string(stderr), Equals, "")
+	}
+
+	c.Check(s.options(c), DeepEquals, &devicestate.InstallDeviceOptions{
+		SystemOptions: map[string]interface{}{
+			"service.ssh.disable": true,
+			"system.timezone":     "UTC",
+			"system.hostname":     "foo",
+		},
+		KernelCmdlineExtra: "quiet splash console=ttyS0",
+	})
+}
+
+func (s *installDeviceSuite) TestInstallDeviceErrors(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"install-device", "kernel-cmdline", "quiet"}, 0)
+	c.Check(err, ErrorMatches, `cannot provision the device without a context`)
+
+	_, _, err = ctlcmd.Run(s.hookContext(c, "configure"), []string{"install-device", "kernel-cmdline", "quiet"}, 0)
+	c.Check(err, ErrorMatches, `cannot use install-device options outside of the install-device hook`)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"install-device", "kernel-cmdline", "quiet"}, 1000)
+	c.Check(err, ErrorMatches, `cannot use "install-device" with uid 1000, try with sudo`)
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"install-device", "system-option", "foo"}, `invalid parameter: "foo" \(want key=value\)`},
+		{[]string{"install-device", "system-option", "foo=bar"}, `invalid system option "foo"`},
+		{[]string{"install-device", "kernel-cmdline", "$(reboot)"}, `invalid character '\$' in kernel command line arguments`},
+		{[]string{"install-device", "encryption", "disabled"}, `unknown install-device action "encryption", expected system-option or kernel-cmdline`},
+		{[]string{"install-device", "partition", "foo"}, `unknown install-device action "partition", expected system-option or kernel-cmdline`},
+	} {
+		_, _, err := ctlcmd.Run(s.mockContext, t.args, 0)
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.args))
+	}
+
+	// nothing was recorded
+	c.Check(s.options(c), DeepEquals, &devicestate.InstallDeviceOptions{})
+}
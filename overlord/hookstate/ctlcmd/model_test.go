@@ -0,0 +1,200 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type modelSuite struct {
+	testutil.BaseTest
+	state        *state.State
+	storeSigning *assertstest.StoreStack
+	brands       *assertstest.SigningAccounts
+	model        *asserts.Model
+	serial       *asserts.Serial
+}
+
+var _ = Suite(&modelSuite{})
+
+func (s *modelSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.state = state.New(nil)
+
+	s.storeSigning = assertstest.NewStoreStack("can0nical", nil)
+	s.brands = assertstest.NewSigningAccounts(s.storeSigning)
+	brandPrivKey, _ := assertstest.GenerateKey(752)
+	s.brands.Register("my-brand", brandPrivKey, nil)
+	s.model = s.brands.Model("my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       "my-gadget",
+		"kernel":       "my-kernel",
+	})
+	s.AddCleanup(snapstatetest.MockDeviceModel(s.model))
+
+	devKey, _ := assertstest.GenerateKey(752)
+	encDevKey, err := asserts.EncodePublicKey(devKey.PublicKey())
+	c.Assert(err, IsNil)
+	serial, err := s.brands.Signing("my-brand").Sign(asserts.SerialType, map[string]interface{}{
+		"brand-id":            "my-brand",
+		"model":               "my-model",
+		"serial":              "serialserial",
+		"device-key":          string(encDevKey),
+		"device-key-sha3-384": devKey.PublicKey().ID(),
+		"timestamp":           time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	s.serial = serial.(*asserts.Serial)
+	s.AddCleanup(ctlcmd.MockDevicestateSerialFromState(func(*state.State) (*asserts.Serial, error) {
+		return s.serial, nil
+	}))
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+	assertstate.ReplaceDB(s.state, db)
+	c.Assert(db.Add(s.storeSigning.StoreAccountKey("")), IsNil)
+	for _, a := range s.brands.AccountsAndKeys("my-brand") {
+		c.Assert(db.Add(a), IsNil)
+	}
+	otherAcct := assertstest.NewAccount(s.storeSigning, "other", map[string]interface{}{
+		"account-id": "other-id",
+	}, "")
+	c.Assert(db.Add(otherAcct), IsNil)
+
+	for _, sn := range []struct{ name, publisher string }{
+		{"my-gadget", "other-id"},
+		{"my-kernel", "other-id"},
+		{"brand-snap", "my-brand"},
+		{"other-snap", "other-id"},
+	} {
+		decl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+			"series":       "16",
+			"snap-id":      sn.name + "-id",
+			"snap-name":    sn.name,
+			"publisher-id": sn.publisher,
+			"timestamp":    time.Now().Format(time.RFC3339),
+		}, nil, "")
+		c.Assert(err, IsNil)
+		c.Assert(db.Add(decl), IsNil)
+		snapstate.Set(s.state, sn.name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{{RealName: sn.name, SnapID: sn.name + "-id", Revision: snap.R(1)}},
+			Current:  snap.R(1),
+		})
+	}
+	snapstate.Set(s.state, "local-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "local-snap", Revision: snap.R(-1)}},
+		Current:  snap.R(-1),
+	})
+}
+
+func (s *modelSuite) newContext(c *C, snapName string) *hookstate.Context {
+	s.state.Lock()
+	task := s.state.NewTask("test-task", "my test task")
+	s.state.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: snapName, Revision: snap.R(1), Hook: "configure"}
+	ctx, err := hookstate.NewContext(task, s.state, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+	return ctx
+}
+
+func (s *modelSuite) TestModelNoContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"model"}, 0)
+	c.Check(err, ErrorMatches, `cannot get model without a context`)
+}
+
+func (s *modelSuite) TestModel(c *C) {
+	for _, name := range []string{"my-gadget", "my-kernel", "brand-snap"} {
+		stdout, stderr, err := ctlcmd.Run(s.newContext(c, name), []string{"model"}, 0)
+		c.Assert(err, IsNil, Commentf(name))
+		c.Check(string(stdout), Equals, string(asserts.Encode(s.model))+"\n")
+		c.Check(string(stderr), Equals, "")
+	}
+
+	// non-root users can get the model too
+	_, _, err := ctlcmd.Run(s.newContext(c, "brand-snap"), []string{"model"}, 1000)
+	c.Check(err, IsNil)
+}
+
+func (s *modelSuite) TestModelNotFromBrand(c *C) {
+	_, _, err := ctlcmd.Run(s.newContext(c, "other-snap"), []string{"model"}, 0)
+	c.Check(err, ErrorMatches, `cannot get model: snap "other-snap" is not published by the brand of the model "my-brand"`)
+
+	_, _, err = ctlcmd.Run(s.newContext(c, "local-snap"), []string{"model"}, 0)
+	c.Check(err, ErrorMatches, `cannot get model: snap "local-snap" is not published by the brand of the model "my-brand"`)
+}
+
+func (s *modelSuite) TestModelNotYetKnown(c *C) {
+	restore := snapstatetest.MockDeviceContext(nil)
+	defer restore()
+
+	_, _, err := ctlcmd.Run(s.newContext(c, "my-gadget"), []string{"model"}, 0)
+	c.Check(err, ErrorMatches, `cannot get model: no model assertion yet`)
+}
+
+func (s *modelSuite) TestSerial(c *C) {
+	for _, name := range []string{"my-gadget", "brand-snap"} {
+		stdout, _, err := ctlcmd.Run(s.newContext(c, name), []string{"serial"}, 0)
+		c.Assert(err, IsNil, Commentf(name))
+		c.Check(string(stdout), Equals, string(asserts.Encode(s.serial))+"\n")
+	}
+}
+
+func (s *modelSuite) TestSerialOnlyGadgetAndBrand(c *C) {
+	for _, name := range []string{"my-kernel", "other-snap", "local-snap"} {
+		_, _, err := ctlcmd.Run(s.newContext(c, name), []string{"serial"}, 0)
+		c.Check(err, ErrorMatches, `cannot get serial: snap ".*" is not published by the brand of the model "my-brand"`)
+	}
+
+	_, _, err := ctlcmd.Run(s.newContext(c, "brand-snap"), []string{"serial"}, 1000)
+	c.Check(err, ErrorMatches, `cannot use "serial" with uid 1000, try with sudo`)
+}
+
+func (s *modelSuite) TestSerialNotRegistered(c *C) {
+	restore := ctlcmd.MockDevicestateSerialFromState(func(*state.State) (*asserts.Serial, error) {
+		return nil, state.ErrNoState
+	})
+	defer restore()
+
+	_, _, err := ctlcmd.Run(s.newContext(c, "my-gadget"), []string{"serial"}, 0)
+	c.Check(err, ErrorMatches, `cannot get serial: device not yet registered`)
+}
@@ -0,0 +1,90 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+var (
+	shortRefreshHelp = i18n.G("Hold or proceed with pending auto-refreshes")
+	longRefreshHelp  = i18n.G(`
+The refresh command is called from the gate-auto-refresh hook of a snap to
+decide about the auto-refresh of the snaps affecting it, that is the snap
+itself and the snaps connected to it, which are about to be refreshed.
+
+With --hold the auto-refresh of those snaps is postponed until the next
+auto-refresh, when the hook runs again. Snaps can postpone the refresh of
+another snap for a limited time only, after which --hold fails and the snap
+is refreshed.
+
+With --proceed, or when the hook does not call 'snapctl refresh', the snaps
+are refreshed. They are held when the hook fails.
+`)
+)
+
+func init() {
+	addCommand("refresh", shortRefreshHelp, longRefreshHelp, func() command { return &refreshCommand{} })
+}
+
+type refreshCommand struct {
+	baseCommand
+	Hold    bool `long:"hold" description:"Postpone the auto-refresh of the snaps affecting this snap"`
+	Proceed bool `long:"proceed" description:"Proceed with the auto-refresh of the snaps affecting this snap"`
+}
+
+func (c *refreshCommand) Execute([]string) error {
+	ctx := c.context()
+	if ctx == nil {
+		// reuses the i18n'ed error message from service ctl
+		return fmt.Errorf(i18n.G("cannot %s without a context"), "refresh")
+	}
+	if ctx.IsEphemeral() || ctx.HookName() != "gate-auto-refresh" {
+		return fmt.Errorf("can only be used from gate-auto-refresh hook")
+	}
+	if c.Hold == c.Proceed {
+		return fmt.Errorf("either --hold or --proceed must be used")
+	}
+
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	st := ctx.State()
+	if c.Proceed {
+		if err := snapstate.ProceedWithRefresh(st, ctx.InstanceName()); err != nil {
+			return err
+		}
+		ctx.Set("refresh-decision", "proceed")
+		return nil
+	}
+
+	var affecting []string
+	if err := ctx.Get("affecting-snaps", &affecting); err != nil {
+		return fmt.Errorf("internal error: cannot get snaps affecting %q: %v", ctx.InstanceName(), err)
+	}
+	if err := snapstate.HoldRefresh(st, ctx.InstanceName(), affecting...); err != nil {
+		return err
+	}
+	ctx.Set("refresh-decision", "hold")
+	return nil
+}
@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type refreshSuite struct {
+	testutil.BaseTest
+	state       *state.State
+	mockHandler *hooktest.MockHandler
+}
+
+var _ = check.Suite(&refreshSuite{})
+
+func (s *refreshSuite) SetUpTest(c *check.C) {
+	s.BaseTest.SetUpTest(c)
+	s.mockHandler = hooktest.NewMockHandler()
+	s.state = state.New(nil)
+}
+
+func (s *refreshSuite) newContext(c *check.C, hook string) *hookstate.Context {
+	s.state.Lock()
+	task := s.state.NewTask("test-task", "my test task")
+	s.state.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "gating-snap", Revision: snap.R(1), Hook: hook}
+	ctx, err := hookstate.NewContext(task, s.state, setup, s.mockHandler, "")
+	c.Assert(err, check.IsNil)
+
+	ctx.Lock()
+	ctx.Set("affecting-snaps", []string{"gating-snap", "some-snap"})
+	ctx.Unlock()
+	return ctx
+}
+
+func (s *refreshSuite) TestBadArgs(c *check.C) {
+	_, _, err := ctlcmd.Run(nil, []string{"refresh", "--hold"}, 0)
+	c.Check(err, check.ErrorMatches, `cannot refresh without a context`)
+
+	ctx := s.newContext(c, "configure")
+	_, _, err = ctlcmd.Run(ctx, []string{"refresh", "--hold"}, 0)
+	c.Check(err, check.ErrorMatches, `can only be used from gate-auto-refresh hook`)
+
+	ctx = s.newContext(c, "gate-auto-refresh")
+	_, _, err = ctlcmd.Run(ctx, []string{"refresh"}, 0)
+	c.Check(err, check.ErrorMatches, `either --hold or --proceed must be used`)
+	_, _, err = ctlcmd.Run(ctx, []string{"refresh", "--hold", "--proceed"}, 0)
+	c.Check(err, check.ErrorMatches, `either --hold or --proceed must be used`)
+}
+
+func (s *refreshSuite) TestHoldAndProceed(c *check.C) {
+	ctx := s.newContext(c, "gate-auto-refresh")
+
+	_, _, err := ctlcmd.Run(ctx, []string{"refresh", "--hold"}, 0)
+	c.Assert(err, check.IsNil)
+
+	ctx.Lock()
+	var holds map[string]map[string]interface{}
+	c.Assert(ctx.State().Get("snaps-hold", &holds), check.IsNil)
+	c.Check(holds, check.HasLen, 2)
+	c.Check(holds["gating-snap"]["gating-snap"], check.NotNil)
+	c.Check(holds["some-snap"]["gating-snap"], check.NotNil)
+	var decision string
+	c.Assert(ctx.Get("refresh-decision", &decision), check.IsNil)
+	c.Check(decision, check.Equals, "hold")
+	ctx.Unlock()
+
+	_, _, err = ctlcmd.Run(ctx, []string{"refresh", "--proceed"}, 0)
+	c.Assert(err, check.IsNil)
+
+	ctx.Lock()
+	defer ctx.Unlock()
+	holds = nil
+	c.Assert(ctx.State().Get("snaps-hold", &holds), check.IsNil)
+	c.Check(holds, check.HasLen, 0)
+	c.Assert(ctx.Get("refresh-decision", &decision), check.IsNil)
+	c.Check(decision, check.Equals, "proceed")
+}
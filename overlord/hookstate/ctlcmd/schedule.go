@@ -0,0 +1,123 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+var (
+	snapstateAddSchedule    = snapstate.AddSchedule
+	snapstateRemoveSchedule = snapstate.RemoveSchedule
+)
+
+type scheduleCommand struct {
+	baseCommand
+
+	Positional struct {
+		Action   string `positional-arg-name:"<add|remove|list>" required:"yes"`
+		Name     string `positional-arg-name:"<name>"`
+		Schedule string `positional-arg-name:"<schedule>"`
+		App      string `positional-arg-name:"<app>"`
+	} `positional-args:"yes"`
+}
+
+var shortScheduleHelp = i18n.G(`Manage the scheduled tasks of the snap`)
+var longScheduleHelp = i18n.G(`
+The schedule command adds, removes and lists tasks running apps of the
+calling snap on a schedule, in the same format as the timer of services.
+
+$ snapctl schedule add nightly-backup 23:00-01:00 backup
+$ snapctl schedule list
+Name            Schedule     App
+nightly-backup  23:00-01:00  backup
+$ snapctl schedule remove nightly-backup
+
+The scheduled apps cannot be services. The tasks are kept across refreshes
+and removed along with the snap.
+`)
+
+func init() {
+	addCommand("schedule", shortScheduleHelp, longScheduleHelp, func() command { return &scheduleCommand{} })
+}
+
+func (c *scheduleCommand) Execute(args []string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf("cannot manage schedules without a context")
+	}
+
+	pos := c.Positional
+	switch pos.Action {
+	case "add":
+		if pos.Name == "" || pos.Schedule == "" || pos.App == "" {
+			return fmt.Errorf(i18n.G("add requires <name> <schedule> <app>"))
+		}
+	case "remove":
+		if pos.Name == "" || pos.Schedule != "" {
+			return fmt.Errorf(i18n.G("remove requires only <name>"))
+		}
+	case "list":
+		if pos.Name != "" {
+			return fmt.Errorf(i18n.G("list takes no arguments"))
+		}
+	default:
+		return fmt.Errorf(i18n.G("unknown schedule action %q, expected add, remove or list"), pos.Action)
+	}
+
+	st := context.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapName := context.InstanceName()
+	switch pos.Action {
+	case "add":
+		return snapstateAddSchedule(st, snapName, pos.Name, pos.Schedule, pos.App)
+	case "remove":
+		return snapstateRemoveSchedule(st, snapName, pos.Name)
+	}
+
+	schedules, err := snapstate.Schedules(st, snapName)
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(schedules))
+	for name := range schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(c.stdout, 5, 3, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Name\tSchedule\tApp"))
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, schedules[name].Schedule, schedules[name].App)
+	}
+	return nil
+}
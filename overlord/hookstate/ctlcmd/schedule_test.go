@@ -0,0 +1,120 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type scheduleSuite struct {
+	testutil.BaseTest
+	state       *state.State
+	mockContext *hookstate.Context
+}
+
+var _ = Suite(&scheduleSuite{})
+
+func (s *scheduleSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.state = state.New(nil)
+
+	s.state.Lock()
+	task := s.state.NewTask("test-task", "my test task")
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "test-snap", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+		Schedules: map[string]*snapstate.ScheduleInfo{
+			"weekly": {App: "report", Schedule: "mon,9:00"},
+			"backup": {App: "backup", Schedule: "23:00-01:00"},
+		},
+	})
+	s.state.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "configure"}
+	ctx, err := hookstate.NewContext(task, s.state, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+	s.mockContext = ctx
+}
+
+func (s *scheduleSuite) TestScheduleList(c *C) {
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"schedule", "list"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `Name    Schedule     App
+backup  23:00-01:00  backup
+weekly  mon,9:00     report
+`)
+	c.Check(string(stderr), Equals, "")
+}
+
+func (s *scheduleSuite) TestScheduleAddRemove(c *C) {
+	var calls []string
+	s.AddCleanup(ctlcmd.MockSnapstateAddSchedule(func(st *state.State, instanceName, name, schedule, appName string) error {
+		calls = append(calls, fmt.Sprintf("add %s %s %s %s", instanceName, name, schedule, appName))
+		return nil
+	}))
+	s.AddCleanup(ctlcmd.MockSnapstateRemoveSchedule(func(st *state.State, instanceName, name string) error {
+		calls = append(calls, fmt.Sprintf("remove %s %s", instanceName, name))
+		return fmt.Errorf("schedule %q not found", name)
+	}))
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"schedule", "add", "nightly", "23:00", "backup"}, 0)
+	c.Assert(err, IsNil)
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"schedule", "remove", "hourly"}, 0)
+	c.Assert(err, ErrorMatches, `schedule "hourly" not found`)
+	c.Check(calls, DeepEquals, []string{
+		"add test-snap nightly 23:00 backup",
+		"remove test-snap hourly",
+	})
+}
+
+func (s *scheduleSuite) TestScheduleErrors(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"schedule", "list"}, 0)
+	c.Check(err, ErrorMatches, `cannot manage schedules without a context`)
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"schedule", "add", "nightly", "23:00"}, `add requires <name> <schedule> <app>`},
+		{[]string{"schedule", "remove"}, `remove requires only <name>`},
+		{[]string{"schedule", "remove", "nightly", "23:00"}, `remove requires only <name>`},
+		{[]string{"schedule", "list", "nightly"}, `list takes no arguments`},
+		{[]string{"schedule", "run", "nightly"}, `unknown schedule action "run", expected add, remove or list`},
+	} {
+		_, _, err := ctlcmd.Run(s.mockContext, t.args, 0)
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.args))
+	}
+
+	// only root can manage schedules
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"schedule", "list"}, 1000)
+	c.Check(err, ErrorMatches, `cannot use "schedule" with uid 1000, try with sudo`)
+}
@@ -0,0 +1,85 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	shortSerialHelp = i18n.G("Print the serial assertion of the device")
+	longSerialHelp  = i18n.G(`
+The serial command prints the serial assertion the device got when it
+registered with the brand.
+
+The serial can only be accessed by the gadget snap of the model and by
+snaps published by the brand of the model.
+`)
+)
+
+var devicestateSerialFromState = devicestate.SerialFromState
+
+func init() {
+	addCommand("serial", shortSerialHelp, longSerialHelp, func() command { return &serialCommand{} })
+}
+
+type serialCommand struct {
+	baseCommand
+}
+
+func (c *serialCommand) Execute([]string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf("cannot get serial without a context")
+	}
+
+	st := context.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err == state.ErrNoState {
+		return fmt.Errorf("cannot get serial: no model assertion yet")
+	}
+	if err != nil {
+		return fmt.Errorf("internal error: cannot get device context: %v", err)
+	}
+	model := deviceCtx.Model()
+	if err := canAccessDeviceIdentity(st, context.InstanceName(), model, model.Gadget()); err != nil {
+		return fmt.Errorf("cannot get serial: %v", err)
+	}
+
+	serial, err := devicestateSerialFromState(st)
+	if err == state.ErrNoState {
+		return fmt.Errorf("cannot get serial: device not yet registered")
+	}
+	if err != nil {
+		return fmt.Errorf("internal error: cannot get serial: %v", err)
+	}
+
+	c.printf("%s\n", asserts.Encode(serial))
+	return nil
+}
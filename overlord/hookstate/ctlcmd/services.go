@@ -71,7 +71,7 @@ func (c *servicesCommand) Execute([]string) error {
 	}
 	sort.Sort(byApp(svcInfos))
 
-	services, err := cmd.ClientAppInfosFromSnapAppInfos(svcInfos)
+	services, err := cmd.ClientAppInfosFromSnapAppInfos(svcInfos, nil)
 	if err != nil || len(services) == 0 {
 		return err
 	}
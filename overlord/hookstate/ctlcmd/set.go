@@ -29,6 +29,8 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
 )
 
 type setCommand struct {
@@ -104,6 +106,9 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 		parts := strings.SplitN(patchValue, "=", 2)
 		if len(parts) == 1 && strings.HasSuffix(patchValue, "!") {
 			key := strings.TrimSuffix(patchValue, "!")
+			if err := checkNotJournalOption(key); err != nil {
+				return err
+			}
 			tr.Set(s.context().InstanceName(), key, nil)
 			continue
 		}
@@ -111,6 +116,9 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 			return fmt.Errorf(i18n.G("invalid parameter: %q (want key=value)"), patchValue)
 		}
 		key := parts[0]
+		if err := checkNotJournalOption(key); err != nil {
+			return err
+		}
 		var value interface{}
 		if err := jsonutil.DecodeWithNumber(strings.NewReader(parts[1]), &value); err != nil {
 			// Not valid JSON-- just save the string as-is.
@@ -123,6 +131,16 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 	return nil
 }
 
+// checkNotJournalOption refuses changes to the journal options of the
+// snap, these limits are set by the administrator with "snap set" and
+// must not be raised by the snap itself.
+func checkNotJournalOption(key string) error {
+	if snapstate.IsJournalOption(key) {
+		return fmt.Errorf(i18n.G("cannot change %q: journal options can only be changed with \"snap set\""), key)
+	}
+	return nil
+}
+
 func setInterfaceAttribute(context *hookstate.Context, staticAttrs map[string]interface{}, dynamicAttrs map[string]interface{}, key string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
@@ -155,6 +173,26 @@ func setInterfaceAttribute(context *hookstate.Context, staticAttrs map[string]in
 	return err
 }
 
+// interfaceAttributeSchema returns the attribute schema declared for the
+// given plug or slot of the snap running the hook, if any.
+func interfaceAttributeSchema(context *hookstate.Context, which, name string) snap.AttributeSchema {
+	info, err := snapstate.CurrentInfo(context.State(), context.InstanceName())
+	if err != nil {
+		// the connect task validates the final attributes anyway
+		return nil
+	}
+	if which == "plug" {
+		if plug, ok := info.Plugs[name]; ok {
+			return plug.AttributeSchema
+		}
+		return nil
+	}
+	if slot, ok := info.Slots[name]; ok {
+		return slot.AttributeSchema
+	}
+	return nil
+}
+
 func (s *setCommand) setInterfaceSetting(context *hookstate.Context, plugOrSlot string) error {
 	// Make sure set :<plug|slot> is only supported during the execution of prepare-[plug|slot] hooks
 	hookType, _ := interfaceHookType(context.HookName())
@@ -192,6 +230,8 @@ func (s *setCommand) setInterfaceSetting(context *hookstate.Context, plugOrSlot
 		return fmt.Errorf(i18n.G("internal error: cannot get %s from appropriate task, %s"), which, err)
 	}
 
+	schema := interfaceAttributeSchema(context, which, plugOrSlot)
+
 	for _, attrValue := range s.Positional.ConfValues {
 		parts := strings.SplitN(attrValue, "=", 2)
 		if len(parts) != 2 {
@@ -203,6 +243,13 @@ func (s *setCommand) setInterfaceSetting(context *hookstate.Context, plugOrSlot
 			// Not valid JSON, save the string as-is
 			value = parts[1]
 		}
+		// nested values are checked as part of their top-level attribute
+		// when connecting
+		if !strings.Contains(parts[0], ".") {
+			if err := schema.ValidateAttr(parts[0], value); err != nil {
+				return fmt.Errorf(i18n.G("cannot set attribute: %v"), err)
+			}
+		}
 		err = setInterfaceAttribute(context, staticAttrs, dynamicAttrs, parts[0], value)
 		if err != nil {
 			return fmt.Errorf(i18n.G("cannot set attribute: %v"), err)
@@ -23,13 +23,16 @@ import (
 	"encoding/json"
 	"strings"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 
 	. "gopkg.in/check.v1"
 )
@@ -99,6 +102,13 @@ func (s *setSuite) TestCommand(c *C) {
 	c.Check(value, Equals, "qux")
 }
 
+func (s *setSuite) TestSetJournalOptionsForbidden(c *C) {
+	for _, arg := range []string{"logs.max-size=1G", "logs.rate-limit=1000/1s", `logs={"max-size":"1G"}`, "logs!", "logs.max-size!"} {
+		_, _, err := ctlcmd.Run(s.mockContext, []string{"set", arg}, 0)
+		c.Check(err, ErrorMatches, `cannot change "logs.*": journal options can only be changed with "snap set"`, Commentf(arg))
+	}
+}
+
 func (s *setSuite) TestSetRegularUserForbidden(c *C) {
 	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "test-key1"}, 1000)
 	c.Assert(err, ErrorMatches, `cannot use "set" with uid 1000, try with sudo`)
@@ -327,6 +337,53 @@ func (s *setAttrSuite) TestSetPlugAttributesSupportsDottedSyntax(c *C) {
 	c.Check(dynattrs["my"], DeepEquals, map[string]interface{}{"attr1": "foo", "attr2": "bar"})
 }
 
+func (s *setAttrSuite) TestSetPlugAttributesValidatedAgainstSchema(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+	restore := snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {})
+	defer restore()
+
+	st := s.mockPlugHookContext.State()
+	st.Lock()
+	info := snaptest.MockSnapCurrent(c, `name: test-snap
+version: 1
+plugs:
+  aplug:
+    interface: content
+    attribute-schema:
+      port:
+        type: int
+        maximum: 65535
+      mode:
+        type: string
+        enum: [ro, rw]
+`, &snap.SideInfo{Revision: snap.R(1)})
+	snapstate.Set(st, info.InstanceName(), &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: info.SnapName(), Revision: info.Revision}},
+		Current:  info.Revision,
+	})
+	st.Unlock()
+
+	_, _, err := ctlcmd.Run(s.mockPlugHookContext, []string{"set", ":aplug", "port=70000"}, 0)
+	c.Check(err, ErrorMatches, `cannot set attribute: attribute "port" must be at most 65535`)
+	_, _, err = ctlcmd.Run(s.mockPlugHookContext, []string{"set", ":aplug", "mode=wo"}, 0)
+	c.Check(err, ErrorMatches, `cannot set attribute: attribute "mode" must be one of \[ro rw\]`)
+
+	_, _, err = ctlcmd.Run(s.mockPlugHookContext, []string{"set", ":aplug", "port=8080", "mode=ro"}, 0)
+	c.Assert(err, IsNil)
+
+	attrsTask, err := ctlcmd.AttributesTask(s.mockPlugHookContext)
+	c.Assert(err, IsNil)
+	st.Lock()
+	defer st.Unlock()
+	dynattrs := make(map[string]interface{})
+	err = attrsTask.Get("plug-dynamic", &dynattrs)
+	c.Assert(err, IsNil)
+	c.Check(dynattrs["mode"], Equals, "ro")
+	c.Check(dynattrs["port"], Equals, float64(8080))
+}
+
 func (s *setAttrSuite) TestPlugOrSlotEmpty(c *C) {
 	stdout, stderr, err := ctlcmd.Run(s.mockPlugHookContext, []string{"set", ":", "foo=bar"}, 0)
 	c.Check(err, ErrorMatches, "plug or slot name not provided")
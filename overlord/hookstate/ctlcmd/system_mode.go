@@ -0,0 +1,76 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	shortSystemModeHelp = i18n.G("Print the mode the system runs in")
+	longSystemModeHelp  = i18n.G(`
+The system-mode command prints the mode the system runs in, that is run,
+recover or install, and whether the system is seeded.
+
+$ snapctl system-mode
+system-mode: run
+seeded: true
+`)
+)
+
+func init() {
+	addCommand("system-mode", shortSystemModeHelp, longSystemModeHelp, func() command { return &systemModeCommand{} })
+}
+
+type systemModeCommand struct {
+	baseCommand
+}
+
+func (c *systemModeCommand) Execute([]string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf("cannot get system mode without a context")
+	}
+
+	st := context.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err == state.ErrNoState {
+		return fmt.Errorf("cannot get system mode: no model assertion yet")
+	}
+	if err != nil {
+		return fmt.Errorf("internal error: cannot get device context: %v", err)
+	}
+
+	var seeded bool
+	if err := st.Get("seeded", &seeded); err != nil && err != state.ErrNoState {
+		return fmt.Errorf("internal error: cannot get seeded status: %v", err)
+	}
+
+	c.printf("system-mode: %s\n", deviceCtx.SystemMode())
+	c.printf("seeded: %t\n", seeded)
+	return nil
+}
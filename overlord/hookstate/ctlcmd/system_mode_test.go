@@ -0,0 +1,88 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type systemModeSuite struct {
+	testutil.BaseTest
+	state       *state.State
+	mockContext *hookstate.Context
+}
+
+var _ = Suite(&systemModeSuite{})
+
+func (s *systemModeSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.state = state.New(nil)
+
+	s.state.Lock()
+	task := s.state.NewTask("test-task", "my test task")
+	s.state.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "configure"}
+	ctx, err := hookstate.NewContext(task, s.state, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+	s.mockContext = ctx
+}
+
+func (s *systemModeSuite) TestSystemMode(c *C) {
+	for _, t := range []struct {
+		mode   string
+		seeded bool
+	}{
+		{"run", true},
+		{"install", false},
+		{"recover", true},
+	} {
+		restore := snapstatetest.MockDeviceModelAndMode(nil, t.mode)
+		s.state.Lock()
+		s.state.Set("seeded", t.seeded)
+		s.state.Unlock()
+
+		stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"system-mode"}, 1000)
+		restore()
+		c.Assert(err, IsNil)
+		c.Check(string(stdout), Equals, fmt.Sprintf("system-mode: %s\nseeded: %t\n", t.mode, t.seeded))
+		c.Check(string(stderr), Equals, "")
+	}
+}
+
+func (s *systemModeSuite) TestSystemModeErrors(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"system-mode"}, 0)
+	c.Check(err, ErrorMatches, `cannot get system mode without a context`)
+
+	restore := snapstatetest.MockDeviceContext(nil)
+	defer restore()
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"system-mode"}, 0)
+	c.Check(err, ErrorMatches, `cannot get system mode: no model assertion yet`)
+}
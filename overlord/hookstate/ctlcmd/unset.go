@@ -67,6 +67,9 @@ func (s *unsetCommand) Execute(args []string) error {
 	context.Unlock()
 
 	for _, confKey := range s.Positional.ConfKeys {
+		if err := checkNotJournalOption(confKey); err != nil {
+			return err
+		}
 		tr.Set(context.InstanceName(), confKey, nil)
 	}
 
@@ -119,6 +119,11 @@ func (s *unsetSuite) TestUnsetMany(c *C) {
 	c.Check(value, Equals, "c")
 }
 
+func (s *unsetSuite) TestUnsetJournalOptionsForbidden(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"unset", "foo", "logs.max-size"}, 0)
+	c.Check(err, ErrorMatches, `cannot change "logs.max-size": journal options can only be changed with "snap set"`)
+}
+
 func (s *unsetSuite) TestUnsetRegularUserForbidden(c *C) {
 	_, _, err := ctlcmd.Run(s.mockContext, []string{"unset", "key"}, 1000)
 	c.Assert(err, ErrorMatches, `cannot use "unset" with uid 1000, try with sudo`)
@@ -72,6 +72,22 @@ type Handler interface {
 	Error(err error) error
 }
 
+// IgnoredErrorHandler is implemented by the handlers that need to know
+// about the errors of hooks set up to ignore them.
+type IgnoredErrorHandler interface {
+	// IgnoredError is called if the hook encountered an error that
+	// is ignored, right before Done.
+	IgnoredError(err error) error
+}
+
+// EnvHandler is implemented by the handlers that need to pass
+// variables to their hook.
+type EnvHandler interface {
+	// Env is called right after Before and returns the variables, as
+	// "NAME=value", to add to the environment of the hook.
+	Env() []string
+}
+
 // HandlerGenerator is the function signature required to register for hooks.
 type HandlerGenerator func(*Context) Handler
 
@@ -372,6 +388,11 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 			task.State().Lock()
 			task.Errorf("ignoring failure in hook %q: %v", hooksup.Hook, err)
 			task.State().Unlock()
+			if h, ok := context.Handler().(IgnoredErrorHandler); ok {
+				if handlerErr := h.IgnoredError(err); handlerErr != nil {
+					return handlerErr
+				}
+			}
 		} else {
 			if handlerErr := context.Handler().Error(err); handlerErr != nil {
 				return handlerErr
@@ -395,7 +416,11 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 }
 
 func runHookImpl(c *Context, tomb *tomb.Tomb) ([]byte, error) {
-	return runHookAndWait(c.InstanceName(), c.SnapRevision(), c.HookName(), c.ID(), c.Timeout(), tomb)
+	var extraEnv []string
+	if h, ok := c.Handler().(EnvHandler); ok {
+		extraEnv = h.Env()
+	}
+	return runHookAndWait(c.InstanceName(), c.SnapRevision(), c.HookName(), c.ID(), c.Timeout(), extraEnv, tomb)
 }
 
 var runHook = runHookImpl
@@ -434,7 +459,7 @@ func snapCmd() string {
 
 var defaultHookTimeout = 10 * time.Minute
 
-func runHookAndWait(snapName string, revision snap.Revision, hookName, hookContext string, timeout time.Duration, tomb *tomb.Tomb) ([]byte, error) {
+func runHookAndWait(snapName string, revision snap.Revision, hookName, hookContext string, timeout time.Duration, extraEnv []string, tomb *tomb.Tomb) ([]byte, error) {
 	argv := []string{snapCmd(), "run", "--hook", hookName, "-r", revision.String(), snapName}
 	if timeout == 0 {
 		timeout = defaultHookTimeout
@@ -449,6 +474,7 @@ func runHookAndWait(snapName string, revision snap.Revision, hookName, hookConte
 		// hook would fail during transition.
 		fmt.Sprintf("SNAP_CONTEXT=%s", hookContext),
 	}
+	env = append(env, extraEnv...)
 
 	return osutil.RunAndWait(argv, env, timeout, tomb)
 }
@@ -22,6 +22,7 @@ import (
 	"regexp"
 
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 )
@@ -31,6 +32,7 @@ func init() {
 	snapstate.SetupPreRefreshHook = SetupPreRefreshHook
 	snapstate.SetupPostRefreshHook = SetupPostRefreshHook
 	snapstate.SetupRemoveHook = SetupRemoveHook
+	snapstate.SetupGateAutoRefreshHook = SetupGateAutoRefreshHook
 }
 
 func SetupInstallHook(st *state.State, snapName string) *state.Task {
@@ -70,6 +72,60 @@ func SetupPreRefreshHook(st *state.State, snapName string) *state.Task {
 	return task
 }
 
+// SetupGateAutoRefreshHook returns the task running the
+// gate-auto-refresh hook of the snap, which is told the snaps about to
+// be auto-refreshed that affect it.
+func SetupGateAutoRefreshHook(st *state.State, snapName string, affectingSnaps []string) *state.Task {
+	hooksup := &HookSetup{
+		Snap:        snapName,
+		Hook:        "gate-auto-refresh",
+		Optional:    true,
+		IgnoreError: true,
+	}
+
+	summary := fmt.Sprintf(i18n.G("Run gate-auto-refresh hook of %q snap if present"), hooksup.Snap)
+	contextData := map[string]interface{}{"affecting-snaps": affectingSnaps}
+	return HookTask(st, summary, hooksup, contextData)
+}
+
+// gateAutoRefreshHookHandler applies the decision of the
+// gate-auto-refresh hook: proceeding if the hook did not decide, and
+// holding the refresh if the hook failed.
+type gateAutoRefreshHookHandler struct {
+	snapHookHandler
+	context *Context
+}
+
+func (h *gateAutoRefreshHookHandler) Done() error {
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	var decision string
+	if err := h.context.Get("refresh-decision", &decision); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if decision != "" {
+		return nil
+	}
+	return snapstate.ProceedWithRefresh(h.context.State(), h.context.InstanceName())
+}
+
+func (h *gateAutoRefreshHookHandler) IgnoredError(hookErr error) error {
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	var affecting []string
+	if err := h.context.Get("affecting-snaps", &affecting); err != nil {
+		return err
+	}
+	if err := snapstate.HoldRefresh(h.context.State(), h.context.InstanceName(), affecting...); err != nil {
+		logger.Noticef("cannot hold refresh after gate-auto-refresh hook of %q failed: %v", h.context.InstanceName(), err)
+		return nil
+	}
+	h.context.Set("refresh-decision", "hold")
+	return nil
+}
+
 type snapHookHandler struct {
 }
 
@@ -108,4 +164,7 @@ func setupHooks(hookMgr *HookManager) {
 	hookMgr.Register(regexp.MustCompile("^post-refresh$"), handlerGenerator)
 	hookMgr.Register(regexp.MustCompile("^pre-refresh$"), handlerGenerator)
 	hookMgr.Register(regexp.MustCompile("^remove$"), handlerGenerator)
+	hookMgr.Register(regexp.MustCompile("^gate-auto-refresh$"), func(context *Context) Handler {
+		return &gateAutoRefreshHookHandler{context: context}
+	})
 }
@@ -434,6 +434,65 @@ func (s *hookManagerSuite) TestHookTaskHandleIgnoreErrorWorks(c *C) {
 	checkTaskLogContains(c, s.task, ".*ignoring failure in hook.*")
 }
 
+var gatingSnapYaml = `
+name: gating-snap
+version: 1.0
+hooks:
+    gate-auto-refresh:
+`
+
+func (s *hookManagerSuite) runGateAutoRefreshHook(c *C) *state.Task {
+	s.state.Lock()
+	sideInfo := &snap.SideInfo{RealName: "gating-snap", Revision: snap.R(1)}
+	snaptest.MockSnap(c, gatingSnapYaml, sideInfo)
+	snapstate.Set(s.state, "gating-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{sideInfo},
+		Current:  snap.R(1),
+	})
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "other-snap"), IsNil)
+
+	task := hookstate.SetupGateAutoRefreshHook(s.state, "gating-snap", []string{"some-snap"})
+	change := s.state.NewChange("kind", "summary")
+	change.AddTask(task)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(task.Status(), Equals, state.DoneStatus)
+	return task
+}
+
+func (s *hookManagerSuite) TestGateAutoRefreshHookProceedsByDefault(c *C) {
+	s.runGateAutoRefreshHook(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var holds map[string]interface{}
+	c.Assert(s.state.Get("snaps-hold", &holds), IsNil)
+	c.Check(holds, HasLen, 0)
+}
+
+func (s *hookManagerSuite) TestGateAutoRefreshHookFailureHolds(c *C) {
+	cmd := testutil.MockCommand(c, "snap", "exit 1")
+	defer cmd.Restore()
+
+	task := s.runGateAutoRefreshHook(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var holds map[string]map[string]interface{}
+	c.Assert(s.state.Get("snaps-hold", &holds), IsNil)
+	c.Check(holds, HasLen, 2)
+	c.Check(holds["some-snap"]["gating-snap"], NotNil)
+	c.Check(holds["other-snap"]["gating-snap"], NotNil)
+	checkTaskLogContains(c, task, `.*ignoring failure in hook "gate-auto-refresh".*`)
+}
+
 func (s *hookManagerSuite) TestHookTaskEnforcesTimeout(c *C) {
 	var hooksup hookstate.HookSetup
 
@@ -574,6 +633,22 @@ func (s *hookManagerSuite) TestHookTaskCorrectlyIncludesContext(c *C) {
 	checkTaskLogContains(c, s.task, `.*SNAP_COOKIE=\S+`)
 }
 
+func (s *hookManagerSuite) TestHookTaskIncludesHandlerEnv(c *C) {
+	s.mockHandler.Environ = []string{"SOME_VAR=some-value"}
+	cmd := testutil.MockCommand(
+		c, "snap", ">&2 echo \"SOME_VAR=$SOME_VAR\"; exit 1")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+	checkTaskLogContains(c, s.task, `.*SOME_VAR=some-value`)
+}
+
 func (s *hookManagerSuite) TestHookTaskHandlerBeforeError(c *C) {
 	s.mockHandler.BeforeError = true
 
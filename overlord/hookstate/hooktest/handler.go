@@ -33,6 +33,9 @@ type MockHandler struct {
 	ErrorError  bool
 	Err         error
 
+	// Environ is returned by Env
+	Environ []string
+
 	// callbacks useful for testing
 	BeforeCallback func()
 	DoneCallback   func()
@@ -76,3 +79,8 @@ func (h *MockHandler) Error(err error) error {
 	}
 	return nil
 }
+
+// Env satisfies hookstate.EnvHandler.Env
+func (h *MockHandler) Env() []string {
+	return h.Environ
+}
@@ -50,6 +50,7 @@ var (
 	UpdateDevice                 = updateDevice
 	FindConnsForHotplugKey       = findConnsForHotplugKey
 	CheckSystemSnapIsPresent     = checkSystemSnapIsPresent
+	ConnectedSlotServices        = connectedSlotServices
 	SystemSnapInfo               = systemSnapInfo
 	IsHotplugChange              = isHotplugChange
 	GetHotplugChangeAttrs        = getHotplugChangeAttrs
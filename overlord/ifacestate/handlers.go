@@ -385,6 +385,32 @@ func setDynamicHookAttributes(task *state.Task, plugAttrs, slotAttrs map[string]
 	task.Set("slot-dynamic", slotAttrs)
 }
 
+// mergedAttrs returns the static attributes overridden by the dynamic ones,
+// which is the view the interfaces get of a connected plug or slot.
+func mergedAttrs(staticAttrs, dynamicAttrs map[string]interface{}) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(staticAttrs)+len(dynamicAttrs))
+	for k, v := range staticAttrs {
+		attrs[k] = v
+	}
+	for k, v := range dynamicAttrs {
+		attrs[k] = v
+	}
+	return attrs
+}
+
+// validateConnectionAttrs checks the attributes of the plug and slot,
+// as possibly modified by the interface hooks, against the attribute
+// schemas declared by the snaps.
+func validateConnectionAttrs(plug *snap.PlugInfo, slot *snap.SlotInfo, plugDynamicAttrs, slotDynamicAttrs map[string]interface{}) error {
+	if err := plug.AttributeSchema.ValidateAttrs(mergedAttrs(plug.Attrs, plugDynamicAttrs), false); err != nil {
+		return fmt.Errorf("cannot connect plug %q of snap %q: %v", plug.Name, plug.Snap.InstanceName(), err)
+	}
+	if err := slot.AttributeSchema.ValidateAttrs(mergedAttrs(slot.Attrs, slotDynamicAttrs), false); err != nil {
+		return fmt.Errorf("cannot connect slot %q of snap %q: %v", slot.Name, slot.Snap.InstanceName(), err)
+	}
+	return nil
+}
+
 func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 	st := task.State()
 	st.Lock()
@@ -406,6 +432,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err := task.Get("by-gadget", &byGadget); err != nil && err != state.ErrNoState {
 		return err
 	}
+	var byHotplugSlots bool
+	if err := task.Get("by-hotplug-slots", &byHotplugSlots); err != nil && err != state.ErrNoState {
+		return err
+	}
 	var delayedSetupProfiles bool
 	if err := task.Get("delayed-setup-profiles", &delayedSetupProfiles); err != nil && err != state.ErrNoState {
 		return err
@@ -461,10 +491,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 
 	var policyChecker interfaces.PolicyFunc
 
-	// manual connections and connections by the gadget obey the
-	// policy "connection" rules, other auto-connections obey the
-	// "auto-connection" rules
-	if autoConnect && !byGadget {
+	// manual connections and connections by the gadget or declared
+	// with hotplug.slots obey the policy "connection" rules, other
+	// auto-connections obey the "auto-connection" rules
+	if autoConnect && !byGadget && !byHotplugSlots {
 		autochecker, err := newAutoConnectChecker(st, task, m.repo, deviceCtx)
 		if err != nil {
 			return err
@@ -481,6 +511,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		policyChecker = policyCheck.check
 	}
 
+	if err := validateConnectionAttrs(plug, slot, plugDynamicAttrs, slotDynamicAttrs); err != nil {
+		return err
+	}
+
 	// static attributes of the plug and slot not provided, the ones from snap infos will be used
 	conn, err := m.repo.Connect(connRef, nil, plugDynamicAttrs, nil, slotDynamicAttrs, policyChecker)
 	if err != nil || conn == nil {
@@ -518,10 +552,15 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		DynamicSlotAttrs: conn.Slot.DynamicAttrs(),
 		Auto:             autoConnect,
 		ByGadget:         byGadget,
+		ByHotplugSlots:   byHotplugSlots,
 		HotplugKey:       slot.HotplugKey,
 	}
 	setConns(st, conns)
 
+	if err := snapstate.UpdateServiceProviders(st, plugRef.Snap); err != nil {
+		return err
+	}
+
 	// the dynamic attributes might have been updated by the interface's BeforeConnectPlug/Slot code,
 	// so we need to update the task for connect-plug- and connect-slot- hooks to see new values.
 	setDynamicHookAttributes(task, conn.Plug.DynamicAttrs(), conn.Slot.DynamicAttrs())
@@ -632,7 +671,7 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 	}
 	setConns(st, conns)
 
-	return nil
+	return snapstate.UpdateServiceProviders(st, plugRef.Snap)
 }
 
 func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error {
@@ -712,7 +751,7 @@ func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error
 	conns[connRef.ID()] = &oldconn
 	setConns(st, conns)
 
-	return nil
+	return snapstate.UpdateServiceProviders(st, plugRef.Snap)
 }
 
 func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
@@ -749,6 +788,10 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	if err := snapstate.UpdateServiceProviders(st, connRef.PlugRef.Snap); err != nil {
+		return err
+	}
+
 	var delayedSetupProfiles bool
 	if err := task.Get("delayed-setup-profiles", &delayedSetupProfiles); err != nil && err != state.ErrNoState {
 		return err
@@ -1413,15 +1456,51 @@ func (m *InterfaceManager) doHotplugConnect(task *state.Task, _ *tomb.Tomb) erro
 		return err
 	}
 
-	if len(recreate) == 0 && len(newconns) == 0 {
+	// find connections to the plugs declared for the slot with hotplug.slots
+	var declaredPlugs []string
+	if err := task.Get("declared-plugs", &declaredPlugs); err != nil && err != state.ErrNoState {
+		return err
+	}
+	var declared []*interfaces.ConnRef
+	for _, declaredPlug := range declaredPlugs {
+		plugSnap, plugName, err := hotplug.ParsePlug(declaredPlug)
+		if err != nil {
+			task.Logf("cannot connect hotplug slot %s: %v", slot, err)
+			continue
+		}
+		plug := m.repo.Plug(plugSnap, plugName)
+		if plug == nil || plug.Interface != slot.Interface {
+			task.Logf("cannot connect hotplug slot %s to plug %s: no such plug of interface %q", slot, declaredPlug, slot.Interface)
+			continue
+		}
+		connRef := interfaces.NewConnRef(plug, slot)
+		if conn, ok := conns[connRef.ID()]; ok {
+			// the user disconnected it, it must not come back
+			if conn.Undesired {
+				task.Logf("hotplug slot %s is not connected to plug %s, it was disconnected manually", slot, declaredPlug)
+			}
+			// otherwise it is either connected or recreated above
+			continue
+		}
+		if _, ok := newconns[connRef.ID()]; ok {
+			continue
+		}
+		if err := checkAutoconnectConflicts(st, task, plugSnap, instanceName); err != nil {
+			retry, _ := err.(*state.Retry)
+			return conflictError(retry, err)
+		}
+		declared = append(declared, connRef)
+	}
+
+	if len(recreate) == 0 && len(newconns) == 0 && len(declared) == 0 {
 		return nil
 	}
 
 	// Create connect tasks and interface hooks for old connections
 	connectTs := state.NewTaskSet()
 	for _, conn := range recreate {
-		wasAutoconnected := conns[conn.ID()].Auto
-		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: wasAutoconnected})
+		old := conns[conn.ID()]
+		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: old.Auto, ByHotplugSlots: old.ByHotplugSlots})
 		if err != nil {
 			return fmt.Errorf("internal error: connect of %q failed: %s", conn, err)
 		}
@@ -1436,6 +1515,18 @@ func (m *InterfaceManager) doHotplugConnect(task *state.Task, _ *tomb.Tomb) erro
 		connectTs.AddAll(ts)
 	}
 
+	// Create connect tasks and interface hooks for the declared connections,
+	// they are checked like manual ones since the administrator asked for
+	// them, but are remembered as undesired once disconnected and go away
+	// with the device like other auto-connections
+	for _, conn := range declared {
+		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: true, ByHotplugSlots: true})
+		if err != nil {
+			return fmt.Errorf("internal error: connect of %q failed: %s", conn, err)
+		}
+		connectTs.AddAll(ts)
+	}
+
 	if len(connectTs.Tasks()) > 0 {
 		snapstate.InjectTasks(task, connectTs)
 		st.EnsureBefore(0)
@@ -1624,10 +1715,26 @@ func (m *InterfaceManager) doHotplugAddSlot(task *state.Task, _ *tomb.Tomb) erro
 		return fmt.Errorf("internal error: cannot find interface %s", ifaceName)
 	}
 
+	// the name of the slot may have been declared with hotplug.slots
+	var declared bool
+	if err := task.Get("declared-slot", &declared); err != nil && err != state.ErrNoState {
+		return fmt.Errorf("internal error: cannot get declared-slot from task attributes: %s", err)
+	}
+
 	slot := findHotplugSlot(stateSlots, ifaceName, hotplugKey)
 
 	// if we know this slot already, restore / update it.
 	if slot != nil {
+		if declared && slot.Name != proposedSlot.Name {
+			if !slot.HotplugGone {
+				// the slot is in use, it cannot be renamed under its connections
+				msg := fmt.Sprintf("hotplug slot %q of device %s is declared as %q with hotplug.slots, the name will be used once the device is plugged in again", slot.Name, devinfo.ShortString(), proposedSlot.Name)
+				logger.Noticef("%s", msg)
+				task.Logf("%s", msg)
+			} else if err := renameHotplugSlot(st, m.repo, systemSnap.InstanceName(), stateSlots, slot, proposedSlot.Name); err != nil {
+				return fmt.Errorf("cannot use hotplug slot name %q declared with hotplug.slots for device %s: %v", proposedSlot.Name, devinfo.ShortString(), err)
+			}
+		}
 		if slot.HotplugGone {
 			// hotplugGone means the device was unplugged, so its disconnect hooks were run and can now
 			// simply recreate the slot with potentially new attributes, and old connections will be re-created
@@ -1654,6 +1761,10 @@ func (m *InterfaceManager) doHotplugAddSlot(task *state.Task, _ *tomb.Tomb) erro
 
 	// New slot.
 	slotName := hotplugSlotName(hotplugKey, systemSnap.InstanceName(), proposedSlot.Name, iface.Name(), &devinfo, m.repo, stateSlots)
+	if declared && slotName != proposedSlot.Name {
+		// a suffixed name would not be what the administrator asked for
+		return fmt.Errorf("cannot use hotplug slot name %q declared with hotplug.slots for device %s: name already in use", proposedSlot.Name, devinfo.ShortString())
+	}
 	newSlot := &snap.SlotInfo{
 		Name:       slotName,
 		Label:      proposedSlot.Label,
@@ -37,9 +37,11 @@ import (
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -451,9 +453,11 @@ type connState struct {
 	// disappeared because the device was removed, but may potentially be
 	// restored in the future if we see the device again. HotplugKey is the
 	// key of the associated device; it's empty for connections of regular
-	// slots.
-	HotplugGone bool            `json:"hotplug-gone,omitempty"`
-	HotplugKey  snap.HotplugKey `json:"hotplug-key,omitempty"`
+	// slots. ByHotplugSlots marks auto-connections declared by the
+	// administrator with hotplug.slots.
+	HotplugGone    bool            `json:"hotplug-gone,omitempty"`
+	HotplugKey     snap.HotplugKey `json:"hotplug-key,omitempty"`
+	ByHotplugSlots bool            `json:"by-hotplug-slots,omitempty"`
 }
 
 type gadgetConnect struct {
@@ -1136,6 +1140,53 @@ func connectDisconnectAffectedSnaps(t *state.Task) ([]string, error) {
 	return []string{plugRef.Snap, slotRef.Snap}, nil
 }
 
+// connectedSnaps returns the snaps with a connection to the given snap.
+func connectedSnaps(st *state.State, instanceName string) ([]string, error) {
+	conns, err := ifacerepo.Get(st).Connections(instanceName)
+	if err != nil {
+		return nil, err
+	}
+	var snaps []string
+	for _, conn := range conns {
+		for _, other := range []string{conn.PlugRef.Snap, conn.SlotRef.Snap} {
+			if other != instanceName && !strutil.ListContains(snaps, other) {
+				snaps = append(snaps, other)
+			}
+		}
+	}
+	sort.Strings(snaps)
+	return snaps, nil
+}
+
+// connectedSlotServices returns the services of other snaps bound to the
+// slots connected to the given plug of the snap.
+func connectedSlotServices(st *state.State, instanceName, plugName string) ([]*snap.AppInfo, error) {
+	repo := ifacerepo.Get(st)
+	conns, err := repo.Connected(instanceName, plugName)
+	if err != nil {
+		return nil, err
+	}
+	var svcs []*snap.AppInfo
+	for _, conn := range conns {
+		if conn.SlotRef.Snap == instanceName {
+			continue
+		}
+		slot := repo.Slot(conn.SlotRef.Snap, conn.SlotRef.Name)
+		if slot == nil {
+			continue
+		}
+		for _, app := range slot.Apps {
+			if app.IsService() {
+				svcs = append(svcs, app)
+			}
+		}
+	}
+	sort.Slice(svcs, func(i, j int) bool {
+		return svcs[i].ServiceName() < svcs[j].ServiceName()
+	})
+	return svcs, nil
+}
+
 func checkSystemSnapIsPresent(st *state.State) bool {
 	st.Lock()
 	defer st.Unlock()
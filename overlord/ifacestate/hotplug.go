@@ -22,6 +22,7 @@ package ifacestate
 import (
 	"crypto/sha256"
 	"fmt"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -104,6 +105,35 @@ func defaultDeviceKey(devinfo *hotplug.HotplugDeviceInfo, keyVersion int) (snap.
 	return snap.HotplugKey(fmt.Sprintf("%x%x", keyVersion, key.Sum(nil))), nil
 }
 
+// hotplugDeclaredSlots returns the hotplug slots declared by the
+// administrator with the hotplug.slots system option, keyed by slot name.
+func hotplugDeclaredSlots(st *state.State) (map[string]*hotplug.SlotMapping, error) {
+	var declared map[string]*hotplug.SlotMapping
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "hotplug.slots", &declared); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	return declared, nil
+}
+
+// findDeclaredSlot returns the name and declaration of the hotplug slot of
+// given interface that matches the device, if any. If more than one
+// declaration matches, the one with the first name in lexical order wins.
+func findDeclaredSlot(declaredSlots map[string]*hotplug.SlotMapping, ifaceName string, devinfo *hotplug.HotplugDeviceInfo) (string, *hotplug.SlotMapping) {
+	names := make([]string, 0, len(declaredSlots))
+	for name := range declaredSlots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		declared := declaredSlots[name]
+		if declared != nil && declared.Interface == ifaceName && declared.Matches(devinfo) {
+			return name, declared
+		}
+	}
+	return "", nil
+}
+
 // hotplugDeviceAdded gets called when a device is added to the system.
 func (m *InterfaceManager) hotplugDeviceAdded(devinfo *hotplug.HotplugDeviceInfo) {
 	st := m.state
@@ -137,6 +167,11 @@ func (m *InterfaceManager) hotplugDeviceAdded(devinfo *hotplug.HotplugDeviceInfo
 		logger.Noticef("internal error: cannot get gadget information: %v", err)
 	}
 
+	declaredSlots, err := hotplugDeclaredSlots(st)
+	if err != nil {
+		logger.Noticef("cannot get hotplug slots declared with hotplug.slots: %v", err)
+	}
+
 	hotplugIfaces := m.repo.AllHotplugInterfaces()
 	gadgetSlotsByInterface := make(map[string][]*snap.SlotInfo)
 	if gadget != nil {
@@ -195,6 +230,15 @@ InterfacesLoop:
 			proposedSlot.Label = si.Summary
 		}
 
+		// the administrator may have declared the name and connections of the slot
+		var declaredSlot bool
+		var declaredPlugs []string
+		if name, declared := findDeclaredSlot(declaredSlots, iface.Name(), devinfo); declared != nil {
+			proposedSlot.Name = name
+			declaredSlot = true
+			declaredPlugs = declared.Connect
+		}
+
 		if !hotplugFeature {
 			logger.Noticef("hotplug device add event ignored, enable experimental.hotplug")
 			return
@@ -224,10 +268,16 @@ InterfacesLoop:
 		setHotplugAttrs(hotplugAdd, iface.Name(), key)
 		hotplugAdd.Set("device-info", devinfo)
 		hotplugAdd.Set("proposed-slot", proposedSlot)
+		if declaredSlot {
+			hotplugAdd.Set("declared-slot", true)
+		}
 
 		hotplugConnect := st.NewTask("hotplug-connect", fmt.Sprintf("Recreate connections of interface %q for device %s with hotplug key %q", iface.Name(), devinfo.ShortString(), key.ShortString()))
 		setHotplugAttrs(hotplugConnect, iface.Name(), key)
 		hotplugConnect.WaitFor(hotplugAdd)
+		if len(declaredPlugs) > 0 {
+			hotplugConnect.Set("declared-plugs", declaredPlugs)
+		}
 
 		chg := st.NewChange(fmt.Sprintf("hotplug-add-slot-%s", iface), fmt.Sprintf("Add hotplug slot of interface %q for device %s with hotplug key %q", devinfo.ShortString(), iface.Name(), key.ShortString()))
 		chg.AddTask(hotplugAdd)
@@ -427,6 +477,36 @@ func hotplugSlotName(hotplugKey snap.HotplugKey, systemSnapInstanceName, slotSpe
 	return proposedName
 }
 
+// renameHotplugSlot renames the hotplug slot of a device that is gone,
+// along with the connections remembered for it so that they are recreated
+// under the new name.
+func renameHotplugSlot(st *state.State, repo *interfaces.Repository, systemSnapInstanceName string, stateSlots map[string]*HotplugSlotInfo, slot *HotplugSlotInfo, newName string) error {
+	if _, ok := stateSlots[newName]; ok || repo.Slot(systemSnapInstanceName, newName) != nil {
+		return fmt.Errorf("name already in use")
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	for _, id := range findConnsForHotplugKey(conns, slot.Interface, slot.HotplugKey) {
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return err
+		}
+		connRef.SlotRef.Name = newName
+		conns[connRef.ID()] = conns[id]
+		delete(conns, id)
+	}
+	setConns(st, conns)
+
+	delete(stateSlots, slot.Name)
+	slot.Name = newName
+	stateSlots[newName] = slot
+	setHotplugSlots(st, stateSlots)
+	return nil
+}
+
 // updateDevice creates tasks to disconnect slots of given device and update the slot in the repository.
 func updateDevice(st *state.State, ifaceName string, hotplugKey snap.HotplugKey, newAttrs map[string]interface{}) *state.TaskSet {
 	hotplugDisconnect := st.NewTask("hotplug-disconnect", fmt.Sprintf("Disable connections of interface %q, hotplug key %q", ifaceName, hotplugKey.ShortString()))
@@ -500,6 +500,306 @@ hooks:
  disconnect-plug-plug:
 `
 
+func (s *hotplugSuite) mockDeclaredSlots(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// mock the consumer snap/plug
+	si := &snap.SideInfo{RealName: "consumer", Revision: snap.R(1)}
+	testSnap := snaptest.MockSnapInstance(c, "", testSnapYaml, si)
+	c.Assert(s.mgr.Repository().AddPlug(testSnap.Plugs["plug"]), IsNil)
+	snapstate.Set(s.state, "consumer", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "hotplug.slots", map[string]interface{}{
+		"scanner": map[string]interface{}{
+			"interface": "test-a",
+			"match":     map[string]interface{}{"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200"},
+			"connect":   []interface{}{"consumer:plug", "consumer:missing"},
+		},
+		// does not match the interface of the device
+		"other": map[string]interface{}{
+			"interface": "test-c",
+			"match":     map[string]interface{}{"ID_VENDOR_ID": "05e0"},
+		},
+	}), IsNil)
+	tr.Commit()
+}
+
+func (s *hotplugSuite) TestHotplugAddWithDeclaredSlot(c *C) {
+	s.MockModel(c, nil)
+	s.mockDeclaredSlots(c)
+
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "a/path", "ACTION": "add", "SUBSYSTEM": "foo",
+		"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200", "ID_SERIAL": "1234"})
+	c.Assert(err, IsNil)
+	s.udevMon.AddDevice(di)
+
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	var hp hotplugTasksWitness
+	hp.checkTasks(c, st)
+	// test-d creates a slot too since the default key can be computed
+	c.Check(hp.seenTasks, DeepEquals, map[string]int{"hotplug-seq-wait": 3, "hotplug-add-slot": 3, "hotplug-connect": 3, "connect": 1})
+	c.Check(hp.connects, DeepEquals, []string{"consumer:plug core:scanner"})
+
+	// the slot of test-a got the declared name, test-b is not affected
+	repo := s.mgr.Repository()
+	slot, err := repo.SlotForHotplugKey("test-a", "key-1")
+	c.Assert(err, IsNil)
+	c.Assert(slot, NotNil)
+	c.Check(slot.Name, Equals, "scanner")
+	slot, err = repo.SlotForHotplugKey("test-b", "key-2")
+	c.Assert(err, IsNil)
+	c.Assert(slot, NotNil)
+	c.Check(slot.Name, Equals, "hotplugslot-b")
+
+	repoConn, err := repo.Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "core", Name: "scanner"}})
+	c.Assert(err, IsNil)
+	c.Assert(repoConn, NotNil)
+
+	// the connection is remembered as an auto-connection, so that it is
+	// not made again once disconnected
+	var conns map[string]interface{}
+	c.Assert(st.Get("conns", &conns), IsNil)
+	conn, ok := conns["consumer:plug core:scanner"].(map[string]interface{})
+	c.Assert(ok, Equals, true)
+	c.Check(conn["hotplug-key"], Equals, "key-1")
+	c.Check(conn["auto"], Equals, true)
+	c.Check(conn["by-gadget"], IsNil)
+	c.Check(conn["by-hotplug-slots"], Equals, true)
+}
+
+var serialConsumerSnapYaml = `
+name: serial-consumer
+version: 1
+plugs:
+ serial:
+  interface: serial-port
+`
+
+func (s *hotplugSuite) TestHotplugAddWithDeclaredSlotDeniedAutoConnection(c *C) {
+	s.MockModel(c, nil)
+
+	st := s.state
+	st.Lock()
+	si := &snap.SideInfo{RealName: "serial-consumer", Revision: snap.R(1)}
+	consumer := snaptest.MockSnapInstance(c, "", serialConsumerSnapYaml, si)
+	c.Assert(s.mgr.Repository().AddPlug(consumer.Plugs["serial"]), IsNil)
+	snapstate.Set(s.state, "serial-consumer", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+	// serial-port denies auto-connection in the base declaration, the
+	// declared connection must still be made
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "hotplug.slots", map[string]interface{}{
+		"scanner": map[string]interface{}{
+			"interface": "serial-port",
+			"match":     map[string]interface{}{"ID_VENDOR_ID": "0403", "ID_MODEL_ID": "6001"},
+			"connect":   []interface{}{"serial-consumer:serial"},
+		},
+	}), IsNil)
+	tr.Commit()
+	st.Unlock()
+
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "a/path", "ACTION": "add", "SUBSYSTEM": "tty",
+		"ID_BUS": "usb", "DEVNAME": "/dev/ttyUSB0", "ID_VENDOR_ID": "0403", "ID_MODEL_ID": "6001", "ID_SERIAL": "FTDI_1234"})
+	c.Assert(err, IsNil)
+	s.udevMon.AddDevice(di)
+
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	st.Lock()
+	defer st.Unlock()
+
+	var hp hotplugTasksWitness
+	hp.checkTasks(c, st)
+	c.Check(hp.connects, DeepEquals, []string{"serial-consumer:serial core:scanner"})
+
+	repoConn, err := s.mgr.Repository().Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "serial-consumer", Name: "serial"},
+		SlotRef: interfaces.SlotRef{Snap: "core", Name: "scanner"}})
+	c.Assert(err, IsNil)
+	c.Assert(repoConn, NotNil)
+
+	var conns map[string]interface{}
+	c.Assert(st.Get("conns", &conns), IsNil)
+	conn, ok := conns["serial-consumer:serial core:scanner"].(map[string]interface{})
+	c.Assert(ok, Equals, true)
+	c.Check(conn["interface"], Equals, "serial-port")
+	c.Check(conn["auto"], Equals, true)
+	c.Check(conn["by-hotplug-slots"], Equals, true)
+}
+
+func (s *hotplugSuite) TestHotplugAddWithDeclaredSlotUndesired(c *C) {
+	s.MockModel(c, nil)
+	s.mockDeclaredSlots(c)
+
+	st := s.state
+	st.Lock()
+	// the user disconnected the declared connection
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug core:scanner": map[string]interface{}{
+			"interface":   "test-a",
+			"hotplug-key": "key-1",
+			"auto":        true,
+			"undesired":   true,
+		}})
+	st.Unlock()
+
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "a/path", "ACTION": "add", "SUBSYSTEM": "foo",
+		"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200"})
+	c.Assert(err, IsNil)
+	s.udevMon.AddDevice(di)
+
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	st.Lock()
+	defer st.Unlock()
+
+	var hp hotplugTasksWitness
+	hp.checkTasks(c, st)
+	c.Check(hp.seenTasks, DeepEquals, map[string]int{"hotplug-seq-wait": 3, "hotplug-add-slot": 3, "hotplug-connect": 3})
+	c.Check(hp.connects, HasLen, 0)
+
+	slot, err := s.mgr.Repository().SlotForHotplugKey("test-a", "key-1")
+	c.Assert(err, IsNil)
+	c.Assert(slot, NotNil)
+	c.Check(slot.Name, Equals, "scanner")
+}
+
+func (s *hotplugSuite) TestHotplugAddWithDeclaredSlotNameInUse(c *C) {
+	s.MockModel(c, nil)
+	s.mockDeclaredSlots(c)
+
+	st := s.state
+	st.Lock()
+	// another device got the declared name before it was declared
+	st.Set("hotplug-slots", map[string]interface{}{
+		"scanner": map[string]interface{}{
+			"name":         "scanner",
+			"interface":    "test-a",
+			"hotplug-key":  "key-other-device",
+			"hotplug-gone": true,
+		}})
+	st.Unlock()
+
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "a/path", "ACTION": "add", "SUBSYSTEM": "foo",
+		"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200"})
+	c.Assert(err, IsNil)
+	s.udevMon.AddDevice(di)
+
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	st.Lock()
+	defer st.Unlock()
+
+	var chg *state.Change
+	for _, ch := range st.Changes() {
+		if ch.Kind() == "hotplug-add-slot-test-a" {
+			chg = ch
+		}
+	}
+	c.Assert(chg, NotNil)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot use hotplug slot name "scanner" declared with hotplug.slots for device .*: name already in use.*`)
+
+	// no slot with a suffixed name was created
+	slot, err := s.mgr.Repository().SlotForHotplugKey("test-a", "key-1")
+	c.Assert(err, IsNil)
+	c.Check(slot, IsNil)
+}
+
+func (s *hotplugSuite) TestHotplugAddWithDeclaredSlotRenamesGoneSlot(c *C) {
+	s.MockModel(c, nil)
+	s.mockDeclaredSlots(c)
+
+	st := s.state
+	st.Lock()
+	// the device was connected and unplugged before its slot was declared
+	st.Set("hotplug-slots", map[string]interface{}{
+		"hotplugslot-a": map[string]interface{}{
+			"name":         "hotplugslot-a",
+			"interface":    "test-a",
+			"hotplug-key":  "key-1",
+			"hotplug-gone": true,
+		}})
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug core:hotplugslot-a": map[string]interface{}{
+			"interface":    "test-a",
+			"hotplug-key":  "key-1",
+			"hotplug-gone": true,
+		}})
+	st.Unlock()
+
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "a/path", "ACTION": "add", "SUBSYSTEM": "foo",
+		"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200"})
+	c.Assert(err, IsNil)
+	s.udevMon.AddDevice(di)
+
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	st.Lock()
+	defer st.Unlock()
+
+	var hp hotplugTasksWitness
+	hp.checkTasks(c, st)
+	// the old connection is recreated under the declared name
+	c.Check(hp.connects, DeepEquals, []string{"consumer:plug core:scanner"})
+
+	slot, err := s.mgr.Repository().SlotForHotplugKey("test-a", "key-1")
+	c.Assert(err, IsNil)
+	c.Assert(slot, NotNil)
+	c.Check(slot.Name, Equals, "scanner")
+
+	var hotplugSlots map[string]interface{}
+	c.Assert(st.Get("hotplug-slots", &hotplugSlots), IsNil)
+	c.Check(hotplugSlots["hotplugslot-a"], IsNil)
+	c.Check(hotplugSlots["scanner"], NotNil)
+
+	var conns map[string]interface{}
+	c.Assert(st.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug core:hotplugslot-a"], IsNil)
+	conn, ok := conns["consumer:plug core:scanner"].(map[string]interface{})
+	c.Assert(ok, Equals, true)
+	c.Check(conn["hotplug-key"], Equals, "key-1")
+	c.Check(conn["hotplug-gone"], IsNil)
+}
+
+func (s *hotplugSuite) TestHotplugAddWithDeclaredSlotNoMatch(c *C) {
+	s.MockModel(c, nil)
+	s.mockDeclaredSlots(c)
+
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "a/path", "ACTION": "add", "SUBSYSTEM": "foo",
+		"ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "9999"})
+	c.Assert(err, IsNil)
+	s.udevMon.AddDevice(di)
+
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	var hp hotplugTasksWitness
+	hp.checkTasks(c, st)
+	c.Check(hp.seenTasks, DeepEquals, map[string]int{"hotplug-seq-wait": 3, "hotplug-add-slot": 3, "hotplug-connect": 3})
+	c.Check(hp.connects, HasLen, 0)
+
+	slot, err := s.mgr.Repository().SlotForHotplugKey("test-a", "key-1")
+	c.Assert(err, IsNil)
+	c.Assert(slot, NotNil)
+	c.Check(slot.Name, Equals, "hotplugslot-a")
+}
+
 func (s *hotplugSuite) TestHotplugRemove(c *C) {
 	st := s.state
 	st.Lock()
@@ -82,6 +82,10 @@ func findSymmetricAutoconnectTask(st *state.State, plugSnap, slotSnap string, in
 type connectOpts struct {
 	ByGadget    bool
 	AutoConnect bool
+	// ByHotplugSlots is set for automatic connections declared by the
+	// administrator with hotplug.slots, which like the ones of the
+	// gadget obey the "connection" rules of the policy
+	ByHotplugSlots bool
 
 	DelayedSetupProfiles bool
 }
@@ -205,6 +209,9 @@ func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, fla
 	if flags.ByGadget {
 		connectInterface.Set("by-gadget", true)
 	}
+	if flags.ByHotplugSlots {
+		connectInterface.Set("by-hotplug-slots", true)
+	}
 	if flags.DelayedSetupProfiles {
 		connectInterface.Set("delayed-setup-profiles", true)
 	}
@@ -531,6 +538,13 @@ func delayedCrossMgrInit() {
 		// hook into conflict checks mechanisms
 		snapstate.AddAffectedSnapsByKind("connect", connectDisconnectAffectedSnaps)
 		snapstate.AddAffectedSnapsByKind("disconnect", connectDisconnectAffectedSnaps)
+
+		// let snapstate find the snaps gating the auto-refresh of a snap
+		snapstate.ConnectedSnaps = connectedSnaps
+
+		// let snapstate find the services of other snaps that services
+		// depend on through their after-plugs
+		snapstate.ConnectedSlotServices = connectedSlotServices
 	})
 }
 
@@ -1274,6 +1274,88 @@ func (s *interfaceManagerSuite) TestConnectTaskCheckInterfaceMismatch(c *C) {
 	c.Check(change.Status(), Equals, state.ErrorStatus)
 }
 
+var consumerWithSchemaYaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+  attr1: value1
+  attribute-schema:
+   attr1:
+    type: string
+    enum: [value1, value2]
+   target:
+    type: string
+    required: true
+hooks:
+ prepare-plug-plug:
+`
+
+func (s *interfaceManagerSuite) testConnectTaskAttributeSchema(c *C, plugDynamicAttrs map[string]interface{}) *state.Change {
+	s.MockModel(c, nil)
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerWithSchemaYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+
+	var connectTask *state.Task
+	for _, t := range ts.Tasks() {
+		if t.Kind() == "connect" {
+			connectTask = t
+		}
+	}
+	c.Assert(connectTask, NotNil)
+	// mimic the prepare-plug hook setting attributes
+	connectTask.Set("plug-dynamic", plugDynamicAttrs)
+
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	return change
+}
+
+func (s *interfaceManagerSuite) TestConnectTaskAttributeSchemaValid(c *C) {
+	change := s.testConnectTaskAttributeSchema(c, map[string]interface{}{"target": "/srv"})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+
+	ifaces := s.manager(c).Repository().Interfaces()
+	c.Check(ifaces.Connections, HasLen, 1)
+}
+
+func (s *interfaceManagerSuite) TestConnectTaskAttributeSchemaMissingRequired(c *C) {
+	change := s.testConnectTaskAttributeSchema(c, map[string]interface{}{})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot connect plug "plug" of snap "consumer": required attribute "target" is not set.*`)
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+
+	ifaces := s.manager(c).Repository().Interfaces()
+	c.Check(ifaces.Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestConnectTaskAttributeSchemaInvalidDynamic(c *C) {
+	change := s.testConnectTaskAttributeSchema(c, map[string]interface{}{"target": "/srv", "attr1": "value3"})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot connect plug "plug" of snap "consumer": attribute "attr1" must be one of \[value1 value2\].*`)
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+}
+
 func (s *interfaceManagerSuite) TestConnectTaskNoSuchSlot(c *C) {
 	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
 	s.mockSnap(c, consumerYaml)
@@ -4335,6 +4417,59 @@ func (s *interfaceManagerSuite) TestManagerReloadsHotlugConnection(c *C) {
 	c.Assert(conn, NotNil)
 }
 
+func (s *interfaceManagerSuite) TestConnectedSlotServices(c *C) {
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+	err := repo.AddInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	c.Assert(err, IsNil)
+
+	consumer := s.mockSnap(c, `name: consumer
+version: 1
+plugs:
+  plug:
+    interface: test
+apps:
+  app:
+    daemon: simple
+    after-plugs: [plug]
+`)
+	producer := s.mockSnap(c, `name: producer
+version: 1
+slots:
+  slot:
+    interface: test
+apps:
+  db:
+    daemon: simple
+    slots: [slot]
+  tool:
+    command: tool
+    slots: [slot]
+`)
+	c.Assert(repo.AddSnap(consumer), IsNil)
+	c.Assert(repo.AddSnap(producer), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	svcs, err := ifacestate.ConnectedSlotServices(s.state, "consumer", "plug")
+	c.Assert(err, IsNil)
+	c.Check(svcs, HasLen, 0)
+
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	_, err = repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	svcs, err = ifacestate.ConnectedSlotServices(s.state, "consumer", "plug")
+	c.Assert(err, IsNil)
+	c.Assert(svcs, HasLen, 1)
+	c.Check(svcs[0].Snap.InstanceName(), Equals, "producer")
+	c.Check(svcs[0].Name, Equals, "db")
+}
+
 func (s *interfaceManagerSuite) TestSetupProfilesDevModeMultiple(c *C) {
 	s.MockModel(c, nil)
 
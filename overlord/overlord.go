@@ -39,6 +39,7 @@ import (
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/cmdstate"
 	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/healthstate"
@@ -52,6 +53,8 @@ import (
 	"github.com/snapcore/snapd/overlord/storecontext"
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -65,6 +68,8 @@ var (
 
 	defaultCachedDownloads = 5
 
+	defaultCachedResponsesSize int64 = 16 * 1024 * 1024
+
 	configstateInit = configstate.Init
 )
 
@@ -101,6 +106,8 @@ type Overlord struct {
 	shotMgr   *snapshotstate.SnapshotManager
 	// proxyConf mediates the http proxy config
 	proxyConf func(req *http.Request) (*url.URL, error)
+
+	peerCacheMgr *peerCacheManager
 }
 
 // RestartBehavior controls how to hanndle and carry forward restart requests
@@ -115,7 +122,10 @@ type RestartBehavior interface {
 	RebootDidNotHappen(st *state.State) error
 }
 
-var storeNew = store.New
+var (
+	storeNew         = store.New
+	offlineStoreKeys = devicestate.OfflineStoreKeys
+)
 
 // New creates a new Overlord with all its state managers.
 // It can be provided with an optional RestartBehavior.
@@ -178,10 +188,14 @@ func New(restartBehavior RestartBehavior) (*Overlord, error) {
 	o.addManager(cmdstate.Manager(s, o.runner))
 	o.addManager(snapshotstate.Manager(s, o.runner))
 
+	o.peerCacheMgr = newPeerCacheManager(s, o.storePeers)
+	o.addManager(o.peerCacheMgr)
+
 	if err := configstateInit(s, hookMgr); err != nil {
 		return nil, err
 	}
 	healthstate.Init(hookMgr)
+	snapshotstate.Init(hookMgr)
 
 	// the shared task runner should be added last!
 	o.stateEng.AddManager(o.runner)
@@ -194,10 +208,111 @@ func New(restartBehavior RestartBehavior) (*Overlord, error) {
 	sto := o.newStoreWithContext(storeCtx)
 
 	snapstate.ReplaceStore(s, sto)
+	setupOfflineStore(s)
 
 	return o, nil
 }
 
+// storeEndpoints returns the store endpoints to fail over to set with
+// the store.endpoints option.
+func (o *Overlord) storeEndpoints() ([]*url.URL, error) {
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	st.Unlock()
+
+	var endpoints string
+	if err := tr.Get("core", "store.endpoints", &endpoints); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	return store.ParseEndpoints(endpoints)
+}
+
+// assertionsEndpoints returns the assertions service endpoints to fail
+// over to set with the store.assertions-endpoints option.
+func (o *Overlord) assertionsEndpoints() ([]*url.URL, error) {
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	st.Unlock()
+
+	var endpoints string
+	if err := tr.Get("core", "store.assertions-endpoints", &endpoints); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	return store.ParseEndpoints(endpoints)
+}
+
+// storePeers returns the peer caches to download from set with the
+// store.peer-cache.peers option.
+func (o *Overlord) storePeers() ([]string, error) {
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	st.Unlock()
+
+	var peers string
+	if err := tr.Get("core", "store.peer-cache.peers", &peers); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	return store.ParsePeers(peers)
+}
+
+// storeCacheMaxSize returns the maximum size of the download cache set
+// with the store.cache.max-size option, 0 if unset.
+func (o *Overlord) storeCacheMaxSize() (int64, error) {
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	st.Unlock()
+
+	var maxSize string
+	if err := tr.Get("core", "store.cache.max-size", &maxSize); err != nil && !config.IsNoOption(err) {
+		return 0, err
+	}
+	if maxSize == "" {
+		return 0, nil
+	}
+	return strutil.ParseByteSize(maxSize)
+}
+
+// trimDownloadCache makes the download cache fit in its maximum size,
+// which might have been lowered while snapd was not running.
+func (o *Overlord) trimDownloadCache() {
+	maxSize, err := o.storeCacheMaxSize()
+	if err != nil {
+		logger.Noticef("cannot get maximum download cache size: %v", err)
+		return
+	}
+	if err := store.TrimDownloadCache(dirs.SnapDownloadCacheDir, maxSize); err != nil {
+		logger.Noticef("cannot trim download cache: %v", err)
+	}
+}
+
+// setupOfflineStore makes the snap manager use the directory selected
+// with the store.offline-source option, if any.
+func setupOfflineStore(st *state.State) {
+	var dir string
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "store.offline-source", &dir); err != nil && !config.IsNoOption(err) {
+		logger.Noticef("cannot get store.offline-source option: %v", err)
+		return
+	}
+	if dir == "" {
+		return
+	}
+	keys, err := offlineStoreKeys(st)
+	if err != nil {
+		logger.Noticef("cannot get the keys trusted for the offline store: %v", err)
+	}
+	// the directory might be on a medium that is not there yet, in
+	// that case requests fail instead of going to the network
+	if _, err := offline.New(dir, keys); err != nil {
+		logger.Noticef("cannot use %q as offline store yet: %v", dir, err)
+	}
+	snapstate.ReplaceOfflineStore(st, offline.NewUnchecked(dir, keys))
+}
+
 func (o *Overlord) addManager(mgr StateManager) {
 	switch x := mgr.(type) {
 	case *hookstate.HookManager:
@@ -294,8 +409,13 @@ func verifyReboot(s *state.State, curBootID string, restartBehavior RestartBehav
 func (o *Overlord) newStoreWithContext(storeCtx store.DeviceAndAuthContext) snapstate.StoreService {
 	cfg := store.DefaultConfig()
 	cfg.Proxy = o.proxyConf
+	cfg.Endpoints = o.storeEndpoints
+	cfg.AssertionsEndpoints = o.assertionsEndpoints
+	cfg.Peers = o.storePeers
+	cfg.CacheMaxSize = o.storeCacheMaxSize
 	sto := storeNew(cfg, storeCtx)
 	sto.SetCacheDownloads(defaultCachedDownloads)
+	sto.SetCacheResponses(defaultCachedResponsesSize)
 	return sto
 }
 
@@ -327,6 +447,8 @@ func (o *Overlord) StartUp() error {
 		}
 	}
 
+	o.trimDownloadCache()
+
 	// slow down for tests
 	if s := os.Getenv("SNAPD_SLOW_STARTUP"); s != "" {
 		if d, err := time.ParseDuration(s); err == nil {
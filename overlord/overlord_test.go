@@ -20,12 +20,15 @@
 package overlord_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -33,10 +36,13 @@ import (
 	. "gopkg.in/check.v1"
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
@@ -47,6 +53,7 @@ import (
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/snapdtool"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -151,6 +158,177 @@ func (ovs *overlordSuite) TestNewStore(c *C) {
 	c.Check(sto.(*store.Store).CacheDownloads(), Equals, 5)
 }
 
+func (ovs *overlordSuite) TestNewWithOfflineStore(c *C) {
+	storeStack := assertstest.NewStoreStack("canonical", nil)
+	brandAcct := assertstest.NewAccount(storeStack, "my-brand", map[string]interface{}{
+		"account-id": "my-brand",
+	}, "")
+	brandKey, _ := assertstest.GenerateKey(752)
+	brandAccKey := assertstest.NewAccountKey(storeStack, brandAcct, map[string]interface{}{
+		"since": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}, brandKey.PublicKey(), "")
+	restore := overlord.MockOfflineStoreKeys(func(*state.State) ([]*asserts.AccountKey, error) {
+		return []*asserts.AccountKey{brandAccKey}, nil
+	})
+	defer restore()
+
+	offlineDir := c.MkDir()
+	manifest := []byte("snaps: []\n")
+	err := ioutil.WriteFile(filepath.Join(offlineDir, offline.ManifestFile), manifest, 0644)
+	c.Assert(err, IsNil)
+	sig, err := asserts.SignContent(manifest, brandKey)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(offlineDir, offline.ManifestSignatureFile), sig, 0644)
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		dir string
+		err string
+	}{
+		{offlineDir, ""},
+		// a missing offline source does not fall back to the network
+		{filepath.Join(offlineDir, "missing"), `cannot read offline store manifest: .*no such file or directory`},
+	} {
+		fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"config":{"core":{"store":{"offline-source":%q}}}},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, snapdtool.Version, t.dir))
+		err = ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+		c.Assert(err, IsNil)
+
+		o, err := overlord.New(nil)
+		c.Assert(err, IsNil)
+
+		st := o.State()
+		st.Lock()
+		sto := snapstate.Store(st, nil)
+		st.Unlock()
+		c.Check(sto, FitsTypeOf, &offline.Store{})
+		_, err = sto.Find(context.TODO(), &store.Search{}, nil)
+		if t.err == "" {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, ErrorMatches, t.err)
+		}
+	}
+}
+
+func (ovs *overlordSuite) TestNewStoreEndpoints(c *C) {
+	var cfg *store.Config
+	restore := overlord.MockStoreNew(func(c *store.Config, dac store.DeviceAndAuthContext) *store.Store {
+		cfg = c
+		return store.New(c, dac)
+	})
+	defer restore()
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	c.Assert(cfg, NotNil)
+	c.Assert(cfg.Endpoints, NotNil)
+
+	endpoints, err := cfg.Endpoints()
+	c.Assert(err, IsNil)
+	c.Check(endpoints, HasLen, 0)
+
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.endpoints", "https://eu.proxy.example/,https://us.proxy.example/")
+	tr.Commit()
+	st.Unlock()
+
+	endpoints, err = cfg.Endpoints()
+	c.Assert(err, IsNil)
+	c.Assert(endpoints, HasLen, 2)
+	c.Check(endpoints[0].String(), Equals, "https://eu.proxy.example/")
+	c.Check(endpoints[1].String(), Equals, "https://us.proxy.example/")
+
+	c.Assert(cfg.AssertionsEndpoints, NotNil)
+	st.Lock()
+	tr = config.NewTransaction(st)
+	tr.Set("core", "store.assertions-endpoints", "https://assertions.proxy.example/")
+	tr.Commit()
+	st.Unlock()
+
+	endpoints, err = cfg.AssertionsEndpoints()
+	c.Assert(err, IsNil)
+	c.Assert(endpoints, HasLen, 1)
+	c.Check(endpoints[0].String(), Equals, "https://assertions.proxy.example/")
+}
+
+func (ovs *overlordSuite) TestNewStorePeers(c *C) {
+	var cfg *store.Config
+	restore := overlord.MockStoreNew(func(c *store.Config, dac store.DeviceAndAuthContext) *store.Store {
+		cfg = c
+		return store.New(c, dac)
+	})
+	defer restore()
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	c.Assert(cfg, NotNil)
+	c.Assert(cfg.Peers, NotNil)
+
+	peers, err := cfg.Peers()
+	c.Assert(err, IsNil)
+	c.Check(peers, HasLen, 0)
+
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.peer-cache.peers", "10.0.0.2:8738, peer.lan:8738")
+	tr.Commit()
+	st.Unlock()
+
+	peers, err = cfg.Peers()
+	c.Assert(err, IsNil)
+	c.Check(peers, DeepEquals, []string{"10.0.0.2:8738", "peer.lan:8738"})
+}
+
+func (ovs *overlordSuite) TestPeerCacheManagerServes(c *C) {
+	digest := strings.Repeat("ab", 48)
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapDownloadCacheDir, digest), []byte("blob"), 0600), IsNil)
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	mgr := o.PeerCacheManager()
+	defer mgr.(overlord.StateStopper).Stop()
+
+	// not serving by default
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(o.PeerCacheAddr(), IsNil)
+
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.peer-cache.listen", "127.0.0.1:0")
+	tr.Set("core", "store.peer-cache.peers", "127.0.0.1:8738")
+	tr.Commit()
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), IsNil)
+	addr := o.PeerCacheAddr()
+	c.Assert(addr, NotNil)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/v1/blobs/%s", addr, digest))
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, 200)
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Equals, "blob")
+
+	// unsetting the option stops serving
+	st.Lock()
+	tr = config.NewTransaction(st)
+	tr.Set("core", "store.peer-cache.listen", "")
+	tr.Commit()
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(o.PeerCacheAddr(), IsNil)
+	_, err = http.Get(fmt.Sprintf("http://%s/v1/blobs/%s", addr, digest))
+	c.Check(err, NotNil)
+}
+
 func (ovs *overlordSuite) TestNewWithGoodState(c *C) {
 	// ensure we don't write state load timing in the state on really
 	// slow architectures (e.g. risc-v)
@@ -349,6 +527,30 @@ func (ovs *overlordSuite) TestUnknownTasks(c *C) {
 	c.Check(chg.Status(), Equals, state.DoneStatus)
 }
 
+func (ovs *overlordSuite) TestStartUpTrimsDownloadCache(c *C) {
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"config":{"core":{"store":{"cache":{"max-size":"15B"}}}}},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, snapdtool.Version))
+	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+
+	// the bound was lowered while snapd was not running
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), IsNil)
+	then := time.Now().Add(-time.Hour)
+	for i, name := range []string{"older", "newer"} {
+		p := filepath.Join(dirs.SnapDownloadCacheDir, name)
+		c.Assert(ioutil.WriteFile(p, []byte("0123456789"), 0644), IsNil)
+		mtime := then.Add(time.Duration(i) * time.Minute)
+		c.Assert(os.Chtimes(p, mtime, mtime), IsNil)
+	}
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	markSeeded(o)
+	c.Assert(o.StartUp(), IsNil)
+
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, "older"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapDownloadCacheDir, "newer"), testutil.FilePresent)
+}
+
 func (ovs *overlordSuite) TestEnsureLoopRunAndStop(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(10 * time.Millisecond)
 	defer restoreIntv()
@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package overlord
+
+import (
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+)
+
+// peerCacheManager serves the download cache to the peers while the
+// store.peer-cache.listen option is set.
+type peerCacheManager struct {
+	state  *state.State
+	server *store.PeerCacheServer
+}
+
+func newPeerCacheManager(st *state.State, peers func() ([]string, error)) *peerCacheManager {
+	return &peerCacheManager{
+		state:  st,
+		server: store.NewPeerCacheServer(dirs.SnapDownloadCacheDir, peers),
+	}
+}
+
+// Ensure implements StateManager.Ensure.
+func (m *peerCacheManager) Ensure() error {
+	m.state.Lock()
+	tr := config.NewTransaction(m.state)
+	m.state.Unlock()
+
+	var addr string
+	if err := tr.Get("core", "store.peer-cache.listen", &addr); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	return m.server.Listen(addr)
+}
+
+// Stop implements StateStopper.
+func (m *peerCacheManager) Stop() {
+	m.server.Stop()
+}
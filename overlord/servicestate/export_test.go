@@ -19,6 +19,29 @@
 
 package servicestate
 
+import (
+	"time"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
 var (
 	UpdateSnapstateServices = updateSnapstateServices
 )
+
+func MockSnapstateRevert(f func(st *state.State, name string, flags snapstate.Flags) (*state.TaskSet, error)) (restore func()) {
+	old := snapstateRevert
+	snapstateRevert = f
+	return func() {
+		snapstateRevert = old
+	}
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
@@ -0,0 +1,165 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+)
+
+const (
+	defaultFailureLimit  = 3
+	defaultFailureWindow = 10 * time.Minute
+)
+
+var (
+	failureCheckInterval = time.Minute
+
+	snapstateRevert = snapstate.Revert
+	timeNow         = time.Now
+)
+
+// failurePolicy returns how many failures of a service within how long
+// after a refresh trigger its on-failure-action, as set with the
+// services.failure-limit and services.failure-window system options.
+func failurePolicy(st *state.State) (limit int, window time.Duration, err error) {
+	limit, window = defaultFailureLimit, defaultFailureWindow
+
+	tr := config.NewTransaction(st)
+	var limitStr, windowStr interface{} = "", ""
+	if err := tr.Get("core", "services.failure-limit", &limitStr); err != nil && !config.IsNoOption(err) {
+		return 0, 0, err
+	}
+	if err := tr.Get("core", "services.failure-window", &windowStr); err != nil && !config.IsNoOption(err) {
+		return 0, 0, err
+	}
+	if s := fmt.Sprintf("%v", limitStr); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil {
+			return 0, 0, fmt.Errorf("invalid services.failure-limit: %v", err)
+		}
+	}
+	if s := fmt.Sprintf("%v", windowStr); s != "" {
+		if window, err = time.ParseDuration(s); err != nil {
+			return 0, 0, fmt.Errorf("invalid services.failure-window: %v", err)
+		}
+	}
+	return limit, window, nil
+}
+
+// failingService returns the first service of the snap asking to be
+// reverted on failure that was restarted at least limit times or gave up.
+func failingService(sysd systemd.Systemd, info *snap.Info, limit int) (*snap.AppInfo, error) {
+	for _, app := range info.Services() {
+		if app.OnFailureAction != snap.FailureActionRevert || app.DaemonScope != snap.SystemDaemon {
+			continue
+		}
+		restarts, failed, err := sysd.FailureState(app.ServiceName())
+		if err != nil {
+			return nil, err
+		}
+		if failed || restarts >= limit {
+			return app, nil
+		}
+	}
+	return nil, nil
+}
+
+// ensureFailureActions reverts snaps that were refreshed recently and
+// whose services, marked with "on-failure-action: revert", keep failing.
+func (m *ServiceManager) ensureFailureActions() error {
+	now := timeNow()
+	if !m.lastFailureCheck.IsZero() && now.Sub(m.lastFailureCheck) < failureCheckInterval {
+		return nil
+	}
+	m.lastFailureCheck = now
+
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	limit, window, err := failurePolicy(st)
+	if err != nil {
+		return err
+	}
+	all, err := snapstate.All(st)
+	if err != nil {
+		return err
+	}
+	var reverted map[string]snap.Revision
+	if err := st.Get("failure-reverted", &reverted); err != nil && err != state.ErrNoState {
+		return err
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, progress.Null)
+	for name, snapst := range all {
+		// there needs to be something to go back to
+		if !snapst.Active || snapst.LastIndex(snapst.Current) < 1 {
+			continue
+		}
+		// do not keep reverting a revision we reverted to
+		if reverted[name] == snapst.Current {
+			continue
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			logger.Noticef("cannot check services of %q for failures: %v", name, err)
+			continue
+		}
+		if now.Sub(info.InstallDate()) > window {
+			continue
+		}
+		app, err := failingService(sysd, info, limit)
+		if err != nil {
+			logger.Noticef("cannot check services of %q for failures: %v", name, err)
+			continue
+		}
+		if app == nil {
+			continue
+		}
+
+		ts, err := snapstateRevert(st, name, snapstate.Flags{})
+		if err != nil {
+			logger.Noticef("cannot revert %q after its service %q failed: %v", name, app.Name, err)
+			continue
+		}
+		msg := fmt.Sprintf("Revert %q after its service %q failed repeatedly", name, app.Name)
+		logger.Noticef("%s", msg)
+		chg := st.NewChange("revert-snap", msg)
+		chg.AddAll(ts)
+
+		if reverted == nil {
+			reverted = make(map[string]snap.Revision)
+		}
+		reverted[name] = snapst.Sequence[snapst.LastIndex(snapst.Current)-1].Revision
+		st.Set("failure-reverted", reverted)
+		st.EnsureBefore(0)
+	}
+	return nil
+}
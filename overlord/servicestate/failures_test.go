@@ -0,0 +1,193 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type failuresSuite struct {
+	testutil.BaseTest
+	state      *state.State
+	serviceMgr *servicestate.ServiceManager
+
+	sysctlArgs [][]string
+	restarts   string
+	active     string
+	reverts    []string
+}
+
+var _ = Suite(&failuresSuite{})
+
+const failingSnapYaml = `name: test-snap
+version: 1.0
+apps:
+  foo:
+    daemon: simple
+    on-failure-action: revert
+  bar:
+    daemon: simple
+`
+
+func (s *failuresSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	o := overlord.Mock()
+	s.state = o.State()
+	s.serviceMgr = servicestate.Manager(s.state, o.TaskRunner())
+
+	s.sysctlArgs = nil
+	s.restarts = "0"
+	s.active = "active"
+	s.AddCleanup(systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysctlArgs = append(s.sysctlArgs, cmd)
+		return []byte("NRestarts=" + s.restarts + "\nActiveState=" + s.active + "\n"), nil
+	}))
+
+	s.reverts = nil
+	s.AddCleanup(servicestate.MockSnapstateRevert(func(st *state.State, name string, flags snapstate.Flags) (*state.TaskSet, error) {
+		s.reverts = append(s.reverts, name)
+		return state.NewTaskSet(st.NewTask("fake-revert", "...")), nil
+	}))
+
+	s.mockRefreshedSnap(c)
+}
+
+// mockRefreshedSnap mocks test-snap as just refreshed from revision 1 to 2.
+func (s *failuresSuite) mockRefreshedSnap(c *C) {
+	si1 := &snap.SideInfo{RealName: "test-snap", Revision: snap.R(1)}
+	si2 := &snap.SideInfo{RealName: "test-snap", Revision: snap.R(2)}
+	snaptest.MockSnap(c, failingSnapYaml, si1)
+	info := snaptest.MockSnap(c, failingSnapYaml, si2)
+	c.Assert(os.Symlink("2", filepath.Join(filepath.Dir(info.MountDir()), "current")), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si1, si2},
+		Current:  snap.R(2),
+		SnapType: "app",
+	})
+}
+
+func (s *failuresSuite) revertChanges() []*state.Change {
+	s.state.Lock()
+	defer s.state.Unlock()
+	var chgs []*state.Change
+	for _, chg := range s.state.Changes() {
+		if chg.Kind() == "revert-snap" {
+			chgs = append(chgs, chg)
+		}
+	}
+	return chgs
+}
+
+func (s *failuresSuite) TestRevertsAfterRepeatedFailures(c *C) {
+	s.restarts = "3"
+
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+
+	// only the service asking for it is watched
+	c.Check(s.sysctlArgs, DeepEquals, [][]string{
+		{"show", "--property=NRestarts,ActiveState", "snap.test-snap.foo.service"},
+	})
+	c.Check(s.reverts, DeepEquals, []string{"test-snap"})
+	chgs := s.revertChanges()
+	c.Assert(chgs, HasLen, 1)
+	c.Check(chgs[0].Summary(), Equals, `Revert "test-snap" after its service "foo" failed repeatedly`)
+
+	// once reverted, revision 1 is not reverted any further
+	s.state.Lock()
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "test-snap", &snapst), IsNil)
+	snapst.Current = snap.R(1)
+	snapstate.Set(s.state, "test-snap", &snapst)
+	s.state.Unlock()
+
+	restore := servicestate.MockTimeNow(func() time.Time { return time.Now().Add(2 * time.Minute) })
+	defer restore()
+	s.sysctlArgs = nil
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Check(s.sysctlArgs, HasLen, 0)
+	c.Check(s.reverts, HasLen, 1)
+}
+
+func (s *failuresSuite) TestRevertsWhenServiceGaveUp(c *C) {
+	s.active = "failed"
+
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Check(s.reverts, DeepEquals, []string{"test-snap"})
+}
+
+func (s *failuresSuite) TestNoRevertBelowLimit(c *C) {
+	s.restarts = "4"
+
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "services.failure-limit", "5")
+	tr.Commit()
+	s.state.Unlock()
+
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Check(s.sysctlArgs, HasLen, 1)
+	c.Check(s.reverts, HasLen, 0)
+	c.Check(s.revertChanges(), HasLen, 0)
+}
+
+func (s *failuresSuite) TestNoRevertOutsideWindow(c *C) {
+	s.restarts = "10"
+
+	restore := servicestate.MockTimeNow(func() time.Time { return time.Now().Add(11 * time.Minute) })
+	defer restore()
+
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Check(s.sysctlArgs, HasLen, 0)
+	c.Check(s.reverts, HasLen, 0)
+}
+
+func (s *failuresSuite) TestChecksAtMostOncePerInterval(c *C) {
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Check(s.sysctlArgs, HasLen, 1)
+
+	restore := servicestate.MockTimeNow(func() time.Time { return time.Now().Add(time.Minute) })
+	defer restore()
+	c.Assert(s.serviceMgr.Ensure(), IsNil)
+	c.Check(s.sysctlArgs, HasLen, 2)
+}
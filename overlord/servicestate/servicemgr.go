@@ -21,6 +21,7 @@ package servicestate
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -29,6 +30,8 @@ import (
 // ServiceManager is responsible for starting and stopping snap services.
 type ServiceManager struct {
 	state *state.State
+
+	lastFailureCheck time.Time
 }
 
 // Manager returns a new service manager.
@@ -44,7 +47,7 @@ func Manager(st *state.State, runner *state.TaskRunner) *ServiceManager {
 
 // Ensure implements StateManager.Ensure.
 func (m *ServiceManager) Ensure() error {
-	return nil
+	return m.ensureFailureActions()
 }
 
 func delayedCrossMgrInit() {
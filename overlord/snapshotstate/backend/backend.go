@@ -64,6 +64,14 @@ type Flags struct {
 	Auto bool
 }
 
+// StagingDir returns the directory, inside the snap's system data, where
+// the pre-snapshot hook stages state to be archived and where the
+// post-restore hook finds it again once restored. The hooks get it in
+// $SNAPSHOT_STAGING_DIR.
+func StagingDir(si *snap.Info) string {
+	return filepath.Join(si.DataDir(), ".snapshot")
+}
+
 // Iter loops over all snapshots in the snapshots directory, applying the given
 // function to each. The snapshot will be closed after the function returns. If
 // the function returns an error, iteration is stopped (and if the error isn't
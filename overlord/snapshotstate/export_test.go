@@ -162,3 +162,8 @@ func MockConfigSetSnapConfig(f func(*state.State, string, *json.RawMessage) erro
 func (mgr *SnapshotManager) SetLastForgetExpiredSnapshotTime(t time.Time) {
 	mgr.lastForgetExpiredSnapshotTime = t
 }
+
+var (
+	NewPreSnapshotHandler = newPreSnapshotHandler
+	NewPostRestoreHandler = newPostRestoreHandler
+)
@@ -0,0 +1,166 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// StagingDirEnv is the variable telling the pre-snapshot and
+// post-restore hooks the directory to stage their state into, or to
+// find it in once restored, see backend.StagingDir.
+const StagingDirEnv = "SNAPSHOT_STAGING_DIR"
+
+// Init registers the handlers of the pre-snapshot and post-restore hooks.
+func Init(hookManager *hookstate.HookManager) {
+	hookManager.Register(regexp.MustCompile("^pre-snapshot$"), newPreSnapshotHandler)
+	hookManager.Register(regexp.MustCompile("^post-restore$"), newPostRestoreHandler)
+}
+
+// hookTask returns a task running the given snapshot hook of the snap,
+// or nil if the snap does not declare it.
+func hookTask(st *state.State, info *snap.Info, hookName string, setID uint64, ignoreError bool) *state.Task {
+	if info == nil || info.Hooks[hookName] == nil {
+		return nil
+	}
+	summary := fmt.Sprintf("Run %s hook of %q snap for snapshot set #%d", hookName, info.InstanceName(), setID)
+	hooksup := &hookstate.HookSetup{
+		Snap:        info.InstanceName(),
+		Revision:    info.Revision,
+		Hook:        hookName,
+		Optional:    true,
+		IgnoreError: ignoreError,
+	}
+	return hookstate.HookTask(st, summary, hooksup, nil)
+}
+
+func hookSnapInfo(ctx *hookstate.Context) (*snap.Info, error) {
+	ctx.Lock()
+	defer ctx.Unlock()
+	return snapstateCurrentInfo(ctx.State(), ctx.InstanceName())
+}
+
+func stagingDirEnv(stagingDir string) []string {
+	return []string{fmt.Sprintf("%s=%s", StagingDirEnv, stagingDir)}
+}
+
+type preSnapshotHandler struct {
+	context    *hookstate.Context
+	stagingDir string
+}
+
+func newPreSnapshotHandler(ctx *hookstate.Context) hookstate.Handler {
+	return &preSnapshotHandler{context: ctx}
+}
+
+// Before provides the hook with an empty staging directory to dump its
+// state into.
+func (h *preSnapshotHandler) Before() error {
+	info, err := hookSnapInfo(h.context)
+	if err != nil {
+		return err
+	}
+	stagingDir := backend.StagingDir(info)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stagingDir, 0700); err != nil {
+		return err
+	}
+	h.stagingDir = stagingDir
+	return nil
+}
+
+// Env tells the hook where the staging directory is.
+func (h *preSnapshotHandler) Env() []string {
+	return stagingDirEnv(h.stagingDir)
+}
+
+// Done does nothing: the staging directory is archived and removed by
+// the save-snapshot task.
+func (h *preSnapshotHandler) Done() error {
+	return nil
+}
+
+// Error removes whatever the hook managed to stage before failing.
+func (h *preSnapshotHandler) Error(hookErr error) error {
+	return h.removeStagingDir()
+}
+
+// IgnoredError removes whatever the hook managed to stage before failing
+// during an automatic snapshot, so that it is not archived.
+func (h *preSnapshotHandler) IgnoredError(hookErr error) error {
+	return h.removeStagingDir()
+}
+
+func (h *preSnapshotHandler) removeStagingDir() error {
+	info, err := hookSnapInfo(h.context)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(backend.StagingDir(info))
+}
+
+type postRestoreHandler struct {
+	context    *hookstate.Context
+	stagingDir string
+}
+
+func newPostRestoreHandler(ctx *hookstate.Context) hookstate.Handler {
+	return &postRestoreHandler{context: ctx}
+}
+
+// Before only looks up the staging directory: it was put in place by
+// the restore-snapshot task.
+func (h *postRestoreHandler) Before() error {
+	info, err := hookSnapInfo(h.context)
+	if err != nil {
+		return err
+	}
+	h.stagingDir = backend.StagingDir(info)
+	return nil
+}
+
+// Env tells the hook where the staging directory is.
+func (h *postRestoreHandler) Env() []string {
+	return stagingDirEnv(h.stagingDir)
+}
+
+// Done removes the staging directory once the hook re-imported it.
+func (h *postRestoreHandler) Done() error {
+	info, err := hookSnapInfo(h.context)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(backend.StagingDir(info))
+}
+
+// Error leaves the staging directory alone: undoing the restore-snapshot
+// task puts the previous data back.
+func (h *postRestoreHandler) Error(hookErr error) error {
+	return nil
+}
@@ -0,0 +1,112 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func mockHookContext(c *check.C, hook string) (ctx *hookstate.Context, stagingDir string, restore func()) {
+	info := snaptest.MockInfo(c, "{name: a-snap, version: v1}", &snap.SideInfo{Revision: snap.R(1)})
+	restore = snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, name string) (*snap.Info, error) {
+		c.Check(name, check.Equals, "a-snap")
+		return info, nil
+	})
+
+	st := state.New(nil)
+	ctx, err := hookstate.NewContext(nil, st, &hookstate.HookSetup{Snap: "a-snap", Revision: snap.R(1), Hook: hook}, nil, "")
+	c.Assert(err, check.IsNil)
+	return ctx, backend.StagingDir(info), restore
+}
+
+func (snapshotSuite) TestPreSnapshotHandlerBeforeCreatesEmptyStagingDir(c *check.C) {
+	ctx, stagingDir, restore := mockHookContext(c, "pre-snapshot")
+	defer restore()
+
+	// leftovers from an earlier attempt are not archived
+	c.Assert(os.MkdirAll(stagingDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(stagingDir, "stale"), nil, 0644), check.IsNil)
+
+	handler := snapshotstate.NewPreSnapshotHandler(ctx)
+	c.Assert(handler.Before(), check.IsNil)
+	c.Check(filepath.Join(stagingDir, "stale"), testutil.FileAbsent)
+	st, err := os.Stat(stagingDir)
+	c.Assert(err, check.IsNil)
+	c.Check(st.IsDir(), check.Equals, true)
+	c.Check(st.Mode().Perm(), check.Equals, os.FileMode(0700))
+
+	envHandler, ok := handler.(hookstate.EnvHandler)
+	c.Assert(ok, check.Equals, true)
+	c.Check(envHandler.Env(), check.DeepEquals, []string{"SNAPSHOT_STAGING_DIR=" + stagingDir})
+
+	c.Assert(handler.Done(), check.IsNil)
+	c.Check(stagingDir, testutil.FilePresent)
+}
+
+func (snapshotSuite) TestPreSnapshotHandlerErrorRemovesStagingDir(c *check.C) {
+	ctx, stagingDir, restore := mockHookContext(c, "pre-snapshot")
+	defer restore()
+
+	handler := snapshotstate.NewPreSnapshotHandler(ctx)
+	c.Assert(handler.Before(), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(stagingDir, "partial"), nil, 0644), check.IsNil)
+
+	c.Assert(handler.Error(errors.New("boom")), check.IsNil)
+	c.Check(stagingDir, testutil.FileAbsent)
+
+	c.Assert(handler.Before(), check.IsNil)
+	ignoredHandler, ok := handler.(hookstate.IgnoredErrorHandler)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(ignoredHandler.IgnoredError(errors.New("boom")), check.IsNil)
+	c.Check(stagingDir, testutil.FileAbsent)
+}
+
+func (snapshotSuite) TestPostRestoreHandlerDoneRemovesStagingDir(c *check.C) {
+	ctx, stagingDir, restore := mockHookContext(c, "post-restore")
+	defer restore()
+
+	c.Assert(os.MkdirAll(stagingDir, 0700), check.IsNil)
+	handler := snapshotstate.NewPostRestoreHandler(ctx)
+	c.Assert(handler.Before(), check.IsNil)
+	c.Check(stagingDir, testutil.FilePresent)
+
+	envHandler, ok := handler.(hookstate.EnvHandler)
+	c.Assert(ok, check.Equals, true)
+	c.Check(envHandler.Env(), check.DeepEquals, []string{"SNAPSHOT_STAGING_DIR=" + stagingDir})
+
+	c.Assert(handler.Error(errors.New("boom")), check.IsNil)
+	c.Check(stagingDir, testutil.FilePresent)
+
+	c.Assert(handler.Done(), check.IsNil)
+	c.Check(stagingDir, testutil.FileAbsent)
+}
@@ -214,6 +214,13 @@ func doSave(task *state.Task, tomb *tomb.Tomb) error {
 		return err
 	}
 	_, err = backendSave(tomb.Context(nil), snapshot.SetID, cur, cfg, snapshot.Users, &backend.Flags{Auto: snapshot.Auto})
+	if cur.Hooks["pre-snapshot"] != nil {
+		// the staged state is in the archive now, or needs to be
+		// staged again by the hook on the next attempt
+		if rmErr := os.RemoveAll(backend.StagingDir(cur)); rmErr != nil {
+			logger.Noticef("cannot remove snapshot staging directory of %q: %v", cur.InstanceName(), rmErr)
+		}
+	}
 	if err != nil {
 		st := task.State()
 		st.Lock()
@@ -281,6 +281,12 @@ func Save(st *state.State, instanceNames []string, users []string) (setID uint64
 	for _, name := range instanceNames {
 		desc := fmt.Sprintf("Save data of snap %q in snapshot set #%d", name, setID)
 		task := st.NewTask("save-snapshot", desc)
+		// a snap that is not installed fails in the save-snapshot task
+		info, _ := snapstateCurrentInfo(st, name)
+		if hook := hookTask(st, info, "pre-snapshot", setID, false); hook != nil {
+			task.WaitFor(hook)
+			ts.AddTask(hook)
+		}
 		snapshot := snapshotSetup{
 			SetID: setID,
 			Snap:  name,
@@ -326,6 +332,12 @@ func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err
 		Auto:  true,
 	}
 	task.Set("snapshot-setup", &snapshot)
+	// a failing hook must not block the removal of the snap
+	info, _ := snapstateCurrentInfo(st, snapName)
+	if hook := hookTask(st, info, "pre-snapshot", setID, true); hook != nil {
+		task.WaitFor(hook)
+		ts.AddTask(hook)
+	}
 	ts.AddTask(task)
 
 	return ts, nil
@@ -358,8 +370,9 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 
 	for _, summary := range summaries {
 		var current snap.Revision
+		var info *snap.Info
 		if snapst, ok := all[summary.snap]; ok {
-			info, err := snapst.CurrentInfo()
+			info, err = snapst.CurrentInfo()
 			if err != nil {
 				// how?
 				return nil, nil, fmt.Errorf("unexpected error while reading snap info: %v", err)
@@ -387,6 +400,10 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 		task.Set("snapshot-setup", &snapshot)
 		// see the note about snapshots not using lanes, above.
 		ts.AddTask(task)
+		if hook := hookTask(st, info, "post-restore", setID, false); hook != nil {
+			hook.WaitFor(task)
+			ts.AddTask(hook)
+		}
 	}
 
 	return snapsFound, ts, nil
@@ -0,0 +1,390 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// gateAutoRefreshHook is the hook that lets snaps hold or proceed
+// with the auto-refresh of themselves and of the snaps connected to
+// them.
+const gateAutoRefreshHook = "gate-auto-refresh"
+
+// ConnectedSnaps is set up by ifacestate and returns the snaps with
+// a connection to the given snap.
+var ConnectedSnaps func(st *state.State, instanceName string) ([]string, error)
+
+var SetupGateAutoRefreshHook = func(st *state.State, snapName string, affectingSnaps []string) *state.Task {
+	panic("internal error: snapstate.SetupGateAutoRefreshHook is unset")
+}
+
+// holdState records for how long a snap has been holding the refresh
+// of another one.
+type holdState struct {
+	// FirstHeld is when the snap started holding the refresh.
+	FirstHeld time.Time `json:"first-held"`
+}
+
+// refreshHolds returns the holds in the state, by held snap and then
+// by holding snap.
+func refreshHolds(st *state.State) (map[string]map[string]*holdState, error) {
+	var holds map[string]map[string]*holdState
+	if err := st.Get("snaps-hold", &holds); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if holds == nil {
+		holds = make(map[string]map[string]*holdState)
+	}
+	return holds, nil
+}
+
+// HoldRefresh records that gatingSnap holds the auto-refresh of the
+// given snaps. A snap can hold the refresh of another one for up to
+// maxPostponement from when it first held it, an error is returned
+// afterwards.
+func HoldRefresh(st *state.State, gatingSnap string, heldSnaps ...string) error {
+	holds, err := refreshHolds(st)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, held := range heldSnaps {
+		hold := holds[held][gatingSnap]
+		if hold != nil && now.Sub(hold.FirstHeld) > maxPostponement {
+			days := int(maxPostponement.Truncate(time.Hour).Hours() / 24)
+			return fmt.Errorf("cannot hold refresh of %q any longer: it was held by %q for more than %d days", held, gatingSnap, days)
+		}
+	}
+	for _, held := range heldSnaps {
+		if holds[held] == nil {
+			holds[held] = make(map[string]*holdState)
+		}
+		if holds[held][gatingSnap] == nil {
+			holds[held][gatingSnap] = &holdState{FirstHeld: now}
+		}
+	}
+	st.Set("snaps-hold", holds)
+	return nil
+}
+
+// ProceedWithRefresh removes all the holds of gatingSnap on the
+// auto-refresh of other snaps.
+func ProceedWithRefresh(st *state.State, gatingSnap string) error {
+	holds, err := refreshHolds(st)
+	if err != nil {
+		return err
+	}
+	for held, byGating := range holds {
+		delete(byGating, gatingSnap)
+		if len(byGating) == 0 {
+			delete(holds, held)
+		}
+	}
+	st.Set("snaps-hold", holds)
+	return nil
+}
+
+// heldSnaps returns the snaps whose auto-refresh is held by other snaps
+// for less than maxPostponement, with the snaps holding them.
+func heldSnaps(st *state.State) (map[string][]string, error) {
+	holds, err := refreshHolds(st)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	held := make(map[string][]string)
+	for heldSnap, byGating := range holds {
+		for gatingSnap, hold := range byGating {
+			if now.Sub(hold.FirstHeld) <= maxPostponement {
+				held[heldSnap] = append(held[heldSnap], gatingSnap)
+			}
+		}
+		sort.Strings(held[heldSnap])
+	}
+	return held, nil
+}
+
+// clearHolds removes the holds on the refresh of the given snaps.
+func clearHolds(st *state.State, snaps []string) error {
+	holds, err := refreshHolds(st)
+	if err != nil {
+		return err
+	}
+	for _, name := range snaps {
+		delete(holds, name)
+	}
+	st.Set("snaps-hold", holds)
+	return nil
+}
+
+func hasGateAutoRefreshHook(st *state.State, instanceName string) (bool, error) {
+	var snapst SnapState
+	if err := Get(st, instanceName, &snapst); err != nil {
+		if err == state.ErrNoState {
+			return false, nil
+		}
+		return false, err
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return false, err
+	}
+	return info.Hooks[gateAutoRefreshHook] != nil, nil
+}
+
+// pruneHolds removes the holds of the snaps that cannot hold the
+// auto-refresh of other snaps anymore, as they are not installed or do
+// not have a gate-auto-refresh hook.
+func pruneHolds(st *state.State) error {
+	holds, err := refreshHolds(st)
+	if err != nil {
+		return err
+	}
+	canHold := make(map[string]bool)
+	changed := false
+	for held, byGating := range holds {
+		for gatingSnap := range byGating {
+			ok, known := canHold[gatingSnap]
+			if !known {
+				ok, err = hasGateAutoRefreshHook(st, gatingSnap)
+				if err != nil {
+					return err
+				}
+				canHold[gatingSnap] = ok
+			}
+			if !ok {
+				delete(byGating, gatingSnap)
+				changed = true
+			}
+		}
+		if len(byGating) == 0 {
+			delete(holds, held)
+			changed = true
+		}
+	}
+	if changed {
+		st.Set("snaps-hold", holds)
+	}
+	return nil
+}
+
+// removeHolds removes the holds of the given removed snap on other
+// snaps and the holds on its own auto-refresh.
+func removeHolds(st *state.State, instanceName string) error {
+	if err := ProceedWithRefresh(st, instanceName); err != nil {
+		return err
+	}
+	return clearHolds(st, []string{instanceName})
+}
+
+// gatingSnaps returns the snaps with a gate-auto-refresh hook among
+// the given snaps about to be refreshed and the snaps connected to
+// them, with the snaps affecting each of them. The refreshes of snapd,
+// core, the kernel and the gadget cannot be gated, most snaps are
+// connected to them through the system slots.
+func gatingSnaps(st *state.State, updates []*snap.Info) (map[string][]string, error) {
+	affecting := make(map[string][]string)
+	for _, update := range updates {
+		switch update.Type() {
+		case snap.TypeSnapd, snap.TypeOS, snap.TypeKernel, snap.TypeGadget:
+			continue
+		}
+		name := update.InstanceName()
+		affected := []string{name}
+		if ConnectedSnaps != nil {
+			connected, err := ConnectedSnaps(st, name)
+			if err != nil {
+				return nil, err
+			}
+			affected = append(affected, connected...)
+		}
+		for _, snapName := range affected {
+			ok, err := hasGateAutoRefreshHook(st, snapName)
+			if err != nil {
+				return nil, err
+			}
+			if ok && !strutil.ListContains(affecting[snapName], name) {
+				affecting[snapName] = append(affecting[snapName], name)
+			}
+		}
+	}
+	for _, snaps := range affecting {
+		sort.Strings(snaps)
+	}
+	return affecting, nil
+}
+
+// autoRefreshGateTasks returns the tasks running the gate-auto-refresh
+// hooks of the gating snaps followed by the task refreshing the
+// candidates that are not held.
+func autoRefreshGateTasks(st *state.State, candidates []string, gating map[string][]string) *state.TaskSet {
+	ts := state.NewTaskSet()
+	conditional := st.NewTask("conditional-auto-refresh", fmt.Sprintf(i18n.G("Auto-refresh %s unless held"), strutil.Quoted(candidates)))
+	conditional.Set("snap-names", candidates)
+	for _, name := range sortedKeys(gating) {
+		hook := SetupGateAutoRefreshHook(st, name, gating[name])
+		ts.AddTask(hook)
+		conditional.WaitFor(hook)
+	}
+	ts.AddTask(conditional)
+	return ts
+}
+
+// autoRefreshUpdateMany exists just to make testing simpler
+var autoRefreshUpdateMany = updateManyFiltered
+
+func (m *SnapManager) doConditionalAutoRefresh(t *state.Task, tomb *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var candidates []string
+	if err := t.Get("snap-names", &candidates); err != nil {
+		return err
+	}
+	if err := pruneHolds(st); err != nil {
+		return err
+	}
+	held, err := heldSnaps(st)
+	if err != nil {
+		return err
+	}
+	for _, name := range candidates {
+		if gating := held[name]; len(gating) > 0 {
+			t.Logf("Auto-refresh of %q held by %s.", name, strutil.Quoted(gating))
+		}
+	}
+	filter, err := autoRefreshFilter(st, candidates, held)
+	if err != nil {
+		return err
+	}
+
+	chg := t.Change()
+	updated, tasksets, err := autoRefreshUpdateMany(tomb.Context(nil), st, nil, 0, filter, &Flags{IsAutoRefresh: true}, chg.ID())
+	if err != nil {
+		return err
+	}
+	if err := clearHolds(st, updated); err != nil {
+		return err
+	}
+
+	if len(updated) == 0 {
+		t.Logf("No snaps to auto-refresh.")
+	} else {
+		for _, taskset := range tasksets {
+			chg.AddAll(taskset)
+		}
+		st.EnsureBefore(0)
+	}
+	chg.Set("snap-names", updated)
+	chg.Set("api-data", map[string]interface{}{"snap-names": updated})
+	t.SetStatus(state.DoneStatus)
+
+	return nil
+}
+
+// autoRefreshFilter returns the filter of the auto-refresh updates,
+// excluding the snaps of pinned cohorts, the held snaps and, if
+// candidates is not nil, the snaps that are not candidates.
+func autoRefreshFilter(st *state.State, candidates []string, held map[string][]string) (updateFilter, error) {
+	pinned, err := pinnedCohortSnaps(st)
+	if err != nil {
+		return nil, err
+	}
+	if len(pinned) == 0 && len(held) == 0 && candidates == nil {
+		return nil, nil
+	}
+	return func(update *snap.Info, _ *SnapState) bool {
+		name := update.InstanceName()
+		if candidates != nil && !strutil.ListContains(candidates, name) {
+			return false
+		}
+		// snaps of pinned cohorts are only refreshed with their cohort
+		return !pinned[name] && len(held[name]) == 0
+	}, nil
+}
+
+// autoRefreshGated returns the snaps to auto-refresh and the tasks to
+// do so, running the gate-auto-refresh hooks first if any of the
+// snaps to refresh, or of the snaps connected to them, has one.
+func autoRefreshGated(ctx context.Context, st *state.State, userID int) ([]string, []*state.TaskSet, error) {
+	if err := pruneHolds(st); err != nil {
+		return nil, nil, err
+	}
+	held, err := heldSnaps(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	// held snaps are kept among the updates so that the gating snaps
+	// get asked again about them, doConditionalAutoRefresh filters out
+	// the ones still held once the hooks ran
+	filter, err := autoRefreshFilter(st, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	flags := &Flags{IsAutoRefresh: true}
+	updates, stateByInstanceName, deviceCtx, err := refreshUpdates(ctx, st, nil, userID, filter, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	gating, err := gatingSnaps(st, updates)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(gating) == 0 {
+		// no hook to ask, held snaps stay held
+		notHeld := make([]*snap.Info, 0, len(updates))
+		for _, update := range updates {
+			if len(held[update.InstanceName()]) == 0 {
+				notHeld = append(notHeld, update)
+			}
+		}
+		return updateManyTasks(ctx, st, nil, notHeld, stateByInstanceName, userID, flags, deviceCtx, "")
+	}
+
+	candidates := make([]string, len(updates))
+	for i, update := range updates {
+		candidates[i] = update.InstanceName()
+	}
+	sort.Strings(candidates)
+	logger.Debugf("auto-refresh of %s gated by %s", strutil.Quoted(candidates), strutil.Quoted(sortedKeys(gating)))
+	return candidates, []*state.TaskSet{autoRefreshGateTasks(st, candidates, gating)}, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
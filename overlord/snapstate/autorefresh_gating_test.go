@@ -0,0 +1,309 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setupGating(c *C) {
+	for _, name := range []string{"some-snap", "services-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{{RealName: name, SnapID: name + "-id", Revision: snap.R(7)}},
+			Current:  snap.R(7),
+			SnapType: "app",
+		})
+	}
+	// sideloaded, so never refreshed itself
+	snapstate.Set(s.state, "gating-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "gating-snap", Revision: snap.R(-1)}},
+		Current:  snap.R(-1),
+		SnapType: "app",
+	})
+
+	old := snapstate.ConnectedSnaps
+	snapstate.ConnectedSnaps = func(st *state.State, instanceName string) ([]string, error) {
+		if instanceName == "some-snap" {
+			return []string{"gating-snap"}, nil
+		}
+		return nil, nil
+	}
+	s.AddCleanup(func() { snapstate.ConnectedSnaps = old })
+}
+
+func (s *snapmgrTestSuite) TestHoldRefreshAndProceed(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "some-snap", "services-snap"), IsNil)
+	c.Assert(snapstate.HoldRefresh(s.state, "other-gating-snap", "some-snap"), IsNil)
+	held, err := snapstate.HeldSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string][]string{
+		"some-snap":     {"gating-snap", "other-gating-snap"},
+		"services-snap": {"gating-snap"},
+	})
+
+	c.Assert(snapstate.ProceedWithRefresh(s.state, "gating-snap"), IsNil)
+	held, err = snapstate.HeldSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string][]string{
+		"some-snap": {"other-gating-snap"},
+	})
+}
+
+func (s *snapmgrTestSuite) TestHoldRefreshMaxPostponement(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	longAgo := time.Now().Add(-61 * 24 * time.Hour)
+	s.state.Set("snaps-hold", map[string]map[string]interface{}{
+		"some-snap": {"gating-snap": map[string]interface{}{"first-held": longAgo}},
+	})
+
+	err := snapstate.HoldRefresh(s.state, "gating-snap", "some-snap")
+	c.Check(err, ErrorMatches, `cannot hold refresh of "some-snap" any longer: it was held by "gating-snap" for more than 60 days`)
+
+	// expired holds do not count
+	held, err := snapstate.HeldSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(held, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshGated(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+
+	updated, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"services-snap", "some-snap"})
+	c.Assert(tss, HasLen, 1)
+
+	tasks := tss[0].Tasks()
+	c.Assert(tasks, HasLen, 2)
+	hook, conditional := tasks[0], tasks[1]
+
+	c.Check(hook.Kind(), Equals, "run-hook")
+	var hooksup hookstate.HookSetup
+	c.Assert(hook.Get("hook-setup", &hooksup), IsNil)
+	c.Check(hooksup, DeepEquals, hookstate.HookSetup{
+		Snap:        "gating-snap",
+		Hook:        "gate-auto-refresh",
+		Optional:    true,
+		IgnoreError: true,
+	})
+	var hookContext map[string]interface{}
+	c.Assert(hook.Get("hook-context", &hookContext), IsNil)
+	c.Check(hookContext, DeepEquals, map[string]interface{}{
+		"affecting-snaps": []interface{}{"some-snap"},
+	})
+
+	c.Check(conditional.Kind(), Equals, "conditional-auto-refresh")
+	c.Check(conditional.WaitTasks(), DeepEquals, []*state.Task{hook})
+	var names []string
+	c.Assert(conditional.Get("snap-names", &names), IsNil)
+	c.Check(names, DeepEquals, []string{"services-snap", "some-snap"})
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshGatedAsksAgainAboutHeld(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "some-snap"), IsNil)
+
+	// the held snap is still a candidate so that the hook of the gating
+	// snap runs again and can proceed with its refresh
+	updated, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"services-snap", "some-snap"})
+	c.Assert(tss, HasLen, 1)
+
+	tasks := tss[0].Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Kind(), Equals, "run-hook")
+	var hookContext map[string]interface{}
+	c.Assert(tasks[0].Get("hook-context", &hookContext), IsNil)
+	c.Check(hookContext, DeepEquals, map[string]interface{}{
+		"affecting-snaps": []interface{}{"some-snap"},
+	})
+	c.Check(tasks[1].Kind(), Equals, "conditional-auto-refresh")
+	var names []string
+	c.Assert(tasks[1].Get("snap-names", &names), IsNil)
+	c.Check(names, DeepEquals, []string{"services-snap", "some-snap"})
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshNotGatedSkipsHeld(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+	// the holding snap is no longer connected, there is no hook to ask
+	snapstate.ConnectedSnaps = nil
+
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "some-snap"), IsNil)
+
+	updated, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"services-snap"})
+	for _, ts := range tss {
+		for _, t := range ts.Tasks() {
+			c.Check(t.Kind(), Not(Equals), "conditional-auto-refresh")
+		}
+	}
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshGatedPrunesHolds(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+	snapstate.ConnectedSnaps = nil
+
+	// neither is installed with a gate-auto-refresh hook
+	c.Assert(snapstate.HoldRefresh(s.state, "not-installed-snap", "some-snap"), IsNil)
+	c.Assert(snapstate.HoldRefresh(s.state, "services-snap", "some-snap"), IsNil)
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "services-snap"), IsNil)
+
+	updated, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"some-snap"})
+
+	held, err := snapstate.HeldSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string][]string{
+		"services-snap": {"gating-snap"},
+	})
+}
+
+func (s *snapmgrTestSuite) TestRemoveClearsHolds(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "some-snap", "services-snap"), IsNil)
+	c.Assert(snapstate.HoldRefresh(s.state, "other-gating-snap", "gating-snap", "some-snap"), IsNil)
+
+	chg := s.state.NewChange("remove", "remove a snap")
+	ts, err := snapstate.Remove(s.state, "gating-snap", snap.R(0), nil)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	// the holds of and on the removed snap are gone
+	held, err := snapstate.HeldSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string][]string{
+		"some-snap": {"other-gating-snap"},
+	})
+}
+
+func (s *snapmgrTestSuite) TestGatingSnapsExcludesSystemSnaps(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+	// the gating snap is connected to every snap, e.g. via system slots
+	snapstate.ConnectedSnaps = func(st *state.State, instanceName string) ([]string, error) {
+		return []string{"gating-snap"}, nil
+	}
+
+	var updates []*snap.Info
+	for name, typ := range map[string]snap.Type{
+		"snapd":     snap.TypeSnapd,
+		"core":      snap.TypeOS,
+		"pc-kernel": snap.TypeKernel,
+		"pc":        snap.TypeGadget,
+		"core18":    snap.TypeBase,
+		"some-snap": snap.TypeApp,
+	} {
+		updates = append(updates, &snap.Info{SideInfo: snap.SideInfo{RealName: name}, SnapType: typ})
+	}
+
+	gating, err := snapstate.GatingSnaps(s.state, updates)
+	c.Assert(err, IsNil)
+	c.Check(gating, DeepEquals, map[string][]string{
+		"gating-snap": {"core18", "some-snap"},
+	})
+}
+
+func (s *snapmgrTestSuite) TestConditionalAutoRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGating(c)
+
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "some-snap"), IsNil)
+	// a leftover expired hold of a snap that gets refreshed
+	c.Assert(snapstate.HoldRefresh(s.state, "gating-snap", "services-snap"), IsNil)
+	var holds map[string]map[string]map[string]interface{}
+	c.Assert(s.state.Get("snaps-hold", &holds), IsNil)
+	holds["services-snap"]["gating-snap"]["first-held"] = time.Now().Add(-61 * 24 * time.Hour)
+	s.state.Set("snaps-hold", holds)
+
+	chg := s.state.NewChange("auto-refresh", "...")
+	t := s.state.NewTask("conditional-auto-refresh", "...")
+	t.Set("snap-names", []string{"services-snap", "some-snap"})
+	chg.AddTask(t)
+
+	var filtered []string
+	restore := snapstate.MockAutoRefreshUpdateMany(func(ctx context.Context, st *state.State, names []string, userID int, filter snapstate.UpdateFilter, flags *snapstate.Flags, fromChange string) ([]string, []*state.TaskSet, error) {
+		c.Check(names, HasLen, 0)
+		c.Check(flags, DeepEquals, &snapstate.Flags{IsAutoRefresh: true})
+		c.Check(fromChange, Equals, chg.ID())
+		for _, name := range []string{"some-snap", "services-snap", "other-snap"} {
+			if filter(&snap.Info{SideInfo: snap.SideInfo{RealName: name}}, nil) {
+				filtered = append(filtered, name)
+			}
+		}
+		return filtered, nil, nil
+	})
+	defer restore()
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(filtered, DeepEquals, []string{"services-snap"})
+	var names []string
+	c.Assert(chg.Get("snap-names", &names), IsNil)
+	c.Check(names, DeepEquals, []string{"services-snap"})
+	c.Check(t.Log(), HasLen, 1)
+	c.Check(t.Log()[0], Matches, `.* Auto-refresh of "some-snap" held by "gating-snap".`)
+
+	// the refreshed snap is not held anymore
+	holds = nil
+	c.Assert(s.state.Get("snaps-hold", &holds), IsNil)
+	c.Check(holds["services-snap"], IsNil)
+	c.Check(holds["some-snap"], NotNil)
+}
@@ -56,6 +56,7 @@ type StoreService interface {
 	Buy(options *client.BuyOptions, user *auth.UserState) (*client.BuyResult, error)
 	ReadyToBuy(*auth.UserState) error
 	ConnectivityCheck() (map[string]bool, error)
+	EndpointsStatus() []store.EndpointStatus
 	CreateCohorts(context.Context, []string) (map[string]string, error)
 
 	LoginUser(username, password, otp string) (string, string, error)
@@ -69,8 +70,11 @@ type managerBackend interface {
 	LinkSnap(info *snap.Info, dev boot.Device, linkCtx backend.LinkContext, tm timings.Measurer) (rebootRequired bool, err error)
 	StartServices(svcs []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error
 	StopServices(svcs []*snap.AppInfo, reason snap.ServiceStopReason, meter progress.Meter, tm timings.Measurer) error
+	RestartServices(svcs []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error
 	ServicesEnableState(info *snap.Info, meter progress.Meter) (map[string]bool, error)
 	QueryDisabledServices(info *snap.Info, pb progress.Meter) ([]string, error)
+	RegenerateServices(info *snap.Info, linkCtx backend.LinkContext, meter progress.Meter) error
+	ApplyJournalSettings(info *snap.Info, apps []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error
 
 	// the undoers for install
 	UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, installRecord *backend.InstallRecord, dev boot.Device, meter progress.Meter) error
@@ -45,6 +45,21 @@ type LinkContext struct {
 	// VitalityRank is used to hint how much the services should be
 	// protected from the OOM killer
 	VitalityRank int
+
+	// ServiceProviders maps the services of the snap to the units of
+	// the services of other snaps they want and start after
+	ServiceProviders map[string][]string
+
+	// JournalSettings carries the journald settings of the services
+	// of the snap
+	JournalSettings *wrappers.JournalSettings
+
+	// ServiceOverrides maps the services of the snap to the
+	// environment and arguments they are configured to run with
+	ServiceOverrides map[string]*wrappers.ServiceOverrides
+
+	// ScheduledTasks are the tasks the snap scheduled to run its apps
+	ScheduledTasks []*wrappers.ScheduledTask
 }
 
 func updateCurrentSymlinks(info *snap.Info) (e error) {
@@ -154,10 +169,39 @@ func (b Backend) StartServices(apps []*snap.AppInfo, meter progress.Meter, tm ti
 	return wrappers.StartServices(apps, nil, nil, meter, tm)
 }
 
+// RestartServices restarts the given services of an already linked snap.
+func (b Backend) RestartServices(apps []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error {
+	return wrappers.RestartServices(apps, nil, meter, tm)
+}
+
 func (b Backend) StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, meter progress.Meter, tm timings.Measurer) error {
 	return wrappers.StopServices(apps, nil, reason, meter, tm)
 }
 
+// RegenerateServices rewrites the service units of an already linked snap,
+// for example after the services of other snaps it depends on changed.
+func (b Backend) RegenerateServices(info *snap.Info, linkCtx LinkContext, meter progress.Meter) error {
+	opts := &wrappers.AddSnapServicesOptions{
+		Preseeding:       b.preseed,
+		VitalityRank:     linkCtx.VitalityRank,
+		ServiceProviders: linkCtx.ServiceProviders,
+		Journal:          linkCtx.JournalSettings,
+		Overrides:        linkCtx.ServiceOverrides,
+		ScheduledTasks:   linkCtx.ScheduledTasks,
+	}
+	return wrappers.AddSnapServices(info, linkCtx.PrevDisabledServices, opts, meter)
+}
+
+// ApplyJournalSettings restarts the journal namespace of an already
+// linked snap, if it has one, and then the given services of the snap so
+// that the journal settings they were regenerated with take effect.
+func (b Backend) ApplyJournalSettings(info *snap.Info, apps []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error {
+	if err := wrappers.RestartJournalNamespace(info, meter); err != nil {
+		return err
+	}
+	return wrappers.RestartServices(apps, nil, meter, tm)
+}
+
 func (b Backend) generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 	var err error
 	var cleanupFuncs []func(*snap.Info) error
@@ -183,8 +227,12 @@ func (b Backend) generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 
 	// add the daemons from the snap.yaml
 	opts := &wrappers.AddSnapServicesOptions{
-		Preseeding:   b.preseed,
-		VitalityRank: linkCtx.VitalityRank,
+		Preseeding:       b.preseed,
+		VitalityRank:     linkCtx.VitalityRank,
+		ServiceProviders: linkCtx.ServiceProviders,
+		Journal:          linkCtx.JournalSettings,
+		Overrides:        linkCtx.ServiceOverrides,
+		ScheduledTasks:   linkCtx.ScheduledTasks,
 	}
 	if err = wrappers.AddSnapServices(s, disabledSvcs, opts, progress.Null); err != nil {
 		return err
@@ -205,6 +253,12 @@ func (b Backend) generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 	}
 	cleanupFuncs = append(cleanupFuncs, wrappers.RemoveSnapIcons)
 
+	// add the D-Bus activation files
+	if err = wrappers.AddSnapDBusActivationFiles(s); err != nil {
+		return err
+	}
+	cleanupFuncs = append(cleanupFuncs, wrappers.RemoveSnapDBusActivationFiles)
+
 	return nil
 }
 
@@ -233,7 +287,12 @@ func removeGeneratedWrappers(s *snap.Info, firstInstallUndo bool, meter progress
 		logger.Noticef("Cannot remove desktop icons for %q: %v", s.InstanceName(), err4)
 	}
 
-	return firstErr(err1, err2, err3, err4)
+	err5 := wrappers.RemoveSnapDBusActivationFiles(s)
+	if err5 != nil {
+		logger.Noticef("Cannot remove D-Bus activation files for %q: %v", s.InstanceName(), err5)
+	}
+
+	return firstErr(err1, err2, err3, err4, err5)
 }
 
 func generateSnapdWrappers(s *snap.Info) error {
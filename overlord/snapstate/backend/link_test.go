@@ -112,6 +112,52 @@ apps:
 	c.Assert(svcsDisabled, DeepEquals, []string{"snap.hello.bin.service"})
 }
 
+func (s *linkSuite) TestRegenerateServices(c *C) {
+	const yaml = `name: hello
+version: 1.0
+plugs:
+ database:
+  interface: content
+apps:
+ svc:
+   command: svc
+   daemon: simple
+   after-plugs: [database]
+ other:
+   command: other
+   daemon: simple
+`
+	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
+
+	var enabled []string
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		if len(cmd) >= 3 && cmd[0] == "--root" {
+			cmd = cmd[2:]
+		}
+		if len(cmd) >= 2 && cmd[0] == "enable" {
+			enabled = append(enabled, cmd[1])
+		}
+		return nil, nil
+	})
+	defer r()
+
+	linkCtx := backend.LinkContext{
+		PrevDisabledServices: []string{"other"},
+		ServiceProviders: map[string][]string{
+			"svc": {"snap.db.server.service"},
+		},
+	}
+	err := s.be.RegenerateServices(info, linkCtx, progress.Null)
+	c.Assert(err, IsNil)
+
+	c.Check(enabled, DeepEquals, []string{"snap.hello.svc.service"})
+	svcFile := filepath.Join(dirs.SnapServicesDir, "snap.hello.svc.service")
+	c.Check(svcFile, testutil.FileContains, "\nWants=snap.db.server.service\n")
+	c.Check(svcFile, testutil.FileContains, " snap.db.server.service\nX-Snappy=yes\n")
+	otherFile := filepath.Join(dirs.SnapServicesDir, "snap.hello.other.service")
+	c.Check(otherFile, Not(testutil.FileContains), "snap.db.server.service")
+}
+
 func (s *linkSuite) TestLinkDoUndoGenerateWrappers(c *C) {
 	const yaml = `name: hello
 version: 1.0
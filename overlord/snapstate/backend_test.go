@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -42,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/store/storetest"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timings"
+	"github.com/snapcore/snapd/wrappers"
 )
 
 type fakeOp struct {
@@ -70,7 +72,11 @@ type fakeOp struct {
 	services         []string
 	disabledServices []string
 
-	vitalityRank int
+	vitalityRank     int
+	serviceProviders map[string][]string
+	journalSettings  *wrappers.JournalSettings
+	serviceOverrides map[string]*wrappers.ServiceOverrides
+	scheduledTasks   []string
 }
 
 type fakeOps []fakeOp
@@ -144,6 +150,9 @@ type fakeStore struct {
 	fakeTotalProgress   int
 	state               *state.State
 	seenPrivacyKeys     map[string]bool
+	// downloadCheckpoint, if set, is called with the checkpoint
+	// function of downloads, its error is returned by them
+	downloadCheckpoint func(checkpoint func(offset int64, partialSha3_384 string)) error
 }
 
 func (f *fakeStore) pokeStateLock() {
@@ -355,6 +364,12 @@ func (f *fakeStore) lookupRefresh(cand refreshCand) (*snap.Info, error) {
 		typ = snap.TypeGadget
 	case "alias-snap-id":
 		name = "snap-id"
+	case "dependant-snap-id":
+		name = "dependant-snap"
+	case "other-dependant-snap-id":
+		name = "other-dependant-snap"
+	case "scheduling-snap-id":
+		name = "scheduling-snap"
 	default:
 		panic(fmt.Sprintf("refresh: unknown snap-id: %s", cand.snapID))
 	}
@@ -602,8 +617,13 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	if user != nil {
 		macaroon = user.StoreMacaroon
 	}
+	checkpoint := dlOpts.Checkpoint
+	// functions cannot be compared, tests use downloadCheckpoint
+	opts := *dlOpts
+	opts.Checkpoint = nil
+	dlOpts = &opts
 	// only add the options if they contain anything interesting
-	if *dlOpts == (store.DownloadOptions{}) {
+	if reflect.DeepEqual(*dlOpts, store.DownloadOptions{}) {
 		dlOpts = nil
 	}
 	f.downloads = append(f.downloads, fakeDownload{
@@ -617,9 +637,26 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	pb.SetTotal(float64(f.fakeTotalProgress))
 	pb.Set(float64(f.fakeCurrentProgress))
 
+	if f.downloadCheckpoint != nil && checkpoint != nil {
+		return f.downloadCheckpoint(checkpoint)
+	}
+
 	return nil
 }
 
+func (f *fakeStore) CreateCohorts(ctx context.Context, snaps []string) (map[string]string, error) {
+	f.pokeStateLock()
+
+	cohorts := make(map[string]string, len(snaps))
+	for _, name := range snaps {
+		if name == "fakestore-please-error-on-cohort" {
+			continue
+		}
+		cohorts[name] = name + "-cohort-key"
+	}
+	return cohorts, nil
+}
+
 func (f *fakeStore) WriteCatalogs(ctx context.Context, _ io.Writer, _ store.SnapAdder) error {
 	if ctx == nil {
 		panic("context required")
@@ -789,6 +826,59 @@ apps:
   svc3:
     daemon: simple
     before: [svc2]
+`))
+		if err != nil {
+			panic(err)
+		}
+		info.SideInfo = *si
+	case "dependant-snap", "other-dependant-snap":
+		var err error
+		info, err = snap.InfoFromSnapYaml([]byte(`name: dependant-snap
+plugs:
+  database:
+    interface: content
+apps:
+  svc:
+    daemon: simple
+    after-plugs: [database]
+`))
+		if err != nil {
+			panic(err)
+		}
+		info.SideInfo = *si
+	case "configurable-snap":
+		var err error
+		info, err = snap.InfoFromSnapYaml([]byte(`name: configurable-snap
+apps:
+  svc:
+    daemon: simple
+    configurable-env: [LOG_LEVEL]
+    configurable-args: [--verbose, --log-format=]
+  other-svc:
+    daemon: simple
+`))
+		if err != nil {
+			panic(err)
+		}
+		info.SideInfo = *si
+	case "scheduling-snap":
+		var err error
+		info, err = snap.InfoFromSnapYaml([]byte(`name: scheduling-snap
+apps:
+  backup:
+  report:
+  svc:
+    daemon: simple
+`))
+		if err != nil {
+			panic(err)
+		}
+		info.SideInfo = *si
+	case "gating-snap":
+		var err error
+		info, err = snap.InfoFromSnapYaml([]byte(`name: gating-snap
+hooks:
+  gate-auto-refresh:
 `))
 		if err != nil {
 			panic(err)
@@ -870,6 +960,10 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, dev boot.Device, linkCtx b
 		op.disabledServices = linkCtx.PrevDisabledServices
 	}
 	op.vitalityRank = linkCtx.VitalityRank
+	op.serviceProviders = linkCtx.ServiceProviders
+	op.journalSettings = linkCtx.JournalSettings
+	op.serviceOverrides = linkCtx.ServiceOverrides
+	op.scheduledTasks = scheduledTaskNames(linkCtx.ScheduledTasks)
 
 	if info.MountDir() == f.linkSnapFailTrigger {
 		op.op = "link-snap.failed"
@@ -887,6 +981,14 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, dev boot.Device, linkCtx b
 	return reboot, nil
 }
 
+func scheduledTaskNames(tasks []*wrappers.ScheduledTask) []string {
+	var names []string
+	for _, task := range tasks {
+		names = append(names, fmt.Sprintf("%s:%s:%s", task.Name, task.App.Name, task.Schedule))
+	}
+	return names
+}
+
 func svcSnapMountDir(svcs []*snap.AppInfo) string {
 	if len(svcs) == 0 {
 		return "<no services>"
@@ -910,6 +1012,19 @@ func (f *fakeSnappyBackend) StartServices(svcs []*snap.AppInfo, meter progress.M
 	return nil
 }
 
+func (f *fakeSnappyBackend) RestartServices(svcs []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error {
+	services := make([]string, 0, len(svcs))
+	for _, svc := range svcs {
+		services = append(services, svc.Name)
+	}
+	f.appendOp(&fakeOp{
+		op:       "restart-snap-services",
+		path:     svcSnapMountDir(svcs),
+		services: services,
+	})
+	return nil
+}
+
 func (f *fakeSnappyBackend) StopServices(svcs []*snap.AppInfo, reason snap.ServiceStopReason, meter progress.Meter, tm timings.Measurer) error {
 	f.appendOp(&fakeOp{
 		op:   fmt.Sprintf("stop-snap-services:%s", reason),
@@ -933,6 +1048,33 @@ func (f *fakeSnappyBackend) ServicesEnableState(info *snap.Info, meter progress.
 	return m, nil
 }
 
+func (f *fakeSnappyBackend) RegenerateServices(info *snap.Info, linkCtx backend.LinkContext, meter progress.Meter) error {
+	f.appendOp(&fakeOp{
+		op:               "regenerate-services",
+		path:             info.MountDir(),
+		disabledServices: linkCtx.PrevDisabledServices,
+		vitalityRank:     linkCtx.VitalityRank,
+		serviceProviders: linkCtx.ServiceProviders,
+		journalSettings:  linkCtx.JournalSettings,
+		serviceOverrides: linkCtx.ServiceOverrides,
+		scheduledTasks:   scheduledTaskNames(linkCtx.ScheduledTasks),
+	})
+	return nil
+}
+
+func (f *fakeSnappyBackend) ApplyJournalSettings(info *snap.Info, svcs []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error {
+	services := make([]string, 0, len(svcs))
+	for _, svc := range svcs {
+		services = append(services, svc.Name)
+	}
+	f.appendOp(&fakeOp{
+		op:       "apply-journal-settings",
+		path:     info.MountDir(),
+		services: services,
+	})
+	return nil
+}
+
 func (f *fakeSnappyBackend) QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error) {
 	var l []string
 
@@ -35,6 +35,7 @@ import (
 	seccomp_compiler "github.com/snapcore/snapd/sandbox/seccomp"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snapdtool"
+	"github.com/snapcore/snapd/systemd"
 )
 
 // featureSet contains the flag values that can be listed in assumes entries
@@ -653,10 +654,25 @@ func checkAndCreateSystemUsernames(si *snap.Info) error {
 	return nil
 }
 
+// checkRestartBackoff refuses services escalating the delay between their
+// restarts on systems whose systemd would silently ignore it.
+func checkRestartBackoff(_ *state.State, snapInfo, _ *snap.Info, _ snap.Container, _ Flags, _ DeviceContext) error {
+	for _, app := range snapInfo.Services() {
+		if app.RestartSteps == 0 && app.RestartMaxDelay == 0 {
+			continue
+		}
+		if err := systemd.EnsureAtLeast(systemd.RestartBackoffMinVersion); err != nil {
+			return fmt.Errorf("cannot install snap %q: service %q uses restart-steps or restart-max-delay: %v", snapInfo.InstanceName(), app.Name, err)
+		}
+	}
+	return nil
+}
+
 func init() {
 	AddCheckSnapCallback(checkCoreName)
 	AddCheckSnapCallback(checkSnapdName)
 	AddCheckSnapCallback(checkGadgetOrKernel)
 	AddCheckSnapCallback(checkBases)
 	AddCheckSnapCallback(checkEpochs)
+	AddCheckSnapCallback(checkRestartBackoff)
 }
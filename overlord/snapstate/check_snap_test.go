@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/snapdtool"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -969,6 +970,52 @@ func (s *checkSnapSuite) TestCheckSnapdHappy(c *C) {
 	}
 }
 
+func (s *checkSnapSuite) TestCheckSnapRestartBackoff(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	const yaml = `name: foo
+version: 1
+apps:
+  svc:
+    daemon: simple
+    restart-delay: 1s
+    restart-steps: 5
+    restart-max-delay: 1m
+  plain:
+    daemon: simple
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		return info, emptyContainer(c), nil
+	}
+	restore := snapstate.MockOpenSnapFile(openSnapFile)
+	defer restore()
+
+	for _, t := range []struct {
+		version int
+		errStr  string
+	}{
+		{254, ""},
+		{253, `cannot install snap "foo": service "svc" uses restart-steps or restart-max-delay: systemd version 253 is too old \(expected at least 254\)`},
+	} {
+		restore := systemd.MockSystemdVersion(t.version, nil)
+		defer restore()
+
+		st.Unlock()
+		err = snapstate.CheckSnap(st, "snap-path", "foo", nil, nil, snapstate.Flags{}, nil)
+		st.Lock()
+		if t.errStr == "" {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, ErrorMatches, t.errStr)
+		}
+	}
+}
+
 // Note, invalid usernames checked in snap/info_snap_yaml.go
 var systemUsernamesTests = []struct {
 	sysIDs      string
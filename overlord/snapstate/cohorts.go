@@ -0,0 +1,247 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+)
+
+// CohortRefreshPolicy controls how the snaps of a cohort are refreshed.
+type CohortRefreshPolicy string
+
+const (
+	// CohortRefreshAuto lets the snaps of the cohort auto-refresh as
+	// usual, within their cohort.
+	CohortRefreshAuto CohortRefreshPolicy = "auto"
+	// CohortRefreshPinned keeps the snaps of the cohort out of
+	// auto-refreshes, they are only refreshed with the whole cohort.
+	CohortRefreshPinned CohortRefreshPolicy = "pinned"
+)
+
+// Cohort is a named set of snaps, each with its cohort key, that are
+// refreshed together to coordinate their revisions across devices.
+type Cohort struct {
+	Name string `json:"name"`
+	// Keys maps the snaps of the cohort to their cohort key.
+	Keys          map[string]string   `json:"keys"`
+	Created       time.Time           `json:"created"`
+	RefreshPolicy CohortRefreshPolicy `json:"refresh-policy"`
+}
+
+// Snaps returns the sorted snaps of the cohort.
+func (c *Cohort) Snaps() []string {
+	snaps := make([]string, 0, len(c.Keys))
+	for name := range c.Keys {
+		snaps = append(snaps, name)
+	}
+	sort.Strings(snaps)
+	return snaps
+}
+
+// CohortNotFoundError is returned when the named cohort is not known.
+type CohortNotFoundError struct {
+	Name string
+}
+
+func (e *CohortNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find cohort %q", e.Name)
+}
+
+var validCohortName = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
+
+func cohorts(st *state.State) (map[string]*Cohort, error) {
+	var cohorts map[string]*Cohort
+	if err := st.Get("cohorts", &cohorts); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if cohorts == nil {
+		cohorts = make(map[string]*Cohort)
+	}
+	return cohorts, nil
+}
+
+// Cohorts returns the named cohorts, sorted by name.
+func Cohorts(st *state.State) ([]*Cohort, error) {
+	all, err := cohorts(st)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Cohort, 0, len(all))
+	for _, c := range all {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// CohortByName returns the named cohort.
+func CohortByName(st *state.State, name string) (*Cohort, error) {
+	all, err := cohorts(st)
+	if err != nil {
+		return nil, err
+	}
+	c := all[name]
+	if c == nil {
+		return nil, &CohortNotFoundError{Name: name}
+	}
+	return c, nil
+}
+
+// CreateCohort creates cohort keys for the given snaps with the store
+// and records them as the named cohort.
+// Note that the state must be locked by the caller, it is unlocked
+// while talking to the store.
+func CreateCohort(ctx context.Context, st *state.State, name string, snaps []string, policy CohortRefreshPolicy) (*Cohort, error) {
+	if !validCohortName.MatchString(name) {
+		return nil, fmt.Errorf("invalid cohort name %q", name)
+	}
+	switch policy {
+	case "":
+		policy = CohortRefreshAuto
+	case CohortRefreshAuto, CohortRefreshPinned:
+	default:
+		return nil, fmt.Errorf("invalid cohort refresh policy %q", policy)
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("cannot create cohort %q without snaps", name)
+	}
+	if _, err := CohortByName(st, name); err == nil {
+		return nil, fmt.Errorf("cohort %q already exists", name)
+	}
+
+	theStore := Store(st, nil)
+	st.Unlock() // calls to the store should be done without holding the state lock
+	keys, err := theStore.CreateCohorts(ctx, snaps)
+	st.Lock()
+	if err != nil {
+		return nil, err
+	}
+	for _, snapName := range snaps {
+		if keys[snapName] == "" {
+			return nil, fmt.Errorf("store did not return a cohort key for snap %q", snapName)
+		}
+	}
+
+	// re-read as the state was unlocked
+	all, err := cohorts(st)
+	if err != nil {
+		return nil, err
+	}
+	if all[name] != nil {
+		return nil, fmt.Errorf("cohort %q already exists", name)
+	}
+	c := &Cohort{
+		Name:          name,
+		Keys:          make(map[string]string, len(snaps)),
+		Created:       time.Now(),
+		RefreshPolicy: policy,
+	}
+	for _, snapName := range snaps {
+		c.Keys[snapName] = keys[snapName]
+	}
+	all[name] = c
+	st.Set("cohorts", all)
+	return c, nil
+}
+
+// RemoveCohort forgets about the named cohort. The snaps of the cohort
+// stay in their cohort until they are refreshed out of it.
+func RemoveCohort(st *state.State, name string) error {
+	all, err := cohorts(st)
+	if err != nil {
+		return err
+	}
+	if all[name] == nil {
+		return &CohortNotFoundError{Name: name}
+	}
+	delete(all, name)
+	st.Set("cohorts", all)
+	return nil
+}
+
+// RefreshCohort refreshes all the installed snaps of the named cohort
+// into their cohort key. The returned task sets share a lane so that
+// if refreshing one of the snaps fails, all of them are undone.
+// Note that the state must be locked by the caller.
+func RefreshCohort(st *state.State, name string, userID int, flags *Flags) ([]string, []*state.TaskSet, error) {
+	if flags == nil {
+		flags = &Flags{}
+	}
+	c, err := CohortByName(st, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var updated []string
+	var tasksets []*state.TaskSet
+	for _, snapName := range c.Snaps() {
+		var snapst SnapState
+		if err := Get(st, snapName, &snapst); err != nil && err != state.ErrNoState {
+			return nil, nil, err
+		}
+		if !snapst.IsInstalled() {
+			// the cohort can cover snaps that are not on every device
+			continue
+		}
+		ts, err := Update(st, snapName, &RevisionOptions{CohortKey: c.Keys[snapName]}, userID, *flags)
+		if err == store.ErrNoUpdateAvailable {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot refresh snap %q of cohort %q: %v", snapName, name, err)
+		}
+		updated = append(updated, snapName)
+		tasksets = append(tasksets, ts)
+	}
+
+	if len(tasksets) > 0 {
+		lane := st.NewLane()
+		for _, ts := range tasksets {
+			ts.JoinLane(lane)
+		}
+	}
+	return updated, tasksets, nil
+}
+
+// pinnedCohortSnaps returns the snaps held back from auto-refreshes by
+// the refresh policy of their cohort.
+func pinnedCohortSnaps(st *state.State) (map[string]bool, error) {
+	all, err := cohorts(st)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[string]bool)
+	for _, c := range all {
+		if c.RefreshPolicy != CohortRefreshPinned {
+			continue
+		}
+		for snapName := range c.Keys {
+			pinned[snapName] = true
+		}
+	}
+	return pinned, nil
+}
@@ -0,0 +1,165 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *snapmgrTestSuite) TestCreateCohort(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	cohort, err := snapstate.CreateCohort(context.Background(), s.state, "fleet-a", []string{"some-snap", "services-snap"}, snapstate.CohortRefreshPinned)
+	c.Assert(err, IsNil)
+	c.Check(cohort.Name, Equals, "fleet-a")
+	c.Check(cohort.Keys, DeepEquals, map[string]string{
+		"some-snap":     "some-snap-cohort-key",
+		"services-snap": "services-snap-cohort-key",
+	})
+	c.Check(cohort.Snaps(), DeepEquals, []string{"services-snap", "some-snap"})
+	c.Check(cohort.RefreshPolicy, Equals, snapstate.CohortRefreshPinned)
+	c.Check(cohort.Created.IsZero(), Equals, false)
+
+	_, err = snapstate.CreateCohort(context.Background(), s.state, "fleet-b", []string{"some-snap"}, "")
+	c.Assert(err, IsNil)
+
+	cohorts, err := snapstate.Cohorts(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(cohorts, HasLen, 2)
+	c.Check(cohorts[0].Name, Equals, "fleet-a")
+	c.Check(cohorts[1].Name, Equals, "fleet-b")
+	c.Check(cohorts[1].RefreshPolicy, Equals, snapstate.CohortRefreshAuto)
+
+	found, err := snapstate.CohortByName(s.state, "fleet-a")
+	c.Assert(err, IsNil)
+	c.Check(found, DeepEquals, cohorts[0])
+}
+
+func (s *snapmgrTestSuite) TestCreateCohortErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.CreateCohort(context.Background(), s.state, "fleet-a", []string{"some-snap"}, "")
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		name   string
+		snaps  []string
+		policy snapstate.CohortRefreshPolicy
+		err    string
+	}{
+		{"Fleet", []string{"some-snap"}, "", `invalid cohort name "Fleet"`},
+		{"fleet--b", []string{"some-snap"}, "", `invalid cohort name "fleet--b"`},
+		{"fleet-b", []string{"some-snap"}, "sometimes", `invalid cohort refresh policy "sometimes"`},
+		{"fleet-b", nil, "", `cannot create cohort "fleet-b" without snaps`},
+		{"fleet-a", []string{"some-snap"}, "", `cohort "fleet-a" already exists`},
+		{"fleet-b", []string{"some-snap", "fakestore-please-error-on-cohort"}, "", `store did not return a cohort key for snap "fakestore-please-error-on-cohort"`},
+	} {
+		_, err := snapstate.CreateCohort(context.Background(), s.state, t.name, t.snaps, t.policy)
+		c.Check(err, ErrorMatches, t.err)
+	}
+
+	cohorts, err := snapstate.Cohorts(s.state)
+	c.Assert(err, IsNil)
+	c.Check(cohorts, HasLen, 1)
+}
+
+func (s *snapmgrTestSuite) TestRemoveCohort(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.CreateCohort(context.Background(), s.state, "fleet-a", []string{"some-snap"}, "")
+	c.Assert(err, IsNil)
+
+	c.Assert(snapstate.RemoveCohort(s.state, "fleet-a"), IsNil)
+	_, err = snapstate.CohortByName(s.state, "fleet-a")
+	c.Check(err, FitsTypeOf, &snapstate.CohortNotFoundError{})
+	c.Check(err, ErrorMatches, `cannot find cohort "fleet-a"`)
+
+	err = snapstate.RemoveCohort(s.state, "fleet-a")
+	c.Check(err, ErrorMatches, `cannot find cohort "fleet-a"`)
+}
+
+func (s *snapmgrTestSuite) TestRefreshCohort(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"some-snap", "services-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{{RealName: name, SnapID: name + "-id", Revision: snap.R(7)}},
+			Current:  snap.R(7),
+			SnapType: "app",
+		})
+	}
+
+	// the cohort also covers snaps not installed here
+	_, err := snapstate.CreateCohort(context.Background(), s.state, "fleet-a", []string{"some-snap", "services-snap", "not-installed"}, "")
+	c.Assert(err, IsNil)
+
+	updated, tss, err := snapstate.RefreshCohort(s.state, "fleet-a", 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"services-snap", "some-snap"})
+	c.Assert(tss, HasLen, 2)
+
+	// the refreshes share a lane so they succeed or fail together
+	lanes := tss[0].Tasks()[0].Lanes()
+	shared := lanes[len(lanes)-1]
+	for i, ts := range tss {
+		for _, t := range ts.Tasks() {
+			c.Check(t.Lanes(), testutil.DeepContains, shared)
+		}
+		var snapsup snapstate.SnapSetup
+		c.Assert(ts.Tasks()[0].Get("snap-setup", &snapsup), IsNil)
+		c.Check(snapsup.CohortKey, Equals, updated[i]+"-cohort-key")
+	}
+	c.Check(tss[1].Tasks()[0].Lanes(), Not(DeepEquals), lanes)
+
+	_, _, err = snapstate.RefreshCohort(s.state, "unknown", 0, nil)
+	c.Check(err, ErrorMatches, `cannot find cohort "unknown"`)
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshSkipsPinnedCohorts(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"some-snap", "services-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{{RealName: name, SnapID: name + "-id", Revision: snap.R(7)}},
+			Current:  snap.R(7),
+			SnapType: "app",
+		})
+	}
+
+	_, err := snapstate.CreateCohort(context.Background(), s.state, "fleet-a", []string{"some-snap"}, snapstate.CohortRefreshPinned)
+	c.Assert(err, IsNil)
+
+	updated, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"services-snap"})
+}
@@ -195,6 +195,17 @@ func MockReRefreshUpdateMany(f func(context.Context, *state.State, []string, int
 	}
 }
 
+func MockAutoRefreshUpdateMany(f func(context.Context, *state.State, []string, int, UpdateFilter, *Flags, string) ([]string, []*state.TaskSet, error)) (restore func()) {
+	old := autoRefreshUpdateMany
+	autoRefreshUpdateMany = f
+	return func() {
+		autoRefreshUpdateMany = old
+	}
+}
+
+var HeldSnaps = heldSnaps
+var GatingSnaps = gatingSnaps
+
 func MockReRefreshRetryTimeout(d time.Duration) (restore func()) {
 	old := reRefreshRetryTimeout
 	reRefreshRetryTimeout = d
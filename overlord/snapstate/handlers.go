@@ -526,6 +526,43 @@ func autoRefreshRateLimited(st *state.State) (rate int64) {
 	return val
 }
 
+// downloadConcurrency returns the maximum number of concurrent snap
+// downloads or 0 if there is no bound.
+func downloadConcurrency(st *state.State) int {
+	var concurrency int
+	if err := config.NewTransaction(st).Get("core", "refresh.download-concurrency", &concurrency); err != nil {
+		return 0
+	}
+	return concurrency
+}
+
+// SetDownloadPriority sets the priority of the snap downloads of the
+// change, one of the store.DownloadPriority* values.
+func SetDownloadPriority(chg *state.Change, priority int) {
+	chg.Set("download-priority", priority)
+}
+
+// downloadPriority returns the priority of the download done by the
+// task, as set on its change. Changes without one get the background
+// priority for auto-refreshes and the default one otherwise.
+func downloadPriority(t *state.Task, snapsup *SnapSetup) int {
+	if chg := t.Change(); chg != nil {
+		var priority int
+		err := chg.Get("download-priority", &priority)
+		if err == nil {
+			return priority
+		}
+		if err != state.ErrNoState {
+			logger.Noticef("cannot get download priority of change %s: %v", chg.ID(), err)
+		}
+	}
+	if snapsup.IsAutoRefresh {
+		// let interactive downloads go first
+		return store.DownloadPriorityBackground
+	}
+	return store.DownloadPriorityDefault
+}
+
 func downloadSnapParams(st *state.State, t *state.Task) (*SnapSetup, StoreService, *auth.UserState, error) {
 	snapsup, err := TaskSnapSetup(t)
 	if err != nil {
@@ -550,13 +587,28 @@ func downloadSnapParams(st *state.State, t *state.Task) (*SnapSetup, StoreServic
 func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	st := t.State()
 	var rate int64
+	var concurrency int
+	var priority int
 
 	st.Lock()
 	perfTimings := state.TimingsForTask(t)
 	snapsup, theStore, user, err := downloadSnapParams(st, t)
-	if snapsup != nil && snapsup.IsAutoRefresh {
-		// NOTE rate is never negative
-		rate = autoRefreshRateLimited(st)
+	if snapsup != nil {
+		if snapsup.IsAutoRefresh {
+			// NOTE rate is never negative
+			rate = autoRefreshRateLimited(st)
+		}
+		priority = downloadPriority(t, snapsup)
+	}
+	concurrency = downloadConcurrency(st)
+	// left behind by a previous run, e.g. before snapd restarted
+	var resume *store.DownloadProgress
+	if t.Has("download-progress") {
+		resume = &store.DownloadProgress{}
+		if err := t.Get("download-progress", resume); err != nil {
+			st.Unlock()
+			return err
+		}
 	}
 	st.Unlock()
 	if err != nil {
@@ -569,25 +621,40 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	dlOpts := &store.DownloadOptions{
 		IsAutoRefresh: snapsup.IsAutoRefresh,
 		RateLimit:     rate,
+		Concurrency:   concurrency,
+		Priority:      priority,
+		Resume:        resume,
 	}
-	if snapsup.DownloadInfo == nil {
-		var storeInfo store.SnapActionResult
+	downloadInfo := snapsup.DownloadInfo
+	var sideInfo *snap.SideInfo
+	if downloadInfo == nil {
 		// COMPATIBILITY - this task was created from an older version
 		// of snapd that did not store the DownloadInfo in the state
 		// yet. Therefore do not worry about DeviceContext.
-		storeInfo, err = installInfoUnlocked(st, snapsup, nil)
+		storeInfo, err := installInfoUnlocked(st, snapsup, nil)
 		if err != nil {
 			return err
 		}
-		timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
-			err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, &storeInfo.DownloadInfo, meter, user, dlOpts)
-		})
-		snapsup.SideInfo = &storeInfo.SideInfo
-	} else {
-		timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
-			err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, snapsup.DownloadInfo, meter, user, dlOpts)
+		downloadInfo = &storeInfo.DownloadInfo
+		sideInfo = &storeInfo.SideInfo
+	}
+	// recorded so that the download can be resumed across restarts
+	dlOpts.Checkpoint = func(offset int64, partialSha3_384 string) {
+		st.Lock()
+		defer st.Unlock()
+		t.Set("download-progress", &store.DownloadProgress{
+			Sha3_384:        downloadInfo.Sha3_384,
+			Size:            downloadInfo.Size,
+			Offset:          offset,
+			PartialSha3_384: partialSha3_384,
 		})
 	}
+	timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
+		err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, downloadInfo, meter, user, dlOpts)
+	})
+	if sideInfo != nil {
+		snapsup.SideInfo = sideInfo
+	}
 	if err != nil {
 		return err
 	}
@@ -597,6 +664,7 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	// update the snap setup for the follow up tasks
 	st.Lock()
 	t.Set("snap-setup", snapsup)
+	t.Clear("download-progress")
 	perfTimings.Save(st)
 	st.Unlock()
 
@@ -899,10 +967,18 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	serviceProviders, err := ServiceProviders(st, oldInfo)
+	if err != nil {
+		return err
+	}
 	linkCtx := backend.LinkContext{
 		PrevDisabledServices: svcsToDisable,
 		FirstInstall:         false,
 		VitalityRank:         vitalityRank,
+		ServiceProviders:     serviceProviders,
+		JournalSettings:      JournalSettings(st, snapsup.InstanceName()),
+		ServiceOverrides:     ServiceOverrides(st, oldInfo),
+		ScheduledTasks:       scheduledTasks(snapst, oldInfo),
 	}
 	reboot, err := m.backend.LinkSnap(oldInfo, deviceCtx, linkCtx, perfTimings)
 	if err != nil {
@@ -1215,10 +1291,18 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	if err != nil {
 		return err
 	}
+	serviceProviders, err := ServiceProviders(st, newInfo)
+	if err != nil {
+		return err
+	}
 	linkCtx := backend.LinkContext{
 		FirstInstall:         oldCurrent.Unset(),
 		PrevDisabledServices: svcsToDisable,
 		VitalityRank:         vitalityRank,
+		ServiceProviders:     serviceProviders,
+		JournalSettings:      JournalSettings(st, snapsup.InstanceName()),
+		ServiceOverrides:     ServiceOverrides(st, newInfo),
+		ScheduledTasks:       scheduledTasks(snapst, newInfo),
 	}
 	reboot, err := m.backend.LinkSnap(newInfo, deviceCtx, linkCtx, perfTimings)
 	// defer a cleanup helper which will unlink the snap if anything fails after
@@ -1978,6 +2062,10 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		if err := m.removeSnapCookie(st, snapsup.InstanceName()); err != nil {
 			return fmt.Errorf("cannot remove snap cookie: %v", err)
 		}
+		// the removed snap cannot hold refreshes anymore
+		if err := removeHolds(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
 
 		otherInstances, err := hasOtherInstances(st, snapsup.InstanceName())
 		if err != nil {
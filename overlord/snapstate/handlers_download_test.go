@@ -20,6 +20,7 @@
 package snapstate_test
 
 import (
+	"errors"
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
@@ -211,6 +212,129 @@ func (s *downloadSnapSuite) TestDoDownloadSnapWithDeviceContext(c *C) {
 	})
 }
 
+func (s *downloadSnapSuite) TestDoDownloadRecordsProgress(c *C) {
+	s.fakeStore.downloadCheckpoint = func(checkpoint func(offset int64, partialSha3_384 string)) error {
+		checkpoint(1024, "partial-sha3")
+		checkpoint(2048, "more-partial-sha3")
+		// like snapd going away mid-download
+		return errors.New("download cancelled")
+	}
+
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+			Sha3_384:    "sha3",
+			Size:        4096,
+		},
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	var progress store.DownloadProgress
+	c.Assert(t.Get("download-progress", &progress), IsNil)
+	c.Check(progress, DeepEquals, store.DownloadProgress{
+		Sha3_384:        "sha3",
+		Size:            4096,
+		Offset:          2048,
+		PartialSha3_384: "more-partial-sha3",
+	})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadRecordsProgressCompatibility(c *C) {
+	s.fakeStore.downloadCheckpoint = func(checkpoint func(offset int64, partialSha3_384 string)) error {
+		checkpoint(1024, "partial-sha3")
+		return errors.New("download cancelled")
+	}
+
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+		},
+		Channel: "some-channel",
+		// the store is queried in the task
+		DownloadInfo: nil,
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	var progress store.DownloadProgress
+	c.Assert(t.Get("download-progress", &progress), IsNil)
+	c.Check(progress, DeepEquals, store.DownloadProgress{
+		Offset:          1024,
+		PartialSha3_384: "partial-sha3",
+	})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadResumesProgress(c *C) {
+	progress := &store.DownloadProgress{
+		Sha3_384:        "sha3",
+		Size:            4096,
+		Offset:          2048,
+		PartialSha3_384: "partial-sha3",
+	}
+
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+			Sha3_384:    "sha3",
+			Size:        4096,
+		},
+	})
+	// left by the task before snapd restarted
+	t.Set("download-progress", progress)
+	s.state.NewChange("dummy", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Assert(s.fakeStore.downloads, DeepEquals, []fakeDownload{
+		{
+			name:   "foo",
+			target: filepath.Join(dirs.SnapBlobDir, "foo_11.snap"),
+			opts: &store.DownloadOptions{
+				Resume: progress,
+			},
+		},
+	})
+	// done with it
+	c.Check(t.Has("download-progress"), Equals, false)
+}
+
 func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 	s.state.Lock()
 	si := &snap.SideInfo{
@@ -290,8 +414,91 @@ func (s *downloadSnapSuite) TestDoDownloadRateLimitedIntegration(c *C) {
 			opts: &store.DownloadOptions{
 				RateLimit:     1234,
 				IsAutoRefresh: true,
+				Priority:      store.DownloadPriorityBackground,
+			},
+		},
+	})
+
+}
+
+func (s *downloadSnapSuite) TestDoDownloadConcurrencyIntegration(c *C) {
+	s.state.Lock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.download-concurrency", 2)
+	tr.Commit()
+
+	si := &snap.SideInfo{
+		RealName: "foo",
+		SnapID:   "foo-id",
+		Revision: snap.R(11),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	// the bound is passed on, the download is not a background one
+	c.Assert(s.fakeStore.downloads, DeepEquals, []fakeDownload{
+		{
+			name:   "foo",
+			target: filepath.Join(dirs.SnapBlobDir, "foo_11.snap"),
+			opts: &store.DownloadOptions{
+				Concurrency: 2,
+				Priority:    store.DownloadPriorityDefault,
 			},
 		},
 	})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadPriorityFromChange(c *C) {
+	s.state.Lock()
+
+	// two changes downloading at the same time, each with its own
+	// priority
+	for i, name := range []string{"foo", "bar"} {
+		t := s.state.NewTask("download-snap", "test")
+		t.Set("snap-setup", &snapstate.SnapSetup{
+			SideInfo: &snap.SideInfo{
+				RealName: name,
+				SnapID:   name + "-id",
+				Revision: snap.R(11),
+			},
+			DownloadInfo: &snap.DownloadInfo{
+				DownloadURL: "http://some-url.com/" + name,
+			},
+			// the priority of the change wins
+			IsAutoRefresh: name == "bar",
+		})
+		chg := s.state.NewChange("dummy", "...")
+		chg.AddTask(t)
+		if i == 0 {
+			snapstate.SetDownloadPriority(chg, store.DownloadPriorityBackground)
+		} else {
+			snapstate.SetDownloadPriority(chg, 10)
+		}
+	}
+
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
 
+	priorities := make(map[string]int)
+	for _, dl := range s.fakeStore.downloads {
+		priorities[dl.name] = dl.opts.Priority
+	}
+	c.Check(priorities, DeepEquals, map[string]int{
+		"foo": store.DownloadPriorityBackground,
+		"bar": 10,
+	})
 }
@@ -0,0 +1,181 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timings"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+const (
+	journalMaxSizeOpt   = "logs.max-size"
+	journalRateLimitOpt = "logs.rate-limit"
+
+	minJournalMaxSize = 1000 * 1000
+)
+
+// IsJournalOption returns whether the given option of a snap configures
+// the journal of its services, such options are handled by snapd itself.
+func IsJournalOption(key string) bool {
+	return key == "logs" || strings.HasPrefix(key, "logs.")
+}
+
+// ParseJournalSettings parses the values of the logs.max-size and
+// logs.rate-limit options of a snap, empty values mean unset. The
+// rate limit is expressed as <messages>/<interval>, e.g. 1000/30s.
+func ParseJournalSettings(maxSize, rateLimit string) (*wrappers.JournalSettings, error) {
+	var settings wrappers.JournalSettings
+	if maxSize != "" {
+		size, err := strutil.ParseByteSize(maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot set %q: %v", journalMaxSizeOpt, err)
+		}
+		if size < minJournalMaxSize {
+			return nil, fmt.Errorf("cannot set %q: size must be at least %s", journalMaxSizeOpt, strutil.SizeToStr(minJournalMaxSize))
+		}
+		settings.MaxSize = size
+	}
+	if rateLimit != "" {
+		errInvalid := fmt.Errorf("cannot set %q: expected <messages>/<interval>, e.g. 1000/30s, got %q", journalRateLimitOpt, rateLimit)
+		l := strings.SplitN(rateLimit, "/", 2)
+		if len(l) != 2 {
+			return nil, errInvalid
+		}
+		burst, err := strconv.Atoi(l[0])
+		if err != nil || burst <= 0 {
+			return nil, errInvalid
+		}
+		interval, err := time.ParseDuration(l[1])
+		if err != nil || interval <= 0 {
+			return nil, errInvalid
+		}
+		settings.RateLimitBurst = burst
+		settings.RateLimitInterval = interval
+	}
+	if settings == (wrappers.JournalSettings{}) {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+// CheckJournalSettings returns an error if the systemd of the system is
+// too old for the given journal settings.
+func CheckJournalSettings(settings *wrappers.JournalSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.MaxSize > 0 {
+		if err := systemd.EnsureAtLeast(systemd.LogNamespaceMinVersion); err != nil {
+			return fmt.Errorf("cannot set %q: %v", journalMaxSizeOpt, err)
+		}
+	}
+	if settings.RateLimitBurst > 0 {
+		if err := systemd.EnsureAtLeast(systemd.LogRateLimitMinVersion); err != nil {
+			return fmt.Errorf("cannot set %q: %v", journalRateLimitOpt, err)
+		}
+	}
+	return nil
+}
+
+func journalOption(tr config.ConfGetter, instanceName, key string) (string, error) {
+	var v interface{} = ""
+	if err := tr.GetMaybe(instanceName, key, &v); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", v), nil
+}
+
+// JournalSettingsFromConfig returns the journal settings of the services
+// of the snap as found in the given configuration.
+func JournalSettingsFromConfig(tr config.ConfGetter, instanceName string) (*wrappers.JournalSettings, error) {
+	maxSize, err := journalOption(tr, instanceName, journalMaxSizeOpt)
+	if err != nil {
+		return nil, err
+	}
+	rateLimit, err := journalOption(tr, instanceName, journalRateLimitOpt)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJournalSettings(maxSize, rateLimit)
+}
+
+// JournalSettings returns the journal settings of the services of the
+// snap, as configured with its logs.max-size and logs.rate-limit options.
+// Settings that cannot be read are logged and ignored, so that they never
+// prevent the services of the snap from being set up.
+func JournalSettings(st *state.State, instanceName string) *wrappers.JournalSettings {
+	settings, err := JournalSettingsFromConfig(config.NewTransaction(st), instanceName)
+	if err != nil {
+		logger.Noticef("ignoring journal settings of snap %q: %v", instanceName, err)
+		return nil
+	}
+	return settings
+}
+
+// UpdateJournalSettings regenerates the service units of the given snap,
+// if it is active, with its current journal settings, and restarts its
+// enabled services for the settings to take effect. It is called by
+// configstate once changes to the journal options of the snap are
+// committed.
+func UpdateJournalSettings(st *state.State, instanceName string) error {
+	var snapst SnapState
+	err := Get(st, instanceName, &snapst)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !snapst.Active {
+		return nil
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	svcs := info.Services()
+	if len(svcs) == 0 {
+		return nil
+	}
+
+	disabledSvcs, err := regenerateServices(st, info)
+	if err != nil {
+		return err
+	}
+	var toRestart []*snap.AppInfo
+	for _, svc := range svcs {
+		if !strutil.ListContains(disabledSvcs, svc.Name) {
+			toRestart = append(toRestart, svc)
+		}
+	}
+	return snapManager(st).backend.ApplyJournalSettings(info, toRestart, progress.Null, timings.New(nil))
+}
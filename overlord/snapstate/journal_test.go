@@ -0,0 +1,158 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+func (s *snapmgrTestSuite) TestParseJournalSettings(c *C) {
+	settings, err := snapstate.ParseJournalSettings("", "")
+	c.Assert(err, IsNil)
+	c.Check(settings, IsNil)
+
+	settings, err = snapstate.ParseJournalSettings("64MB", "1000/30s")
+	c.Assert(err, IsNil)
+	c.Check(settings, DeepEquals, &wrappers.JournalSettings{
+		MaxSize:           64 * 1000 * 1000,
+		RateLimitBurst:    1000,
+		RateLimitInterval: 30 * time.Second,
+	})
+
+	for _, t := range []struct {
+		maxSize, rateLimit, err string
+	}{
+		{"64", "", `cannot set "logs.max-size": cannot parse "64": need a number with a unit as input`},
+		{"10kB", "", `cannot set "logs.max-size": size must be at least 1MB`},
+		{"", "1000", `cannot set "logs.rate-limit": expected <messages>/<interval>, e.g. 1000/30s, got "1000"`},
+		{"", "0/30s", `cannot set "logs.rate-limit": expected .*`},
+		{"", "x/30s", `cannot set "logs.rate-limit": expected .*`},
+		{"", "10/0s", `cannot set "logs.rate-limit": expected .*`},
+		{"", "10/forever", `cannot set "logs.rate-limit": expected .*`},
+	} {
+		_, err := snapstate.ParseJournalSettings(t.maxSize, t.rateLimit)
+		c.Check(err, ErrorMatches, t.err, Commentf("%q %q", t.maxSize, t.rateLimit))
+	}
+}
+
+func (s *snapmgrTestSuite) TestCheckJournalSettings(c *C) {
+	maxSize := &wrappers.JournalSettings{MaxSize: 64 * 1000 * 1000}
+	rateLimit := &wrappers.JournalSettings{RateLimitBurst: 1000, RateLimitInterval: 30 * time.Second}
+
+	restore := systemd.MockSystemdVersion(245, nil)
+	defer restore()
+	c.Check(snapstate.CheckJournalSettings(nil), IsNil)
+	c.Check(snapstate.CheckJournalSettings(maxSize), IsNil)
+	c.Check(snapstate.CheckJournalSettings(rateLimit), IsNil)
+
+	restore = systemd.MockSystemdVersion(240, nil)
+	defer restore()
+	c.Check(snapstate.CheckJournalSettings(maxSize), ErrorMatches, `cannot set "logs.max-size": systemd version 240 is too old \(expected at least 245\)`)
+	c.Check(snapstate.CheckJournalSettings(rateLimit), IsNil)
+
+	restore = systemd.MockSystemdVersion(237, nil)
+	defer restore()
+	c.Check(snapstate.CheckJournalSettings(rateLimit), ErrorMatches, `cannot set "logs.rate-limit": systemd version 237 is too old \(expected at least 240\)`)
+}
+
+func (s *snapmgrTestSuite) TestJournalSettings(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(snapstate.JournalSettings(s.state, "some-snap"), IsNil)
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("some-snap", "logs.max-size", "10MB"), IsNil)
+	c.Assert(tr.Set("other-snap", "logs.rate-limit", "bogus"), IsNil)
+	tr.Commit()
+
+	c.Check(snapstate.JournalSettings(s.state, "some-snap"), DeepEquals, &wrappers.JournalSettings{
+		MaxSize: 10 * 1000 * 1000,
+	})
+	// invalid settings are ignored
+	c.Check(snapstate.JournalSettings(s.state, "other-snap"), IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateJournalSettings(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("dependant-snap")
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("dependant-snap", "logs.rate-limit", "100/10s"), IsNil)
+	tr.Commit()
+
+	c.Assert(snapstate.UpdateJournalSettings(s.state, "not-installed"), IsNil)
+	c.Check(s.fakeBackend.ops, HasLen, 0)
+
+	c.Assert(snapstate.UpdateJournalSettings(s.state, "dependant-snap"), IsNil)
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op: "current-snap-service-states",
+		},
+		{
+			op:   "regenerate-services",
+			path: snap.MountDir("dependant-snap", snap.R(7)),
+			journalSettings: &wrappers.JournalSettings{
+				RateLimitBurst:    100,
+				RateLimitInterval: 10 * time.Second,
+			},
+		},
+		{
+			op:       "apply-journal-settings",
+			path:     snap.MountDir("dependant-snap", snap.R(7)),
+			services: []string{"svc"},
+		},
+	})
+}
+
+func (s *snapmgrTestSuite) TestLinkSnapJournalSettings(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("some-snap")
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("some-snap", "logs.max-size", "10MB"), IsNil)
+	tr.Commit()
+
+	ts, err := snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("refresh", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	op := s.fakeBackend.ops.First("link-snap")
+	c.Assert(op, NotNil)
+	c.Check(op.journalSettings, DeepEquals, &wrappers.JournalSettings{
+		MaxSize: 10 * 1000 * 1000,
+	})
+}
@@ -0,0 +1,172 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+// maxSchedules is how many tasks a snap can schedule.
+const maxSchedules = 64
+
+var validScheduleName = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$").MatchString
+
+// ScheduleInfo describes a task a snap scheduled at runtime with snapctl
+// schedule, to run one of its apps, which is not a service, on a schedule.
+type ScheduleInfo struct {
+	App string `json:"app"`
+	// Schedule is in the format understood by timeutil.ParseSchedule
+	Schedule string `json:"schedule"`
+}
+
+// scheduledTasks returns the tasks scheduled by the snap that can run
+// with the given revision of it. Tasks whose app is gone or became a
+// service are logged and skipped, they are kept in the state should the
+// snap be reverted.
+func scheduledTasks(snapst *SnapState, info *snap.Info) []*wrappers.ScheduledTask {
+	if len(snapst.Schedules) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(snapst.Schedules))
+	for name := range snapst.Schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tasks := make([]*wrappers.ScheduledTask, 0, len(names))
+	for _, name := range names {
+		sched := snapst.Schedules[name]
+		app := info.Apps[sched.App]
+		if app == nil || app.IsService() {
+			logger.Noticef("ignoring scheduled task %q of snap %q: app %q is not available", name, info.InstanceName(), sched.App)
+			continue
+		}
+		tasks = append(tasks, &wrappers.ScheduledTask{
+			Name:     name,
+			App:      app,
+			Schedule: sched.Schedule,
+		})
+	}
+	return tasks
+}
+
+// ScheduledTasks returns the tasks scheduled by the snap that can run with
+// the given revision of it, for its service units to be generated with.
+func ScheduledTasks(st *state.State, info *snap.Info) []*wrappers.ScheduledTask {
+	var snapst SnapState
+	if err := Get(st, info.InstanceName(), &snapst); err != nil {
+		if err != state.ErrNoState {
+			logger.Noticef("cannot get scheduled tasks of snap %q: %v", info.InstanceName(), err)
+		}
+		return nil
+	}
+	return scheduledTasks(&snapst, info)
+}
+
+// Schedules returns the tasks the given snap scheduled, by name.
+func Schedules(st *state.State, instanceName string) (map[string]*ScheduleInfo, error) {
+	var snapst SnapState
+	if err := Get(st, instanceName, &snapst); err != nil {
+		return nil, err
+	}
+	return snapst.Schedules, nil
+}
+
+func updateSchedules(st *state.State, instanceName string, update func(snapst *SnapState, info *snap.Info) error) error {
+	var snapst SnapState
+	if err := Get(st, instanceName, &snapst); err != nil {
+		return err
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	old := snapst.Schedules
+	snapst.Schedules = make(map[string]*ScheduleInfo, len(old))
+	for name, sched := range old {
+		snapst.Schedules[name] = sched
+	}
+	if err := update(&snapst, info); err != nil {
+		return err
+	}
+	if len(snapst.Schedules) == 0 {
+		snapst.Schedules = nil
+	}
+	Set(st, instanceName, &snapst)
+	if !snapst.Active {
+		// realised once the snap gets enabled again
+		return nil
+	}
+
+	if _, err := regenerateServices(st, info); err != nil {
+		snapst.Schedules = old
+		Set(st, instanceName, &snapst)
+		return err
+	}
+	return nil
+}
+
+// AddSchedule makes the given snap run its given app, which cannot be a
+// service, on the given schedule, until the task gets removed with
+// RemoveSchedule or the snap gets removed.
+func AddSchedule(st *state.State, instanceName, name, schedule, appName string) error {
+	if !validScheduleName(name) {
+		return fmt.Errorf("invalid schedule name: %q", name)
+	}
+	if _, err := timeutil.ParseSchedule(schedule); err != nil {
+		return fmt.Errorf("cannot parse schedule %q: %v", schedule, err)
+	}
+	return updateSchedules(st, instanceName, func(snapst *SnapState, info *snap.Info) error {
+		if _, ok := snapst.Schedules[name]; ok {
+			return fmt.Errorf("schedule %q already exists", name)
+		}
+		if len(snapst.Schedules) >= maxSchedules {
+			return fmt.Errorf("cannot add schedule %q: snap %q cannot have more than %d schedules", name, instanceName, maxSchedules)
+		}
+		app := info.Apps[appName]
+		if app == nil {
+			return fmt.Errorf("cannot add schedule %q: snap %q has no app %q", name, instanceName, appName)
+		}
+		if app.IsService() {
+			return fmt.Errorf("cannot add schedule %q: app %q is a service", name, appName)
+		}
+		snapst.Schedules[name] = &ScheduleInfo{App: appName, Schedule: schedule}
+		return nil
+	})
+}
+
+// RemoveSchedule removes the given task scheduled by the snap.
+func RemoveSchedule(st *state.State, instanceName, name string) error {
+	return updateSchedules(st, instanceName, func(snapst *SnapState, info *snap.Info) error {
+		if _, ok := snapst.Schedules[name]; !ok {
+			return fmt.Errorf("schedule %q not found", name)
+		}
+		delete(snapst.Schedules, name)
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) TestAddRemoveSchedule(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("scheduling-snap")
+
+	err := snapstate.AddSchedule(s.state, "scheduling-snap", "nightly", "23:00", "backup")
+	c.Assert(err, IsNil)
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op: "current-snap-service-states",
+		},
+		{
+			op:             "regenerate-services",
+			path:           snap.MountDir("scheduling-snap", snap.R(7)),
+			scheduledTasks: []string{"nightly:backup:23:00"},
+		},
+	})
+
+	schedules, err := snapstate.Schedules(s.state, "scheduling-snap")
+	c.Assert(err, IsNil)
+	c.Check(schedules, DeepEquals, map[string]*snapstate.ScheduleInfo{
+		"nightly": {App: "backup", Schedule: "23:00"},
+	})
+
+	s.fakeBackend.ops = nil
+	err = snapstate.RemoveSchedule(s.state, "scheduling-snap", "nightly")
+	c.Assert(err, IsNil)
+	c.Check(s.fakeBackend.ops.Ops(), DeepEquals, []string{"current-snap-service-states", "regenerate-services"})
+	c.Check(s.fakeBackend.ops[1].scheduledTasks, HasLen, 0)
+
+	schedules, err = snapstate.Schedules(s.state, "scheduling-snap")
+	c.Assert(err, IsNil)
+	c.Check(schedules, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestAddScheduleErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("scheduling-snap")
+
+	c.Assert(snapstate.AddSchedule(s.state, "scheduling-snap", "nightly", "23:00", "backup"), IsNil)
+	s.fakeBackend.ops = nil
+
+	for _, t := range []struct {
+		name, schedule, app string
+		err                 string
+	}{
+		{"Nightly", "23:00", "backup", `invalid schedule name: "Nightly"`},
+		{"hourly", "sometimes", "backup", `cannot parse schedule "sometimes": .*`},
+		{"nightly", "22:00", "report", `schedule "nightly" already exists`},
+		{"hourly", "23:00", "missing", `cannot add schedule "hourly": snap "scheduling-snap" has no app "missing"`},
+		{"hourly", "23:00", "svc", `cannot add schedule "hourly": app "svc" is a service`},
+	} {
+		err := snapstate.AddSchedule(s.state, "scheduling-snap", t.name, t.schedule, t.app)
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t))
+	}
+	c.Check(s.fakeBackend.ops, HasLen, 0)
+
+	err := snapstate.RemoveSchedule(s.state, "scheduling-snap", "hourly")
+	c.Check(err, ErrorMatches, `schedule "hourly" not found`)
+
+	// the schedule was not touched
+	schedules, err := snapstate.Schedules(s.state, "scheduling-snap")
+	c.Assert(err, IsNil)
+	c.Check(schedules, DeepEquals, map[string]*snapstate.ScheduleInfo{
+		"nightly": {App: "backup", Schedule: "23:00"},
+	})
+}
+
+func (s *snapmgrTestSuite) TestLinkSnapScheduledTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("scheduling-snap")
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "scheduling-snap", &snapst), IsNil)
+	snapst.Schedules = map[string]*snapstate.ScheduleInfo{
+		"nightly": {App: "backup", Schedule: "23:00"},
+		// no longer available
+		"gone": {App: "gone", Schedule: "23:00"},
+	}
+	snapstate.Set(s.state, "scheduling-snap", &snapst)
+
+	ts, err := snapstate.Update(s.state, "scheduling-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("refresh", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	op := s.fakeBackend.ops.First("link-snap")
+	c.Assert(op, NotNil)
+	c.Check(op.scheduledTasks, DeepEquals, []string{"nightly:backup:23:00"})
+
+	// the schedules are kept in the state
+	c.Assert(snapstate.Get(s.state, "scheduling-snap", &snapst), IsNil)
+	c.Check(snapst.Schedules, HasLen, 2)
+}
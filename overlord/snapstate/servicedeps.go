@@ -0,0 +1,222 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"sort"
+
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+)
+
+// ConnectedSlotServices is a hook set by ifacestate returning the services
+// of other snaps bound to the slots connected to the given plug of a snap.
+var ConnectedSlotServices func(st *state.State, instanceName, plugName string) ([]*snap.AppInfo, error)
+
+type cachedSnapManagerKey struct{}
+
+func snapManager(st *state.State) *SnapManager {
+	mgr := st.Cached(cachedSnapManagerKey{})
+	if mgr == nil {
+		panic("internal error: snap manager is not yet associated with state")
+	}
+	return mgr.(*SnapManager)
+}
+
+func hasAfterPlugs(info *snap.Info) bool {
+	for _, app := range info.Apps {
+		if len(app.AfterPlugs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceProviders returns, for the services of the snap declaring
+// after-plugs, the units of the services of other snaps that are bound
+// to the slots connected to those plugs and run in the same daemon
+// scope.
+func ServiceProviders(st *state.State, info *snap.Info) (map[string][]string, error) {
+	if ConnectedSlotServices == nil || !hasAfterPlugs(info) {
+		return nil, nil
+	}
+
+	var providers map[string][]string
+	for _, app := range info.Services() {
+		seen := make(map[string]bool)
+		for _, plugName := range app.AfterPlugs {
+			svcs, err := ConnectedSlotServices(st, info.InstanceName(), plugName)
+			if err != nil {
+				return nil, err
+			}
+			for _, svc := range svcs {
+				unit := svc.ServiceName()
+				if svc.DaemonScope != app.DaemonScope || seen[unit] {
+					continue
+				}
+				seen[unit] = true
+				if providers == nil {
+					providers = make(map[string][]string)
+				}
+				providers[app.Name] = append(providers[app.Name], unit)
+			}
+		}
+	}
+	return providers, nil
+}
+
+// providerSnaps returns the sorted names of the other snaps providing
+// services the services of the snap depend on.
+func providerSnaps(st *state.State, info *snap.Info) ([]string, error) {
+	if ConnectedSlotServices == nil || !hasAfterPlugs(info) {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, app := range info.Services() {
+		for _, plugName := range app.AfterPlugs {
+			svcs, err := ConnectedSlotServices(st, info.InstanceName(), plugName)
+			if err != nil {
+				return nil, err
+			}
+			for _, svc := range svcs {
+				name := svc.Snap.InstanceName()
+				if name == info.InstanceName() || seen[name] {
+					continue
+				}
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// waitForServiceProviders makes the refresh of each snap wait for the
+// refresh of the other snaps providing services its services depend on,
+// so that providers get restarted before their dependants. Dependencies
+// that would create a cycle are ignored.
+func waitForServiceProviders(st *state.State, refreshed map[string]*state.TaskSet, stateByInstanceName map[string]*SnapState) error {
+	waits := make(map[string][]string)
+	var reaches func(from, to string) bool
+	reaches = func(from, to string) bool {
+		if from == to {
+			return true
+		}
+		for _, next := range waits[from] {
+			if reaches(next, to) {
+				return true
+			}
+		}
+		return false
+	}
+
+	names := make([]string, 0, len(refreshed))
+	for name := range refreshed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		snapst := stateByInstanceName[name]
+		if snapst == nil {
+			continue
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return err
+		}
+		providers, err := providerSnaps(st, info)
+		if err != nil {
+			return err
+		}
+		for _, provider := range providers {
+			providerTs := refreshed[provider]
+			if providerTs == nil || reaches(provider, name) {
+				continue
+			}
+			refreshed[name].WaitAll(providerTs)
+			waits[name] = append(waits[name], provider)
+		}
+	}
+	return nil
+}
+
+// UpdateServiceProviders regenerates the service units of the given snap,
+// if it is active and any of its services declares after-plugs, so that
+// they want and start after the services currently providing those plugs.
+// It is called by ifacestate when connections of the snap change.
+func UpdateServiceProviders(st *state.State, instanceName string) error {
+	var snapst SnapState
+	err := Get(st, instanceName, &snapst)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !snapst.Active {
+		return nil
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	if !hasAfterPlugs(info) {
+		return nil
+	}
+
+	_, err = regenerateServices(st, info)
+	return err
+}
+
+// regenerateServices rewrites the service units of the given active snap
+// with its current link time settings and returns its disabled services.
+func regenerateServices(st *state.State, info *snap.Info) (disabledSvcs []string, err error) {
+	providers, err := ServiceProviders(st, info)
+	if err != nil {
+		return nil, err
+	}
+	rank, err := vitalityRank(st, info.InstanceName())
+	if err != nil {
+		return nil, err
+	}
+
+	m := snapManager(st)
+	disabledSvcs, err = m.backend.QueryDisabledServices(info, progress.Null)
+	if err != nil {
+		return nil, err
+	}
+	linkCtx := backend.LinkContext{
+		PrevDisabledServices: disabledSvcs,
+		VitalityRank:         rank,
+		ServiceProviders:     providers,
+		JournalSettings:      JournalSettings(st, info.InstanceName()),
+		ServiceOverrides:     ServiceOverrides(st, info),
+		ScheduledTasks:       ScheduledTasks(st, info),
+	}
+	if err := m.backend.RegenerateServices(info, linkCtx, progress.Null); err != nil {
+		return nil, err
+	}
+	return disabledSvcs, nil
+}
@@ -0,0 +1,194 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) mockServiceProviders(c *C, providersOf map[string]string) {
+	old := snapstate.ConnectedSlotServices
+	snapstate.ConnectedSlotServices = func(st *state.State, instanceName, plugName string) ([]*snap.AppInfo, error) {
+		provider := providersOf[instanceName]
+		if provider == "" || plugName != "database" {
+			return nil, nil
+		}
+		info := &snap.Info{SuggestedName: provider}
+		svc := &snap.AppInfo{Snap: info, Name: "svc1", Daemon: "simple", DaemonScope: snap.SystemDaemon}
+		info.Apps = map[string]*snap.AppInfo{"svc1": svc}
+		return []*snap.AppInfo{svc}, nil
+	}
+	s.AddCleanup(func() { snapstate.ConnectedSlotServices = old })
+}
+
+func (s *snapmgrTestSuite) setActiveSnaps(names ...string) {
+	for _, name := range names {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{{RealName: name, SnapID: name + "-id", Revision: snap.R(7)}},
+			Current:  snap.R(7),
+			SnapType: "app",
+		})
+	}
+}
+
+func (s *snapmgrTestSuite) TestServiceProviders(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockServiceProviders(c, map[string]string{"dependant-snap": "services-snap"})
+	s.setActiveSnaps("dependant-snap", "some-snap")
+
+	info, err := snapstate.CurrentInfo(s.state, "dependant-snap")
+	c.Assert(err, IsNil)
+	providers, err := snapstate.ServiceProviders(s.state, info)
+	c.Assert(err, IsNil)
+	c.Check(providers, DeepEquals, map[string][]string{
+		"svc": {"snap.services-snap.svc1.service"},
+	})
+
+	// no after-plugs
+	info, err = snapstate.CurrentInfo(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	providers, err = snapstate.ServiceProviders(s.state, info)
+	c.Assert(err, IsNil)
+	c.Check(providers, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateServiceProviders(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockServiceProviders(c, map[string]string{"dependant-snap": "services-snap"})
+	s.setActiveSnaps("dependant-snap", "some-snap")
+
+	c.Assert(snapstate.UpdateServiceProviders(s.state, "some-snap"), IsNil)
+	c.Assert(snapstate.UpdateServiceProviders(s.state, "not-installed"), IsNil)
+	c.Check(s.fakeBackend.ops, HasLen, 0)
+
+	c.Assert(snapstate.UpdateServiceProviders(s.state, "dependant-snap"), IsNil)
+	c.Check(s.fakeBackend.ops.First("regenerate-services"), DeepEquals, &fakeOp{
+		op:   "regenerate-services",
+		path: snap.MountDir("dependant-snap", snap.R(7)),
+		serviceProviders: map[string][]string{
+			"svc": {"snap.services-snap.svc1.service"},
+		},
+	})
+}
+
+func (s *snapmgrTestSuite) TestLinkSnapServiceProviders(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockServiceProviders(c, map[string]string{"dependant-snap": "services-snap"})
+	s.setActiveSnaps("dependant-snap")
+
+	ts, err := snapstate.Update(s.state, "dependant-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("refresh", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	op := s.fakeBackend.ops.First("link-snap")
+	c.Assert(op, NotNil)
+	c.Check(op.serviceProviders, DeepEquals, map[string][]string{
+		"svc": {"snap.services-snap.svc1.service"},
+	})
+}
+
+func taskSetOfSnap(c *C, tss []*state.TaskSet, name string) *state.TaskSet {
+	for _, ts := range tss {
+		snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+		if err == nil && snapsup.InstanceName() == name {
+			return ts
+		}
+	}
+	c.Fatalf("no task set for %q", name)
+	return nil
+}
+
+func waitsForAll(t *state.Task, ts *state.TaskSet) bool {
+	waits := make(map[string]bool)
+	for _, w := range t.WaitTasks() {
+		waits[w.ID()] = true
+	}
+	for _, other := range ts.Tasks() {
+		if !waits[other.ID()] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyWaitsForServiceProviders(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockServiceProviders(c, map[string]string{"dependant-snap": "services-snap"})
+	s.setActiveSnaps("dependant-snap", "services-snap", "some-snap")
+
+	updated, tss, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updated, HasLen, 3)
+
+	// to make TaskSnapSetup work
+	chg := s.state.NewChange("refresh", "...")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	dependantTs := taskSetOfSnap(c, tss, "dependant-snap")
+	providerTs := taskSetOfSnap(c, tss, "services-snap")
+	otherTs := taskSetOfSnap(c, tss, "some-snap")
+
+	c.Check(waitsForAll(dependantTs.Tasks()[0], providerTs), Equals, true)
+	c.Check(waitsForAll(providerTs.Tasks()[0], dependantTs), Equals, false)
+	c.Check(waitsForAll(otherTs.Tasks()[0], providerTs), Equals, false)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyServiceProvidersCycle(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockServiceProviders(c, map[string]string{
+		"dependant-snap":       "other-dependant-snap",
+		"other-dependant-snap": "dependant-snap",
+	})
+	s.setActiveSnaps("dependant-snap", "other-dependant-snap")
+
+	_, tss, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+
+	chg := s.state.NewChange("refresh", "...")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	// only one of the dependencies is kept to avoid a deadlock
+	dependantTs := taskSetOfSnap(c, tss, "dependant-snap")
+	otherTs := taskSetOfSnap(c, tss, "other-dependant-snap")
+	c.Check(waitsForAll(dependantTs.Tasks()[0], otherTs), Equals, true)
+	c.Check(waitsForAll(otherTs.Tasks()[0], dependantTs), Equals, false)
+}
@@ -0,0 +1,244 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timings"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+// IsServiceOverrideOption returns whether the given option of a snap
+// overrides the environment or the arguments of one of its services, as
+// services.<app>.env or services.<app>.args, such options are handled by
+// snapd itself.
+//
+// services.<app>.env is a map from environment variable names to values,
+// e.g. services.svc.env='{"LOG_LEVEL":"debug"}', as variable names are
+// usually not valid option names.
+func IsServiceOverrideOption(key string) bool {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || parts[0] != "services" {
+		return false
+	}
+	return parts[2] == "env" || parts[2] == "args"
+}
+
+// ServiceOverrideSubtree returns the part of the value of the services
+// option of a snap that overrides the environment or the arguments of
+// the given service.
+func ServiceOverrideSubtree(services map[string]interface{}, appName string) map[string]interface{} {
+	m, ok := services[appName].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	sub := make(map[string]interface{})
+	for _, k := range []string{"env", "args"} {
+		if v, ok := m[k]; ok {
+			sub[k] = v
+		}
+	}
+	if len(sub) == 0 {
+		return nil
+	}
+	return sub
+}
+
+func isConfigurableArg(app *snap.AppInfo, arg string) bool {
+	for _, allowed := range app.ConfigurableArgs {
+		if arg == allowed {
+			return true
+		}
+		if strings.HasSuffix(allowed, "=") && strings.HasPrefix(arg, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func validOverrideValue(v string) bool {
+	return !strings.ContainsAny(v, "\x00\n\r")
+}
+
+// parseServiceOverrides checks the env and args overrides of the given
+// service against what it declared as configurable.
+func parseServiceOverrides(app *snap.AppInfo, sub map[string]interface{}) (*wrappers.ServiceOverrides, error) {
+	var overrides wrappers.ServiceOverrides
+	if v, ok := sub["env"]; ok && v != nil {
+		opt := fmt.Sprintf("services.%s.env", app.Name)
+		env, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot set %q: expected a map of environment variables", opt)
+		}
+		for name, value := range env {
+			if !strutil.ListContains(app.ConfigurableEnv, name) {
+				return nil, fmt.Errorf("cannot set %q: service %q does not declare %q in configurable-env", opt, app.Name, name)
+			}
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				return nil, fmt.Errorf("cannot set %q: expected a single value for %q", opt, name)
+			}
+			s := fmt.Sprintf("%v", value)
+			if !validOverrideValue(s) {
+				return nil, fmt.Errorf("cannot set %q: value of %q contains control characters", opt, name)
+			}
+			if overrides.Env == nil {
+				overrides.Env = make(map[string]string)
+			}
+			overrides.Env[name] = s
+		}
+	}
+	if v, ok := sub["args"]; ok && v != nil {
+		opt := fmt.Sprintf("services.%s.args", app.Name)
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot set %q: expected a string of arguments", opt)
+		}
+		if !validOverrideValue(s) {
+			return nil, fmt.Errorf("cannot set %q: value contains control characters", opt)
+		}
+		for _, arg := range strings.Fields(s) {
+			if !isConfigurableArg(app, arg) {
+				return nil, fmt.Errorf("cannot set %q: service %q does not declare %q in configurable-args", opt, app.Name, arg)
+			}
+			overrides.Args = append(overrides.Args, arg)
+		}
+	}
+	if overrides.Env == nil && overrides.Args == nil {
+		return nil, nil
+	}
+	return &overrides, nil
+}
+
+func serviceOverridesFromConfig(tr config.ConfGetter, info *snap.Info, ignoreErrors bool) (map[string]*wrappers.ServiceOverrides, error) {
+	var services map[string]interface{}
+	if err := tr.GetMaybe(info.InstanceName(), "services", &services); err != nil {
+		return nil, err
+	}
+	appNames := make([]string, 0, len(services))
+	for appName := range services {
+		appNames = append(appNames, appName)
+	}
+	sort.Strings(appNames)
+
+	var all map[string]*wrappers.ServiceOverrides
+	for _, appName := range appNames {
+		sub := ServiceOverrideSubtree(services, appName)
+		if sub == nil {
+			continue
+		}
+		app := info.Apps[appName]
+		var overrides *wrappers.ServiceOverrides
+		var err error
+		if app == nil || !app.IsService() {
+			err = fmt.Errorf("cannot set \"services.%s\": snap %q has no service %q", appName, info.InstanceName(), appName)
+		} else {
+			overrides, err = parseServiceOverrides(app, sub)
+		}
+		if err != nil {
+			if !ignoreErrors {
+				return nil, err
+			}
+			logger.Noticef("ignoring overrides of service %q of snap %q: %v", appName, info.InstanceName(), err)
+			continue
+		}
+		if overrides == nil {
+			continue
+		}
+		if all == nil {
+			all = make(map[string]*wrappers.ServiceOverrides)
+		}
+		all[appName] = overrides
+	}
+	return all, nil
+}
+
+// ServiceOverridesFromConfig returns the environment and arguments
+// overrides of the services of the snap as found in the given
+// configuration, checked against what the services declared as
+// configurable with configurable-env and configurable-args.
+func ServiceOverridesFromConfig(tr config.ConfGetter, info *snap.Info) (map[string]*wrappers.ServiceOverrides, error) {
+	return serviceOverridesFromConfig(tr, info, false)
+}
+
+// ServiceOverrides returns the environment and arguments overrides of the
+// services of the snap, as configured with its services.<app>.env and
+// services.<app>.args options. Overrides that are not valid, e.g. because
+// a new revision of the snap no longer declares them as configurable, are
+// logged and ignored, so that they never prevent the services of the snap
+// from being set up.
+func ServiceOverrides(st *state.State, info *snap.Info) map[string]*wrappers.ServiceOverrides {
+	overrides, err := serviceOverridesFromConfig(config.NewTransaction(st), info, true)
+	if err != nil {
+		logger.Noticef("ignoring service overrides of snap %q: %v", info.InstanceName(), err)
+		return nil
+	}
+	return overrides
+}
+
+// UpdateServiceOverrides regenerates the service units of the given snap,
+// if it is active, with its current service overrides, and restarts the
+// given services among its enabled ones for the overrides to take effect.
+// It is called by configstate once changes to the service overrides of the
+// snap are committed.
+func UpdateServiceOverrides(st *state.State, instanceName string, appNames []string) error {
+	var snapst SnapState
+	err := Get(st, instanceName, &snapst)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !snapst.Active {
+		return nil
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	if len(info.Services()) == 0 {
+		return nil
+	}
+
+	disabledSvcs, err := regenerateServices(st, info)
+	if err != nil {
+		return err
+	}
+	var toRestart []*snap.AppInfo
+	for _, svc := range info.Services() {
+		if strutil.ListContains(appNames, svc.Name) && !strutil.ListContains(disabledSvcs, svc.Name) {
+			toRestart = append(toRestart, svc)
+		}
+	}
+	if len(toRestart) == 0 {
+		return nil
+	}
+	return snapManager(st).backend.RestartServices(toRestart, progress.Null, timings.New(nil))
+}
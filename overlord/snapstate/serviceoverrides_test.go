@@ -0,0 +1,141 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+func (s *snapmgrTestSuite) TestIsServiceOverrideOption(c *C) {
+	for key, expected := range map[string]bool{
+		"services.svc.env":           true,
+		"services.svc.env.log-level": false,
+		"services.svc.args":          true,
+		"services.svc.args.foo":      false,
+		"services.svc.enabled":       false,
+		"services.svc":               false,
+		"services":                   false,
+		"logs.max-size":              false,
+		"foo.svc.env":                false,
+	} {
+		c.Check(snapstate.IsServiceOverrideOption(key), Equals, expected, Commentf("%s", key))
+	}
+}
+
+func (s *snapmgrTestSuite) TestServiceOverridesFromConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("configurable-snap")
+
+	info, err := snapstate.CurrentInfo(s.state, "configurable-snap")
+	c.Assert(err, IsNil)
+
+	tr := config.NewTransaction(s.state)
+	overrides, err := snapstate.ServiceOverridesFromConfig(tr, info)
+	c.Assert(err, IsNil)
+	c.Check(overrides, IsNil)
+
+	c.Assert(tr.Set("configurable-snap", "services.svc.env", map[string]interface{}{"LOG_LEVEL": "debug"}), IsNil)
+	c.Assert(tr.Set("configurable-snap", "services.svc.args", "--verbose  --log-format=json"), IsNil)
+	c.Assert(tr.Set("configurable-snap", "services.other-svc.enabled", true), IsNil)
+	overrides, err = snapstate.ServiceOverridesFromConfig(tr, info)
+	c.Assert(err, IsNil)
+	c.Check(overrides, DeepEquals, map[string]*wrappers.ServiceOverrides{
+		"svc": {
+			Env:  map[string]string{"LOG_LEVEL": "debug"},
+			Args: []string{"--verbose", "--log-format=json"},
+		},
+	})
+
+	for _, t := range []struct {
+		key   string
+		value interface{}
+		err   string
+	}{
+		{"services.svc.env", map[string]interface{}{"HOME": "/tmp"}, `cannot set "services.svc.env": service "svc" does not declare "HOME" in configurable-env`},
+		{"services.svc.env", map[string]interface{}{"LOG_LEVEL": map[string]interface{}{"a": "b"}}, `cannot set "services.svc.env": expected a single value for "LOG_LEVEL"`},
+		{"services.svc.env", map[string]interface{}{"LOG_LEVEL": "a\nb"}, `cannot set "services.svc.env": value of "LOG_LEVEL" contains control characters`},
+		{"services.svc.env", "LOG_LEVEL=debug", `cannot set "services.svc.env": expected a map of environment variables`},
+		{"services.svc.args", "--log-format", `cannot set "services.svc.args": service "svc" does not declare "--log-format" in configurable-args`},
+		{"services.svc.args", 42, `cannot set "services.svc.args": expected a string of arguments`},
+		{"services.other-svc.args", "--verbose", `cannot set "services.other-svc.args": service "other-svc" does not declare "--verbose" in configurable-args`},
+		{"services.missing.env", map[string]interface{}{"LOG_LEVEL": "debug"}, `cannot set "services.missing": snap "configurable-snap" has no service "missing"`},
+	} {
+		tr := config.NewTransaction(s.state)
+		c.Assert(tr.Set("configurable-snap", t.key, t.value), IsNil)
+		_, err := snapstate.ServiceOverridesFromConfig(tr, info)
+		c.Check(err, ErrorMatches, t.err, Commentf("%s", t.key))
+	}
+}
+
+func (s *snapmgrTestSuite) TestServiceOverridesIgnoresInvalid(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("configurable-snap")
+
+	info, err := snapstate.CurrentInfo(s.state, "configurable-snap")
+	c.Assert(err, IsNil)
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("configurable-snap", "services.svc.args", "--verbose"), IsNil)
+	c.Assert(tr.Set("configurable-snap", "services.other-svc.env", map[string]interface{}{"LOG_LEVEL": "debug"}), IsNil)
+	tr.Commit()
+
+	c.Check(snapstate.ServiceOverrides(s.state, info), DeepEquals, map[string]*wrappers.ServiceOverrides{
+		"svc": {Args: []string{"--verbose"}},
+	})
+}
+
+func (s *snapmgrTestSuite) TestUpdateServiceOverrides(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setActiveSnaps("configurable-snap")
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("configurable-snap", "services.svc.env", map[string]interface{}{"LOG_LEVEL": "debug"}), IsNil)
+	tr.Commit()
+
+	c.Assert(snapstate.UpdateServiceOverrides(s.state, "not-installed", []string{"svc"}), IsNil)
+	c.Check(s.fakeBackend.ops, HasLen, 0)
+
+	c.Assert(snapstate.UpdateServiceOverrides(s.state, "configurable-snap", []string{"svc"}), IsNil)
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op: "current-snap-service-states",
+		},
+		{
+			op:   "regenerate-services",
+			path: snap.MountDir("configurable-snap", snap.R(7)),
+			serviceOverrides: map[string]*wrappers.ServiceOverrides{
+				"svc": {Env: map[string]string{"LOG_LEVEL": "debug"}},
+			},
+		},
+		{
+			op:       "restart-snap-services",
+			path:     snap.MountDir("configurable-snap", snap.R(7)),
+			services: []string{"svc"},
+		},
+	})
+}
@@ -44,6 +44,7 @@ import (
 	"github.com/snapcore/snapd/snap/channel"
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
 )
 
 var (
@@ -179,6 +180,10 @@ type SnapState struct {
 	// attempted but inhibited because the snap was busy. This value is
 	// reset on each successful refresh.
 	RefreshInhibitedTime *time.Time `json:"refresh-inhibited-time,omitempty"`
+
+	// Schedules are the tasks the snap scheduled with snapctl schedule,
+	// by name, see schedules.go
+	Schedules map[string]*ScheduleInfo `json:"schedules,omitempty"`
 }
 
 func (snapst *SnapState) SetTrackingChannel(s string) error {
@@ -376,13 +381,30 @@ func cachedStore(st *state.State) StoreService {
 	return ubuntuStore.(StoreService)
 }
 
-// the store implementation has the interface consumed here
+type cachedOfflineStoreKey struct{}
+
+// ReplaceOfflineStore sets the store serving a local directory that is
+// used instead of any other store while the store.offline-source option
+// is set. Passing nil goes back to the regular store.
+func ReplaceOfflineStore(state *state.State, store StoreService) {
+	if store == nil {
+		state.Cache(cachedOfflineStoreKey{}, nil)
+		return
+	}
+	state.Cache(cachedOfflineStoreKey{}, store)
+}
+
+// the store implementations have the interface consumed here
 var _ StoreService = (*store.Store)(nil)
+var _ StoreService = (*offline.Store)(nil)
 
-// Store returns the store service provided by the optional device context or
-// the one used by the snapstate package if the former has no
-// override.
+// Store returns the offline store if one is set, otherwise the store
+// service provided by the optional device context or the one used by
+// the snapstate package if the former has no override.
 func Store(st *state.State, deviceCtx DeviceContext) StoreService {
+	if offlineStore, ok := st.Cached(cachedOfflineStoreKey{}).(StoreService); ok {
+		return offlineStore
+	}
 	if deviceCtx != nil {
 		sto := deviceCtx.Store()
 		if sto != nil {
@@ -411,6 +433,10 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 		m.backend = backend.Backend{}
 	}
 
+	st.Lock()
+	st.Cache(cachedSnapManagerKey{}, m)
+	st.Unlock()
+
 	if err := os.MkdirAll(dirs.SnapCookieDir, 0700); err != nil {
 		return nil, fmt.Errorf("cannot create directory %q: %v", dirs.SnapCookieDir, err)
 	}
@@ -440,6 +466,7 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	runner.AddHandler("switch-snap-channel", m.doSwitchSnapChannel, nil)
 	runner.AddHandler("toggle-snap-flags", m.doToggleSnapFlags, nil)
 	runner.AddHandler("check-rerefresh", m.doCheckReRefresh, nil)
+	runner.AddHandler("conditional-auto-refresh", m.doConditionalAutoRefresh, nil)
 
 	// FIXME: drop the task entirely after a while
 	// (having this wart here avoids yet-another-patch)
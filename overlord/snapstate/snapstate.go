@@ -906,21 +906,31 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, us
 	if flags == nil {
 		flags = &Flags{}
 	}
-	user, err := userFromUserID(st, userID)
+	updates, stateByInstanceName, deviceCtx, err := refreshUpdates(ctx, st, names, userID, filter, flags)
 	if err != nil {
 		return nil, nil, err
 	}
+	return updateManyTasks(ctx, st, names, updates, stateByInstanceName, userID, flags, deviceCtx, fromChange)
+}
+
+// refreshUpdates returns the validated updates of the given snaps, or
+// of all snaps if names is empty, that pass the filter.
+func refreshUpdates(ctx context.Context, st *state.State, names []string, userID int, filter updateFilter, flags *Flags) ([]*snap.Info, map[string]*SnapState, DeviceContext, error) {
+	user, err := userFromUserID(st, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	// need to have a model set before trying to talk the store
 	deviceCtx, err := DevicePastSeeding(st, nil)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	refreshOpts := &store.RefreshOptions{IsAutoRefresh: flags.IsAutoRefresh}
 	updates, stateByInstanceName, ignoreValidation, err := refreshCandidates(ctx, st, names, user, refreshOpts)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	if filter != nil {
@@ -938,13 +948,18 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, us
 		if err != nil {
 			// not doing "refresh all" report the error
 			if len(names) != 0 {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			// doing "refresh all", log the problems
 			logger.Noticef("cannot refresh some snaps: %v", err)
 		}
 	}
+	return updates, stateByInstanceName, deviceCtx, nil
+}
 
+// updateManyTasks returns the tasks refreshing the snaps to the given
+// updates.
+func updateManyTasks(ctx context.Context, st *state.State, names []string, updates []*snap.Info, stateByInstanceName map[string]*SnapState, userID int, flags *Flags, deviceCtx DeviceContext, fromChange string) ([]string, []*state.TaskSet, error) {
 	params := func(update *snap.Info) (*RevisionOptions, Flags, *SnapState) {
 		snapst := stateByInstanceName[update.InstanceName()]
 		// setting options to what's in state as multi-refresh doesn't let you change these
@@ -1012,6 +1027,8 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 	// first snapd, core, bases, then rest
 	sort.Stable(snap.ByType(updates))
 	prereqs := make(map[string]*state.TaskSet)
+	refreshed := make(map[string]*state.TaskSet)
+	refreshedStates := make(map[string]*SnapState)
 	waitPrereq := func(ts *state.TaskSet, prereqName string) {
 		preTs := prereqs[prereqName]
 		if preTs != nil {
@@ -1097,6 +1114,12 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 
 		scheduleUpdate(update.InstanceName(), ts)
 		tasksets = append(tasksets, ts)
+		refreshed[update.InstanceName()] = ts
+		refreshedStates[update.InstanceName()] = snapst
+	}
+
+	if err := waitForServiceProviders(st, refreshed, refreshedStates); err != nil {
+		return nil, nil, err
 	}
 
 	if len(newAutoAliases) != 0 {
@@ -1608,7 +1631,7 @@ func AutoRefresh(ctx context.Context, st *state.State) ([]string, []*state.TaskS
 		}
 	}
 
-	return UpdateMany(ctx, st, nil, userID, &Flags{IsAutoRefresh: true})
+	return autoRefreshGated(ctx, st, userID)
 }
 
 // LinkNewBaseOrKernel will create prepare/link-snap tasks for a remodel
@@ -56,6 +56,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timeutil"
 
@@ -118,10 +119,12 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 	oldSetupPreRefreshHook := snapstate.SetupPreRefreshHook
 	oldSetupPostRefreshHook := snapstate.SetupPostRefreshHook
 	oldSetupRemoveHook := snapstate.SetupRemoveHook
+	oldSetupGateAutoRefreshHook := snapstate.SetupGateAutoRefreshHook
 	snapstate.SetupInstallHook = hookstate.SetupInstallHook
 	snapstate.SetupPreRefreshHook = hookstate.SetupPreRefreshHook
 	snapstate.SetupPostRefreshHook = hookstate.SetupPostRefreshHook
 	snapstate.SetupRemoveHook = hookstate.SetupRemoveHook
+	snapstate.SetupGateAutoRefreshHook = hookstate.SetupGateAutoRefreshHook
 
 	var err error
 	s.snapmgr, err = snapstate.Manager(s.state, s.o.TaskRunner())
@@ -152,6 +155,7 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 		snapstate.SetupPreRefreshHook = oldSetupPreRefreshHook
 		snapstate.SetupPostRefreshHook = oldSetupPostRefreshHook
 		snapstate.SetupRemoveHook = oldSetupRemoveHook
+		snapstate.SetupGateAutoRefreshHook = oldSetupGateAutoRefreshHook
 
 		dirs.SetRootDir("/")
 	})
@@ -331,6 +335,23 @@ func (s *snapmgrTestSuite) TestStoreWithDeviceContext(c *C) {
 	c.Check(store3, Equals, stoB)
 }
 
+func (s *snapmgrTestSuite) TestStoreWithOfflineStore(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	sto := &store.Store{}
+	snapstate.ReplaceStore(s.state, sto)
+
+	offlineSto := &offline.Store{}
+	snapstate.ReplaceOfflineStore(s.state, offlineSto)
+	c.Check(snapstate.Store(s.state, nil), Equals, offlineSto)
+	// the offline store wins over the one of the device context
+	c.Check(snapstate.Store(s.state, &snapstatetest.TrivialDeviceContext{CtxStore: &store.Store{}}), Equals, offlineSto)
+
+	snapstate.ReplaceOfflineStore(s.state, nil)
+	c.Check(snapstate.Store(s.state, nil), Equals, sto)
+}
+
 func (s *snapmgrTestSuite) TestUserFromUserID(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -4037,6 +4058,11 @@ func (s *snapmgrTestSuite) TestEnsureRefreshesWithUpdate(c *C) {
 	s.verifyRefreshLast(c)
 
 	checkIsAutoRefresh(c, chg.Tasks(), true)
+
+	// the downloads of the change go in the background
+	var priority int
+	c.Assert(chg.Get("download-priority", &priority), IsNil)
+	c.Check(priority, Equals, store.DownloadPriorityBackground)
 }
 
 func (s *snapmgrTestSuite) TestEnsureRefreshesImmediateWithUpdate(c *C) {
@@ -66,3 +66,14 @@ func SnapAppFromPid(pid int) (snap, app, hook string, err error) {
 	}
 	return decodeLabel(label)
 }
+
+// SnapAppFromLabel returns the snap, app and hook of the given security
+// label, as logged by the kernel in apparmor and audit messages. Labels of
+// child profiles, such as "snap.foo.bar//null-/usr/bin/baz", are attributed
+// to their parent.
+func SnapAppFromLabel(label string) (snap, app, hook string, err error) {
+	if pos := strings.Index(label, "//"); pos != -1 {
+		label = label[:pos]
+	}
+	return decodeLabel(label)
+}
@@ -57,6 +57,30 @@ func (s *apparmorSuite) TestDecodeLabelUnrecognisedSnapLabel(c *C) {
 	c.Assert(err, ErrorMatches, `unknown snap related security label "snap.weird"`)
 }
 
+func (s *apparmorSuite) TestSnapAppFromLabel(c *C) {
+	for _, t := range []struct {
+		label           string
+		name, app, hook string
+		err             string
+	}{
+		{label: "snap.foo.app", name: "foo", app: "app"},
+		{label: "snap.foo.hook.configure", name: "foo", hook: "configure"},
+		{label: "snap.foo.app//null-/usr/bin/bar", name: "foo", app: "app"},
+		{label: "snap-update-ns.foo", err: `security label "snap-update-ns.foo" does not belong to a snap`},
+		{label: "unconfined", err: `security label "unconfined" does not belong to a snap`},
+	} {
+		name, app, hook, err := apparmor.SnapAppFromLabel(t.label)
+		if t.err != "" {
+			c.Check(err, ErrorMatches, t.err)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Check(name, Equals, t.name, Commentf("%s", t.label))
+		c.Check(app, Equals, t.app, Commentf("%s", t.label))
+		c.Check(hook, Equals, t.hook, Commentf("%s", t.label))
+	}
+}
+
 func (s *apparmorSuite) TestSnapAppFromPid(c *C) {
 	d := c.MkDir()
 	restore := apparmor.MockFsRootPath(d)
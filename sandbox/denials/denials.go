@@ -0,0 +1,268 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package denials parses the apparmor and seccomp denials logged by the
+// kernel and attributes them to snap apps and hooks, suggesting the
+// interface that would grant the denied access.
+package denials
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/systemd"
+)
+
+const (
+	BackendAppArmor = "apparmor"
+	BackendSecComp  = "seccomp"
+)
+
+// Denial is a single apparmor or seccomp denial of a snap app or hook.
+type Denial struct {
+	Time    time.Time
+	Backend string
+	Snap    string
+	App     string
+	Hook    string
+	// Operation is the apparmor operation, such as "open" or "capable",
+	// or "syscall" for seccomp.
+	Operation string
+	// Target is what the operation was denied on: a path, a capability,
+	// a network family, a D-Bus name or a system call.
+	Target string
+	// Allowed is set when the access was only logged because the snap
+	// runs in complain mode, as it happens in devmode.
+	Allowed bool
+}
+
+// Summary describes all the similar denials of a snap app or hook.
+type Summary struct {
+	Snap      string    `json:"snap"`
+	App       string    `json:"app,omitempty"`
+	Hook      string    `json:"hook,omitempty"`
+	Backend   string    `json:"backend"`
+	Operation string    `json:"operation"`
+	Target    string    `json:"target,omitempty"`
+	Allowed   bool      `json:"allowed,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	Count     int       `json:"count"`
+	LastSeen  time.Time `json:"last-seen"`
+}
+
+// fields splits an audit message into its key=value fields. Values may be
+// quoted; tokens without a value are ignored.
+func fields(msg string) map[string]string {
+	result := make(map[string]string)
+	for len(msg) > 0 {
+		msg = strings.TrimLeft(msg, " ")
+		eq := strings.IndexAny(msg, "= ")
+		if eq == -1 {
+			break
+		}
+		if msg[eq] == ' ' {
+			msg = msg[eq:]
+			continue
+		}
+		key := msg[:eq]
+		msg = msg[eq+1:]
+		var value string
+		if strings.HasPrefix(msg, `"`) {
+			end := strings.Index(msg[1:], `"`)
+			if end == -1 {
+				end = len(msg) - 1
+			}
+			value = msg[1 : end+1]
+			msg = msg[min(end+2, len(msg)):]
+		} else {
+			end := strings.IndexByte(msg, ' ')
+			if end == -1 {
+				end = len(msg)
+			}
+			value = msg[:end]
+			msg = msg[end:]
+		}
+		result[key] = value
+	}
+	return result
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Parse returns the denial logged in the given message, if any.
+func Parse(msg string) (*Denial, bool) {
+	var d Denial
+	var label string
+	f := fields(msg)
+	switch {
+	case f["apparmor"] == "DENIED" || f["apparmor"] == "ALLOWED":
+		d.Backend = BackendAppArmor
+		d.Allowed = f["apparmor"] == "ALLOWED"
+		d.Operation = f["operation"]
+		// D-Bus mediation uses label rather than profile
+		label = f["profile"]
+		if label == "" {
+			label = f["label"]
+		}
+		switch {
+		case d.Operation == "capable":
+			d.Target = f["capname"]
+		case f["family"] != "":
+			d.Target = f["family"]
+			if f["sock_type"] != "" {
+				d.Target += " " + f["sock_type"]
+			}
+		case strings.HasPrefix(d.Operation, "dbus_"):
+			d.Target = f["name"]
+			if f["interface"] != "" {
+				d.Target += " " + f["interface"] + "." + f["member"]
+			}
+		default:
+			d.Target = f["name"]
+		}
+	case f["syscall"] != "" && f["subj"] != "":
+		// type=1326 (AUDIT_SECCOMP)
+		d.Backend = BackendSecComp
+		d.Operation = "syscall"
+		label = f["subj"]
+		// SECCOMP_RET_LOG is used in complain mode
+		d.Allowed = f["code"] == "0x7ffc0000"
+		d.Target = syscallName(f["arch"], f["syscall"])
+	default:
+		return nil, false
+	}
+	snapName, app, hook, err := apparmor.SnapAppFromLabel(label)
+	if err != nil {
+		return nil, false
+	}
+	d.Snap, d.App, d.Hook = snapName, app, hook
+	return &d, true
+}
+
+// auditArch maps the architectures to their AUDIT_ARCH_* value, as logged
+// by the kernel in hexadecimal.
+var auditArch = map[string]string{
+	"386":     "40000003",
+	"amd64":   "c000003e",
+	"arm":     "40000028",
+	"arm64":   "c00000b7",
+	"ppc64le": "c0000015",
+	"s390x":   "80000016",
+}
+
+// syscallName returns the name of a system call of the native architecture
+// known to be granted by an interface, or a description of it otherwise.
+func syscallName(arch, number string) string {
+	if arch == auditArch[runtime.GOARCH] {
+		if nr, err := strconv.Atoi(number); err == nil {
+			if name, ok := syscallNames[nr]; ok {
+				return name
+			}
+		}
+	}
+	return "syscall " + number
+}
+
+// Collect reads journal entries, as produced by journalctl -o json, and
+// summarises the denials of the given snap, or of all snaps if snapName is
+// empty. Summaries are sorted by snap, app, hook, backend and target.
+func Collect(r io.Reader, snapName string) ([]*Summary, error) {
+	type key struct {
+		snap, app, hook, backend, operation, target string
+		allowed                                     bool
+	}
+	byKey := make(map[key]*Summary)
+
+	rd := bufio.NewReader(r)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(line) > 0 {
+			var log systemd.Log
+			// entries with binary messages are encoded as arrays
+			// and cannot be denials
+			if json.Unmarshal(line, &log) == nil {
+				if d, ok := Parse(log.Message()); ok && (snapName == "" || d.Snap == snapName) {
+					d.Time, _ = log.Time()
+					k := key{d.Snap, d.App, d.Hook, d.Backend, d.Operation, d.Target, d.Allowed}
+					s := byKey[k]
+					if s == nil {
+						s = &Summary{
+							Snap:      d.Snap,
+							App:       d.App,
+							Hook:      d.Hook,
+							Backend:   d.Backend,
+							Operation: d.Operation,
+							Target:    d.Target,
+							Allowed:   d.Allowed,
+							Interface: SuggestInterface(d),
+						}
+						byKey[k] = s
+					}
+					s.Count++
+					if d.Time.After(s.LastSeen) {
+						s.LastSeen = d.Time
+					}
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	summaries := make([]*Summary, 0, len(byKey))
+	for _, s := range byKey {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Snap != b.Snap {
+			return a.Snap < b.Snap
+		}
+		if a.App != b.App {
+			return a.App < b.App
+		}
+		if a.Hook != b.Hook {
+			return a.Hook < b.Hook
+		}
+		if a.Backend != b.Backend {
+			return a.Backend < b.Backend
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Operation < b.Operation
+	})
+	return summaries, nil
+}
@@ -0,0 +1,150 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type denialsSuite struct{}
+
+var _ = Suite(&denialsSuite{})
+
+func (s *denialsSuite) TestParseAppArmor(c *C) {
+	for _, t := range []struct {
+		msg    string
+		denial *denials.Denial
+		iface  string
+	}{{
+		msg:    `audit: type=1400 audit(1593452237.396:1231): apparmor="DENIED" operation="open" profile="snap.foo.bar" name="/dev/video0" pid=1234 comm="bar" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", App: "bar", Operation: "open", Target: "/dev/video0"},
+		iface:  "camera",
+	}, {
+		msg:    `apparmor="ALLOWED" operation="open" profile="snap.foo.hook.configure" name="/home/user/.config/foo" pid=1 comm="foo" requested_mask="r" denied_mask="r"`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", Hook: "configure", Operation: "open", Target: "/home/user/.config/foo", Allowed: true},
+		iface:  "home",
+	}, {
+		msg:    `apparmor="DENIED" operation="capable" profile="snap.foo.bar" pid=1234 comm="bar" capability=12  capname="net_admin"`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", App: "bar", Operation: "capable", Target: "net_admin"},
+		iface:  "network-control",
+	}, {
+		msg:    `apparmor="DENIED" operation="create" profile="snap.foo.bar" pid=1234 comm="bar" family="inet" sock_type="stream" protocol=6 requested_mask="create" denied_mask="create"`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", App: "bar", Operation: "create", Target: "inet stream"},
+		iface:  "network",
+	}, {
+		msg:    `apparmor="DENIED" operation="bind" profile="snap.foo.bar" pid=1234 comm="bar" family="inet6" sock_type="stream" protocol=6 requested_mask="bind" denied_mask="bind"`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", App: "bar", Operation: "bind", Target: "inet6 stream"},
+		iface:  "network-bind",
+	}, {
+		msg:    `apparmor="DENIED" operation="dbus_method_call"  bus="system" path="/org/freedesktop/NetworkManager" interface="org.freedesktop.NetworkManager" member="GetDevices" mask="send" name="org.freedesktop.NetworkManager" pid=1234 label="snap.foo.bar" peer_pid=5 peer_label="unconfined"`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", App: "bar", Operation: "dbus_method_call", Target: "org.freedesktop.NetworkManager org.freedesktop.NetworkManager.GetDevices"},
+		iface:  "network-manager",
+	}, {
+		msg:    `apparmor="DENIED" operation="exec" profile="snap.foo.bar//null-/usr/bin/baz" name="/usr/bin/qux" pid=1234 comm="baz"`,
+		denial: &denials.Denial{Backend: "apparmor", Snap: "foo", App: "bar", Operation: "exec", Target: "/usr/bin/qux"},
+	}} {
+		d, ok := denials.Parse(t.msg)
+		c.Assert(ok, Equals, true, Commentf(t.msg))
+		c.Check(d, DeepEquals, t.denial)
+		c.Check(denials.SuggestInterface(d), Equals, t.iface)
+	}
+}
+
+func (s *denialsSuite) TestParseSecComp(c *C) {
+	arch := map[string]string{"amd64": "c000003e", "arm64": "c00000b7"}[runtime.GOARCH]
+	if arch == "" {
+		c.Skip("test only runs on amd64 and arm64")
+	}
+	nr := map[string]string{"amd64": "101", "arm64": "117"}[runtime.GOARCH]
+
+	d, ok := denials.Parse(`audit: type=1326 audit(1593452237.396:1232): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.foo.bar (enforce) pid=1234 comm="bar" exe="/snap/foo/1/bin/bar" sig=31 arch=` + arch + ` syscall=` + nr + ` compat=0 ip=0x7f0000000000 code=0x0`)
+	c.Assert(ok, Equals, true)
+	c.Check(d, DeepEquals, &denials.Denial{Backend: "seccomp", Snap: "foo", App: "bar", Operation: "syscall", Target: "ptrace"})
+	c.Check(denials.SuggestInterface(d), Equals, "system-observe")
+
+	// complain mode and a system call no interface grants
+	d, ok = denials.Parse(`auid=1000 uid=1000 subj=snap.foo.bar pid=1234 comm="bar" sig=0 arch=` + arch + ` syscall=99999 compat=0 code=0x7ffc0000`)
+	c.Assert(ok, Equals, true)
+	c.Check(d, DeepEquals, &denials.Denial{Backend: "seccomp", Snap: "foo", App: "bar", Operation: "syscall", Target: "syscall 99999", Allowed: true})
+	c.Check(denials.SuggestInterface(d), Equals, "")
+
+	// foreign architectures are not mapped
+	d, ok = denials.Parse(`subj=snap.foo.bar sig=31 arch=40000003 syscall=` + nr + ` code=0x0`)
+	c.Assert(ok, Equals, true)
+	c.Check(d.Target, Equals, "syscall "+nr)
+}
+
+func (s *denialsSuite) TestParseNotADenial(c *C) {
+	for _, msg := range []string{
+		"",
+		"usb 1-2: new full-speed USB device number 3 using xhci_hcd",
+		`apparmor="STATUS" operation="profile_replace" profile="unconfined" name="snap.foo.bar" pid=1234 comm="apparmor_parser"`,
+		`apparmor="DENIED" operation="open" profile="/usr/sbin/cupsd" name="/etc/foo" pid=1 comm="cupsd"`,
+		`apparmor="DENIED" operation="open" profile="snap-update-ns.foo" name="/etc/foo" pid=1 comm="snap-update-ns"`,
+		`subj=unconfined sig=31 arch=c000003e syscall=101 code=0x0`,
+	} {
+		_, ok := denials.Parse(msg)
+		c.Check(ok, Equals, false, Commentf(msg))
+	}
+}
+
+const journal = `{"__REALTIME_TIMESTAMP":"1593452237000000","MESSAGE":"apparmor=\"DENIED\" operation=\"open\" profile=\"snap.foo.bar\" name=\"/dev/video0\" pid=1 comm=\"bar\""}
+{"__REALTIME_TIMESTAMP":"1593452239000000","MESSAGE":[1,2,3]}
+{"__REALTIME_TIMESTAMP":"1593452240000000","MESSAGE":"usb 1-2: new full-speed USB device"}
+{"__REALTIME_TIMESTAMP":"1593452241000000","MESSAGE":"apparmor=\"DENIED\" operation=\"open\" profile=\"snap.foo.bar\" name=\"/dev/video0\" pid=2 comm=\"bar\""}
+{"__REALTIME_TIMESTAMP":"1593452242000000","MESSAGE":"apparmor=\"DENIED\" operation=\"capable\" profile=\"snap.foo.bar\" pid=2 comm=\"bar\" capability=25 capname=\"sys_time\""}
+{"__REALTIME_TIMESTAMP":"1593452243000000","MESSAGE":"apparmor=\"DENIED\" operation=\"open\" profile=\"snap.baz.app\" name=\"/var/log/syslog\" pid=3 comm=\"baz\""}
+{"__REALTIME_TIMESTAMP":"1593452244000000","MESSAGE":"apparmor=\"DENIED\" operation=\"open\" profile=\"snap.foo.bar\" name=\"/etc/unknown\" pid=2 comm=\"bar\""}`
+
+func (s *denialsSuite) TestCollect(c *C) {
+	summaries, err := denials.Collect(strings.NewReader(journal), "")
+	c.Assert(err, IsNil)
+	c.Check(summaries, DeepEquals, []*denials.Summary{{
+		Snap: "baz", App: "app", Backend: "apparmor", Operation: "open", Target: "/var/log/syslog",
+		Interface: "log-observe", Count: 1, LastSeen: time.Unix(1593452243, 0).UTC(),
+	}, {
+		Snap: "foo", App: "bar", Backend: "apparmor", Operation: "open", Target: "/dev/video0",
+		Interface: "camera", Count: 2, LastSeen: time.Unix(1593452241, 0).UTC(),
+	}, {
+		Snap: "foo", App: "bar", Backend: "apparmor", Operation: "open", Target: "/etc/unknown",
+		Count: 1, LastSeen: time.Unix(1593452244, 0).UTC(),
+	}, {
+		Snap: "foo", App: "bar", Backend: "apparmor", Operation: "capable", Target: "sys_time",
+		Interface: "time-control", Count: 1, LastSeen: time.Unix(1593452242, 0).UTC(),
+	}})
+
+	summaries, err = denials.Collect(strings.NewReader(journal), "baz")
+	c.Assert(err, IsNil)
+	c.Assert(summaries, HasLen, 1)
+	c.Check(summaries[0].Snap, Equals, "baz")
+
+	summaries, err = denials.Collect(strings.NewReader(""), "")
+	c.Assert(err, IsNil)
+	c.Check(summaries, HasLen, 0)
+}
@@ -0,0 +1,165 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// pathInterfaces maps the paths denied by apparmor to the interface that
+// grants access to them. The first match wins.
+var pathInterfaces = []struct {
+	path  *regexp.Regexp
+	iface string
+}{
+	{regexp.MustCompile(`^/dev/video[0-9]+$`), "camera"},
+	{regexp.MustCompile(`^/dev/snd/`), "alsa"},
+	{regexp.MustCompile(`^/dev/tty(S|USB|ACM)[0-9]+$`), "serial-port"},
+	{regexp.MustCompile(`^/dev/input/js[0-9]+$`), "joystick"},
+	{regexp.MustCompile(`^/dev/bus/usb/`), "raw-usb"},
+	{regexp.MustCompile(`^/dev/i2c-[0-9]+$`), "i2c"},
+	{regexp.MustCompile(`^/dev/hidraw[0-9]+$`), "hidraw"},
+	{regexp.MustCompile(`^/dev/dri/`), "opengl"},
+	{regexp.MustCompile(`^/dev/kvm$`), "kvm"},
+	{regexp.MustCompile(`^/dev/fuse$`), "fuse-support"},
+	{regexp.MustCompile(`^/dev/tpm[0-9]+$`), "tpm"},
+	{regexp.MustCompile(`^/dev/gpiomem$`), "gpio-memory-control"},
+	{regexp.MustCompile(`^/dev/(net/tun|rfkill)$`), "network-control"},
+	{regexp.MustCompile(`^/sys/class/gpio/`), "gpio"},
+	{regexp.MustCompile(`^/(sys/class/net|proc/[0-9]+/net|proc/net)/`), "network-observe"},
+	{regexp.MustCompile(`^/proc/([0-9]+/)?(mounts|mountinfo|swaps)$`), "mount-observe"},
+	{regexp.MustCompile(`^/(run/udev/data|sys/devices|sys/bus|sys/class)/`), "hardware-observe"},
+	{regexp.MustCompile(`^/var/log/`), "log-observe"},
+	{regexp.MustCompile(`^/run/user/[0-9]+/pulse/`), "pulseaudio"},
+	{regexp.MustCompile(`^/run/user/[0-9]+/wayland-`), "wayland"},
+	{regexp.MustCompile(`^/tmp/\.X11-unix/`), "x11"},
+	{regexp.MustCompile(`^/(media|run/media|mnt)/`), "removable-media"},
+	{regexp.MustCompile(`^/(home/[^/]+|root)/`), "home"},
+}
+
+// capabilityInterfaces maps the capabilities denied by apparmor to the
+// interface that grants them.
+var capabilityInterfaces = map[string]string{
+	"net_admin":        "network-control",
+	"net_raw":          "network-control",
+	"net_bind_service": "network-bind",
+	"sys_time":         "time-control",
+	"sys_module":       "kernel-module-control",
+	"sys_ptrace":       "system-observe",
+	"sys_nice":         "process-control",
+	"kill":             "process-control",
+}
+
+// networkInterfaces maps the socket families denied by apparmor to the
+// interface that grants them.
+var networkInterfaces = map[string]string{
+	"inet":      "network",
+	"inet6":     "network",
+	"packet":    "network-control",
+	"bluetooth": "bluetooth-control",
+}
+
+// dbusInterfaces maps the D-Bus names denied by apparmor to the interface
+// that grants talking to them.
+var dbusInterfaces = map[string]string{
+	"org.freedesktop.NetworkManager": "network-manager",
+	"org.freedesktop.ModemManager1":  "modem-manager",
+	"org.freedesktop.timedate1":      "time-control",
+	"org.freedesktop.hostname1":      "hostname-control",
+	"org.freedesktop.UPower":         "upower-observe",
+	"org.freedesktop.UDisks2":        "udisks2",
+	"org.freedesktop.PackageKit":     "packagekit-control",
+	"org.freedesktop.Notifications":  "desktop",
+	"org.bluez":                      "bluez",
+}
+
+// syscallNames maps the numbers of the system calls known to be granted by
+// an interface to their names.
+var syscallNames = map[int]string{
+	unix.SYS_PTRACE:             "ptrace",
+	unix.SYS_SETPRIORITY:        "setpriority",
+	unix.SYS_SCHED_SETSCHEDULER: "sched_setscheduler",
+	unix.SYS_INIT_MODULE:        "init_module",
+	unix.SYS_FINIT_MODULE:       "finit_module",
+	unix.SYS_DELETE_MODULE:      "delete_module",
+	unix.SYS_SETHOSTNAME:        "sethostname",
+	unix.SYS_SETTIMEOFDAY:       "settimeofday",
+	unix.SYS_CLOCK_SETTIME:      "clock_settime",
+	unix.SYS_ADJTIMEX:           "adjtimex",
+	unix.SYS_SETNS:              "setns",
+	unix.SYS_UNSHARE:            "unshare",
+	unix.SYS_QUOTACTL:           "quotactl",
+	unix.SYS_BIND:               "bind",
+}
+
+// syscallInterfaces maps the system calls denied by seccomp to the
+// interface that grants them.
+var syscallInterfaces = map[string]string{
+	"ptrace":             "system-observe",
+	"setpriority":        "process-control",
+	"sched_setscheduler": "process-control",
+	"init_module":        "kernel-module-control",
+	"finit_module":       "kernel-module-control",
+	"delete_module":      "kernel-module-control",
+	"sethostname":        "hostname-control",
+	"settimeofday":       "time-control",
+	"clock_settime":      "time-control",
+	"adjtimex":           "time-control",
+	"setns":              "network-control",
+	"unshare":            "network-control",
+	"quotactl":           "mount-observe",
+	"bind":               "network-bind",
+}
+
+// SuggestInterface returns the name of the interface that would grant the
+// denied access, or an empty string if there is no known one.
+func SuggestInterface(d *Denial) string {
+	if d.Backend == BackendSecComp {
+		return syscallInterfaces[d.Target]
+	}
+	switch {
+	case d.Operation == "capable":
+		return capabilityInterfaces[d.Target]
+	case strings.HasPrefix(d.Operation, "dbus_"):
+		name := strings.Fields(d.Target)
+		if len(name) > 0 {
+			return dbusInterfaces[name[0]]
+		}
+		return ""
+	}
+	family := strings.Fields(d.Target)
+	if len(family) > 0 {
+		if iface, ok := networkInterfaces[family[0]]; ok {
+			if iface == "network" && (d.Operation == "bind" || d.Operation == "listen") {
+				return "network-bind"
+			}
+			return iface
+		}
+	}
+	for _, pi := range pathInterfaces {
+		if pi.path.MatchString(d.Target) {
+			return pi.iface
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seedwriter
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// ManifestSnap records the exact snap revision that went into a seed.
+type ManifestSnap struct {
+	Name     string        `yaml:"name"`
+	Revision snap.Revision `yaml:"revision"`
+	Channel  string        `yaml:"channel,omitempty"`
+	// Sha3_384 is the hex encoded SHA3-384 digest of the snap file.
+	Sha3_384 string `yaml:"sha3-384"`
+}
+
+// Manifest lists the snaps of a seed with their revisions and
+// digests, it can be used to audit the seed or to reproduce it.
+type Manifest struct {
+	Snaps []*ManifestSnap `yaml:"snaps"`
+}
+
+// ReadManifest reads and validates a seed manifest from the given file.
+func ReadManifest(fn string) (*Manifest, error) {
+	errPrefix := "cannot read seed manifest"
+
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", errPrefix, err)
+	}
+
+	var m Manifest
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: cannot unmarshal %q: %s", errPrefix, data, err)
+	}
+
+	seen := make(map[string]bool, len(m.Snaps))
+	for _, sn := range m.Snaps {
+		if sn == nil {
+			return nil, fmt.Errorf("%s: empty element in manifest", errPrefix)
+		}
+		if err := naming.ValidateSnap(sn.Name); err != nil {
+			return nil, fmt.Errorf("%s: %v", errPrefix, err)
+		}
+		if sn.Revision.Unset() {
+			return nil, fmt.Errorf("%s: revision for %q must be set", errPrefix, sn.Name)
+		}
+		if sn.Channel != "" {
+			if _, err := channel.Parse(sn.Channel, ""); err != nil {
+				return nil, fmt.Errorf("%s: %v", errPrefix, err)
+			}
+		}
+		if sn.Sha3_384 == "" {
+			return nil, fmt.Errorf("%s: sha3-384 for %q cannot be empty", errPrefix, sn.Name)
+		}
+		if seen[sn.Name] {
+			return nil, fmt.Errorf("%s: snap name %q must be unique", errPrefix, sn.Name)
+		}
+		seen[sn.Name] = true
+	}
+
+	return &m, nil
+}
+
+// Write writes the manifest to the given file.
+func (m *Manifest) Write(fn string) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(fn, data, 0644, 0)
+}
+
+// Snap returns the manifest entry for the given snap name or nil.
+func (m *Manifest) Snap(name string) *ManifestSnap {
+	for _, sn := range m.Snaps {
+		if sn.Name == name {
+			return sn
+		}
+	}
+	return nil
+}
+
+// Check verifies that the other manifest lists exactly the same
+// snaps with the same revisions and digests as this one.
+func (m *Manifest) Check(other *Manifest) error {
+	for _, sn := range m.Snaps {
+		osn := other.Snap(sn.Name)
+		if osn == nil {
+			return fmt.Errorf("snap %q is missing", sn.Name)
+		}
+		if osn.Revision != sn.Revision {
+			return fmt.Errorf("snap %q has revision %s instead of %s", sn.Name, osn.Revision, sn.Revision)
+		}
+		if osn.Sha3_384 != sn.Sha3_384 {
+			return fmt.Errorf("snap %q (%s) has digest %s instead of %s", sn.Name, sn.Revision, osn.Sha3_384, sn.Sha3_384)
+		}
+	}
+	for _, osn := range other.Snaps {
+		if m.Snap(osn.Name) == nil {
+			return fmt.Errorf("snap %q is not expected", osn.Name)
+		}
+	}
+	return nil
+}
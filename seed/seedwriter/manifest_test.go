@@ -0,0 +1,130 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seedwriter_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/seed/seedwriter"
+	"github.com/snapcore/snapd/snap"
+)
+
+type manifestSuite struct{}
+
+var _ = Suite(&manifestSuite{})
+
+var sampleManifest = &seedwriter.Manifest{
+	Snaps: []*seedwriter.ManifestSnap{
+		{Name: "core18", Revision: snap.R(18), Channel: "stable", Sha3_384: "aaaa"},
+		{Name: "local", Revision: snap.R(-1), Sha3_384: "bbbb"},
+	},
+}
+
+func (s *manifestSuite) TestWriteReadRoundtrip(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.manifest")
+	err := sampleManifest.Write(fn)
+	c.Assert(err, IsNil)
+
+	data, err := ioutil.ReadFile(fn)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `snaps:
+- name: core18
+  revision: "18"
+  channel: stable
+  sha3-384: aaaa
+- name: local
+  revision: x1
+  sha3-384: bbbb
+`)
+
+	m, err := seedwriter.ReadManifest(fn)
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, sampleManifest)
+	c.Check(m.Snap("core18").Revision, Equals, snap.R(18))
+	c.Check(m.Snap("other"), IsNil)
+}
+
+func (s *manifestSuite) TestReadManifestErrors(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.manifest")
+
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"snaps:\n- name: foo\n  revision: 1\n  sha3-384: a\n  extra: 1\n", `(?s)cannot read seed manifest: cannot unmarshal .*field extra not found.*`},
+		{"snaps:\n-\n", `cannot read seed manifest: empty element in manifest`},
+		{"snaps:\n- name: Foo\n  revision: 1\n  sha3-384: a\n", `cannot read seed manifest: invalid snap name: "Foo"`},
+		{"snaps:\n- name: foo\n  sha3-384: a\n", `cannot read seed manifest: revision for "foo" must be set`},
+		{"snaps:\n- name: foo\n  revision: 1\n  channel: a/b/c/d\n  sha3-384: a\n", `cannot read seed manifest: channel name has too many components: a/b/c/d`},
+		{"snaps:\n- name: foo\n  revision: 1\n", `cannot read seed manifest: sha3-384 for "foo" cannot be empty`},
+		{"snaps:\n- name: foo\n  revision: 1\n  sha3-384: a\n- name: foo\n  revision: 2\n  sha3-384: b\n", `cannot read seed manifest: snap name "foo" must be unique`},
+	}
+
+	for _, t := range tests {
+		err := ioutil.WriteFile(fn, []byte(t.content), 0644)
+		c.Assert(err, IsNil)
+		_, err = seedwriter.ReadManifest(fn)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.content))
+	}
+
+	_, err := seedwriter.ReadManifest(filepath.Join(c.MkDir(), "missing"))
+	c.Check(err, ErrorMatches, `cannot read seed manifest: open .*: no such file or directory`)
+}
+
+func (s *manifestSuite) TestCheck(c *C) {
+	same := &seedwriter.Manifest{
+		Snaps: []*seedwriter.ManifestSnap{
+			// channel and order do not matter
+			{Name: "local", Revision: snap.R(-1), Sha3_384: "bbbb"},
+			{Name: "core18", Revision: snap.R(18), Channel: "latest/stable", Sha3_384: "aaaa"},
+		},
+	}
+	c.Check(sampleManifest.Check(same), IsNil)
+
+	tests := []struct {
+		snaps []*seedwriter.ManifestSnap
+		err   string
+	}{
+		{[]*seedwriter.ManifestSnap{
+			{Name: "core18", Revision: snap.R(18), Sha3_384: "aaaa"},
+		}, `snap "local" is missing`},
+		{[]*seedwriter.ManifestSnap{
+			{Name: "core18", Revision: snap.R(19), Sha3_384: "aaaa"},
+			{Name: "local", Revision: snap.R(-1), Sha3_384: "bbbb"},
+		}, `snap "core18" has revision 19 instead of 18`},
+		{[]*seedwriter.ManifestSnap{
+			{Name: "core18", Revision: snap.R(18), Sha3_384: "aaaa"},
+			{Name: "local", Revision: snap.R(-1), Sha3_384: "cccc"},
+		}, `snap "local" \(x1\) has digest cccc instead of bbbb`},
+		{[]*seedwriter.ManifestSnap{
+			{Name: "core18", Revision: snap.R(18), Sha3_384: "aaaa"},
+			{Name: "local", Revision: snap.R(-1), Sha3_384: "bbbb"},
+			{Name: "other", Revision: snap.R(1), Sha3_384: "dddd"},
+		}, `snap "other" is not expected`},
+	}
+
+	for _, t := range tests {
+		err := sampleManifest.Check(&seedwriter.Manifest{Snaps: t.snaps})
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
@@ -21,6 +21,8 @@
 package seedwriter
 
 import (
+	"crypto"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -1125,3 +1127,36 @@ func (w *Writer) UnassertedSnaps() ([]naming.SnapRef, error) {
 	}
 	return res, nil
 }
+
+// Manifest returns a manifest recording the revision, channel and
+// digest of all the snaps in the seed.
+// It can be invoked only after Downloaded returns complete ==
+// true.
+func (w *Writer) Manifest() (*Manifest, error) {
+	if err := w.checkSnapsAccessor(); err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	addSnaps := func(snaps []*SeedSnap) error {
+		for _, sn := range snaps {
+			dgst, _, err := osutil.FileDigest(sn.Path, crypto.SHA3_384)
+			if err != nil {
+				return fmt.Errorf("cannot compute digest of snap %q: %v", sn.SnapName(), err)
+			}
+			m.Snaps = append(m.Snaps, &ManifestSnap{
+				Name:     sn.SnapName(),
+				Revision: sn.Info.Revision,
+				Channel:  sn.Channel,
+				Sha3_384: hex.EncodeToString(dgst),
+			})
+		}
+		return nil
+	}
+	if err := addSnaps(w.snapsFromModel); err != nil {
+		return nil, err
+	}
+	if err := addSnaps(w.extraSnaps); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,342 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// AttributeSchema describes the attributes of a plug or slot, keyed by
+// attribute name. It is declared in snap.yaml under the attribute-schema
+// key of the plug or slot, e.g.:
+//
+//   plugs:
+//     data:
+//       interface: content
+//       attribute-schema:
+//         target:
+//           type: string
+//           required: true
+//         mode:
+//           type: string
+//           enum: [ro, rw]
+//         port: int
+//
+// Attributes not described by the schema are not checked.
+type AttributeSchema map[string]*AttributeSpec
+
+// AttributeSpec describes the value of a single attribute.
+type AttributeSpec struct {
+	// Type is one of "string", "int", "bool", "list" or "map".
+	Type string
+	// Required attributes must be set statically or by the prepare hooks.
+	Required bool
+	// Enum lists the acceptable values.
+	Enum []interface{}
+	// Pattern is a regular expression that string values must match.
+	Pattern string
+	// Minimum and Maximum bound int values.
+	Minimum *int64
+	Maximum *int64
+	// Items is the type of the elements of list values.
+	Items string
+
+	pattern *regexp.Regexp
+}
+
+var attributeTypes = map[string]bool{
+	"string": true,
+	"int":    true,
+	"bool":   true,
+	"list":   true,
+	"map":    true,
+}
+
+func parseAttributeType(v interface{}) (string, error) {
+	typ, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("type must be a string (found %T)", v)
+	}
+	if !attributeTypes[typ] {
+		return "", fmt.Errorf("unknown type %q", typ)
+	}
+	return typ, nil
+}
+
+func parseAttributeSpec(data interface{}) (*AttributeSpec, error) {
+	switch x := data.(type) {
+	case string:
+		// short form, just the type
+		typ, err := parseAttributeType(x)
+		if err != nil {
+			return nil, err
+		}
+		return &AttributeSpec{Type: typ}, nil
+	case map[string]interface{}:
+		spec := &AttributeSpec{}
+		for key, value := range x {
+			switch key {
+			case "type":
+				typ, err := parseAttributeType(value)
+				if err != nil {
+					return nil, err
+				}
+				spec.Type = typ
+			case "required":
+				required, ok := value.(bool)
+				if !ok {
+					return nil, fmt.Errorf("required must be true or false (found %T)", value)
+				}
+				spec.Required = required
+			case "enum":
+				enum, ok := value.([]interface{})
+				if !ok || len(enum) == 0 {
+					return nil, fmt.Errorf("enum must be a non-empty list")
+				}
+				spec.Enum = enum
+			case "pattern":
+				pattern, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("pattern must be a string (found %T)", value)
+				}
+				re, err := regexp.Compile("^(?:" + pattern + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+				}
+				spec.Pattern = pattern
+				spec.pattern = re
+			case "minimum", "maximum":
+				n, ok := value.(int64)
+				if !ok {
+					return nil, fmt.Errorf("%s must be an integer (found %T)", key, value)
+				}
+				if key == "minimum" {
+					spec.Minimum = &n
+				} else {
+					spec.Maximum = &n
+				}
+			case "items":
+				typ, err := parseAttributeType(value)
+				if err != nil {
+					return nil, fmt.Errorf("items: %v", err)
+				}
+				spec.Items = typ
+			default:
+				return nil, fmt.Errorf("unknown key %q", key)
+			}
+		}
+		if spec.Type == "" {
+			return nil, fmt.Errorf("type must be specified")
+		}
+		if spec.pattern != nil && spec.Type != "string" {
+			return nil, fmt.Errorf("pattern can only be used with string attributes")
+		}
+		if (spec.Minimum != nil || spec.Maximum != nil) && spec.Type != "int" {
+			return nil, fmt.Errorf("minimum and maximum can only be used with int attributes")
+		}
+		if spec.Items != "" && spec.Type != "list" {
+			return nil, fmt.Errorf("items can only be used with list attributes")
+		}
+		for _, v := range spec.Enum {
+			if err := spec.checkType(v); err != nil {
+				return nil, fmt.Errorf("invalid enum value %v: %v", v, err)
+			}
+		}
+		return spec, nil
+	default:
+		return nil, fmt.Errorf("must be a type name or a map (found %T)", data)
+	}
+}
+
+// parseAttributeSchema parses the normalized attribute-schema value of a
+// plug or slot.
+func parseAttributeSchema(data interface{}) (AttributeSchema, error) {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a map of attribute names (found %T)", data)
+	}
+	schema := make(AttributeSchema, len(m))
+	for name, specData := range m {
+		spec, err := parseAttributeSpec(specData)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %v", name, err)
+		}
+		schema[name] = spec
+	}
+	return schema, nil
+}
+
+func asInt64(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int64:
+		return x, true
+	case float64:
+		if x == math.Trunc(x) {
+			return int64(x), true
+		}
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func checkAttributeType(typ string, v interface{}) error {
+	ok := false
+	switch typ {
+	case "string":
+		_, ok = v.(string)
+	case "int":
+		_, ok = asInt64(v)
+	case "bool":
+		_, ok = v.(bool)
+	case "list":
+		_, ok = v.([]interface{})
+	case "map":
+		_, ok = v.(map[string]interface{})
+	}
+	if !ok {
+		return fmt.Errorf("must be of type %s", typ)
+	}
+	return nil
+}
+
+func (spec *AttributeSpec) checkType(v interface{}) error {
+	if err := checkAttributeType(spec.Type, v); err != nil {
+		return err
+	}
+	if spec.Items != "" {
+		for i, el := range v.([]interface{}) {
+			if err := checkAttributeType(spec.Items, el); err != nil {
+				return fmt.Errorf("element %d %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sameValue compares attribute values, integers are compared by value
+// whatever their representation, also inside lists and maps.
+func sameValue(a, b interface{}) bool {
+	if na, ok := asInt64(a); ok {
+		nb, ok := asInt64(b)
+		return ok && na == nb
+	}
+	switch a := a.(type) {
+	case []interface{}:
+		lb, ok := b.([]interface{})
+		if !ok || len(a) != len(lb) {
+			return false
+		}
+		for i := range a {
+			if !sameValue(a[i], lb[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		mb, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(mb) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := mb[k]
+			if !ok || !sameValue(va, vb) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// check verifies that the value conforms to the spec.
+func (spec *AttributeSpec) check(v interface{}) error {
+	if err := spec.checkType(v); err != nil {
+		return err
+	}
+	if len(spec.Enum) > 0 {
+		found := false
+		for _, e := range spec.Enum {
+			if sameValue(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("must be one of %v", spec.Enum)
+		}
+	}
+	if spec.pattern != nil && !spec.pattern.MatchString(v.(string)) {
+		return fmt.Errorf("must match %q", spec.Pattern)
+	}
+	if n, ok := asInt64(v); ok && spec.Type == "int" {
+		if spec.Minimum != nil && n < *spec.Minimum {
+			return fmt.Errorf("must be at least %d", *spec.Minimum)
+		}
+		if spec.Maximum != nil && n > *spec.Maximum {
+			return fmt.Errorf("must be at most %d", *spec.Maximum)
+		}
+	}
+	return nil
+}
+
+// ValidateAttr checks the value of a single attribute against the schema.
+func (schema AttributeSchema) ValidateAttr(name string, value interface{}) error {
+	spec := schema[name]
+	if spec == nil {
+		return nil
+	}
+	if err := spec.check(value); err != nil {
+		return fmt.Errorf("attribute %q %v", name, err)
+	}
+	return nil
+}
+
+// ValidateAttrs checks the given attributes against the schema. Unless
+// partial is set, all the required attributes must be present.
+func (schema AttributeSchema) ValidateAttrs(attrs map[string]interface{}, partial bool) error {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := attrs[name]
+		if !ok {
+			if schema[name].Required && !partial {
+				return fmt.Errorf("required attribute %q is not set", name)
+			}
+			continue
+		}
+		if err := schema.ValidateAttr(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
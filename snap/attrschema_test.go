@@ -0,0 +1,250 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap_test
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type attrSchemaSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&attrSchemaSuite{})
+
+func (s *attrSchemaSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.BaseTest.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+}
+
+func (s *attrSchemaSuite) TearDownTest(c *C) {
+	s.BaseTest.TearDownTest(c)
+}
+
+func (s *attrSchemaSuite) plugSchema(c *C, schemaYaml string) snap.AttributeSchema {
+	info, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+plugs:
+  plug:
+    interface: content
+    attribute-schema:
+` + schemaYaml))
+	c.Assert(err, IsNil)
+	return info.Plugs["plug"].AttributeSchema
+}
+
+func (s *attrSchemaSuite) TestParse(c *C) {
+	schema := s.plugSchema(c, `
+      target:
+        type: string
+        required: true
+        pattern: /[a-z]+
+      mode:
+        type: string
+        enum: [ro, rw]
+      port:
+        type: int
+        minimum: 1
+        maximum: 65535
+      paths:
+        type: list
+        items: string
+      enabled: bool
+`)
+	c.Assert(schema, HasLen, 5)
+	c.Check(schema["target"].Type, Equals, "string")
+	c.Check(schema["target"].Required, Equals, true)
+	c.Check(schema["target"].Pattern, Equals, "/[a-z]+")
+	c.Check(schema["mode"].Enum, DeepEquals, []interface{}{"ro", "rw"})
+	c.Check(*schema["port"].Minimum, Equals, int64(1))
+	c.Check(*schema["port"].Maximum, Equals, int64(65535))
+	c.Check(schema["paths"].Items, Equals, "string")
+	c.Check(schema["enabled"].Type, Equals, "bool")
+	c.Check(schema["enabled"].Required, Equals, false)
+}
+
+func (s *attrSchemaSuite) TestParseErrors(c *C) {
+	for _, t := range []struct {
+		schema string
+		err    string
+	}{
+		{`[]`, `must be a map of attribute names \(found \[\]interface {}\)`},
+		{`{a: float}`, `attribute "a": unknown type "float"`},
+		{`{a: 1}`, `attribute "a": must be a type name or a map \(found int64\)`},
+		{`{a: {required: true}}`, `attribute "a": type must be specified`},
+		{`{a: {type: string, required: 1}}`, `attribute "a": required must be true or false \(found int64\)`},
+		{`{a: {type: string, enum: []}}`, `attribute "a": enum must be a non-empty list`},
+		{`{a: {type: string, enum: [1]}}`, `attribute "a": invalid enum value 1: must be of type string`},
+		{`{a: {type: string, pattern: "("}}`, `attribute "a": invalid pattern "\(": .*`},
+		{`{a: {type: int, pattern: "x"}}`, `attribute "a": pattern can only be used with string attributes`},
+		{`{a: {type: string, minimum: 1}}`, `attribute "a": minimum and maximum can only be used with int attributes`},
+		{`{a: {type: int, maximum: x}}`, `attribute "a": maximum must be an integer \(found string\)`},
+		{`{a: {type: string, items: string}}`, `attribute "a": items can only be used with list attributes`},
+		{`{a: {type: list, items: foo}}`, `attribute "a": items: unknown type "foo"`},
+		{`{a: {type: string, default: x}}`, `attribute "a": unknown key "default"`},
+	} {
+		_, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+plugs:
+  plug:
+    interface: content
+    attribute-schema: ` + t.schema))
+		c.Check(err, ErrorMatches, `attribute schema of plug "plug": `+t.err, Commentf(t.schema))
+	}
+}
+
+func (s *attrSchemaSuite) TestValidateAttrs(c *C) {
+	schema := s.plugSchema(c, `
+      target:
+        type: string
+        required: true
+        pattern: /[a-z]+
+      mode:
+        type: string
+        enum: [ro, rw]
+      port:
+        type: int
+        minimum: 1
+        maximum: 65535
+      paths:
+        type: list
+        items: string
+      options: map
+`)
+	valid := map[string]interface{}{
+		"target":  "/srv",
+		"mode":    "ro",
+		"port":    int64(8080),
+		"paths":   []interface{}{"a", "b"},
+		"options": map[string]interface{}{"a": "b"},
+		"other":   42,
+	}
+	c.Check(schema.ValidateAttrs(valid, false), IsNil)
+	c.Check(schema.ValidateAttrs(map[string]interface{}{}, true), IsNil)
+
+	for _, t := range []struct {
+		attrs map[string]interface{}
+		err   string
+	}{
+		{map[string]interface{}{}, `required attribute "target" is not set`},
+		{map[string]interface{}{"target": 1}, `attribute "target" must be of type string`},
+		{map[string]interface{}{"target": "srv"}, `attribute "target" must match "/\[a-z\]\+"`},
+		{map[string]interface{}{"target": "/srv", "mode": "wo"}, `attribute "mode" must be one of \[ro rw\]`},
+		{map[string]interface{}{"target": "/srv", "port": 0}, `attribute "port" must be at least 1`},
+		{map[string]interface{}{"target": "/srv", "port": 70000}, `attribute "port" must be at most 65535`},
+		{map[string]interface{}{"target": "/srv", "port": 1.5}, `attribute "port" must be of type int`},
+		{map[string]interface{}{"target": "/srv", "paths": "a"}, `attribute "paths" must be of type list`},
+		{map[string]interface{}{"target": "/srv", "paths": []interface{}{"a", 1}}, `attribute "paths" element 1 must be of type string`},
+		{map[string]interface{}{"target": "/srv", "options": []interface{}{}}, `attribute "options" must be of type map`},
+	} {
+		c.Check(schema.ValidateAttrs(t.attrs, false), ErrorMatches, t.err, Commentf("%v", t.attrs))
+	}
+}
+
+func (s *attrSchemaSuite) TestValidateAttrIntegers(c *C) {
+	schema := s.plugSchema(c, `
+      port:
+        type: int
+        enum: [80, 443]
+`)
+	for _, v := range []interface{}{443, int64(443), float64(443), json.Number("443")} {
+		c.Check(schema.ValidateAttr("port", v), IsNil, Commentf("%T", v))
+	}
+	c.Check(schema.ValidateAttr("port", json.Number("8080")), ErrorMatches, `attribute "port" must be one of \[80 443\]`)
+	c.Check(schema.ValidateAttr("port", json.Number("4.5")), ErrorMatches, `attribute "port" must be of type int`)
+	// attributes without schema are not checked
+	c.Check(schema.ValidateAttr("other", "x"), IsNil)
+}
+
+func (s *attrSchemaSuite) TestValidateAttrListAndMapEnum(c *C) {
+	schema := s.plugSchema(c, `
+      ports:
+        type: list
+        items: int
+        enum: [[80, 443], [8080]]
+      options:
+        type: map
+        enum: [{mode: ro}, {mode: rw, size: 1}]
+`)
+	for _, v := range []interface{}{
+		[]interface{}{80, 443},
+		[]interface{}{json.Number("80"), int64(443)},
+		[]interface{}{float64(8080)},
+	} {
+		c.Check(schema.ValidateAttr("ports", v), IsNil, Commentf("%v", v))
+	}
+	for _, v := range []interface{}{
+		[]interface{}{443, 80},
+		[]interface{}{80},
+		[]interface{}{},
+	} {
+		c.Check(schema.ValidateAttr("ports", v), ErrorMatches, `attribute "ports" must be one of .*`, Commentf("%v", v))
+	}
+
+	c.Check(schema.ValidateAttr("options", map[string]interface{}{"mode": "ro"}), IsNil)
+	c.Check(schema.ValidateAttr("options", map[string]interface{}{"mode": "rw", "size": json.Number("1")}), IsNil)
+	c.Check(schema.ValidateAttr("options", map[string]interface{}{"mode": "rw"}), ErrorMatches, `attribute "options" must be one of .*`)
+	c.Check(schema.ValidateAttr("options", map[string]interface{}{"mode": "wo"}), ErrorMatches, `attribute "options" must be one of .*`)
+}
+
+func (s *attrSchemaSuite) TestNilSchema(c *C) {
+	var schema snap.AttributeSchema
+	c.Check(schema.ValidateAttrs(map[string]interface{}{"a": 1}, false), IsNil)
+	c.Check(schema.ValidateAttr("a", 1), IsNil)
+}
+
+func (s *attrSchemaSuite) TestSlotSchemaAndValidate(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+version: 1
+slots:
+  slot:
+    interface: content
+    read: 1
+    attribute-schema:
+      read: list
+`))
+	c.Assert(err, IsNil)
+	c.Check(info.Slots["slot"].AttributeSchema["read"].Type, Equals, "list")
+	c.Check(info.Slots["slot"].Attrs, DeepEquals, map[string]interface{}{"read": int64(1)})
+	c.Check(snap.Validate(info), ErrorMatches, `invalid slot "slot": attribute "read" must be of type list`)
+}
+
+func (s *attrSchemaSuite) TestValidateRequiredNotEnforcedStatically(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+version: 1
+plugs:
+  plug:
+    interface: content
+    attribute-schema:
+      target:
+        type: string
+        required: true
+`))
+	c.Assert(err, IsNil)
+	// the prepare-plug hook may still provide it
+	c.Check(snap.Validate(info), IsNil)
+}
@@ -606,9 +606,10 @@ type DownloadInfo struct {
 	Sha3_384 string `json:"sha3-384,omitempty"`
 
 	// The server can include information about available deltas for a given
-	// snap at a specific revision during refresh. Currently during refresh the
-	// server will provide single matching deltas only, from the clients
-	// revision to the target revision when available, per requested format.
+	// snap at a specific revision during refresh. During refresh the server
+	// will provide matching deltas from the clients revision to the target
+	// revision when available, per requested format, possibly as a chain
+	// of deltas through intermediate revisions.
 	Deltas []DeltaInfo `json:"deltas,omitempty"`
 }
 
@@ -637,6 +638,10 @@ type PlugInfo struct {
 	Label     string
 	Apps      map[string]*AppInfo
 	Hooks     map[string]*HookInfo
+
+	// AttributeSchema describes the attributes of the plug, if
+	// declared in snap.yaml.
+	AttributeSchema AttributeSchema
 }
 
 func lookupAttr(attrs map[string]interface{}, path string) (interface{}, bool) {
@@ -781,6 +786,10 @@ type SlotInfo struct {
 	// slot may be made available if the device is reinserted.
 	// It's empty for regular slots.
 	HotplugKey HotplugKey
+
+	// AttributeSchema describes the attributes of the slot, if
+	// declared in snap.yaml.
+	AttributeSchema AttributeSchema
 }
 
 // SocketInfo provides information on application sockets.
@@ -799,6 +808,26 @@ type TimerInfo struct {
 	Timer string
 }
 
+// FailureAction is the type for the "on-failure-action:" of a snap app,
+// what snapd does when a service keeps failing after a refresh.
+type FailureAction string
+
+const (
+	// FailureActionNone leaves a failing service to systemd.
+	FailureActionNone FailureAction = "none"
+	// FailureActionRevert reverts the snap to its previous revision.
+	FailureActionRevert FailureAction = "revert"
+)
+
+// Validate ensures that the FailureAction has a valid value.
+func (fa FailureAction) Validate() error {
+	switch fa {
+	case "", FailureActionNone, FailureActionRevert:
+		return nil
+	}
+	return fmt.Errorf(`"on-failure-action" field contains invalid value %q`, fa)
+}
+
 // StopModeType is the type for the "stop-mode:" of a snap app
 type StopModeType string
 
@@ -851,6 +880,14 @@ type AppInfo struct {
 	RefreshMode     string
 	StopMode        StopModeType
 
+	// supervision of failing services: systemd start limits,
+	// escalating restart back-off and what snapd does on top
+	StartLimitBurst    int
+	StartLimitInterval timeout.Timeout
+	RestartSteps       int
+	RestartMaxDelay    timeout.Timeout
+	OnFailureAction    FailureAction
+
 	// TODO: this should go away once we have more plumbing and can change
 	// things vs refactor
 	// https://github.com/snapcore/snapd/pull/794#discussion_r58688496
@@ -868,6 +905,18 @@ type AppInfo struct {
 	After  []string
 	Before []string
 
+	// list of plugs of this service whose connected slots are provided
+	// by services of other snaps that this service wants and will start
+	// after
+	AfterPlugs []string
+
+	// environment variables and arguments of this service that can be
+	// overridden through the services.<app>.env map and the
+	// services.<app>.args options of the snap, an argument ending in
+	// "=" allows any value for it
+	ConfigurableEnv  []string
+	ConfigurableArgs []string
+
 	Timer *TimerInfo
 
 	Autostart string
@@ -90,6 +90,12 @@ type appYaml struct {
 	SlotNames    []string         `yaml:"slots,omitempty"`
 	PlugNames    []string         `yaml:"plugs,omitempty"`
 
+	StartLimitBurst    int             `yaml:"start-limit-burst,omitempty"`
+	StartLimitInterval timeout.Timeout `yaml:"start-limit-interval,omitempty"`
+	RestartSteps       int             `yaml:"restart-steps,omitempty"`
+	RestartMaxDelay    timeout.Timeout `yaml:"restart-max-delay,omitempty"`
+	OnFailureAction    FailureAction   `yaml:"on-failure-action,omitempty"`
+
 	BusName     string   `yaml:"bus-name,omitempty"`
 	ActivatesOn []string `yaml:"activates-on,omitempty"`
 	CommonID    string   `yaml:"common-id,omitempty"`
@@ -101,6 +107,11 @@ type appYaml struct {
 	After  []string `yaml:"after,omitempty"`
 	Before []string `yaml:"before,omitempty"`
 
+	AfterPlugs []string `yaml:"after-plugs,omitempty"`
+
+	ConfigurableEnv  []string `yaml:"configurable-env,omitempty"`
+	ConfigurableArgs []string `yaml:"configurable-args,omitempty"`
+
 	Timer string `yaml:"timer,omitempty"`
 
 	Autostart string `yaml:"autostart,omitempty"`
@@ -294,7 +305,7 @@ func infoSkeletonFromSnapYaml(y snapYaml) *Info {
 
 func setPlugsFromSnapYaml(y snapYaml, snap *Info) error {
 	for name, data := range y.Plugs {
-		iface, label, attrs, err := convertToSlotOrPlugData("plug", name, data)
+		iface, label, attrs, schema, err := convertToSlotOrPlugData("plug", name, data)
 		if err != nil {
 			return err
 		}
@@ -304,6 +315,8 @@ func setPlugsFromSnapYaml(y snapYaml, snap *Info) error {
 			Interface: iface,
 			Attrs:     attrs,
 			Label:     label,
+
+			AttributeSchema: schema,
 		}
 		if len(y.Apps) > 0 {
 			snap.Plugs[name].Apps = make(map[string]*AppInfo)
@@ -318,7 +331,7 @@ func setPlugsFromSnapYaml(y snapYaml, snap *Info) error {
 
 func setSlotsFromSnapYaml(y snapYaml, snap *Info) error {
 	for name, data := range y.Slots {
-		iface, label, attrs, err := convertToSlotOrPlugData("slot", name, data)
+		iface, label, attrs, schema, err := convertToSlotOrPlugData("slot", name, data)
 		if err != nil {
 			return err
 		}
@@ -328,6 +341,8 @@ func setSlotsFromSnapYaml(y snapYaml, snap *Info) error {
 			Interface: iface,
 			Attrs:     attrs,
 			Label:     label,
+
+			AttributeSchema: schema,
 		}
 		if len(y.Apps) > 0 {
 			snap.Slots[name].Apps = make(map[string]*AppInfo)
@@ -366,8 +381,18 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) error {
 			RefreshMode:     yApp.RefreshMode,
 			Before:          yApp.Before,
 			After:           yApp.After,
+			AfterPlugs:      yApp.AfterPlugs,
 			Autostart:       yApp.Autostart,
 			WatchdogTimeout: yApp.WatchdogTimeout,
+
+			StartLimitBurst:    yApp.StartLimitBurst,
+			StartLimitInterval: yApp.StartLimitInterval,
+			RestartSteps:       yApp.RestartSteps,
+			RestartMaxDelay:    yApp.RestartMaxDelay,
+			OnFailureAction:    yApp.OnFailureAction,
+
+			ConfigurableEnv:  yApp.ConfigurableEnv,
+			ConfigurableArgs: yApp.ConfigurableArgs,
 		}
 		if len(y.Plugs) > 0 || len(yApp.PlugNames) > 0 {
 			app.Plugs = make(map[string]*PlugInfo)
@@ -616,34 +641,34 @@ func bindImplicitHooks(snap *Info, strk *scopedTracker) {
 	}
 }
 
-func convertToSlotOrPlugData(plugOrSlot, name string, data interface{}) (iface, label string, attrs map[string]interface{}, err error) {
+func convertToSlotOrPlugData(plugOrSlot, name string, data interface{}) (iface, label string, attrs map[string]interface{}, schema AttributeSchema, err error) {
 	iface = name
 	switch data.(type) {
 	case string:
-		return data.(string), "", nil, nil
+		return data.(string), "", nil, nil, nil
 	case nil:
-		return name, "", nil, nil
+		return name, "", nil, nil, nil
 	case map[interface{}]interface{}:
 		for keyData, valueData := range data.(map[interface{}]interface{}) {
 			key, ok := keyData.(string)
 			if !ok {
 				err := fmt.Errorf("%s %q has attribute key that is not a string (found %T)",
 					plugOrSlot, name, keyData)
-				return "", "", nil, err
+				return "", "", nil, nil, err
 			}
 			if strings.HasPrefix(key, "$") {
 				err := fmt.Errorf("%s %q uses reserved attribute %q", plugOrSlot, name, key)
-				return "", "", nil, err
+				return "", "", nil, nil, err
 			}
 			switch key {
 			case "":
-				return "", "", nil, fmt.Errorf("%s %q has an empty attribute key", plugOrSlot, name)
+				return "", "", nil, nil, fmt.Errorf("%s %q has an empty attribute key", plugOrSlot, name)
 			case "interface":
 				value, ok := valueData.(string)
 				if !ok {
 					err := fmt.Errorf("interface name on %s %q is not a string (found %T)",
 						plugOrSlot, name, valueData)
-					return "", "", nil, err
+					return "", "", nil, nil, err
 				}
 				iface = value
 			case "label":
@@ -651,24 +676,32 @@ func convertToSlotOrPlugData(plugOrSlot, name string, data interface{}) (iface,
 				if !ok {
 					err := fmt.Errorf("label of %s %q is not a string (found %T)",
 						plugOrSlot, name, valueData)
-					return "", "", nil, err
+					return "", "", nil, nil, err
 				}
 				label = value
+			case "attribute-schema":
+				value, err := metautil.NormalizeValue(valueData)
+				if err == nil {
+					schema, err = parseAttributeSchema(value)
+				}
+				if err != nil {
+					return "", "", nil, nil, fmt.Errorf("attribute schema of %s %q: %v", plugOrSlot, name, err)
+				}
 			default:
 				if attrs == nil {
 					attrs = make(map[string]interface{})
 				}
 				value, err := metautil.NormalizeValue(valueData)
 				if err != nil {
-					return "", "", nil, fmt.Errorf("attribute %q of %s %q: %v", key, plugOrSlot, name, err)
+					return "", "", nil, nil, fmt.Errorf("attribute %q of %s %q: %v", key, plugOrSlot, name, err)
 				}
 				attrs[key] = value
 			}
 		}
-		return iface, label, attrs, nil
+		return iface, label, attrs, schema, nil
 	default:
 		err := fmt.Errorf("%s %q has malformed definition (found %T)", plugOrSlot, name, data)
-		return "", "", nil, err
+		return "", "", nil, nil, err
 	}
 }
 
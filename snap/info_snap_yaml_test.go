@@ -1773,6 +1773,37 @@ apps:
 	})
 }
 
+func (s *YamlSuite) TestSnapYamlAppAfterPlugs(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+   daemon: simple
+   plugs: [database]
+   after-plugs: [database]
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	c.Check(info.Apps["foo"].AfterPlugs, DeepEquals, []string{"database"})
+}
+
+func (s *YamlSuite) TestSnapYamlAppConfigurable(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+   daemon: simple
+   configurable-env: [LOG_LEVEL, DEBUG]
+   configurable-args: [--verbose, --log-format=]
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	c.Check(info.Apps["foo"].ConfigurableEnv, DeepEquals, []string{"LOG_LEVEL", "DEBUG"})
+	c.Check(info.Apps["foo"].ConfigurableArgs, DeepEquals, []string{"--verbose", "--log-format="})
+}
+
 func (s *YamlSuite) TestSnapYamlWatchdog(c *C) {
 	y := []byte(`
 name: foo
@@ -1943,6 +1974,31 @@ apps:
 	c.Check(app.RestartDelay, Equals, timeout.Timeout(12*time.Second))
 }
 
+func (s *YamlSuite) TestSnapYamlSupervision(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+  command: bin/foo
+  daemon: simple
+  restart-delay: 1s
+  restart-steps: 5
+  restart-max-delay: 2m
+  start-limit-burst: 10
+  start-limit-interval: 10m
+  on-failure-action: revert
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+	app := info.Apps["foo"]
+	c.Assert(app, NotNil)
+	c.Check(app.RestartSteps, Equals, 5)
+	c.Check(app.RestartMaxDelay, Equals, timeout.Timeout(2*time.Minute))
+	c.Check(app.StartLimitBurst, Equals, 10)
+	c.Check(app.StartLimitInterval, Equals, timeout.Timeout(10*time.Minute))
+	c.Check(app.OnFailureAction, Equals, snap.FailureActionRevert)
+}
+
 func (s *YamlSuite) TestSnapYamlSystemUsernamesParsing(c *C) {
 	y := []byte(`name: binary
 version: 1.0
@@ -37,6 +37,12 @@ import (
 // them through snap-confine (for classic confined snaps).
 const PreservedUnsafePrefix = "SNAP_SAVED_"
 
+// ConfiguredEnv is the variable the units of services set to the comma
+// separated names of the configurable environment variables they
+// override, the values of those take precedence over the ones from
+// snap.yaml.
+const ConfiguredEnv = "SNAP_CONFIGURED_ENV"
+
 // ExtendEnvForRun extends the given environment with what is is
 // required for snap-{confine,exec}, that means SNAP_{NAME,REVISION}
 // etc are all set.
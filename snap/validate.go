@@ -488,6 +488,10 @@ func plugsSlotsInterfacesNames(info *Info) error {
 		if err := ValidateInterfaceName(plug.Interface); err != nil {
 			return fmt.Errorf("invalid interface name %q for plug %q", plug.Interface, plugName)
 		}
+		// required attributes may still be provided by the prepare hooks
+		if err := plug.AttributeSchema.ValidateAttrs(plug.Attrs, true); err != nil {
+			return fmt.Errorf("invalid plug %q: %v", plugName, err)
+		}
 	}
 	for slotName, slot := range info.Slots {
 		if err := ValidateSlotName(slotName); err != nil {
@@ -496,6 +500,9 @@ func plugsSlotsInterfacesNames(info *Info) error {
 		if err := ValidateInterfaceName(slot.Interface); err != nil {
 			return fmt.Errorf("invalid interface name %q for slot %q", slot.Interface, slotName)
 		}
+		if err := slot.AttributeSchema.ValidateAttrs(slot.Attrs, true); err != nil {
+			return fmt.Errorf("invalid slot %q: %v", slotName, err)
+		}
 	}
 	return nil
 }
@@ -560,6 +567,42 @@ func validateAppOrderNames(app *AppInfo, dependencies []string) error {
 	return nil
 }
 
+func validateAppAfterPlugs(app *AppInfo) error {
+	if len(app.AfterPlugs) > 0 && !app.IsService() {
+		return errors.New("must be a service to define after-plugs ordering")
+	}
+
+	for _, plugName := range app.AfterPlugs {
+		if _, ok := app.Plugs[plugName]; !ok {
+			return fmt.Errorf("after-plugs references plug %q not bound to the application", plugName)
+		}
+	}
+	return nil
+}
+
+var isValidEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`).MatchString
+
+func validateAppConfigurable(app *AppInfo) error {
+	if len(app.ConfigurableEnv) == 0 && len(app.ConfigurableArgs) == 0 {
+		return nil
+	}
+	if !app.IsService() {
+		return errors.New("configurable-env and configurable-args are only applicable to services")
+	}
+
+	for _, name := range app.ConfigurableEnv {
+		if !isValidEnvName(name) {
+			return fmt.Errorf("configurable-env contains invalid environment variable name %q", name)
+		}
+	}
+	for _, arg := range app.ConfigurableArgs {
+		if arg == "" || arg == "=" || isNonGraphicalASCII(arg) {
+			return fmt.Errorf("configurable-args contains invalid argument %q", arg)
+		}
+	}
+	return nil
+}
+
 func validateAppTimeouts(app *AppInfo) error {
 	type T struct {
 		desc    string
@@ -624,6 +667,37 @@ func validateAppRestart(app *AppInfo) error {
 	return nil
 }
 
+func validateAppSupervision(app *AppInfo) error {
+	if app.StartLimitBurst == 0 && app.StartLimitInterval == 0 && app.RestartSteps == 0 && app.RestartMaxDelay == 0 && app.OnFailureAction == "" {
+		return nil
+	}
+	if !app.IsService() {
+		return errors.New("start limits, restart back-off and on-failure-action are only applicable to services")
+	}
+
+	if app.StartLimitBurst < 0 {
+		return errors.New("start-limit-burst cannot be negative")
+	}
+	if app.StartLimitInterval < 0 {
+		return errors.New("start-limit-interval cannot be negative")
+	}
+
+	if app.RestartSteps < 0 {
+		return errors.New("restart-steps cannot be negative")
+	}
+	if app.RestartMaxDelay < 0 {
+		return errors.New("restart-max-delay cannot be negative")
+	}
+	if (app.RestartSteps != 0 || app.RestartMaxDelay != 0) && app.RestartDelay == 0 {
+		return errors.New("restart-steps and restart-max-delay require restart-delay")
+	}
+	if app.RestartMaxDelay != 0 && app.RestartMaxDelay < app.RestartDelay {
+		return errors.New("restart-max-delay cannot be shorter than restart-delay")
+	}
+
+	return app.OnFailureAction.Validate()
+}
+
 func validateAppActivatesOn(app *AppInfo) error {
 	if len(app.ActivatesOn) == 0 {
 		return nil
@@ -742,12 +816,21 @@ func ValidateApp(app *AppInfo) error {
 	if err := validateAppRestart(app); err != nil {
 		return err
 	}
+	if err := validateAppSupervision(app); err != nil {
+		return err
+	}
 	if err := validateAppOrderNames(app, app.Before); err != nil {
 		return err
 	}
 	if err := validateAppOrderNames(app, app.After); err != nil {
 		return err
 	}
+	if err := validateAppAfterPlugs(app); err != nil {
+		return err
+	}
+	if err := validateAppConfigurable(app); err != nil {
+		return err
+	}
 
 	if err := validateAppTimeouts(app); err != nil {
 		return err
@@ -1450,6 +1450,126 @@ apps:
 	}
 }
 
+func (s *ValidateSuite) TestValidateAppAfterPlugs(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+plugs:
+  database:
+    interface: content
+`)
+	good := []byte(`
+apps:
+ foo:
+   daemon: simple
+   after-plugs: [database]
+`)
+	notADaemon := []byte(`
+apps:
+ foo:
+   after-plugs: [database]
+`)
+	missingPlug := []byte(`
+apps:
+ foo:
+   daemon: simple
+   after-plugs: [other]
+`)
+
+	tcs := []struct {
+		name string
+		desc []byte
+		err  string
+	}{{
+		name: "all good",
+		desc: good,
+	}, {
+		name: "foo not a daemon",
+		desc: notADaemon,
+		err:  `invalid definition of application "foo": must be a service to define after-plugs ordering`,
+	}, {
+		name: "missing plug",
+		desc: missingPlug,
+		err:  `invalid definition of application "foo": after-plugs references plug "other" not bound to the application`,
+	}}
+	for _, tc := range tcs {
+		c.Logf("trying %q", tc.name)
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Assert(err, ErrorMatches, tc.err)
+		} else {
+			c.Assert(err, IsNil)
+		}
+	}
+}
+
+func (s *ValidateSuite) TestValidateAppConfigurable(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+`)
+	good := []byte(`
+apps:
+ foo:
+   daemon: simple
+   configurable-env: [LOG_LEVEL, _DEBUG2]
+   configurable-args: [--verbose, --log-format=]
+`)
+	notADaemon := []byte(`
+apps:
+ foo:
+   configurable-env: [LOG_LEVEL]
+`)
+	badEnv := []byte(`
+apps:
+ foo:
+   daemon: simple
+   configurable-env: [LOG-LEVEL]
+`)
+	badArg := []byte(`
+apps:
+ foo:
+   daemon: simple
+   configurable-args: ["--log format"]
+`)
+
+	tcs := []struct {
+		name string
+		desc []byte
+		err  string
+	}{{
+		name: "all good",
+		desc: good,
+	}, {
+		name: "foo not a daemon",
+		desc: notADaemon,
+		err:  `invalid definition of application "foo": configurable-env and configurable-args are only applicable to services`,
+	}, {
+		name: "bad env name",
+		desc: badEnv,
+		err:  `invalid definition of application "foo": configurable-env contains invalid environment variable name "LOG-LEVEL"`,
+	}, {
+		name: "bad argument",
+		desc: badArg,
+		err:  `invalid definition of application "foo": configurable-args contains invalid argument "--log format"`,
+	}}
+	for _, tc := range tcs {
+		c.Logf("trying %q", tc.name)
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Assert(err, ErrorMatches, tc.err)
+		} else {
+			c.Assert(err, IsNil)
+		}
+	}
+}
+
 func (s *ValidateSuite) TestValidateAppWatchdogTimeout(c *C) {
 	s.testValidateAppTimeout(c, "watchdog")
 }
@@ -1838,6 +1958,107 @@ apps:
 	}
 }
 
+func (s *ValidateSuite) TestValidateAppSupervision(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+`)
+	tcs := []struct {
+		name string
+		desc string
+		err  string
+	}{{
+		name: "all good",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    restart-delay: 1s
+    restart-steps: 5
+    restart-max-delay: 1m
+    start-limit-burst: 5
+    start-limit-interval: 10m
+    on-failure-action: revert
+`,
+	}, {
+		name: "not a service",
+		desc: `
+apps:
+  foo:
+    on-failure-action: revert
+`,
+		err: `start limits, restart back-off and on-failure-action are only applicable to services`,
+	}, {
+		name: "negative burst",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    start-limit-burst: -1
+`,
+		err: `start-limit-burst cannot be negative`,
+	}, {
+		name: "negative interval",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    start-limit-interval: -1s
+`,
+		err: `start-limit-interval cannot be negative`,
+	}, {
+		name: "negative steps",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    restart-delay: 1s
+    restart-steps: -2
+`,
+		err: `restart-steps cannot be negative`,
+	}, {
+		name: "back-off without restart-delay",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    restart-max-delay: 1m
+`,
+		err: `restart-steps and restart-max-delay require restart-delay`,
+	}, {
+		name: "max delay shorter than delay",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    restart-delay: 1m
+    restart-max-delay: 1s
+`,
+		err: `restart-max-delay cannot be shorter than restart-delay`,
+	}, {
+		name: "unknown action",
+		desc: `
+apps:
+  foo:
+    daemon: simple
+    on-failure-action: reboot
+`,
+		err: `"on-failure-action" field contains invalid value "reboot"`,
+	}}
+	for _, tc := range tcs {
+		c.Logf("trying %q", tc.name)
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, `invalid definition of application "foo": `+tc.err)
+		} else {
+			c.Check(err, IsNil)
+		}
+	}
+}
+
 func (s *ValidateSuite) TestValidateSystemUsernames(c *C) {
 	const yaml1 = `name: binary
 version: 1.0
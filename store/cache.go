@@ -20,8 +20,11 @@
 package store
 
 import (
+	"crypto"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
@@ -67,6 +70,9 @@ func (s changesByMtime) Less(i, j int) bool { return s[i].ModTime().Before(s[j].
 type CacheManager struct {
 	cacheDir string
 	maxItems int
+	// maxSize returns the maximum size in bytes of the entries not
+	// linked elsewhere, there is no bound if it is nil or returns 0
+	maxSize func() int64
 }
 
 // NewCacheManager returns a new CacheManager with the given cacheDir
@@ -74,19 +80,27 @@ type CacheManager struct {
 // following algorithm:
 //
 // 1. When starting a download, check if it exists in $cacheDir
-// 2. If found, update its mtime, hardlink into target location, and
-//    return success
-// 3. If not found, download the snap
+// 2. If found, hardlink into target location, verify its digest,
+//    update its mtime and return success
+// 3. If not found (or corrupted), download the snap
 // 4. On success, hardlink into $cacheDir/<digest>
-// 5. If cache dir has more than maxItems entries, remove oldest mtimes
-//    until it has maxItems
+// 5. If cache dir has more than maxItems entries (or more than the
+//    maximum size), remove oldest mtimes until it fits
 //
 // The caching part is done here, the downloading happens in the store.go
 // code.
 func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
+	return newCacheManager(cacheDir, maxItems, nil)
+}
+
+// newCacheManager returns a new CacheManager that, on top of the
+// maximum amount of items, keeps the entries not linked elsewhere
+// under the size returned by maxSize.
+func newCacheManager(cacheDir string, maxItems int, maxSize func() int64) *CacheManager {
 	return &CacheManager{
 		cacheDir: cacheDir,
 		maxItems: maxItems,
+		maxSize:  maxSize,
 	}
 }
 
@@ -99,11 +113,25 @@ func (cm *CacheManager) GetPath(cacheKey string) string {
 	return cm.path(cacheKey)
 }
 
-// Get gets the given cacheKey content and puts it into targetPath
+// Get gets the given cacheKey content and puts it into targetPath.
+// The cacheKey is the sha3-384 of the content, which is verified again
+// as the entry might have been corrupted since it was added.
 func (cm *CacheManager) Get(cacheKey, targetPath string) error {
 	if err := os.Link(cm.path(cacheKey), targetPath); err != nil {
 		return err
 	}
+	digest, _, err := osutil.FileDigest(targetPath, crypto.SHA3_384)
+	if err != nil {
+		os.Remove(targetPath)
+		return err
+	}
+	if actual := hex.EncodeToString(digest); actual != cacheKey {
+		os.Remove(targetPath)
+		if err := osRemove(cm.path(cacheKey)); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("cannot remove corrupted cache entry: %v", err)
+		}
+		return fmt.Errorf("cannot use cache entry %s: content has sha3-384 %s", cacheKey, actual)
+	}
 	logger.Debugf("using cache for %s", targetPath)
 	now := time.Now()
 	return os.Chtimes(targetPath, now, now)
@@ -138,6 +166,22 @@ func (cm *CacheManager) Put(cacheKey, sourcePath string) error {
 	return cm.cleanup()
 }
 
+// TrimDownloadCache removes the least recently used entries of the
+// download cache in cacheDir until the ones not linked elsewhere fit in
+// maxSize bytes, there is no bound if maxSize is 0. Put keeps the cache
+// within the bound, this is for when the cache is opened or the bound
+// changes.
+func TrimDownloadCache(cacheDir string, maxSize int64) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	cm := newCacheManager(cacheDir, math.MaxInt32, func() int64 { return maxSize })
+	if err := cm.cleanup(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // count returns the number of items in the cache
 func (cm *CacheManager) count() int {
 	// TODO: Use something more effective than a list of all entries
@@ -153,17 +197,21 @@ func (cm *CacheManager) path(cacheKey string) string {
 	return filepath.Join(cm.cacheDir, cacheKey)
 }
 
-// cleanup ensures that only maxItems are stored in the cache
+// cleanup ensures that only maxItems are stored in the cache, and
+// that they fit in the maximum size. Entries linked elsewhere, e.g.
+// installed snap revisions, are "free" so they are neither counted nor
+// removed.
 func (cm *CacheManager) cleanup() error {
 	fil, err := ioutil.ReadDir(cm.cacheDir)
 	if err != nil {
 		return err
 	}
-	if len(fil) <= cm.maxItems {
+	if len(fil) <= cm.maxItems && cm.maxSize == nil {
 		return nil
 	}
 
-	numOwned := 0
+	var owned []os.FileInfo
+	var ownedSize int64
 	for _, fi := range fil {
 		n, err := hardLinkCount(fi)
 		if err != nil {
@@ -171,29 +219,29 @@ func (cm *CacheManager) cleanup() error {
 		}
 		// Only count the file if it is not referenced elsewhere in the filesystem
 		if n <= 1 {
-			numOwned++
+			owned = append(owned, fi)
+			ownedSize += fi.Size()
 		}
 	}
 
-	if numOwned <= cm.maxItems {
+	var maxSize int64
+	if cm.maxSize != nil {
+		maxSize = cm.maxSize()
+	}
+	fits := func(count int, size int64) bool {
+		return count <= cm.maxItems && (maxSize <= 0 || size <= maxSize)
+	}
+	if fits(len(owned), ownedSize) {
 		return nil
 	}
 
+	// If there is any error we cleanup the file (it is just a cache
+	// afterall), least recently used first.
 	var lastErr error
-	sort.Sort(changesByMtime(fil))
+	sort.Sort(changesByMtime(owned))
 	deleted := 0
-	for _, fi := range fil {
+	for _, fi := range owned {
 		path := cm.path(fi.Name())
-		n, err := hardLinkCount(fi)
-		if err != nil {
-			logger.Noticef("cannot inspect cache: %s", err)
-		}
-		// If the file is referenced in the filesystem somewhere
-		// else our copy is "free" so skip it. If there is any
-		// error we cleanup the file (it is just a cache afterall).
-		if n > 1 {
-			continue
-		}
 		if err := osRemove(path); err != nil {
 			if !os.IsNotExist(err) {
 				logger.Noticef("cannot cleanup cache: %s", err)
@@ -202,13 +250,70 @@ func (cm *CacheManager) cleanup() error {
 			continue
 		}
 		deleted++
-		if numOwned-deleted <= cm.maxItems {
+		ownedSize -= fi.Size()
+		if fits(len(owned)-deleted, ownedSize) {
 			break
 		}
 	}
 	return lastErr
 }
 
+// CacheEntry describes an entry of the download cache.
+type CacheEntry struct {
+	// Digest is the sha3-384 of the entry, used as its key.
+	Digest   string
+	Size     int64
+	LastUsed time.Time
+	// InUse is set if the entry is linked elsewhere too, e.g. it is
+	// an installed snap revision, so it takes no space of its own.
+	InUse bool
+}
+
+// Entries returns the entries of the cache, most recently used first.
+func (cm *CacheManager) Entries() ([]CacheEntry, error) {
+	fil, err := ioutil.ReadDir(cm.cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(changesByMtime(fil)))
+	entries := make([]CacheEntry, 0, len(fil))
+	for _, fi := range fil {
+		n, err := hardLinkCount(fi)
+		if err != nil {
+			logger.Noticef("cannot inspect cache: %s", err)
+		}
+		entries = append(entries, CacheEntry{
+			Digest:   fi.Name(),
+			Size:     fi.Size(),
+			LastUsed: fi.ModTime(),
+			InUse:    n > 1,
+		})
+	}
+	return entries, nil
+}
+
+// Clean removes all the entries of the cache, it returns the number of
+// bytes freed by doing so.
+func (cm *CacheManager) Clean() (freed int64, err error) {
+	entries, err := cm.Entries()
+	if err != nil {
+		return 0, err
+	}
+	var lastErr error
+	for _, entry := range entries {
+		if err := osRemove(cm.path(entry.Digest)); err != nil {
+			if !os.IsNotExist(err) {
+				lastErr = err
+			}
+			continue
+		}
+		if !entry.InUse {
+			freed += entry.Size
+		}
+	}
+	return freed, lastErr
+}
+
 // hardLinkCount returns the number of hardlinks for the given path
 func hardLinkCount(fi os.FileInfo) (uint64, error) {
 	if stat, ok := fi.Sys().(*syscall.Stat_t); ok && stat != nil {
@@ -27,6 +27,7 @@ import (
 	"strconv"
 	"time"
 
+	"golang.org/x/crypto/sha3"
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/osutil"
@@ -101,19 +102,40 @@ func (s *cacheSuite) TestGetNotExistant(c *C) {
 	c.Check(err, ErrorMatches, `link .*: no such file or directory`)
 }
 
+func digestOf(content string) string {
+	return fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+}
+
 func (s *cacheSuite) TestGet(c *C) {
 	canary := "some content"
 	p := s.makeTestFile(c, "foo", canary)
-	err := s.cm.Put("some-cache-key", p)
+	err := s.cm.Put(digestOf(canary), p)
 	c.Assert(err, IsNil)
 
 	targetPath := filepath.Join(s.tmp, "new-location")
-	err = s.cm.Get("some-cache-key", targetPath)
+	err = s.cm.Get(digestOf(canary), targetPath)
 	c.Check(err, IsNil)
 	c.Check(osutil.FileExists(targetPath), Equals, true)
 	c.Assert(targetPath, testutil.FileEquals, canary)
 }
 
+func (s *cacheSuite) TestGetCorrupted(c *C) {
+	cacheKey := digestOf("some content")
+	p := s.makeTestFile(c, "foo", "some content")
+	err := s.cm.Put(cacheKey, p)
+	c.Assert(err, IsNil)
+	c.Assert(os.Remove(p), IsNil)
+	// bit rot
+	c.Assert(ioutil.WriteFile(filepath.Join(s.cm.CacheDir(), cacheKey), []byte("some c0ntent"), 0644), IsNil)
+
+	targetPath := filepath.Join(s.tmp, "new-location")
+	err = s.cm.Get(cacheKey, targetPath)
+	c.Check(err, ErrorMatches, fmt.Sprintf("cannot use cache entry %s: content has sha3-384 %s", cacheKey, digestOf("some c0ntent")))
+	// neither the target nor the corrupted entry are left behind
+	c.Check(osutil.FileExists(targetPath), Equals, false)
+	c.Check(s.cm.Count(), Equals, 0)
+}
+
 func (s *cacheSuite) makeTestFiles(c *C, n int) (cacheKeys []string, testFiles []string) {
 	cacheKeys = make([]string, n)
 	testFiles = make([]string, n)
@@ -183,6 +205,113 @@ func (s *cacheSuite) TestClenaupContinuesOnError(c *C) {
 	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[0])), Equals, true)
 }
 
+func (s *cacheSuite) TestCleanupMaxSize(c *C) {
+	// allow plenty of items but only 25 bytes
+	s.cm = store.NewCacheManagerWithMaxSize(c.MkDir(), 100, func() int64 { return 25 })
+
+	var installed string
+	var cacheKeys []string
+	for i := 0; i < 5; i++ {
+		content := fmt.Sprintf("content-%d", i)
+		p := s.makeTestFile(c, "foo", content)
+		cacheKeys = append(cacheKeys, digestOf(content))
+		c.Assert(s.cm.Put(digestOf(content), p), IsNil)
+		if i == 0 {
+			// the oldest entry stays linked elsewhere, as
+			// installed snaps are
+			installed = p
+		} else {
+			c.Assert(os.Remove(p), IsNil)
+		}
+		// mtime is not very granular
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(s.cm.Cleanup(), IsNil)
+
+	// the 9 bytes entries not in use elsewhere are evicted least
+	// recently used first until they fit in 25 bytes
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[0])), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[2])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[3])), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[4])), Equals, true)
+	c.Check(installed, testutil.FileEquals, "content-0")
+}
+
+func (s *cacheSuite) TestCleanupMaxSizeUnbounded(c *C) {
+	s.cm = store.NewCacheManagerWithMaxSize(c.MkDir(), 100, func() int64 { return 0 })
+	_, testFiles := s.makeTestFiles(c, 3)
+	for _, p := range testFiles {
+		c.Assert(os.Remove(p), IsNil)
+	}
+	c.Assert(s.cm.Cleanup(), IsNil)
+	c.Check(s.cm.Count(), Equals, 3)
+}
+
+func (s *cacheSuite) TestTrimDownloadCache(c *C) {
+	// filled while there was no bound on the size
+	s.cm = store.NewCacheManager(c.MkDir(), 100)
+	var cacheKeys []string
+	for i := 0; i < 5; i++ {
+		content := fmt.Sprintf("content-%d", i)
+		p := s.makeTestFile(c, "foo", content)
+		cacheKeys = append(cacheKeys, digestOf(content))
+		c.Assert(s.cm.Put(digestOf(content), p), IsNil)
+		c.Assert(os.Remove(p), IsNil)
+		// mtime is not very granular
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// no bound
+	c.Assert(store.TrimDownloadCache(s.cm.CacheDir(), 0), IsNil)
+	c.Check(s.cm.Count(), Equals, 5)
+
+	// the 9 bytes entries are evicted least recently used first
+	c.Assert(store.TrimDownloadCache(s.cm.CacheDir(), 25), IsNil)
+	c.Check(s.cm.Count(), Equals, 2)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[3])), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[4])), Equals, true)
+
+	// a cache that was never used is fine
+	c.Check(store.TrimDownloadCache(filepath.Join(c.MkDir(), "missing"), 25), IsNil)
+}
+
+func (s *cacheSuite) TestEntriesAndClean(c *C) {
+	entries, err := s.cm.Entries()
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+
+	cacheKeys, testFiles := s.makeTestFiles(c, 3)
+	// the first one stays in use elsewhere
+	for _, p := range testFiles[1:] {
+		c.Assert(os.Remove(p), IsNil)
+	}
+
+	entries, err = s.cm.Entries()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 3)
+	// most recently used first
+	for i, entry := range entries {
+		c.Check(entry.Digest, Equals, cacheKeys[2-i])
+		c.Check(entry.Size, Equals, int64(1))
+		c.Check(entry.InUse, Equals, i == 2)
+	}
+	c.Check(entries[0].LastUsed.After(entries[1].LastUsed), Equals, true)
+
+	freed, err := s.cm.Clean()
+	c.Assert(err, IsNil)
+	c.Check(freed, Equals, int64(2))
+	c.Check(s.cm.Count(), Equals, 0)
+	c.Check(testFiles[0], testutil.FileEquals, "0")
+}
+
+func (s *cacheSuite) TestEntriesNoCacheDir(c *C) {
+	cm := store.NewCacheManager(filepath.Join(c.MkDir(), "missing"), 1)
+	entries, err := cm.Entries()
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+}
+
 func (s *cacheSuite) TestHardLinkCount(c *C) {
 	p := filepath.Join(s.tmp, "foo")
 	err := ioutil.WriteFile(p, nil, 0644)
@@ -0,0 +1,150 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/juju/ratelimit"
+)
+
+// Download priorities, downloads with a higher priority are started
+// first when the number of concurrent downloads is bounded.
+const (
+	DownloadPriorityBackground = -1
+	DownloadPriorityDefault    = 0
+)
+
+type downloadWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// downloadScheduler is shared by all the downloads of a store. It
+// bounds how many downloads run at the same time, starting the waiting
+// ones by priority and then in request order, and makes all the
+// rate-limited downloads share a single bandwidth budget.
+type downloadScheduler struct {
+	mu sync.Mutex
+
+	// concurrency is the maximum number of concurrent downloads,
+	// no bound if <= 0
+	concurrency int
+	active      int
+	waiting     []*downloadWaiter
+	seq         uint64
+
+	rate   int64
+	bucket *ratelimit.Bucket
+}
+
+func (ds *downloadScheduler) hasSlotLocked() bool {
+	return ds.concurrency <= 0 || ds.active < ds.concurrency
+}
+
+// startWaitingLocked starts as many of the waiting downloads as the
+// concurrency bound allows.
+func (ds *downloadScheduler) startWaitingLocked() {
+	for len(ds.waiting) > 0 && ds.hasSlotLocked() {
+		w := ds.waiting[0]
+		ds.waiting = ds.waiting[1:]
+		ds.active++
+		close(w.ready)
+	}
+}
+
+func (ds *downloadScheduler) removeWaiterLocked(w *downloadWaiter) bool {
+	for i, other := range ds.waiting {
+		if other == w {
+			ds.waiting = append(ds.waiting[:i], ds.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// acquire waits until a download with the given priority can start,
+// concurrency being the current bound on concurrent downloads. The
+// returned function must be called once the download is over.
+func (ds *downloadScheduler) acquire(ctx context.Context, priority, concurrency int) (release func(), err error) {
+	ds.mu.Lock()
+	ds.concurrency = concurrency
+	// the bound might have been raised
+	ds.startWaitingLocked()
+	if len(ds.waiting) == 0 && ds.hasSlotLocked() {
+		ds.active++
+		ds.mu.Unlock()
+		return ds.release, nil
+	}
+	ds.seq++
+	w := &downloadWaiter{
+		priority: priority,
+		seq:      ds.seq,
+		ready:    make(chan struct{}),
+	}
+	ds.waiting = append(ds.waiting, w)
+	sort.SliceStable(ds.waiting, func(i, j int) bool {
+		if ds.waiting[i].priority != ds.waiting[j].priority {
+			return ds.waiting[i].priority > ds.waiting[j].priority
+		}
+		return ds.waiting[i].seq < ds.waiting[j].seq
+	})
+	ds.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return ds.release, nil
+	case <-ctx.Done():
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if !ds.removeWaiterLocked(w) {
+		// started concurrently, give the slot back
+		ds.active--
+		ds.startWaitingLocked()
+	}
+	return nil, ctx.Err()
+}
+
+func (ds *downloadScheduler) release() {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.active--
+	ds.startWaitingLocked()
+}
+
+// limitReader returns a reader taking from the bandwidth budget
+// shared by the rate-limited downloads. Changing the rate starts
+// a new budget, downloads in progress keep the previous one.
+func (ds *downloadScheduler) limitReader(r io.Reader, rate int64) io.Reader {
+	ds.mu.Lock()
+	if ds.bucket == nil || ds.rate != rate {
+		ds.rate = rate
+		ds.bucket = ratelimit.NewBucketWithRate(float64(rate), 2*rate)
+	}
+	bucket := ds.bucket
+	ds.mu.Unlock()
+	return ratelimitReader(r, bucket)
+}
@@ -0,0 +1,173 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// not using store_test as this is a very low level test
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/juju/ratelimit"
+	. "gopkg.in/check.v1"
+)
+
+type downloadSchedulerSuite struct{}
+
+var _ = Suite(&downloadSchedulerSuite{})
+
+type startedDownload struct {
+	name    string
+	release func()
+}
+
+// acquireAsync acquires a download slot in the background, reporting
+// on started once it got it.
+func acquireAsync(ds *downloadScheduler, ctx context.Context, name string, priority, concurrency int, started chan<- startedDownload) chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		release, err := ds.acquire(ctx, priority, concurrency)
+		if err == nil {
+			started <- startedDownload{name: name, release: release}
+		}
+		errCh <- err
+	}()
+	return errCh
+}
+
+func (s *downloadSchedulerSuite) waitWaiting(c *C, ds *downloadScheduler, n int) {
+	for i := 0; i < 1000; i++ {
+		ds.mu.Lock()
+		waiting := len(ds.waiting)
+		ds.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Fatalf("expected %d waiting downloads", n)
+}
+
+func (s *downloadSchedulerSuite) TestUnbounded(c *C) {
+	var ds downloadScheduler
+	var releases []func()
+	for i := 0; i < 10; i++ {
+		release, err := ds.acquire(context.Background(), DownloadPriorityDefault, 0)
+		c.Assert(err, IsNil)
+		releases = append(releases, release)
+	}
+	c.Check(ds.active, Equals, 10)
+	for _, release := range releases {
+		release()
+	}
+	c.Check(ds.active, Equals, 0)
+}
+
+func (s *downloadSchedulerSuite) TestBoundedByPriority(c *C) {
+	var ds downloadScheduler
+	ctx := context.Background()
+
+	first, err := ds.acquire(ctx, DownloadPriorityDefault, 1)
+	c.Assert(err, IsNil)
+
+	started := make(chan startedDownload, 3)
+	acquireAsync(&ds, ctx, "bg1", DownloadPriorityBackground, 1, started)
+	s.waitWaiting(c, &ds, 1)
+	acquireAsync(&ds, ctx, "bg2", DownloadPriorityBackground, 1, started)
+	s.waitWaiting(c, &ds, 2)
+	acquireAsync(&ds, ctx, "user", DownloadPriorityDefault, 1, started)
+	s.waitWaiting(c, &ds, 3)
+
+	// nothing starts while the slot is taken
+	select {
+	case dl := <-started:
+		c.Fatalf("unexpected start of %s", dl.name)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// the interactive download goes first, then the background
+	// ones in request order
+	first()
+	var order []string
+	for i := 0; i < 3; i++ {
+		dl := <-started
+		c.Check(ds.active, Equals, 1)
+		order = append(order, dl.name)
+		dl.release()
+	}
+	c.Check(order, DeepEquals, []string{"user", "bg1", "bg2"})
+	c.Check(ds.active, Equals, 0)
+}
+
+func (s *downloadSchedulerSuite) TestRaisingBoundStartsWaiting(c *C) {
+	var ds downloadScheduler
+	ctx := context.Background()
+
+	_, err := ds.acquire(ctx, DownloadPriorityDefault, 1)
+	c.Assert(err, IsNil)
+	started := make(chan startedDownload, 1)
+	acquireAsync(&ds, ctx, "waiting", DownloadPriorityBackground, 1, started)
+	s.waitWaiting(c, &ds, 1)
+
+	_, err = ds.acquire(ctx, DownloadPriorityBackground, 3)
+	c.Assert(err, IsNil)
+	c.Check((<-started).name, Equals, "waiting")
+	c.Check(ds.active, Equals, 3)
+}
+
+func (s *downloadSchedulerSuite) TestCancelWhileWaiting(c *C) {
+	var ds downloadScheduler
+
+	release, err := ds.acquire(context.Background(), DownloadPriorityDefault, 1)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan startedDownload, 1)
+	errCh := acquireAsync(&ds, ctx, "cancelled", DownloadPriorityDefault, 1, started)
+	s.waitWaiting(c, &ds, 1)
+	cancel()
+	c.Check(<-errCh, Equals, context.Canceled)
+	c.Check(ds.waiting, HasLen, 0)
+
+	release()
+	c.Check(ds.active, Equals, 0)
+}
+
+func (s *downloadSchedulerSuite) TestSharedBudget(c *C) {
+	var buckets []*ratelimit.Bucket
+	restore := MockRatelimitReader(func(r io.Reader, bucket *ratelimit.Bucket) io.Reader {
+		buckets = append(buckets, bucket)
+		return r
+	})
+	defer restore()
+
+	var ds downloadScheduler
+	ds.limitReader(&bytes.Buffer{}, 1000)
+	ds.limitReader(&bytes.Buffer{}, 1000)
+	// a new rate means a new budget
+	ds.limitReader(&bytes.Buffer{}, 2000)
+
+	c.Assert(buckets, HasLen, 3)
+	c.Check(buckets[0] == buckets[1], Equals, true)
+	c.Check(buckets[1] == buckets[2], Equals, false)
+	c.Check(buckets[0].Rate(), Equals, float64(1000))
+	c.Check(buckets[2].Rate(), Equals, float64(2000))
+}
@@ -470,7 +470,7 @@ var deltaTests = []struct {
 	info: snap.DownloadInfo{
 		AnonDownloadURL: "full-snap-url",
 		Deltas: []snap.DeltaInfo{
-			{AnonDownloadURL: "delta-url", Format: "xdelta3"},
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
 		},
 	},
 	expectedContent: "snap-content-via-delta",
@@ -484,21 +484,63 @@ var deltaTests = []struct {
 	info: snap.DownloadInfo{
 		AnonDownloadURL: "full-snap-url",
 		Deltas: []snap.DeltaInfo{
-			{AnonDownloadURL: "delta-url", Format: "xdelta3"},
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
 		},
 	},
 	expectedContent: "full-snap-url-content",
 }, {
 	// If more than one matching delta is returned by the store
-	// we ignore deltas and do the full download.
+	// for the same revisions we ignore deltas and do the full download.
 	downloads: downloadBehaviour{
 		{url: "full-snap-url"},
 	},
 	info: snap.DownloadInfo{
 		AnonDownloadURL: "full-snap-url",
 		Deltas: []snap.DeltaInfo{
-			{AnonDownloadURL: "delta-url", Format: "xdelta3"},
-			{AnonDownloadURL: "delta-url-2", Format: "xdelta3"},
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+			{AnonDownloadURL: "delta-url-2", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	},
+	expectedContent: "full-snap-url-content",
+}, {
+	// A chain of deltas is downloaded and applied in order.
+	downloads: downloadBehaviour{
+		{url: "delta-url"},
+		{url: "delta-url-2"},
+	},
+	info: snap.DownloadInfo{
+		AnonDownloadURL: "full-snap-url",
+		Deltas: []snap.DeltaInfo{
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 25},
+			{AnonDownloadURL: "delta-url-2", Format: "xdelta3", FromRevision: 25, ToRevision: 26},
+		},
+	},
+	expectedContent: "snap-content-via-delta",
+}, {
+	// If there is an error in the middle of a chain of deltas, the
+	// full snap is downloaded as per normal.
+	downloads: downloadBehaviour{
+		{url: "delta-url"},
+		{error: true},
+		{url: "full-snap-url"},
+	},
+	info: snap.DownloadInfo{
+		AnonDownloadURL: "full-snap-url",
+		Deltas: []snap.DeltaInfo{
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 25},
+			{AnonDownloadURL: "delta-url-2", Format: "xdelta3", FromRevision: 25, ToRevision: 26},
+		},
+	},
+	expectedContent: "full-snap-url-content",
+}, {
+	// Deltas that are not smaller than the snap are not used.
+	downloads: downloadBehaviour{
+		{url: "full-snap-url"},
+	},
+	info: snap.DownloadInfo{
+		AnonDownloadURL: "full-snap-url",
+		Deltas: []snap.DeltaInfo{
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26, Size: 1000},
 		},
 	},
 	expectedContent: "full-snap-url-content",
@@ -523,8 +565,12 @@ func (s *downloadSuite) TestDownloadWithDelta(c *C) {
 			return nil
 		})
 		defer restore()
-		restore = store.MockApplyDelta(func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
-			c.Check(deltaInfo, Equals, &testCase.info.Deltas[0])
+		applyIndex := 0
+		var sourcePaths []string
+		restore = store.MockApplyDelta(func(name string, sourcePath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+			c.Check(deltaInfo, DeepEquals, &testCase.info.Deltas[applyIndex])
+			applyIndex++
+			sourcePaths = append(sourcePaths, sourcePath)
 			err := ioutil.WriteFile(targetPath, []byte("snap-content-via-delta"), 0644)
 			c.Assert(err, IsNil)
 			return nil
@@ -538,6 +584,15 @@ func (s *downloadSuite) TestDownloadWithDelta(c *C) {
 		c.Assert(err, IsNil)
 		defer os.Remove(path)
 		c.Assert(path, testutil.FileEquals, testCase.expectedContent)
+		c.Check(downloadIndex, Equals, len(testCase.downloads))
+		if len(sourcePaths) > 0 {
+			c.Check(sourcePaths[0], Equals, filepath.Join(dirs.SnapBlobDir, "foo_24.snap"))
+		}
+		if len(sourcePaths) > 1 {
+			// intermediate snaps are cleaned up
+			c.Check(sourcePaths[1], Equals, path+".delta-25")
+			c.Check(osutil.FileExists(sourcePaths[1]), Equals, false)
+		}
 	}
 }
 
@@ -562,3 +617,41 @@ func (s *downloadSuite) TestActualDownloadRateLimited(c *C) {
 	c.Check(buf.String(), Equals, canary)
 	c.Check(ratelimitReaderUsed, Equals, true)
 }
+
+func (s *downloadSuite) TestDownloadConcurrencyBounded(c *C) {
+	inFlight := make(chan string)
+	unblock := make(chan struct{})
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		inFlight <- name
+		<-unblock
+		return nil
+	})
+	defer restore()
+
+	theStore := store.New(&store.Config{}, nil)
+	dir := c.MkDir()
+	dlOpts := &store.DownloadOptions{Concurrency: 1}
+	errCh := make(chan error, 2)
+	for _, name := range []string{"foo", "bar"} {
+		name := name
+		go func() {
+			info := &snap.DownloadInfo{DownloadURL: "http://example.com/" + name}
+			errCh <- theStore.Download(context.TODO(), name, filepath.Join(dir, name), info, nil, nil, dlOpts)
+		}()
+	}
+
+	first := <-inFlight
+	// the second download waits for the first one to finish
+	select {
+	case name := <-inFlight:
+		c.Fatalf("download of %s started concurrently", name)
+	case <-time.After(10 * time.Millisecond):
+	}
+	unblock <- struct{}{}
+	c.Check(<-errCh, IsNil)
+
+	second := <-inFlight
+	c.Check(second, Not(Equals), first)
+	unblock <- struct{}{}
+	c.Check(<-errCh, IsNil)
+}
@@ -0,0 +1,307 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// after this many consecutive failures an endpoint is avoided for
+// a back-off period that doubles with every further failure
+const endpointFailureThreshold = 3
+
+var (
+	endpointBackoffInitial = 30 * time.Second
+	endpointBackoffMax     = 10 * time.Minute
+
+	timeNow = time.Now
+)
+
+// EndpointStatus describes the health of a store endpoint as tracked
+// by the store.
+type EndpointStatus struct {
+	URL string
+	// Service is "assertions" for the endpoints of a separate
+	// assertions service, "" for the store ones.
+	Service string
+	// Failures is the number of consecutive failed requests.
+	Failures  int
+	LastError string
+	// BackoffUntil is set while requests avoid the endpoint.
+	BackoffUntil time.Time
+}
+
+type endpointState struct {
+	failures     int
+	lastError    string
+	backoffUntil time.Time
+}
+
+// endpointHealth tracks the health of store endpoints, keyed by their
+// base URL, acting as a circuit breaker for the failing ones.
+type endpointHealth struct {
+	mu     sync.Mutex
+	states map[string]*endpointState
+}
+
+func (h *endpointHealth) state(base string) *endpointState {
+	if h.states == nil {
+		h.states = make(map[string]*endpointState)
+	}
+	st := h.states[base]
+	if st == nil {
+		st = &endpointState{}
+		h.states[base] = st
+	}
+	return st
+}
+
+// backingOff returns whether requests should avoid the endpoint for now.
+func (h *endpointHealth) backingOff(base string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return timeNow().Before(h.state(base).backoffUntil)
+}
+
+func (h *endpointHealth) failed(base string, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.state(base)
+	st.failures++
+	st.lastError = reason
+	if st.failures < endpointFailureThreshold {
+		return
+	}
+	backoff := endpointBackoffInitial
+	for i := endpointFailureThreshold; i < st.failures && backoff < endpointBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > endpointBackoffMax {
+		backoff = endpointBackoffMax
+	}
+	st.backoffUntil = timeNow().Add(backoff)
+}
+
+func (h *endpointHealth) succeeded(base string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.state(base) = endpointState{}
+}
+
+func (h *endpointHealth) status(base string) EndpointStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.state(base)
+	status := EndpointStatus{
+		URL:       base,
+		Failures:  st.failures,
+		LastError: st.lastError,
+	}
+	if timeNow().Before(st.backoffUntil) {
+		status.BackoffUntil = st.backoffUntil
+	}
+	return status
+}
+
+// ParseEndpoints parses a comma separated list of store endpoint base
+// URLs, as used by the store.endpoints option.
+func ParseEndpoints(endpoints string) ([]*url.URL, error) {
+	var urls []*url.URL
+	for _, raw := range strings.Split(endpoints, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid store endpoint %q: %v", raw, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid store endpoint %q: must be an http or https URL", raw)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("invalid store endpoint %q: cannot have a query or fragment", raw)
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// endpointFailure returns a description of the failure if the
+// outcome of a request means the endpoint is unhealthy, "" otherwise.
+func endpointFailure(resp *http.Response, err error) string {
+	if err != nil {
+		urlErr, ok := err.(*url.Error)
+		if !ok || urlErr.Err == context.Canceled {
+			// not a problem with the endpoint
+			return ""
+		}
+		return urlErr.Err.Error()
+	}
+	if resp.StatusCode >= 500 {
+		return resp.Status
+	}
+	return ""
+}
+
+// baseWithSlash returns the string form of the base URL ending in a slash.
+func baseWithSlash(u *url.URL) string {
+	s := u.String()
+	if !strings.HasSuffix(s, "/") {
+		s += "/"
+	}
+	return s
+}
+
+// fallbackEndpoints returns the configured endpoints to fail over to.
+func fallbackEndpoints(what string, endpoints func() ([]*url.URL, error)) []*url.URL {
+	if endpoints == nil {
+		return nil
+	}
+	urls, err := endpoints()
+	if err != nil {
+		logger.Noticef("cannot get %s endpoints: %v", what, err)
+		return nil
+	}
+	return urls
+}
+
+// endpointList returns the base URLs of the primary endpoint followed
+// by the fallback ones.
+func endpointList(primary *url.URL, fallbacks []*url.URL) []string {
+	bases := []string{baseWithSlash(primary)}
+	for _, u := range fallbacks {
+		base := baseWithSlash(u)
+		if base != bases[0] {
+			bases = append(bases, base)
+		}
+	}
+	return bases
+}
+
+// storeEndpoints returns the base URLs of all the store endpoints in
+// order of preference, starting with the (proxy) store one.
+func (s *Store) storeEndpoints() []string {
+	primary := s.baseURL(s.cfg.StoreBaseURL)
+	if primary == nil {
+		return nil
+	}
+	return endpointList(primary, fallbackEndpoints("store", s.cfg.Endpoints))
+}
+
+// assertionsEndpoints returns the base URLs of all the assertions
+// service endpoints in order of preference, nil when the assertions are
+// served by the store endpoints.
+func (s *Store) assertionsEndpoints() []string {
+	if s.cfg.AssertionsBaseURL == nil {
+		return nil
+	}
+	primary := s.baseURL(s.cfg.AssertionsBaseURL)
+	if primary == nil {
+		return nil
+	}
+	return endpointList(primary, fallbackEndpoints("assertions", s.cfg.AssertionsEndpoints))
+}
+
+type endpointCandidate struct {
+	// base is the endpoint base URL, "" for URLs not pointing to
+	// a store endpoint (e.g. downloads)
+	base string
+	url  *url.URL
+}
+
+// endpointCandidates returns the URLs to try in order for the request,
+// healthy endpoints first and the ones we are backing off from last.
+func (s *Store) endpointCandidates(reqOptions *requestOptions) []endpointCandidate {
+	// a separate assertions service can live under the store base
+	// URL, so it is matched first
+	for _, endpoints := range [][]string{s.assertionsEndpoints(), s.storeEndpoints()} {
+		if cands := s.candidatesFrom(endpoints, reqOptions); cands != nil {
+			return cands
+		}
+	}
+	return []endpointCandidate{{url: reqOptions.URL}}
+}
+
+// candidatesFrom returns the URLs to try for the request among the
+// given endpoints, nil if the request does not go to any of them.
+func (s *Store) candidatesFrom(endpoints []string, reqOptions *requestOptions) []endpointCandidate {
+	raw := reqOptions.URL.String()
+	if len(endpoints) == 0 {
+		return nil
+	}
+	primary := endpoints[0]
+	if !strings.HasPrefix(raw, primary) {
+		for _, base := range endpoints[1:] {
+			if strings.HasPrefix(raw, base) {
+				// already pinned to a fallback endpoint
+				return []endpointCandidate{{base: base, url: reqOptions.URL}}
+			}
+		}
+		return nil
+	}
+	if reqOptions.NoFailover {
+		endpoints = endpoints[:1]
+	}
+
+	rest := raw[len(primary):]
+	var healthy, backingOff []endpointCandidate
+	for _, base := range endpoints {
+		u := reqOptions.URL
+		if base != primary {
+			var err error
+			u, err = url.Parse(base + rest)
+			if err != nil {
+				logger.Noticef("cannot use store endpoint %s: %v", base, err)
+				continue
+			}
+		}
+		cand := endpointCandidate{base: base, url: u}
+		if s.health.backingOff(base) {
+			backingOff = append(backingOff, cand)
+		} else {
+			healthy = append(healthy, cand)
+		}
+	}
+	return append(healthy, backingOff...)
+}
+
+// EndpointsStatus returns the health of the store endpoints in order
+// of preference, followed by the ones of a separate assertions service.
+func (s *Store) EndpointsStatus() []EndpointStatus {
+	var status []EndpointStatus
+	for _, base := range s.storeEndpoints() {
+		status = append(status, s.health.status(base))
+	}
+	for _, base := range s.assertionsEndpoints() {
+		epStatus := s.health.status(base)
+		epStatus.Service = "assertions"
+		status = append(status, epStatus)
+	}
+	return status
+}
@@ -0,0 +1,335 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/retry.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/store"
+)
+
+type endpointsSuite struct {
+	baseStoreSuite
+
+	now time.Time
+}
+
+var _ = Suite(&endpointsSuite{})
+
+func (s *endpointsSuite) SetUpTest(c *C) {
+	s.baseStoreSuite.SetUpTest(c)
+
+	store.MockDefaultRetryStrategy(&s.BaseTest, retry.LimitCount(2, retry.Exponential{
+		Initial: time.Millisecond,
+		Factor:  1,
+	}))
+	s.now = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.AddCleanup(store.MockTimeNow(func() time.Time { return s.now }))
+	s.AddCleanup(store.MockEndpointBackoff(time.Minute, 4*time.Minute))
+}
+
+// mockSectionsServer returns a server answering sections requests
+// with the given status, counting them in hits.
+func (s *endpointsSuite) mockSectionsServer(c *C, status *int, hits *int) *httptest.Server {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", sectionsPath)
+		*hits++
+		w.Header().Set("Content-Type", "application/hal+json")
+		w.WriteHeader(*status)
+		if *status == 200 {
+			io.WriteString(w, MockSectionsJSON)
+		}
+	}))
+	s.AddCleanup(mockServer.Close)
+	return mockServer
+}
+
+func (s *endpointsSuite) newStore(c *C, primary string, fallbacks ...string) *store.Store {
+	primaryURL, err := url.Parse(primary)
+	c.Assert(err, IsNil)
+	var fallbackURLs []*url.URL
+	for _, fallback := range fallbacks {
+		u, err := url.Parse(fallback)
+		c.Assert(err, IsNil)
+		fallbackURLs = append(fallbackURLs, u)
+	}
+	return store.New(&store.Config{
+		StoreBaseURL: primaryURL,
+		Endpoints: func() ([]*url.URL, error) {
+			return fallbackURLs, nil
+		},
+	}, nil)
+}
+
+func (s *endpointsSuite) TestParseEndpoints(c *C) {
+	urls, err := store.ParseEndpoints(" https://eu.proxy.example/ ,, http://10.0.0.1:8080/api")
+	c.Assert(err, IsNil)
+	c.Assert(urls, HasLen, 2)
+	c.Check(urls[0].String(), Equals, "https://eu.proxy.example/")
+	c.Check(urls[1].String(), Equals, "http://10.0.0.1:8080/api")
+
+	urls, err = store.ParseEndpoints("")
+	c.Assert(err, IsNil)
+	c.Check(urls, HasLen, 0)
+
+	_, err = store.ParseEndpoints("/just/a/path")
+	c.Check(err, ErrorMatches, `invalid store endpoint "/just/a/path": must be an http or https URL`)
+	_, err = store.ParseEndpoints("https://proxy.example/#frag")
+	c.Check(err, ErrorMatches, `invalid store endpoint "https://proxy.example/#frag": cannot have a query or fragment`)
+}
+
+func (s *endpointsSuite) TestFailoverOnServerError(c *C) {
+	primaryStatus, fallbackStatus := 503, 200
+	var primaryHits, fallbackHits int
+	primary := s.mockSectionsServer(c, &primaryStatus, &primaryHits)
+	fallback := s.mockSectionsServer(c, &fallbackStatus, &fallbackHits)
+
+	sto := s.newStore(c, primary.URL, fallback.URL)
+	sections, err := sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(sections, DeepEquals, []string{"featured", "database"})
+	c.Check(primaryHits, Equals, 1)
+	c.Check(fallbackHits, Equals, 1)
+
+	c.Check(sto.EndpointsStatus(), DeepEquals, []store.EndpointStatus{
+		{URL: primary.URL + "/", Failures: 1, LastError: "503 Service Unavailable"},
+		{URL: fallback.URL + "/"},
+	})
+}
+
+func (s *endpointsSuite) TestFailoverOnNetworkError(c *C) {
+	fallbackStatus := 200
+	var fallbackHits int
+	fallback := s.mockSectionsServer(c, &fallbackStatus, &fallbackHits)
+
+	// nothing is listening there anymore
+	gone := httptest.NewServer(nil)
+	gone.Close()
+
+	sto := s.newStore(c, gone.URL, fallback.URL)
+	sections, err := sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(sections, DeepEquals, []string{"featured", "database"})
+	c.Check(fallbackHits, Equals, 1)
+
+	status := sto.EndpointsStatus()
+	c.Assert(status, HasLen, 2)
+	c.Check(status[0].Failures, Equals, 1)
+	c.Check(status[0].LastError, Matches, ".*connection refused")
+	c.Check(status[1], DeepEquals, store.EndpointStatus{URL: fallback.URL + "/"})
+}
+
+func (s *endpointsSuite) TestBackoffAfterRepeatedFailures(c *C) {
+	primaryStatus, fallbackStatus := 500, 200
+	var primaryHits, fallbackHits int
+	primary := s.mockSectionsServer(c, &primaryStatus, &primaryHits)
+	fallback := s.mockSectionsServer(c, &fallbackStatus, &fallbackHits)
+
+	sto := s.newStore(c, primary.URL, fallback.URL)
+	for i := 0; i < 3; i++ {
+		_, err := sto.Sections(s.ctx, nil)
+		c.Assert(err, IsNil)
+	}
+	c.Check(primaryHits, Equals, 3)
+	c.Check(fallbackHits, Equals, 3)
+	c.Check(sto.EndpointsStatus()[0], DeepEquals, store.EndpointStatus{
+		URL:          primary.URL + "/",
+		Failures:     3,
+		LastError:    "500 Internal Server Error",
+		BackoffUntil: s.now.Add(time.Minute),
+	})
+
+	// the primary endpoint is avoided while backing off
+	_, err := sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(primaryHits, Equals, 3)
+	c.Check(fallbackHits, Equals, 4)
+
+	// and tried again afterwards, failing again doubles the back-off
+	s.now = s.now.Add(time.Minute)
+	_, err = sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(primaryHits, Equals, 4)
+	c.Check(sto.EndpointsStatus()[0].BackoffUntil, Equals, s.now.Add(2*time.Minute))
+
+	// up to the maximum
+	for i := 0; i < 3; i++ {
+		s.now = s.now.Add(4 * time.Minute)
+		_, err = sto.Sections(s.ctx, nil)
+		c.Assert(err, IsNil)
+	}
+	c.Check(primaryHits, Equals, 7)
+	c.Check(sto.EndpointsStatus()[0].BackoffUntil, Equals, s.now.Add(4*time.Minute))
+
+	// once the primary endpoint recovers it is healthy again
+	primaryStatus = 200
+	s.now = s.now.Add(4 * time.Minute)
+	_, err = sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(primaryHits, Equals, 8)
+	c.Check(fallbackHits, Equals, 8)
+	c.Check(sto.EndpointsStatus(), DeepEquals, []store.EndpointStatus{
+		{URL: primary.URL + "/"},
+		{URL: fallback.URL + "/"},
+	})
+}
+
+func (s *endpointsSuite) TestAllEndpointsBackingOff(c *C) {
+	primaryStatus, fallbackStatus := 500, 500
+	var primaryHits, fallbackHits int
+	primary := s.mockSectionsServer(c, &primaryStatus, &primaryHits)
+	fallback := s.mockSectionsServer(c, &fallbackStatus, &fallbackHits)
+
+	sto := s.newStore(c, primary.URL, fallback.URL)
+	// the retry strategy makes each call do two rounds
+	for i := 0; i < 2; i++ {
+		_, err := sto.Sections(s.ctx, nil)
+		c.Assert(err, ErrorMatches, "cannot sections: got unexpected HTTP status code 500 via GET to .*")
+	}
+	c.Check(primaryHits, Equals, 4)
+	c.Check(fallbackHits, Equals, 4)
+	for _, st := range sto.EndpointsStatus() {
+		c.Check(st.BackoffUntil.IsZero(), Equals, false)
+	}
+
+	// with everything backing off requests are still attempted in order
+	primaryStatus = 200
+	sections, err := sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(sections, DeepEquals, []string{"featured", "database"})
+	c.Check(primaryHits, Equals, 5)
+	c.Check(fallbackHits, Equals, 4)
+}
+
+func (s *endpointsSuite) TestSingleEndpointNeverSkipped(c *C) {
+	primaryStatus := 500
+	var primaryHits int
+	primary := s.mockSectionsServer(c, &primaryStatus, &primaryHits)
+
+	sto := s.newStore(c, primary.URL)
+	for i := 0; i < 2; i++ {
+		_, err := sto.Sections(s.ctx, nil)
+		c.Assert(err, NotNil)
+	}
+	c.Check(primaryHits, Equals, 4)
+	c.Check(sto.EndpointsStatus()[0].Failures, Equals, 4)
+
+	primaryStatus = 200
+	_, err := sto.Sections(s.ctx, nil)
+	c.Assert(err, IsNil)
+	c.Check(primaryHits, Equals, 5)
+}
+
+func (s *endpointsSuite) TestAssertionsFailover(c *C) {
+	storeStack := assertstest.NewStoreStack("canonical", nil)
+	mockAssertionsServer := func(status *int, hits *int) *httptest.Server {
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assertRequest(c, r, "GET", "/api/v1/snaps/assertions/account/canonical")
+			*hits++
+			w.Header().Set("Content-Type", "application/x.ubuntu.assertion")
+			w.WriteHeader(*status)
+			if *status == 200 {
+				w.Write(asserts.Encode(storeStack.TrustedAccount))
+			}
+		}))
+		s.AddCleanup(mockServer.Close)
+		return mockServer
+	}
+	primaryStatus, fallbackStatus := 503, 200
+	var primaryHits, fallbackHits int
+	primary := mockAssertionsServer(&primaryStatus, &primaryHits)
+	fallback := mockAssertionsServer(&fallbackStatus, &fallbackHits)
+
+	// the store endpoints are not involved
+	storeURL, err := url.Parse("http://store.invalid/")
+	c.Assert(err, IsNil)
+	primaryURL, err := url.Parse(primary.URL)
+	c.Assert(err, IsNil)
+	fallbackURL, err := url.Parse(fallback.URL)
+	c.Assert(err, IsNil)
+	sto := store.New(&store.Config{
+		StoreBaseURL:      storeURL,
+		AssertionsBaseURL: primaryURL,
+		AssertionsEndpoints: func() ([]*url.URL, error) {
+			return []*url.URL{fallbackURL}, nil
+		},
+	}, nil)
+
+	a, err := sto.Assertion(asserts.AccountType, []string{"canonical"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.Account).AccountID(), Equals, "canonical")
+	c.Check(primaryHits, Equals, 1)
+	c.Check(fallbackHits, Equals, 1)
+
+	c.Check(sto.EndpointsStatus(), DeepEquals, []store.EndpointStatus{
+		{URL: "http://store.invalid/"},
+		{URL: primary.URL + "/", Service: "assertions", Failures: 1, LastError: "503 Service Unavailable"},
+		{URL: fallback.URL + "/", Service: "assertions"},
+	})
+}
+
+func (s *endpointsSuite) TestConnectivityCheckEachEndpoint(c *C) {
+	store.MockConnCheckStrategy(&s.BaseTest, retry.LimitCount(1, retry.Exponential{
+		Initial: time.Millisecond,
+		Factor:  1,
+	}))
+
+	var goodURL *url.URL
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snaps/info/core":
+			io.WriteString(w, `{"channel-map": [{"download": {"url": "`+goodURL.String()+`/download/core"}}]}`)
+		case "/download/core":
+			c.Check(r.Method, Equals, "HEAD")
+		default:
+			c.Fatalf("unexpected request: %s", r.URL.String())
+		}
+	}))
+	defer good.Close()
+	goodURL, _ = url.Parse(good.URL)
+
+	var badHits int
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badHits++
+		w.WriteHeader(502)
+	}))
+	defer bad.Close()
+	badURL, _ := url.Parse(bad.URL)
+
+	// the unhealthy fallback is checked too, not failed over from
+	sto := s.newStore(c, good.URL, bad.URL)
+	connectivity, err := sto.ConnectivityCheck()
+	c.Assert(err, IsNil)
+	c.Check(connectivity, DeepEquals, map[string]bool{
+		goodURL.Host: true,
+		badURL.Host:  false,
+	})
+	c.Check(badHits, Equals, 1)
+	c.Check(sto.EndpointsStatus()[1].LastError, Equals, "502 Bad Gateway")
+}
@@ -25,6 +25,7 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/juju/ratelimit"
 	"gopkg.in/retry.v1"
@@ -42,6 +43,7 @@ var (
 
 	UseDeltas  = useDeltas
 	ApplyDelta = applyDelta
+	DeltaChain = deltaChain
 
 	AuthLocation      = authLocation
 	AuthURL           = authURL
@@ -58,6 +60,8 @@ var (
 
 	JsonContentType  = jsonContentType
 	SnapActionFields = snapActionFields
+
+	NewCacheManagerWithMaxSize = newCacheManager
 )
 
 // MockDefaultRetryStrategy mocks the retry strategy used by several store requests
@@ -97,6 +101,30 @@ func (cm *CacheManager) Count() int {
 	return cm.count()
 }
 
+func MockTimeNow(f func() time.Time) (restore func()) {
+	oldTimeNow := timeNow
+	timeNow = f
+	return func() {
+		timeNow = oldTimeNow
+	}
+}
+
+func MockEndpointBackoff(initial, max time.Duration) (restore func()) {
+	oldInitial, oldMax := endpointBackoffInitial, endpointBackoffMax
+	endpointBackoffInitial, endpointBackoffMax = initial, max
+	return func() {
+		endpointBackoffInitial, endpointBackoffMax = oldInitial, oldMax
+	}
+}
+
+func MockDownloadCheckpointInterval(n int64) (restore func()) {
+	old := downloadCheckpointInterval
+	downloadCheckpointInterval = n
+	return func() {
+		downloadCheckpointInterval = old
+	}
+}
+
 func MockOsRemove(f func(name string) error) func() {
 	oldOsRemove := osRemove
 	osRemove = f
@@ -121,7 +149,7 @@ func MockDoDownloadReq(f func(ctx context.Context, storeURL *url.URL, cdnHeader
 	}
 }
 
-func MockApplyDelta(f func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error) (restore func()) {
+func MockApplyDelta(f func(name string, sourcePath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error) (restore func()) {
 	origApplyDelta := applyDelta
 	applyDelta = f
 	return func() {
@@ -137,12 +165,12 @@ func (sto *Store) MockCacher(obs downloadCache) (restore func()) {
 	}
 }
 
-func (sto *Store) SetDeltaFormat(dfmt string) {
-	sto.deltaFormat = dfmt
+func (sto *Store) AcceptedDeltaFormats() []string {
+	return sto.acceptedDeltaFormats()
 }
 
-func (sto *Store) DownloadDelta(deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
-	return sto.downloadDelta(deltaName, downloadInfo, w, pbar, user, dlOpts)
+func (sto *Store) DownloadDelta(deltaName string, deltaInfo *snap.DeltaInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
+	return sto.downloadDelta(deltaName, deltaInfo, w, pbar, user, dlOpts)
 }
 
 func (sto *Store) DoRequest(ctx context.Context, client *http.Client, reqOptions *requestOptions, user *auth.UserState) (*http.Response, error) {
@@ -202,3 +230,13 @@ type (
 )
 
 var ReportFetchAssertionsError = reportFetchAssertionsError
+
+var DownloadFromPeer = downloadFromPeer
+
+func MockPeerLookupHost(f func(host string) ([]string, error)) (restore func()) {
+	old := peerLookupHost
+	peerLookupHost = f
+	return func() {
+		peerLookupHost = old
+	}
+}
@@ -0,0 +1,905 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package offline implements a store serving snaps and assertions from
+// a local directory, for devices without network access.
+//
+// The directory holds the snap files, any number of *.assert files with
+// the assertions supporting them and a manifest.yaml mapping the
+// channels to the snap revisions:
+//
+//   snaps:
+//     - name: hello
+//       id: mVyGrEwiqSi5PugCwyH7WgpoQLemtTd6
+//       revisions:
+//         - revision: 12
+//           file: hello_12.snap
+//           channels: [latest/stable]
+//         - revision: 13
+//           file: hello_13.snap
+//           channels: [latest/beta, latest/edge]
+//
+// The manifest must come with a manifest.yaml.sig holding its signature,
+// as returned by asserts.SignContent, made with one of the keys the
+// store is told to trust. This way the channel mapping, and so which
+// revisions get installed or refreshed to, cannot be changed by anyone
+// able to write to the directory.
+//
+// Every revision must be covered by a snap-revision assertion, which
+// provides the size and digest the downloads are checked against.
+package offline
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "golang.org/x/crypto/sha3"
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/snap/snapfile"
+	"github.com/snapcore/snapd/store"
+)
+
+// ManifestFile is the name of the file describing the content of an
+// offline store directory.
+const ManifestFile = "manifest.yaml"
+
+// ManifestSignatureFile is the name of the file holding the signature
+// of the manifest.
+const ManifestSignatureFile = ManifestFile + ".sig"
+
+var (
+	// ErrNotSupported is returned for the operations that need the
+	// online store.
+	ErrNotSupported = errors.New("operation not supported by the offline store")
+
+	openSnapFile = snapfile.Open
+)
+
+type manifestRevision struct {
+	Revision snap.Revision `yaml:"revision"`
+	File     string        `yaml:"file"`
+	Channels []string      `yaml:"channels"`
+}
+
+type manifestSnap struct {
+	Name      string             `yaml:"name"`
+	SnapID    string             `yaml:"id"`
+	Revisions []manifestRevision `yaml:"revisions"`
+}
+
+type manifest struct {
+	Snaps []manifestSnap `yaml:"snaps"`
+}
+
+type offlineRevision struct {
+	revision snap.Revision
+	path     string
+	size     int64
+	sha3_384 string
+}
+
+type offlineSnap struct {
+	name   string
+	snapID string
+	// channels maps full channel names to revisions
+	channels  map[string]*offlineRevision
+	revisions map[snap.Revision]*offlineRevision
+}
+
+// content is what the directory provides, as described by a given
+// version of its manifest.
+type content struct {
+	snaps      map[string]*offlineSnap
+	assertions map[string]asserts.Assertion
+}
+
+// manifestStamp identifies a version of the manifest and its signature,
+// to notice when the medium behind the directory got swapped.
+type manifestStamp struct {
+	modTime    time.Time
+	size       int64
+	sigModTime time.Time
+	sigSize    int64
+}
+
+// Store serves snaps and assertions from a directory.
+type Store struct {
+	dir         string
+	trustedKeys []*asserts.AccountKey
+
+	mu      sync.Mutex
+	stamp   manifestStamp
+	content *content
+}
+
+func loadAssertions(dir string) (map[string]asserts.Assertion, error) {
+	fns, err := filepath.Glob(filepath.Join(dir, "*.assert"))
+	if err != nil {
+		return nil, err
+	}
+	assertions := make(map[string]asserts.Assertion)
+	for _, fn := range fns {
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+		dec := asserts.NewDecoder(f)
+		for {
+			a, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("cannot decode assertions from %q: %v", fn, err)
+			}
+			key := a.Ref().Unique()
+			if prev, ok := assertions[key]; ok && prev.Revision() >= a.Revision() {
+				continue
+			}
+			assertions[key] = a
+		}
+		f.Close()
+	}
+	return assertions, nil
+}
+
+// New returns a store serving the content of the given directory,
+// checking that it can be used right away. The manifest must be signed
+// with one of the trusted keys.
+func New(dir string, trustedKeys []*asserts.AccountKey) (*Store, error) {
+	s := NewUnchecked(dir, trustedKeys)
+	if _, err := s.current(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewUnchecked returns a store serving the content of the given
+// directory, which is only read when the store is used. Requests fail
+// while the directory is not available, they never go to the network.
+func NewUnchecked(dir string, trustedKeys []*asserts.AccountKey) *Store {
+	return &Store{dir: dir, trustedKeys: trustedKeys}
+}
+
+// current returns the content of the directory, reading it again
+// whenever the manifest changed since the last request.
+func (s *Store) current() (*content, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, err := os.Stat(filepath.Join(s.dir, ManifestFile))
+	if err != nil {
+		s.content = nil
+		return nil, fmt.Errorf("cannot read offline store manifest: %v", err)
+	}
+	sigFi, err := os.Stat(filepath.Join(s.dir, ManifestSignatureFile))
+	if err != nil {
+		s.content = nil
+		return nil, fmt.Errorf("cannot read offline store manifest signature: %v", err)
+	}
+	stamp := manifestStamp{
+		modTime:    fi.ModTime(),
+		size:       fi.Size(),
+		sigModTime: sigFi.ModTime(),
+		sigSize:    sigFi.Size(),
+	}
+	if s.content != nil && s.stamp == stamp {
+		return s.content, nil
+	}
+	c, err := load(s.dir, s.trustedKeys)
+	if err != nil {
+		s.content = nil
+		return nil, err
+	}
+	s.stamp = stamp
+	s.content = c
+	return c, nil
+}
+
+// checkManifestSignature checks that the manifest was signed with one
+// of the trusted keys.
+func checkManifestSignature(data, sig []byte, trustedKeys []*asserts.AccountKey) error {
+	for _, ak := range trustedKeys {
+		if asserts.CheckContentSignature(data, sig, ak) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("offline store manifest is not signed with a trusted key")
+}
+
+func load(dir string, trustedKeys []*asserts.AccountKey) (*content, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read offline store manifest: %v", err)
+	}
+	sig, err := ioutil.ReadFile(filepath.Join(dir, ManifestSignatureFile))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read offline store manifest signature: %v", err)
+	}
+	if err := checkManifestSignature(data, sig, trustedKeys); err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("cannot parse offline store manifest: %v", err)
+	}
+	assertions, err := loadAssertions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapRevisions := make(map[string]*asserts.SnapRevision)
+	for _, a := range assertions {
+		if snapRev, ok := a.(*asserts.SnapRevision); ok {
+			key := fmt.Sprintf("%s/%d", snapRev.SnapID(), snapRev.SnapRevision())
+			snapRevisions[key] = snapRev
+		}
+	}
+
+	s := &content{
+		snaps:      make(map[string]*offlineSnap, len(m.Snaps)),
+		assertions: assertions,
+	}
+	for _, ms := range m.Snaps {
+		if err := naming.ValidateSnap(ms.Name); err != nil {
+			return nil, err
+		}
+		if ms.SnapID == "" {
+			return nil, fmt.Errorf("snap %q has no snap id in the offline store manifest", ms.Name)
+		}
+		if s.snaps[ms.Name] != nil {
+			return nil, fmt.Errorf("snap %q is listed more than once in the offline store manifest", ms.Name)
+		}
+		if decl, ok := assertions[snapDeclarationKey(ms.SnapID)].(*asserts.SnapDeclaration); ok && decl.SnapName() != ms.Name {
+			return nil, fmt.Errorf("snap %q does not match the name %q in its snap-declaration", ms.Name, decl.SnapName())
+		}
+		sn := &offlineSnap{
+			name:      ms.Name,
+			snapID:    ms.SnapID,
+			channels:  make(map[string]*offlineRevision),
+			revisions: make(map[snap.Revision]*offlineRevision),
+		}
+		for _, mr := range ms.Revisions {
+			if !mr.Revision.Store() {
+				return nil, fmt.Errorf("snap %q has invalid revision %s in the offline store manifest", ms.Name, mr.Revision)
+			}
+			if mr.File == "" || filepath.IsAbs(mr.File) || strings.HasPrefix(filepath.Clean(mr.File), "..") {
+				return nil, fmt.Errorf("snap %q revision %s has invalid file %q", ms.Name, mr.Revision, mr.File)
+			}
+			snapRev := snapRevisions[fmt.Sprintf("%s/%d", ms.SnapID, mr.Revision.N)]
+			if snapRev == nil {
+				return nil, fmt.Errorf("cannot find snap-revision assertion for snap %q revision %s", ms.Name, mr.Revision)
+			}
+			// assertions carry the digest base64 encoded, the
+			// download infos hex encoded
+			digest, err := base64.RawURLEncoding.DecodeString(snapRev.SnapSHA3_384())
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode digest of snap %q revision %s: %v", ms.Name, mr.Revision, err)
+			}
+			rev := &offlineRevision{
+				revision: mr.Revision,
+				path:     filepath.Join(dir, mr.File),
+				size:     int64(snapRev.SnapSize()),
+				sha3_384: hex.EncodeToString(digest),
+			}
+			sn.revisions[mr.Revision] = rev
+			for _, ch := range mr.Channels {
+				full, err := channel.Full(ch)
+				if err != nil || full == "" {
+					return nil, fmt.Errorf("snap %q revision %s has invalid channel %q", ms.Name, mr.Revision, ch)
+				}
+				if other := sn.channels[full]; other != nil {
+					return nil, fmt.Errorf("snap %q has both revision %s and %s in channel %q", ms.Name, other.revision, mr.Revision, full)
+				}
+				sn.channels[full] = rev
+			}
+		}
+		s.snaps[ms.Name] = sn
+	}
+	return s, nil
+}
+
+func snapDeclarationKey(snapID string) string {
+	ref := asserts.Ref{Type: asserts.SnapDeclarationType, PrimaryKey: []string{release.Series, snapID}}
+	return ref.Unique()
+}
+
+var risks = []string{"stable", "candidate", "beta", "edge"}
+
+func riskLevel(risk string) int {
+	for i, r := range risks {
+		if r == risk {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolve returns the revision in the given channel and the channel it
+// was found in, following closed channels to the more stable risks as
+// the store does.
+func (sn *offlineSnap) resolve(action, ch string) (*offlineRevision, string, error) {
+	if ch == "" {
+		ch = "stable"
+	}
+	c, err := channel.Parse(ch, "")
+	if err != nil {
+		return nil, "", err
+	}
+	track := c.Track
+	if track == "" {
+		track = "latest"
+	}
+	candidates := []string{c.Full()}
+	if c.Branch == "" {
+		// closed channels follow the more stable ones
+		for i := riskLevel(c.Risk) - 1; i >= 0; i-- {
+			candidates = append(candidates, track+"/"+risks[i])
+		}
+	}
+	for _, name := range candidates {
+		if rev := sn.channels[name]; rev != nil {
+			return rev, name, nil
+		}
+	}
+	return nil, "", &store.RevisionNotAvailableError{
+		Action:   action,
+		Channel:  ch,
+		Releases: sn.releases(),
+	}
+}
+
+func (sn *offlineSnap) releases() []channel.Channel {
+	names := make([]string, 0, len(sn.channels))
+	for name := range sn.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	releases := make([]channel.Channel, 0, len(names))
+	for _, name := range names {
+		if c, err := channel.Parse(name, ""); err == nil {
+			releases = append(releases, c)
+		}
+	}
+	return releases
+}
+
+// defaultRevision returns the revision shown when no channel was asked
+// for, the one in latest/stable or otherwise the highest one.
+func (sn *offlineSnap) defaultRevision() (*offlineRevision, string) {
+	if rev := sn.channels["latest/stable"]; rev != nil {
+		return rev, "latest/stable"
+	}
+	var best *offlineRevision
+	for _, rev := range sn.revisions {
+		if best == nil || best.revision.N < rev.revision.N {
+			best = rev
+		}
+	}
+	return best, ""
+}
+
+func (s *content) publisher(snapID string) snap.StoreAccount {
+	decl, ok := s.assertions[snapDeclarationKey(snapID)].(*asserts.SnapDeclaration)
+	if !ok {
+		return snap.StoreAccount{}
+	}
+	acct := snap.StoreAccount{ID: decl.PublisherID()}
+	ref := asserts.Ref{Type: asserts.AccountType, PrimaryKey: []string{decl.PublisherID()}}
+	if account, ok := s.assertions[ref.Unique()].(*asserts.Account); ok {
+		acct.Username = account.Username()
+		acct.DisplayName = account.DisplayName()
+		acct.Validation = account.Validation()
+	}
+	return acct
+}
+
+func (s *content) info(sn *offlineSnap, rev *offlineRevision, ch string) (*snap.Info, error) {
+	f, err := openSnapFile(rev.path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := snap.ReadInfoFromSnapFile(f, &snap.SideInfo{
+		RealName: sn.name,
+		SnapID:   sn.snapID,
+		Revision: rev.revision,
+		Channel:  ch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snap %q revision %s: %v", sn.name, rev.revision, err)
+	}
+	info.DownloadInfo = snap.DownloadInfo{
+		DownloadURL: rev.path,
+		Size:        rev.size,
+		Sha3_384:    rev.sha3_384,
+	}
+	info.Publisher = s.publisher(sn.snapID)
+	return info, nil
+}
+
+func (s *content) sortedSnaps() []*offlineSnap {
+	snaps := make([]*offlineSnap, 0, len(s.snaps))
+	for _, sn := range s.snaps {
+		snaps = append(snaps, sn)
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].name < snaps[j].name })
+	return snaps
+}
+
+// SnapInfo returns the snap.Info of the snap in the default channel,
+// together with its channel map.
+func (s *Store) SnapInfo(ctx context.Context, snapSpec store.SnapSpec, user *auth.UserState) (*snap.Info, error) {
+	cnt, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	sn := cnt.snaps[snapSpec.Name]
+	if sn == nil {
+		return nil, store.ErrSnapNotFound
+	}
+	rev, ch := sn.defaultRevision()
+	if rev == nil {
+		return nil, store.ErrSnapNotFound
+	}
+	info, err := cnt.info(sn, rev, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Channels = make(map[string]*snap.ChannelSnapInfo, len(sn.channels))
+	tracks := make(map[string]bool)
+	for name, chRev := range sn.channels {
+		chInfo, err := cnt.info(sn, chRev, name)
+		if err != nil {
+			return nil, err
+		}
+		info.Channels[name] = &snap.ChannelSnapInfo{
+			Revision:    chRev.revision,
+			Confinement: chInfo.Confinement,
+			Version:     chInfo.Version,
+			Channel:     name,
+			Epoch:       chInfo.Epoch,
+			Size:        chRev.size,
+		}
+		tracks[strings.SplitN(name, "/", 2)[0]] = true
+	}
+	for track := range tracks {
+		info.Tracks = append(info.Tracks, track)
+	}
+	sort.Strings(info.Tracks)
+	return info, nil
+}
+
+// Find returns the snaps whose name, title or summary match the search.
+func (s *Store) Find(ctx context.Context, search *store.Search, user *auth.UserState) ([]*snap.Info, error) {
+	cnt, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	if search.Private && user == nil {
+		return nil, store.ErrUnauthenticated
+	}
+	if search.Scope != "" && search.Scope != "wide" {
+		return nil, store.ErrInvalidScope
+	}
+	term := strings.ToLower(strings.TrimSpace(search.Query))
+	if strings.ContainsAny(term, `+=&|><!(){}[]^"~*?:\/`) {
+		return nil, store.ErrBadQuery
+	}
+	if search.Private {
+		// an offline store has no private snaps
+		return nil, nil
+	}
+
+	var infos []*snap.Info
+	for _, sn := range cnt.sortedSnaps() {
+		if search.Prefix && !strings.HasPrefix(sn.name, term) {
+			continue
+		}
+		rev, ch := sn.defaultRevision()
+		if rev == nil || (ch == "" && search.Scope != "wide") {
+			continue
+		}
+		info, err := cnt.info(sn, rev, ch)
+		if err != nil {
+			return nil, err
+		}
+		if !search.Prefix && term != "" &&
+			!strings.Contains(sn.name, term) &&
+			!strings.Contains(strings.ToLower(info.Title()), term) &&
+			!strings.Contains(strings.ToLower(info.Summary()), term) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// SnapAction resolves the install, download and refresh actions against
+// the channel maps of the manifest and the fetch-assertions queries
+// against the assertions in the directory.
+func (s *Store) SnapAction(ctx context.Context, currentSnaps []*store.CurrentSnap, actions []*store.SnapAction, assertQuery store.AssertionQuery, user *auth.UserState, opts *store.RefreshOptions) ([]store.SnapActionResult, []store.AssertionResult, error) {
+	cnt, err := s.current()
+	if err != nil {
+		return nil, nil, err
+	}
+	var ars []store.AssertionResult
+	if assertQuery != nil {
+		var err error
+		ars, err = cnt.resolveAssertions(assertQuery)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(currentSnaps) == 0 && len(actions) == 0 && len(ars) == 0 {
+		// nothing to do
+		return nil, nil, &store.SnapActionError{NoResults: true}
+	}
+
+	curSnaps := make(map[string]*store.CurrentSnap, len(currentSnaps))
+	for _, cur := range currentSnaps {
+		curSnaps[cur.InstanceName] = cur
+	}
+
+	refreshErrors := make(map[string]error)
+	installErrors := make(map[string]error)
+	downloadErrors := make(map[string]error)
+	var sars []store.SnapActionResult
+	for _, a := range actions {
+		errs := installErrors
+		switch a.Action {
+		case "download":
+			errs = downloadErrors
+		case "refresh":
+			errs = refreshErrors
+		case "install":
+		default:
+			return nil, nil, fmt.Errorf("internal error: unsupported action %q", a.Action)
+		}
+
+		snapName, instanceKey := snap.SplitInstanceName(a.InstanceName)
+		var cur *store.CurrentSnap
+		if a.Action == "refresh" {
+			cur = curSnaps[a.InstanceName]
+			if cur == nil {
+				return nil, nil, fmt.Errorf("internal error: no %q in current snaps", a.InstanceName)
+			}
+		}
+		sn := cnt.snaps[snapName]
+		if sn == nil || (a.SnapID != "" && a.SnapID != sn.snapID) {
+			errs[a.InstanceName] = store.ErrSnapNotFound
+			continue
+		}
+
+		var rev *offlineRevision
+		ch := a.Channel
+		if cur != nil && ch == "" && a.Revision.Unset() {
+			ch = cur.TrackingChannel
+		}
+		if !a.Revision.Unset() {
+			rev = sn.revisions[a.Revision]
+			if rev == nil {
+				errs[a.InstanceName] = &store.RevisionNotAvailableError{Action: a.Action, Channel: ch, Releases: sn.releases()}
+				continue
+			}
+		} else {
+			var err error
+			rev, ch, err = sn.resolve(a.Action, ch)
+			if err != nil {
+				errs[a.InstanceName] = err
+				continue
+			}
+		}
+		if cur != nil && (rev.revision == cur.Revision || revisionIn(rev.revision, cur.Block)) {
+			errs[a.InstanceName] = store.ErrNoUpdateAvailable
+			continue
+		}
+
+		info, err := cnt.info(sn, rev, ch)
+		if err != nil {
+			return nil, nil, err
+		}
+		info.InstanceKey = instanceKey
+		sars = append(sars, store.SnapActionResult{Info: info})
+	}
+
+	if len(refreshErrors)+len(installErrors)+len(downloadErrors) != 0 || len(sars)+len(ars) == 0 {
+		// normalize empty maps
+		if len(refreshErrors) == 0 {
+			refreshErrors = nil
+		}
+		if len(installErrors) == 0 {
+			installErrors = nil
+		}
+		if len(downloadErrors) == 0 {
+			downloadErrors = nil
+		}
+		return sars, ars, &store.SnapActionError{
+			NoResults: len(sars)+len(ars) == 0,
+			Refresh:   refreshErrors,
+			Install:   installErrors,
+			Download:  downloadErrors,
+		}
+	}
+	return sars, ars, nil
+}
+
+func revisionIn(needle snap.Revision, haystack []snap.Revision) bool {
+	for _, r := range haystack {
+		if needle == r {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAssertions returns the keys of the assertions newer than the
+// ones asked for, as the stream URLs to use with DownloadAssertions.
+func (s *content) resolveAssertions(assertQuery store.AssertionQuery) ([]store.AssertionResult, error) {
+	toResolve, err := assertQuery.ToResolve()
+	if err != nil {
+		return nil, err
+	}
+	var ars []store.AssertionResult
+	for grp, ats := range toResolve {
+		var keys []string
+		for _, at := range ats {
+			key := at.Ref.Unique()
+			a := s.assertions[key]
+			if a == nil {
+				headers, err := asserts.HeadersFromPrimaryKey(at.Type, at.PrimaryKey)
+				if err != nil {
+					return nil, err
+				}
+				if err := assertQuery.AddError(&asserts.NotFoundError{Type: at.Type, Headers: headers}, &at.Ref); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if a.Revision() > at.Revision {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) != 0 {
+			ars = append(ars, store.AssertionResult{Grouping: grp, StreamURLs: keys})
+		}
+	}
+	return ars, nil
+}
+
+// Assertion returns the assertion with the given type and primary key.
+func (s *Store) Assertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error) {
+	cnt, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	ref := asserts.Ref{Type: assertType, PrimaryKey: primaryKey}
+	if a := cnt.assertions[ref.Unique()]; a != nil {
+		return a, nil
+	}
+	headers, err := asserts.HeadersFromPrimaryKey(assertType, primaryKey)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &asserts.NotFoundError{Type: assertType, Headers: headers}
+}
+
+// DownloadAssertions adds the assertions returned by SnapAction for the
+// fetch-assertions queries to the batch.
+func (s *Store) DownloadAssertions(keys []string, b *asserts.Batch, user *auth.UserState) error {
+	cnt, err := s.current()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		a := cnt.assertions[key]
+		if a == nil {
+			return fmt.Errorf("cannot find assertion %q in the offline store", key)
+		}
+		if err := b.Add(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapPath returns the path of the snap file to download, making sure
+// it belongs to the offline store.
+func (s *content) snapPath(downloadInfo *snap.DownloadInfo) (string, error) {
+	for _, sn := range s.snaps {
+		for _, rev := range sn.revisions {
+			if rev.path == downloadInfo.DownloadURL && rev.sha3_384 == downloadInfo.Sha3_384 {
+				return rev.path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot find %q in the offline store", downloadInfo.DownloadURL)
+}
+
+// Download copies the snap file to targetPath, checking its digest.
+func (s *Store) Download(ctx context.Context, name string, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState, dlOpts *store.DownloadOptions) error {
+	cnt, err := s.current()
+	if err != nil {
+		return err
+	}
+	path, err := cnt.snapPath(downloadInfo)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	partialPath := targetPath + ".partial"
+	w, err := os.OpenFile(partialPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		w.Close()
+		if err != nil {
+			os.Remove(partialPath)
+		}
+	}()
+
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	h := crypto.SHA3_384.New()
+	pbar.Start(name, float64(downloadInfo.Size))
+	_, err = io.Copy(io.MultiWriter(w, h, pbar), src)
+	pbar.Finished()
+	if err != nil {
+		return err
+	}
+	if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != downloadInfo.Sha3_384 {
+		err = fmt.Errorf("sha3-384 mismatch for %q: got %s but expected %s", name, actual, downloadInfo.Sha3_384)
+		return err
+	}
+	if err = w.Sync(); err != nil {
+		return err
+	}
+	err = os.Rename(partialPath, targetPath)
+	return err
+}
+
+// DownloadStream returns a reader of the snap file, starting at resume.
+func (s *Store) DownloadStream(ctx context.Context, name string, downloadInfo *snap.DownloadInfo, resume int64, user *auth.UserState) (io.ReadCloser, int, error) {
+	cnt, err := s.current()
+	if err != nil {
+		return nil, 0, err
+	}
+	path, err := cnt.snapPath(downloadInfo)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resume == 0 {
+		return f, 200, nil
+	}
+	if _, err := f.Seek(resume, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, 206, nil
+}
+
+// WriteCatalogs writes the names and commands of the snaps in their
+// default channels.
+func (s *Store) WriteCatalogs(ctx context.Context, names io.Writer, adder store.SnapAdder) error {
+	cnt, err := s.current()
+	if err != nil {
+		return err
+	}
+	for _, sn := range cnt.sortedSnaps() {
+		rev, ch := sn.defaultRevision()
+		if rev == nil {
+			continue
+		}
+		info, err := cnt.info(sn, rev, ch)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(names, sn.name)
+		if len(info.Apps) == 0 {
+			continue
+		}
+		commands := make([]string, 0, len(info.Apps))
+		for _, app := range info.Apps {
+			commands = append(commands, snap.JoinSnapApp(sn.name, app.Name))
+		}
+		sort.Strings(commands)
+		if err := adder.AddSnap(sn.name, info.Version, info.Summary(), commands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConnectivityCheck reports whether the directory is reachable.
+func (s *Store) ConnectivityCheck() (map[string]bool, error) {
+	_, err := os.Stat(filepath.Join(s.dir, ManifestFile))
+	return map[string]bool{s.dir: err == nil}, nil
+}
+
+// EndpointsStatus reports the directory as the only endpoint.
+func (s *Store) EndpointsStatus() []store.EndpointStatus {
+	return []store.EndpointStatus{{URL: s.dir}}
+}
+
+// EnsureDeviceSession is a no-op, the offline store needs no session.
+func (s *Store) EnsureDeviceSession() (*auth.DeviceState, error) {
+	return nil, nil
+}
+
+// Sections returns no sections, the offline store has no categories.
+func (s *Store) Sections(ctx context.Context, user *auth.UserState) ([]string, error) {
+	return nil, nil
+}
+
+func (s *Store) SuggestedCurrency() string {
+	return ""
+}
+
+func (s *Store) Buy(options *client.BuyOptions, user *auth.UserState) (*client.BuyResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *Store) ReadyToBuy(*auth.UserState) error {
+	return ErrNotSupported
+}
+
+func (s *Store) CreateCohorts(context.Context, []string) (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *Store) LoginUser(username, password, otp string) (string, string, error) {
+	return "", "", ErrNotSupported
+}
+
+func (s *Store) UserInfo(email string) (*store.User, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,597 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package offline_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type offlineSuite struct {
+	testutil.BaseTest
+
+	dir         string
+	storeStack  *assertstest.StoreStack
+	assertions  []asserts.Assertion
+	signingKey  asserts.PrivateKey
+	trustedKeys []*asserts.AccountKey
+}
+
+var _ = Suite(&offlineSuite{})
+
+const helloID = "hello-id-aaaaaaaaaaaaaaaaaaaaaaa"
+
+const manifestYaml = `
+snaps:
+  - name: hello
+    id: hello-id-aaaaaaaaaaaaaaaaaaaaaaa
+    revisions:
+      - revision: 1
+        file: hello_1
+        channels: [latest/stable, 2.0/stable]
+      - revision: 2
+        file: hello_2
+        channels: [beta, latest/edge]
+      - revision: 3
+        file: hello_3.snap
+`
+
+func (s *offlineSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.BaseTest.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+
+	s.dir = c.MkDir()
+	s.storeStack = assertstest.NewStoreStack("canonical", nil)
+	s.assertions = nil
+
+	account := assertstest.NewAccount(s.storeStack, "acme", map[string]interface{}{
+		"account-id": "acme-id",
+	}, "")
+	decl, err := s.storeStack.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      helloID,
+		"publisher-id": "acme-id",
+		"snap-name":    "hello",
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	s.assertions = append(s.assertions, account, decl)
+
+	s.signingKey, _ = assertstest.GenerateKey(752)
+	accKey := assertstest.NewAccountKey(s.storeStack, account, map[string]interface{}{
+		"since": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}, s.signingKey.PublicKey(), "")
+	s.trustedKeys = []*asserts.AccountKey{accKey}
+
+	// unpacked snaps, so that no mksquashfs is needed
+	for _, rev := range []string{"1", "2"} {
+		snapDir := filepath.Join(s.dir, "hello_"+rev)
+		c.Assert(os.MkdirAll(filepath.Join(snapDir, "meta"), 0755), IsNil)
+		snapYaml := "name: hello\nversion: " + rev + ".0\nsummary: Says hello\napps:\n  hello:\n    command: bin/hello\n"
+		c.Assert(ioutil.WriteFile(filepath.Join(snapDir, "meta", "snap.yaml"), []byte(snapYaml), 0644), IsNil)
+		s.addSnapRevision(c, helloID, rev, strings.Repeat(rev, 64), "4096")
+	}
+	// a plain file for the downloads
+	snapFile := filepath.Join(s.dir, "hello_3.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap data"), 0644), IsNil)
+	digest, size, err := asserts.SnapFileSHA3_384(snapFile)
+	c.Assert(err, IsNil)
+	s.addSnapRevision(c, helloID, "3", digest, "9")
+	c.Assert(size, Equals, uint64(9))
+
+	s.writeAssertions(c)
+	s.writeManifest(c, manifestYaml)
+}
+
+func (s *offlineSuite) writeManifest(c *C, manifest string) {
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, offline.ManifestFile), []byte(manifest), 0644), IsNil)
+	sig, err := asserts.SignContent([]byte(manifest), s.signingKey)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, offline.ManifestSignatureFile), sig, 0644), IsNil)
+}
+
+func (s *offlineSuite) addSnapRevision(c *C, snapID, rev, digest, size string) {
+	a, err := s.storeStack.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": digest,
+		"snap-size":     size,
+		"snap-id":       snapID,
+		"developer-id":  "acme-id",
+		"snap-revision": rev,
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	s.assertions = append(s.assertions, a)
+}
+
+func (s *offlineSuite) writeAssertions(c *C) {
+	var buf bytes.Buffer
+	enc := asserts.NewEncoder(&buf)
+	for _, a := range s.assertions {
+		c.Assert(enc.Encode(a), IsNil)
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "snaps.assert"), buf.Bytes(), 0644), IsNil)
+}
+
+func (s *offlineSuite) newStore(c *C) *offline.Store {
+	sto, err := offline.New(s.dir, s.trustedKeys)
+	c.Assert(err, IsNil)
+	return sto
+}
+
+func (s *offlineSuite) TestNewErrors(c *C) {
+	for _, t := range []struct {
+		manifest string
+		err      string
+	}{
+		{"snaps: [{name: hello, id: " + helloID + ", revisions: [{revision: 4, file: hello_4.snap}]}]",
+			`cannot find snap-revision assertion for snap "hello" revision 4`},
+		{"snaps: [{name: hello, id: " + helloID + ", revisions: [{revision: 1, file: ../hello_1}]}]",
+			`snap "hello" revision 1 has invalid file "../hello_1"`},
+		{"snaps: [{name: hello, id: " + helloID + ", revisions: [{revision: x1, file: hello_1}]}]",
+			`snap "hello" has invalid revision x1 in the offline store manifest`},
+		{"snaps: [{name: hello, id: " + helloID + ", revisions: [{revision: 1, file: hello_1, channels: [stable]}, {revision: 2, file: hello_2, channels: [latest/stable]}]}]",
+			`snap "hello" has both revision 1 and 2 in channel "latest/stable"`},
+		{"snaps: [{name: hello, id: " + helloID + ", revisions: [{revision: 1, file: hello_1, channels: [a/b/c/d]}]}]",
+			`snap "hello" revision 1 has invalid channel "a/b/c/d"`},
+		{"snaps: [{name: other, id: " + helloID + "}]",
+			`snap "other" does not match the name "hello" in its snap-declaration`},
+		{"snaps: [{name: hello}]",
+			`snap "hello" has no snap id in the offline store manifest`},
+		{"snaps: [{name: hello, id: " + helloID + "}, {name: hello, id: " + helloID + "}]",
+			`snap "hello" is listed more than once in the offline store manifest`},
+		{"snaps: [{name: hello, idd: x}]",
+			`(?s)cannot parse offline store manifest: .*field idd not found.*`},
+	} {
+		s.writeManifest(c, t.manifest)
+		_, err := offline.New(s.dir, s.trustedKeys)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.manifest))
+	}
+
+	_, err := offline.New(c.MkDir(), s.trustedKeys)
+	c.Check(err, ErrorMatches, `cannot read offline store manifest: .*no such file or directory`)
+}
+
+func (s *offlineSuite) TestNewUnsignedManifest(c *C) {
+	c.Assert(os.Remove(filepath.Join(s.dir, offline.ManifestSignatureFile)), IsNil)
+	_, err := offline.New(s.dir, s.trustedKeys)
+	c.Check(err, ErrorMatches, `cannot read offline store manifest signature: .*no such file or directory`)
+
+	// signed with a key that is not trusted
+	s.writeManifest(c, manifestYaml)
+	_, err = offline.New(s.dir, nil)
+	c.Check(err, ErrorMatches, `offline store manifest is not signed with a trusted key`)
+
+	otherKey, _ := assertstest.GenerateKey(752)
+	sig, err := asserts.SignContent([]byte(manifestYaml), otherKey)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, offline.ManifestSignatureFile), sig, 0644), IsNil)
+	_, err = offline.New(s.dir, s.trustedKeys)
+	c.Check(err, ErrorMatches, `offline store manifest is not signed with a trusted key`)
+}
+
+func (s *offlineSuite) TestTamperedManifest(c *C) {
+	sto := s.newStore(c)
+
+	// the manifest is changed to move stable back to an older
+	// revision, keeping the signature of the original one
+	manifestPath := filepath.Join(s.dir, offline.ManifestFile)
+	tampered := strings.Replace(manifestYaml, "channels: [beta, latest/edge]", "channels: [latest/edge]", 1)
+	tampered = strings.Replace(tampered, "file: hello_3.snap", "file: hello_3.snap\n        channels: [beta]", 1)
+	c.Assert(tampered, Not(Equals), manifestYaml)
+	c.Assert(ioutil.WriteFile(manifestPath, []byte(tampered), 0644), IsNil)
+	later := time.Now().Add(time.Minute)
+	c.Assert(os.Chtimes(manifestPath, later, later), IsNil)
+
+	_, _, err := sto.SnapAction(context.TODO(), nil, []*store.SnapAction{
+		{Action: "install", InstanceName: "hello", Channel: "beta"},
+	}, nil, nil, nil)
+	c.Check(err, ErrorMatches, `offline store manifest is not signed with a trusted key`)
+	_, err = offline.New(s.dir, s.trustedKeys)
+	c.Check(err, ErrorMatches, `offline store manifest is not signed with a trusted key`)
+}
+
+func (s *offlineSuite) TestManifestChanges(c *C) {
+	sto := s.newStore(c)
+
+	info, err := sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "hello"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Revision, Equals, snap.R(1))
+
+	// the medium got swapped
+	manifestPath := filepath.Join(s.dir, offline.ManifestFile)
+	manifest := "snaps: [{name: hello, id: " + helloID + ", revisions: [{revision: 2, file: hello_2, channels: [stable]}]}]"
+	s.writeManifest(c, manifest)
+	later := time.Now().Add(time.Minute)
+	c.Assert(os.Chtimes(manifestPath, later, later), IsNil)
+
+	info, err = sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "hello"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Revision, Equals, snap.R(2))
+
+	// and removed
+	c.Assert(os.Remove(manifestPath), IsNil)
+	_, err = sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "hello"}, nil)
+	c.Check(err, ErrorMatches, `cannot read offline store manifest: .*no such file or directory`)
+}
+
+func (s *offlineSuite) TestNewUnchecked(c *C) {
+	dir := filepath.Join(c.MkDir(), "medium")
+	sto := offline.NewUnchecked(dir, s.trustedKeys)
+
+	_, err := sto.Find(context.TODO(), &store.Search{Query: "hello"}, nil)
+	c.Check(err, ErrorMatches, `cannot read offline store manifest: .*no such file or directory`)
+
+	// the medium shows up
+	c.Assert(os.Rename(s.dir, dir), IsNil)
+	infos, err := sto.Find(context.TODO(), &store.Search{Query: "hello"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(infos, HasLen, 1)
+}
+
+func (s *offlineSuite) TestSnapInfo(c *C) {
+	sto := s.newStore(c)
+
+	info, err := sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "hello"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.SnapName(), Equals, "hello")
+	c.Check(info.SnapID, Equals, helloID)
+	c.Check(info.Revision, Equals, snap.R(1))
+	c.Check(info.Version, Equals, "1.0")
+	c.Check(info.Channel, Equals, "latest/stable")
+	c.Check(info.Publisher, DeepEquals, snap.StoreAccount{
+		ID:          "acme-id",
+		Username:    "acme",
+		DisplayName: "Acme",
+		Validation:  "unproven",
+	})
+	c.Check(info.Tracks, DeepEquals, []string{"2.0", "latest"})
+	c.Check(info.Channels, HasLen, 4)
+	c.Check(info.Channels["latest/beta"], DeepEquals, &snap.ChannelSnapInfo{
+		Revision:    snap.R(2),
+		Confinement: snap.StrictConfinement,
+		Version:     "2.0",
+		Channel:     "latest/beta",
+		Epoch:       snap.E("0"),
+		Size:        4096,
+	})
+
+	_, err = sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "unknown"}, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+}
+
+func (s *offlineSuite) TestFind(c *C) {
+	sto := s.newStore(c)
+
+	for _, search := range []*store.Search{
+		{Query: "hello"},
+		{Query: "says"},
+		{Query: "hel", Prefix: true},
+		{Query: ""},
+	} {
+		infos, err := sto.Find(context.TODO(), search, nil)
+		c.Assert(err, IsNil)
+		c.Assert(infos, HasLen, 1, Commentf("%#v", search))
+		c.Check(infos[0].SnapName(), Equals, "hello")
+	}
+
+	infos, err := sto.Find(context.TODO(), &store.Search{Query: "ello", Prefix: true}, nil)
+	c.Assert(err, IsNil)
+	c.Check(infos, HasLen, 0)
+
+	_, err = sto.Find(context.TODO(), &store.Search{Query: "a*"}, nil)
+	c.Check(err, Equals, store.ErrBadQuery)
+	_, err = sto.Find(context.TODO(), &store.Search{Scope: "narrow"}, nil)
+	c.Check(err, Equals, store.ErrInvalidScope)
+	_, err = sto.Find(context.TODO(), &store.Search{Private: true}, nil)
+	c.Check(err, Equals, store.ErrUnauthenticated)
+}
+
+func (s *offlineSuite) TestSnapActionInstall(c *C) {
+	sto := s.newStore(c)
+
+	for _, t := range []struct {
+		channel  string
+		revision snap.Revision
+		expected snap.Revision
+		effChan  string
+	}{
+		{"", snap.R(0), snap.R(1), "latest/stable"},
+		{"stable", snap.R(0), snap.R(1), "latest/stable"},
+		{"latest/beta", snap.R(0), snap.R(2), "latest/beta"},
+		// closed channels follow the more stable ones
+		{"candidate", snap.R(0), snap.R(1), "latest/stable"},
+		{"edge", snap.R(0), snap.R(2), "latest/edge"},
+		{"2.0/edge", snap.R(0), snap.R(1), "2.0/stable"},
+		{"", snap.R(2), snap.R(2), ""},
+	} {
+		sars, _, err := sto.SnapAction(context.TODO(), nil, []*store.SnapAction{{
+			Action:       "install",
+			InstanceName: "hello_foo",
+			Channel:      t.channel,
+			Revision:     t.revision,
+		}}, nil, nil, nil)
+		c.Assert(err, IsNil, Commentf(t.channel))
+		c.Assert(sars, HasLen, 1)
+		c.Check(sars[0].InstanceName(), Equals, "hello_foo")
+		c.Check(sars[0].Revision, Equals, t.expected, Commentf(t.channel))
+		c.Check(sars[0].Channel, Equals, t.effChan, Commentf(t.channel))
+	}
+}
+
+func (s *offlineSuite) TestSnapActionErrors(c *C) {
+	sto := s.newStore(c)
+
+	_, _, err := sto.SnapAction(context.TODO(), nil, nil, nil, nil, nil)
+	c.Check(err, DeepEquals, &store.SnapActionError{NoResults: true})
+
+	sars, _, err := sto.SnapAction(context.TODO(), nil, []*store.SnapAction{{
+		Action:       "install",
+		InstanceName: "unknown",
+	}, {
+		Action:       "download",
+		InstanceName: "hello",
+		Channel:      "3.0/stable",
+	}}, nil, nil, nil)
+	c.Check(sars, HasLen, 0)
+	saErr, ok := err.(*store.SnapActionError)
+	c.Assert(ok, Equals, true)
+	c.Check(saErr.NoResults, Equals, true)
+	c.Check(saErr.Install, DeepEquals, map[string]error{"unknown": store.ErrSnapNotFound})
+	c.Assert(saErr.Download["hello"], FitsTypeOf, &store.RevisionNotAvailableError{})
+	rnaErr := saErr.Download["hello"].(*store.RevisionNotAvailableError)
+	c.Check(rnaErr.Action, Equals, "download")
+	c.Check(rnaErr.Releases, HasLen, 4)
+}
+
+func (s *offlineSuite) TestSnapActionRefresh(c *C) {
+	sto := s.newStore(c)
+
+	current := []*store.CurrentSnap{{
+		InstanceName:    "hello",
+		SnapID:          helloID,
+		Revision:        snap.R(1),
+		TrackingChannel: "latest/stable",
+	}}
+	refresh := []*store.SnapAction{{
+		Action:       "refresh",
+		InstanceName: "hello",
+		SnapID:       helloID,
+	}}
+	sars, _, err := sto.SnapAction(context.TODO(), current, refresh, nil, nil, nil)
+	c.Check(sars, HasLen, 0)
+	c.Check(err, DeepEquals, &store.SnapActionError{
+		NoResults: true,
+		Refresh:   map[string]error{"hello": store.ErrNoUpdateAvailable},
+	})
+
+	current[0].TrackingChannel = "latest/beta"
+	sars, _, err = sto.SnapAction(context.TODO(), current, refresh, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(sars, HasLen, 1)
+	c.Check(sars[0].Revision, Equals, snap.R(2))
+	c.Check(sars[0].Channel, Equals, "latest/beta")
+
+	current[0].Block = []snap.Revision{snap.R(2)}
+	_, _, err = sto.SnapAction(context.TODO(), current, refresh, nil, nil, nil)
+	c.Check(err, DeepEquals, &store.SnapActionError{
+		NoResults: true,
+		Refresh:   map[string]error{"hello": store.ErrNoUpdateAvailable},
+	})
+}
+
+func (s *offlineSuite) TestAssertion(c *C) {
+	sto := s.newStore(c)
+
+	a, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", helloID}, nil)
+	c.Assert(err, IsNil)
+	c.Check(a.HeaderString("snap-name"), Equals, "hello")
+
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "other-id"}, nil)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+type mockAssertQuery struct {
+	toResolve map[asserts.Grouping][]*asserts.AtRevision
+	errors    map[string]error
+}
+
+func (q *mockAssertQuery) ToResolve() (map[asserts.Grouping][]*asserts.AtRevision, error) {
+	return q.toResolve, nil
+}
+
+func (q *mockAssertQuery) AddError(e error, ref *asserts.Ref) error {
+	q.errors[ref.Unique()] = e
+	return nil
+}
+
+func (q *mockAssertQuery) AddGroupingError(e error, grouping asserts.Grouping) error {
+	q.errors[string(grouping)] = e
+	return nil
+}
+
+func (s *offlineSuite) TestSnapActionFetchAssertions(c *C) {
+	sto := s.newStore(c)
+
+	declRef := asserts.Ref{Type: asserts.SnapDeclarationType, PrimaryKey: []string{"16", helloID}}
+	otherRef := asserts.Ref{Type: asserts.SnapDeclarationType, PrimaryKey: []string{"16", "other-id"}}
+	q := &mockAssertQuery{
+		toResolve: map[asserts.Grouping][]*asserts.AtRevision{
+			"g1": {
+				{Ref: declRef, Revision: asserts.RevisionNotKnown},
+				{Ref: otherRef, Revision: asserts.RevisionNotKnown},
+			},
+		},
+		errors: make(map[string]error),
+	}
+	_, ars, err := sto.SnapAction(context.TODO(), nil, nil, q, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(ars, DeepEquals, []store.AssertionResult{{
+		Grouping:   "g1",
+		StreamURLs: []string{declRef.Unique()},
+	}})
+	c.Check(q.errors, HasLen, 1)
+	c.Check(asserts.IsNotFound(q.errors[otherRef.Unique()]), Equals, true)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeStack.Trusted,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(s.storeStack.StoreAccountKey("")), IsNil)
+	c.Assert(db.Add(s.assertions[0]), IsNil)
+
+	b := asserts.NewBatch(nil)
+	c.Assert(sto.DownloadAssertions(ars[0].StreamURLs, b, nil), IsNil)
+	c.Assert(b.CommitTo(db, nil), IsNil)
+	_, err = declRef.Resolve(db.Find)
+	c.Check(err, IsNil)
+}
+
+func (s *offlineSuite) downloadInfo(c *C, sto *offline.Store) *snap.DownloadInfo {
+	sars, _, err := sto.SnapAction(context.TODO(), nil, []*store.SnapAction{{
+		Action:       "download",
+		InstanceName: "hello",
+		Revision:     snap.R(2),
+	}}, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(sars, HasLen, 1)
+	c.Check(sars[0].DownloadInfo.DownloadURL, Equals, filepath.Join(s.dir, "hello_2"))
+	c.Check(sars[0].DownloadInfo.Size, Equals, int64(4096))
+	c.Check(sars[0].DownloadInfo.Sha3_384, Matches, "[0-9a-f]{96}")
+
+	// hello_3.snap is not a real snap, build its download info by hand
+	digest, _, err := osutil.FileDigest(filepath.Join(s.dir, "hello_3.snap"), crypto.SHA3_384)
+	c.Assert(err, IsNil)
+	return &snap.DownloadInfo{
+		DownloadURL: filepath.Join(s.dir, "hello_3.snap"),
+		Size:        9,
+		Sha3_384:    hex.EncodeToString(digest),
+	}
+}
+
+func (s *offlineSuite) TestDownload(c *C) {
+	sto := s.newStore(c)
+	dlInfo := s.downloadInfo(c, sto)
+
+	target := filepath.Join(c.MkDir(), "hello_3.snap")
+	pbar := &progress.NullMeter{}
+	c.Assert(sto.Download(context.TODO(), "hello", target, dlInfo, pbar, nil, nil), IsNil)
+	c.Check(target, testutil.FileEquals, "snap data")
+	c.Check(target+".partial", testutil.FileAbsent)
+
+	// the content is checked against the snap-revision
+	c.Assert(ioutil.WriteFile(dlInfo.DownloadURL, []byte("tampered"), 0644), IsNil)
+	target = filepath.Join(c.MkDir(), "hello_3.snap")
+	err := sto.Download(context.TODO(), "hello", target, dlInfo, nil, nil, nil)
+	c.Check(err, ErrorMatches, `sha3-384 mismatch for "hello": got .* but expected .*`)
+	c.Check(target, testutil.FileAbsent)
+	c.Check(target+".partial", testutil.FileAbsent)
+
+	// only files listed in the manifest are served
+	dlInfo.DownloadURL = "/etc/passwd"
+	err = sto.Download(context.TODO(), "hello", target, dlInfo, nil, nil, nil)
+	c.Check(err, ErrorMatches, `cannot find "/etc/passwd" in the offline store`)
+}
+
+func (s *offlineSuite) TestDownloadStream(c *C) {
+	sto := s.newStore(c)
+	dlInfo := s.downloadInfo(c, sto)
+
+	r, status, err := sto.DownloadStream(context.TODO(), "hello", dlInfo, 0, nil)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 200)
+	c.Check(string(data), Equals, "snap data")
+
+	r, status, err = sto.DownloadStream(context.TODO(), "hello", dlInfo, 5, nil)
+	c.Assert(err, IsNil)
+	data, err = ioutil.ReadAll(r)
+	r.Close()
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, 206)
+	c.Check(string(data), Equals, "data")
+}
+
+type mockAdder struct {
+	snaps []string
+}
+
+func (a *mockAdder) AddSnap(snapName, version, summary string, commands []string) error {
+	a.snaps = append(a.snaps, snapName+" "+version+" "+summary+" "+strings.Join(commands, ","))
+	return nil
+}
+
+func (s *offlineSuite) TestWriteCatalogs(c *C) {
+	sto := s.newStore(c)
+
+	var names bytes.Buffer
+	adder := &mockAdder{}
+	c.Assert(sto.WriteCatalogs(context.TODO(), &names, adder), IsNil)
+	c.Check(names.String(), Equals, "hello\n")
+	c.Check(adder.snaps, DeepEquals, []string{"hello 1.0 Says hello hello"})
+}
+
+func (s *offlineSuite) TestConnectivityCheck(c *C) {
+	sto := s.newStore(c)
+
+	status, err := sto.ConnectivityCheck()
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, map[string]bool{s.dir: true})
+
+	c.Assert(os.Remove(filepath.Join(s.dir, offline.ManifestFile)), IsNil)
+	status, err = sto.ConnectivityCheck()
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, map[string]bool{s.dir: false})
+}
+
+func (s *offlineSuite) TestUnsupported(c *C) {
+	sto := s.newStore(c)
+
+	_, err := sto.Buy(nil, nil)
+	c.Check(err, Equals, offline.ErrNotSupported)
+	c.Check(sto.ReadyToBuy(nil), Equals, offline.ErrNotSupported)
+	_, err = sto.CreateCohorts(context.TODO(), []string{"hello"})
+	c.Check(err, Equals, offline.ErrNotSupported)
+	_, _, err = sto.LoginUser("user", "pass", "")
+	c.Check(err, Equals, offline.ErrNotSupported)
+}
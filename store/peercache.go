@@ -0,0 +1,295 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+)
+
+// peerBlobPath is the path under which peers serve the blobs of their
+// download cache, followed by the sha3-384 of the blob.
+const peerBlobPath = "/v1/blobs/"
+
+var peerLookupHost = net.LookupHost
+
+// ParsePeers parses a comma separated list of host:port peer cache
+// addresses.
+func ParsePeers(s string) ([]string, error) {
+	var peers []string
+	for _, peer := range strings.Split(s, ",") {
+		peer = strings.TrimSpace(peer)
+		if peer == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer %q: %v", peer, err)
+		}
+		if host == "" {
+			return nil, fmt.Errorf("invalid peer %q: missing host", peer)
+		}
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid peer %q: invalid port %q", peer, port)
+		}
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+// peers returns the configured peer cache addresses.
+func (s *Store) peers() []string {
+	if s.cfg.Peers == nil {
+		return nil
+	}
+	peers, err := s.cfg.Peers()
+	if err != nil {
+		logger.Noticef("cannot get peer caches: %v", err)
+		return nil
+	}
+	return peers
+}
+
+// downloadFromPeers tries to download the blob of downloadInfo from the
+// peer caches into w, which must be empty. It returns whether one of them
+// provided it, w is left empty otherwise.
+func (s *Store) downloadFromPeers(ctx context.Context, name string, downloadInfo *snap.DownloadInfo, w *os.File, pbar progress.Meter) bool {
+	if downloadInfo.Sha3_384 == "" || downloadInfo.Size <= 0 {
+		// nothing to verify or bound what the peers send
+		return false
+	}
+	peers := s.peers()
+	if len(peers) == 0 {
+		return false
+	}
+	cli := httputil.NewHTTPClient(&httputil.ClientOptions{
+		// peers are on the local network, never go through
+		// the store proxy
+		Proxy: func(*http.Request) (*url.URL, error) { return nil, nil },
+	})
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	for _, peer := range peers {
+		err := downloadFromPeer(ctx, cli, peer, name, downloadInfo, w, pbar)
+		if err == nil {
+			logger.Debugf("Downloaded %s from peer %s.", name, peer)
+			return true
+		}
+		logger.Debugf("Cannot download %s from peer %s: %v", name, peer, err)
+		if err := w.Truncate(0); err != nil {
+			return false
+		}
+		if _, err := w.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+		if cancelled(ctx) {
+			return false
+		}
+	}
+	return false
+}
+
+func downloadFromPeer(ctx context.Context, cli *http.Client, peer, name string, downloadInfo *snap.DownloadInfo, w io.Writer, pbar progress.Meter) error {
+	sha3_384 := downloadInfo.Sha3_384
+	req, err := http.NewRequest("GET", "http://"+peer+peerBlobPath+sha3_384, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	// never trust the peers, not even to send a bounded amount of data
+	if resp.ContentLength > downloadInfo.Size {
+		return fmt.Errorf("unexpected size %d, expected %d", resp.ContentLength, downloadInfo.Size)
+	}
+
+	h := crypto.SHA3_384.New()
+	pbar.Start(name, float64(downloadInfo.Size))
+	n, err := io.Copy(io.MultiWriter(w, h, pbar), io.LimitReader(resp.Body, downloadInfo.Size+1))
+	pbar.Finished()
+	if err != nil {
+		return err
+	}
+	if n > downloadInfo.Size {
+		return fmt.Errorf("unexpected size, got more than %d bytes", downloadInfo.Size)
+	}
+	if actualSha3 := fmt.Sprintf("%x", h.Sum(nil)); actualSha3 != sha3_384 {
+		return HashError{name, actualSha3, sha3_384}
+	}
+	return nil
+}
+
+// PeerCacheServer serves the blobs of a download cache to peers over
+// HTTP, only to the configured peers.
+type PeerCacheServer struct {
+	cacheDir string
+	peers    func() ([]string, error)
+
+	mu       sync.Mutex
+	addr     string
+	listener net.Listener
+	srv      *http.Server
+}
+
+// NewPeerCacheServer returns a server for the blobs in cacheDir, only
+// answering requests coming from the hosts of the peers.
+func NewPeerCacheServer(cacheDir string, peers func() ([]string, error)) *PeerCacheServer {
+	return &PeerCacheServer{
+		cacheDir: cacheDir,
+		peers:    peers,
+	}
+}
+
+func (p *PeerCacheServer) allowed(remoteAddr string) bool {
+	remote, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	peers, err := p.peers()
+	if err != nil {
+		logger.Noticef("cannot get peer caches: %v", err)
+		return false
+	}
+	for _, peer := range peers {
+		host, _, err := net.SplitHostPort(peer)
+		if err != nil {
+			continue
+		}
+		if host == remote {
+			return true
+		}
+		addrs, err := peerLookupHost(host)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if addr == remote {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *PeerCacheServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !p.allowed(r.RemoteAddr) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	digest := strings.TrimPrefix(r.URL.Path, peerBlobPath)
+	if digest == r.URL.Path || len(digest) != 2*crypto.SHA3_384.Size() {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(filepath.Join(p.cacheDir, digest))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, digest, fi.ModTime(), f)
+}
+
+// Listen makes the server listen on the given address, or stop
+// listening if it is empty. It does nothing if already listening
+// there.
+func (p *PeerCacheServer) Listen(addr string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if addr == p.addr {
+		return nil
+	}
+	p.stopLocked()
+	if addr == "" {
+		return nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot serve peer cache: %v", err)
+	}
+	srv := &http.Server{Handler: p}
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			logger.Noticef("cannot serve peer cache: %v", err)
+		}
+	}()
+	p.addr, p.listener, p.srv = addr, l, srv
+	return nil
+}
+
+// Addr returns the address the server listens on, nil if it does not.
+func (p *PeerCacheServer) Addr() net.Addr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.listener == nil {
+		return nil
+	}
+	return p.listener.Addr()
+}
+
+// Stop stops listening.
+func (p *PeerCacheServer) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopLocked()
+}
+
+func (p *PeerCacheServer) stopLocked() {
+	if p.srv != nil {
+		p.srv.Close()
+	}
+	p.addr, p.listener, p.srv = "", nil, nil
+}
@@ -0,0 +1,273 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type peerCacheSuite struct {
+	testutil.BaseTest
+
+	content string
+	digest  string
+
+	cdnHits int
+	cdn     *httptest.Server
+}
+
+var _ = Suite(&peerCacheSuite{})
+
+func (s *peerCacheSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.content = "snap-content"
+	s.digest = fmt.Sprintf("%x", sha3.Sum384([]byte(s.content)))
+
+	s.cdnHits = 0
+	s.cdn = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.cdnHits++
+		io.WriteString(w, s.content)
+	}))
+	s.AddCleanup(s.cdn.Close)
+}
+
+// mockPeer returns the address of a peer answering with the given
+// status and body.
+func (s *peerCacheSuite) mockPeer(c *C, status int, body string, hits *int) string {
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v1/blobs/"+s.digest)
+		*hits++
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	s.AddCleanup(peer.Close)
+	u, err := url.Parse(peer.URL)
+	c.Assert(err, IsNil)
+	return u.Host
+}
+
+func (s *peerCacheSuite) download(c *C, peers ...string) string {
+	sto := store.New(&store.Config{
+		Peers: func() ([]string, error) { return peers, nil },
+	}, nil)
+	info := &snap.DownloadInfo{
+		DownloadURL: s.cdn.URL,
+		Sha3_384:    s.digest,
+		Size:        int64(len(s.content)),
+	}
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(context.TODO(), "foo", path, info, nil, nil, nil)
+	c.Assert(err, IsNil)
+	return path
+}
+
+func (s *peerCacheSuite) TestParsePeers(c *C) {
+	peers, err := store.ParsePeers(" 10.0.0.2:8738,,peer.lan:8738, [fe80::1]:8738 ")
+	c.Assert(err, IsNil)
+	c.Check(peers, DeepEquals, []string{"10.0.0.2:8738", "peer.lan:8738", "[fe80::1]:8738"})
+
+	peers, err = store.ParsePeers("")
+	c.Assert(err, IsNil)
+	c.Check(peers, HasLen, 0)
+
+	_, err = store.ParsePeers("10.0.0.2:0")
+	c.Check(err, ErrorMatches, `invalid peer "10.0.0.2:0": invalid port "0"`)
+}
+
+func (s *peerCacheSuite) TestDownloadFromPeer(c *C) {
+	var hits int
+	peer := s.mockPeer(c, 200, s.content, &hits)
+
+	path := s.download(c, peer)
+	c.Check(path, testutil.FileEquals, s.content)
+	c.Check(hits, Equals, 1)
+	c.Check(s.cdnHits, Equals, 0)
+}
+
+func (s *peerCacheSuite) TestDownloadFromNextPeer(c *C) {
+	var missingHits, goodHits int
+	missing := s.mockPeer(c, 404, "", &missingHits)
+	good := s.mockPeer(c, 200, s.content, &goodHits)
+
+	path := s.download(c, missing, good)
+	c.Check(path, testutil.FileEquals, s.content)
+	c.Check(missingHits, Equals, 1)
+	c.Check(goodHits, Equals, 1)
+	c.Check(s.cdnHits, Equals, 0)
+}
+
+func (s *peerCacheSuite) TestDownloadPeerBadContentFallsBack(c *C) {
+	var hits int
+	peer := s.mockPeer(c, 200, "tampered-content-that-is-longer", &hits)
+
+	path := s.download(c, peer)
+	c.Check(path, testutil.FileEquals, s.content)
+	c.Check(hits, Equals, 1)
+	c.Check(s.cdnHits, Equals, 1)
+}
+
+func (s *peerCacheSuite) TestDownloadFromPeerTooLarge(c *C) {
+	var hits int
+	peer := s.mockPeer(c, 200, s.content+"-and-more", &hits)
+	info := &snap.DownloadInfo{Sha3_384: s.digest, Size: int64(len(s.content))}
+
+	var buf bytes.Buffer
+	err := store.DownloadFromPeer(context.TODO(), http.DefaultClient, peer, "foo", info, &buf, progress.Null)
+	c.Check(err, ErrorMatches, "unexpected size 21, expected 12")
+	c.Check(buf.Len(), Equals, 0)
+	c.Check(hits, Equals, 1)
+}
+
+func (s *peerCacheSuite) TestDownloadFromPeerTooLargeWithoutLength(c *C) {
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// flushing first makes the response chunked, without length
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		for i := 0; i < 1000; i++ {
+			io.WriteString(w, s.content)
+		}
+	}))
+	defer peer.Close()
+	u, err := url.Parse(peer.URL)
+	c.Assert(err, IsNil)
+	info := &snap.DownloadInfo{Sha3_384: s.digest, Size: int64(len(s.content))}
+
+	var buf bytes.Buffer
+	err = store.DownloadFromPeer(context.TODO(), http.DefaultClient, u.Host, "foo", info, &buf, progress.Null)
+	c.Check(err, ErrorMatches, "unexpected size, got more than 12 bytes")
+	// no more than one byte over the expected size was read
+	c.Check(buf.Len(), Equals, len(s.content)+1)
+}
+
+func (s *peerCacheSuite) TestDownloadPeerUnreachableFallsBack(c *C) {
+	gone := httptest.NewServer(nil)
+	gone.Close()
+	u, err := url.Parse(gone.URL)
+	c.Assert(err, IsNil)
+
+	path := s.download(c, u.Host)
+	c.Check(path, testutil.FileEquals, s.content)
+	c.Check(s.cdnHits, Equals, 1)
+}
+
+func (s *peerCacheSuite) TestDownloadNoPeers(c *C) {
+	path := s.download(c)
+	c.Check(path, testutil.FileEquals, s.content)
+	c.Check(s.cdnHits, Equals, 1)
+}
+
+func (s *peerCacheSuite) TestServer(c *C) {
+	cacheDir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(cacheDir, s.digest), []byte(s.content), 0600), IsNil)
+	c.Assert(os.Mkdir(filepath.Join(cacheDir, strings.Repeat("cd", 48)), 0700), IsNil)
+
+	peers := []string{"127.0.0.1:8738"}
+	srv := store.NewPeerCacheServer(cacheDir, func() ([]string, error) {
+		return peers, nil
+	})
+	c.Assert(srv.Listen("127.0.0.1:0"), IsNil)
+	defer srv.Stop()
+	// listening again on the same address does nothing
+	addr := srv.Addr()
+	c.Assert(srv.Listen("127.0.0.1:0"), IsNil)
+	c.Check(srv.Addr(), Equals, addr)
+
+	get := func(method, path string) (int, string) {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", addr, path), nil)
+		c.Assert(err, IsNil)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		c.Assert(err, IsNil)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("GET", "/v1/blobs/"+s.digest)
+	c.Check(status, Equals, 200)
+	c.Check(body, Equals, s.content)
+
+	status, _ = get("HEAD", "/v1/blobs/"+s.digest)
+	c.Check(status, Equals, 200)
+
+	for _, path := range []string{
+		"/v1/blobs/" + strings.Repeat("ef", 48),
+		"/v1/blobs/" + strings.Repeat("cd", 48),
+		"/v1/blobs/../" + s.digest,
+		"/v1/blobs/not-a-digest",
+		"/" + s.digest,
+	} {
+		status, _ = get("GET", path)
+		c.Check(status, Equals, 404, Commentf(path))
+	}
+
+	status, _ = get("POST", "/v1/blobs/"+s.digest)
+	c.Check(status, Equals, 405)
+
+	// only the peers are served
+	peers = []string{"10.0.0.2:8738"}
+	status, _ = get("GET", "/v1/blobs/"+s.digest)
+	c.Check(status, Equals, 403)
+
+	srv.Stop()
+	c.Check(srv.Addr(), IsNil)
+}
+
+func (s *peerCacheSuite) TestServerPeerByName(c *C) {
+	restore := store.MockPeerLookupHost(func(host string) ([]string, error) {
+		c.Check(host, Equals, "peer.lan")
+		return []string{"10.0.0.3", "127.0.0.1"}, nil
+	})
+	defer restore()
+
+	srv := store.NewPeerCacheServer(c.MkDir(), func() ([]string, error) {
+		return []string{"peer.lan:8738"}, nil
+	})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/v1/blobs/"+s.digest, nil)
+	req.RemoteAddr = "127.0.0.1:43210"
+	srv.ServeHTTP(rec, req)
+	// allowed, but not there
+	c.Check(rec.Code, Equals, 404)
+
+	rec = httptest.NewRecorder()
+	req.RemoteAddr = "10.0.0.4:43210"
+	srv.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 403)
+}
@@ -0,0 +1,372 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/auth"
+)
+
+type responseCacheStatusKey struct{}
+
+// ResponseCacheStatus tells whether the store answered queries with
+// stale cached responses as it could not be reached.
+type ResponseCacheStatus struct {
+	// Stale is set if at least one stale cached response was used.
+	Stale bool
+	// StoredAt is when the oldest stale cached response used was
+	// stored.
+	StoredAt time.Time
+}
+
+// WithResponseCacheStatus returns a context for store queries that
+// records into the returned status whether they were answered with
+// stale cached responses.
+func WithResponseCacheStatus(parent context.Context) (context.Context, *ResponseCacheStatus) {
+	status := &ResponseCacheStatus{}
+	return context.WithValue(parent, responseCacheStatusKey{}, status), status
+}
+
+// MarkStaleResponse records into the status of the context, if any, that
+// a query was answered with a stale cached response stored at storedAt.
+func MarkStaleResponse(ctx context.Context, storedAt time.Time) {
+	if ctx == nil {
+		return
+	}
+	status, ok := ctx.Value(responseCacheStatusKey{}).(*ResponseCacheStatus)
+	if !ok {
+		return
+	}
+	if !status.Stale || storedAt.Before(status.StoredAt) {
+		status.StoredAt = storedAt
+	}
+	status.Stale = true
+}
+
+// cachedResponse is a successful store response as kept on disk.
+type cachedResponse struct {
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored-at"`
+	// Expires is when the response needs to be revalidated with
+	// the store before being used again.
+	Expires time.Time `json:"expires"`
+}
+
+func (cr *cachedResponse) response(reqOptions *requestOptions) *http.Response {
+	header := make(http.Header, len(cr.Header))
+	for k, v := range cr.Header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    200,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       &http.Request{Method: reqOptions.Method, URL: reqOptions.URL},
+	}
+}
+
+// cacheControl returns whether a response with the given headers can
+// be stored and for how long it is fresh.
+func cacheControl(header http.Header) (storable bool, maxAge time.Duration) {
+	storable = true
+	noCache := false
+	for _, value := range header["Cache-Control"] {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			switch {
+			case directive == "no-store":
+				storable = false
+			case directive == "no-cache":
+				noCache = true
+			case strings.HasPrefix(directive, "max-age="):
+				secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+				if err == nil && secs > 0 {
+					maxAge = time.Duration(secs) * time.Second
+				}
+			}
+		}
+	}
+	if noCache {
+		maxAge = 0
+	}
+	return storable, maxAge
+}
+
+// responseCache keeps the store responses of read-only queries on
+// disk, following the Cache-Control and ETag headers of the store.
+// Fresh responses are used without contacting the store, expired ones
+// are revalidated with If-None-Match when they have an ETag and stale
+// ones are used when the store cannot be reached, marking the context
+// accordingly (see WithResponseCacheStatus). The least recently used
+// entries are removed once they take more than maxSize, and all of them
+// once the store or the device they were stored for changes.
+type responseCache struct {
+	cacheDir string
+	maxSize  int64
+
+	mu sync.Mutex
+	// device is what the cached responses were stored for, see
+	// checkDevice
+	device string
+}
+
+// responseCacheDeviceFile records in the cache directory what device
+// the cached responses were stored for.
+const responseCacheDeviceFile = ".device"
+
+func newResponseCache(cacheDir string, maxSize int64) *responseCache {
+	return &responseCache{
+		cacheDir: cacheDir,
+		maxSize:  maxSize,
+	}
+}
+
+// SetCacheResponses caches the responses of the store to info and
+// find queries, keeping them under the given size in bytes; a size of
+// 0 disables the cache.
+func (s *Store) SetCacheResponses(maxSize int64) {
+	if maxSize > 0 {
+		s.responses = newResponseCache(dirs.SnapStoreResponseCacheDir, maxSize)
+	} else {
+		s.responses = nil
+	}
+}
+
+// responseCacheDevice returns what the store responses depend on
+// besides the request itself: the store ID, the architecture and
+// series, and the model and serial of the device.
+func (s *Store) responseCacheDevice() string {
+	storeID := s.fallbackStoreID
+	var device auth.DeviceState
+	if s.dauthCtx != nil {
+		if cand, err := s.dauthCtx.StoreID(storeID); err == nil {
+			storeID = cand
+		}
+		if cand, err := s.dauthCtx.Device(); err == nil && cand != nil {
+			device = *cand
+		}
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s %s %s %s/%s %s", storeID, s.architecture, s.series, device.Brand, device.Model, device.Serial)))
+	return hex.EncodeToString(h[:])
+}
+
+func (rc *responseCache) path(reqOptions *requestOptions, user *auth.UserState, device string) string {
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s %s %d %s", reqOptions.Method, reqOptions.URL, userID, device)))
+	return filepath.Join(rc.cacheDir, hex.EncodeToString(h[:]))
+}
+
+// checkDevice removes all the cached responses if they were stored for
+// a different device, e.g. before a remodel or a change of store.
+func (rc *responseCache) checkDevice(device string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.device == device {
+		return
+	}
+	deviceFile := filepath.Join(rc.cacheDir, responseCacheDeviceFile)
+	if data, err := ioutil.ReadFile(deviceFile); err == nil && string(data) == device {
+		rc.device = device
+		return
+	}
+	if err := os.RemoveAll(rc.cacheDir); err != nil {
+		logger.Noticef("cannot clear the store response cache: %v", err)
+		return
+	}
+	if err := os.MkdirAll(rc.cacheDir, 0700); err != nil {
+		logger.Noticef("cannot create the store response cache: %v", err)
+		return
+	}
+	if err := osutil.AtomicWriteFile(deviceFile, []byte(device), 0600, 0); err != nil {
+		logger.Noticef("cannot create the store response cache: %v", err)
+		return
+	}
+	rc.device = device
+}
+
+func (rc *responseCache) get(path string) *cachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Noticef("cannot read cached store response: %v", err)
+		}
+		return nil
+	}
+	var cr cachedResponse
+	if err := json.Unmarshal(data, &cr); err != nil {
+		logger.Noticef("cannot decode cached store response %s: %v", path, err)
+		os.Remove(path)
+		return nil
+	}
+	// used entries are the last ones to be removed
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return &cr
+}
+
+func (rc *responseCache) put(path string, cr *cachedResponse) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if err := os.MkdirAll(rc.cacheDir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cr)
+	if err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(path, data, 0600, 0); err != nil {
+		return err
+	}
+	return rc.cleanup()
+}
+
+// cleanup removes the least recently used entries until the cache
+// fits in maxSize.
+func (rc *responseCache) cleanup() error {
+	fil, err := ioutil.ReadDir(rc.cacheDir)
+	if err != nil {
+		return err
+	}
+	var size int64
+	entries := fil[:0]
+	for _, fi := range fil {
+		if fi.Name() == responseCacheDeviceFile {
+			continue
+		}
+		entries = append(entries, fi)
+		size += fi.Size()
+	}
+	fil = entries
+	sort.Sort(changesByMtime(fil))
+	for _, fi := range fil {
+		if size <= rc.maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(rc.cacheDir, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= fi.Size()
+	}
+	return nil
+}
+
+// do answers the request from the cache when possible and uses
+// doRequest to query the store otherwise, caching the result.
+func (rc *responseCache) do(ctx context.Context, reqOptions *requestOptions, user *auth.UserState, device string, doRequest func(*requestOptions) (*http.Response, error)) (*http.Response, error) {
+	rc.checkDevice(device)
+	path := rc.path(reqOptions, user, device)
+	cached := rc.get(path)
+	now := time.Now()
+	if cached != nil && now.Before(cached.Expires) {
+		logger.Debugf("using cached store response for %s", reqOptions.URL)
+		return cached.response(reqOptions), nil
+	}
+
+	opts := *reqOptions
+	if cached != nil {
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			opts.ExtraHeaders = make(map[string]string, len(reqOptions.ExtraHeaders)+1)
+			for k, v := range reqOptions.ExtraHeaders {
+				opts.ExtraHeaders[k] = v
+			}
+			opts.ExtraHeaders["If-None-Match"] = etag
+		}
+	}
+
+	resp, err := doRequest(&opts)
+	if err != nil {
+		if cached != nil && (ctx == nil || ctx.Err() == nil) {
+			logger.Noticef("cannot reach the store, using response for %s cached at %s: %v", reqOptions.URL, cached.StoredAt.Format(time.RFC3339), err)
+			MarkStaleResponse(ctx, cached.StoredAt)
+			return cached.response(reqOptions), nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == 304 && cached != nil:
+		resp.Body.Close()
+		_, maxAge := cacheControl(resp.Header)
+		cached.StoredAt = now
+		cached.Expires = now.Add(maxAge)
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			cached.Header.Set("ETag", etag)
+		}
+		if err := rc.put(path, cached); err != nil {
+			logger.Noticef("cannot cache store response: %v", err)
+		}
+		return cached.response(reqOptions), nil
+	case resp.StatusCode == 200:
+		storable, maxAge := cacheControl(resp.Header)
+		if !storable {
+			return resp, nil
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		cr := &cachedResponse{
+			URL:      reqOptions.URL.String(),
+			Header:   resp.Header,
+			Body:     body,
+			StoredAt: now,
+			Expires:  now.Add(maxAge),
+		}
+		if err := rc.put(path, cr); err != nil {
+			logger.Noticef("cannot cache store response: %v", err)
+		}
+	}
+	return resp, nil
+}
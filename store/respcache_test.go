@@ -0,0 +1,228 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/store"
+)
+
+func (s *storeTestSuite) newCachingStore(c *C, handler http.HandlerFunc) (*store.Store, *httptest.Server) {
+	mockServer := httptest.NewServer(handler)
+	s.AddCleanup(mockServer.Close)
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		StoreBaseURL: mockServerURL,
+		FindFields:   []string{"abc", "def"},
+	}
+	dauthCtx := &testDauthContext{c: c, device: s.device}
+	sto := store.New(&cfg, dauthCtx)
+	sto.SetCacheResponses(1024 * 1024)
+	return sto, mockServer
+}
+
+// cachedResponses returns the responses in the cache, leaving out what
+// records the device they were stored for.
+func cachedResponses(c *C) []string {
+	fis, err := ioutil.ReadDir(dirs.SnapStoreResponseCacheDir)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, fi := range fis {
+		if fi.Name() != ".device" {
+			names = append(names, fi.Name())
+		}
+	}
+	return names
+}
+
+func (s *storeTestSuite) TestInfoCachedWhileFresh(c *C) {
+	n := 0
+	sto, _ := s.newCachingStore(c, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		assertRequest(c, r, "GET", infoPathPattern)
+		w.Header().Set("Cache-Control", "max-age=300")
+		w.Header().Set("X-Suggested-Currency", "GBP")
+		io.WriteString(w, mockInfoJSON)
+	})
+
+	for i := 0; i < 3; i++ {
+		result, err := sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+		c.Assert(err, IsNil)
+		c.Check(result.InstanceName(), Equals, "hello-world")
+	}
+	c.Check(n, Equals, 1)
+	c.Check(sto.SuggestedCurrency(), Equals, "GBP")
+}
+
+func (s *storeTestSuite) TestInfoNotCachedWithNoStore(c *C) {
+	n := 0
+	sto, _ := s.newCachingStore(c, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("Cache-Control", "no-store, max-age=300")
+		io.WriteString(w, mockInfoJSON)
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+		c.Assert(err, IsNil)
+	}
+	c.Check(n, Equals, 2)
+	c.Check(cachedResponses(c), HasLen, 0)
+}
+
+func (s *storeTestSuite) TestInfoRevalidatedWithETag(c *C) {
+	n := 0
+	sto, _ := s.newCachingStore(c, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("ETag", `"v1"`)
+		switch n {
+		case 1:
+			c.Check(r.Header.Get("If-None-Match"), Equals, "")
+			io.WriteString(w, mockInfoJSON)
+		case 2:
+			c.Check(r.Header.Get("If-None-Match"), Equals, `"v1"`)
+			w.WriteHeader(304)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		result, err := sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+		c.Assert(err, IsNil)
+		c.Check(result.InstanceName(), Equals, "hello-world")
+	}
+	c.Check(n, Equals, 2)
+}
+
+func (s *storeTestSuite) TestInfoStaleWhenStoreUnreachable(c *C) {
+	n := 0
+	sto, mockServer := s.newCachingStore(c, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		io.WriteString(w, mockInfoJSON)
+	})
+
+	ctx, status := store.WithResponseCacheStatus(s.ctx)
+	_, err := sto.SnapInfo(ctx, store.SnapSpec{Name: "hello-world"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(status.Stale, Equals, false)
+	c.Check(n, Equals, 1)
+
+	mockServer.Close()
+
+	before := time.Now()
+	ctx, status = store.WithResponseCacheStatus(s.ctx)
+	result, err := sto.SnapInfo(ctx, store.SnapSpec{Name: "hello-world"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(result.InstanceName(), Equals, "hello-world")
+	c.Check(status.Stale, Equals, true)
+	c.Check(status.StoredAt.Before(before), Equals, true)
+
+	// nothing cached for other snaps
+	_, err = sto.SnapInfo(s.ctx, store.SnapSpec{Name: "other"}, nil)
+	c.Check(err, NotNil)
+}
+
+func (s *storeTestSuite) TestFindCachedWhileFresh(c *C) {
+	n := 0
+	sto, _ := s.newCachingStore(c, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		assertRequest(c, r, "GET", findPath)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, MockSearchJSONv2)
+	})
+
+	for i := 0; i < 2; i++ {
+		snaps, err := sto.Find(s.ctx, &store.Search{Query: "hello"}, nil)
+		c.Assert(err, IsNil)
+		c.Assert(snaps, HasLen, 1)
+		c.Check(snaps[0].InstanceName(), Equals, "hello-world")
+	}
+	c.Check(n, Equals, 1)
+
+	// a different query is not answered from the cache
+	_, err := sto.Find(s.ctx, &store.Search{Query: "hell"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+}
+
+func (s *storeTestSuite) TestResponseCacheBoundedSize(c *C) {
+	n := 0
+	sto, _ := s.newCachingStore(c, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("Cache-Control", "max-age=300")
+		io.WriteString(w, mockInfoJSON)
+	})
+	// too small for any response
+	sto.SetCacheResponses(10)
+
+	for i := 0; i < 2; i++ {
+		_, err := sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+		c.Assert(err, IsNil)
+	}
+	c.Check(n, Equals, 2)
+	c.Check(cachedResponses(c), HasLen, 0)
+}
+
+func (s *storeTestSuite) TestResponseCacheClearedOnStoreChange(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Header.Get("Snap-Device-Store"), Equals, fmt.Sprintf("store-%d", n))
+		w.Header().Set("Cache-Control", "max-age=300")
+		io.WriteString(w, mockInfoJSON)
+	}))
+	defer mockServer.Close()
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		StoreBaseURL: mockServerURL,
+	}
+	dauthCtx := &testDauthContext{c: c, device: s.device, storeID: "store-1"}
+	sto := store.New(&cfg, dauthCtx)
+	sto.SetCacheResponses(1024 * 1024)
+
+	_, err := sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+	c.Check(cachedResponses(c), HasLen, 1)
+
+	// the response for the previous store is not used
+	dauthCtx.storeID = "store-2"
+	_, err = sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	// and it was removed
+	c.Check(cachedResponses(c), HasLen, 1)
+
+	_, err = sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+}
@@ -97,14 +97,32 @@ type Config struct {
 	DetailFields []string
 	InfoFields   []string
 	// search v2 fields
-	FindFields  []string
+	FindFields []string
+	// DeltaFormat restricts the delta formats accepted from the
+	// store to the given comma-separated list, all the formats that
+	// can be applied are accepted otherwise.
 	DeltaFormat string
 
 	// CacheDownloads is the number of downloads that should be cached
 	CacheDownloads int
+	// CacheMaxSize returns the maximum size in bytes of the cached
+	// downloads, 0 for no bound
+	CacheMaxSize func() (int64, error)
 
 	// Proxy returns the HTTP proxy to use when talking to the store
 	Proxy func(*http.Request) (*url.URL, error)
+
+	// Endpoints returns the additional store endpoints to fail over
+	// to, in order, when the store (or proxy store) is unhealthy
+	Endpoints func() ([]*url.URL, error)
+	// AssertionsEndpoints returns the additional assertions service
+	// endpoints to fail over to, in order, when AssertionsBaseURL is
+	// set and the assertions service is unhealthy
+	AssertionsEndpoints func() ([]*url.URL, error)
+
+	// Peers returns the host:port addresses of the peer caches to
+	// try downloads from before the store
+	Peers func() ([]string, error)
 }
 
 // setBaseURL updates the store API's base URL in the Config. Must not be used
@@ -140,7 +158,7 @@ type Store struct {
 	detailFields []string
 	infoFields   []string
 	findFields   []string
-	deltaFormat  string
+	deltaFormats []string
 	// reused http client
 	client *http.Client
 
@@ -151,10 +169,16 @@ type Store struct {
 	suggestedCurrency string
 
 	cacher downloadCache
+	// responses caches the responses to read-only queries, if set
+	responses *responseCache
 
 	proxy              func(*http.Request) (*url.URL, error)
 	proxyConnectHeader http.Header
 
+	health endpointHealth
+
+	downloads downloadScheduler
+
 	userAgent string
 }
 
@@ -306,9 +330,6 @@ type sectionResults struct {
 	} `json:"_embedded"`
 }
 
-// The default delta format if not configured.
-var defaultSupportedDeltaFormat = "xdelta3"
-
 // New creates a new Store with the given access configuration and for given the store id.
 func New(cfg *Config, dauthCtx DeviceAndAuthContext) *Store {
 	if cfg == nil {
@@ -340,9 +361,9 @@ func New(cfg *Config, dauthCtx DeviceAndAuthContext) *Store {
 		series = release.Series
 	}
 
-	deltaFormat := cfg.DeltaFormat
-	if deltaFormat == "" {
-		deltaFormat = defaultSupportedDeltaFormat
+	var deltaFormats []string
+	if cfg.DeltaFormat != "" {
+		deltaFormats = strutil.CommaSeparatedList(cfg.DeltaFormat)
 	}
 
 	userAgent := snapdenv.UserAgent()
@@ -358,7 +379,7 @@ func New(cfg *Config, dauthCtx DeviceAndAuthContext) *Store {
 		infoFields:         infoFields,
 		findFields:         findFields,
 		dauthCtx:           dauthCtx,
-		deltaFormat:        deltaFormat,
+		deltaFormats:       deltaFormats,
 		proxy:              cfg.Proxy,
 		proxyConnectHeader: proxyConnectHeader,
 		userAgent:          userAgent,
@@ -684,12 +705,20 @@ type requestOptions struct {
 	ExtraHeaders map[string]string
 	Data         []byte
 
+	// CacheResponse indicates that the response can be served from
+	// and stored into the response cache
+	CacheResponse bool
+
 	// DeviceAuthNeed indicates the level of need to supply device
 	// authorization for this request, can be:
 	//  - deviceAuthPreferred: should be provided if available
 	//  - deviceAuthCustomStoreOnly: should be provided only in case
 	//    of a custom store
 	DeviceAuthNeed deviceAuthNeed
+
+	// NoFailover keeps the request on the given endpoint even if
+	// it is unhealthy
+	NoFailover bool
 }
 
 func (r *requestOptions) addHeader(k, v string) {
@@ -803,8 +832,47 @@ func (s *Store) retryRequestDecodeJSON(ctx context.Context, reqOptions *requestO
 	}, defaultRetryStrategy)
 }
 
-// doRequest does an authenticated request to the store handling a potential macaroon refresh required if needed
+// doRequest does an authenticated request to the store, using the
+// response cache if the request allows it
 func (s *Store) doRequest(ctx context.Context, client *http.Client, reqOptions *requestOptions, user *auth.UserState) (*http.Response, error) {
+	if reqOptions.CacheResponse && s.responses != nil {
+		return s.responses.do(ctx, reqOptions, user, s.responseCacheDevice(), func(opts *requestOptions) (*http.Response, error) {
+			return s.doFailoverRequest(ctx, client, opts, user)
+		})
+	}
+	return s.doFailoverRequest(ctx, client, reqOptions, user)
+}
+
+// doFailoverRequest does an authenticated request to the store failing
+// over to the next store endpoint if the current one is unhealthy
+func (s *Store) doFailoverRequest(ctx context.Context, client *http.Client, reqOptions *requestOptions, user *auth.UserState) (resp *http.Response, err error) {
+	candidates := s.endpointCandidates(reqOptions)
+	for i, cand := range candidates {
+		candOptions := *reqOptions
+		candOptions.URL = cand.url
+		resp, err = s.doEndpointRequest(ctx, client, &candOptions, user)
+		if cand.base == "" {
+			return resp, err
+		}
+		failure := endpointFailure(resp, err)
+		if failure == "" {
+			s.health.succeeded(cand.base)
+			return resp, err
+		}
+		s.health.failed(cand.base, failure)
+		if i == len(candidates)-1 {
+			break
+		}
+		logger.Noticef("store endpoint %s failed (%s), failing over to %s", cand.base, failure, candidates[i+1].base)
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// doEndpointRequest does an authenticated request to the store handling a potential macaroon refresh required if needed
+func (s *Store) doEndpointRequest(ctx context.Context, client *http.Client, reqOptions *requestOptions, user *auth.UserState) (*http.Response, error) {
 	authRefreshes := 0
 	for {
 		req, err := s.newRequest(ctx, reqOptions, user)
@@ -1061,9 +1129,10 @@ func (s *Store) SnapInfo(ctx context.Context, snapSpec SnapSpec, user *auth.User
 
 	u := s.endpointURL(path.Join(snapInfoEndpPath, snapSpec.Name), query)
 	reqOptions := &requestOptions{
-		Method:   "GET",
-		URL:      u,
-		APILevel: apiV2Endps,
+		Method:        "GET",
+		URL:           u,
+		APILevel:      apiV2Endps,
+		CacheResponse: true,
 	}
 
 	var remote storeInfo
@@ -1170,10 +1239,11 @@ func (s *Store) Find(ctx context.Context, search *Search, user *auth.UserState)
 
 	u := s.endpointURL(findEndpPath, q)
 	reqOptions := &requestOptions{
-		Method:   "GET",
-		URL:      u,
-		Accept:   jsonContentType,
-		APILevel: apiV2Endps,
+		Method:        "GET",
+		URL:           u,
+		Accept:        jsonContentType,
+		APILevel:      apiV2Endps,
+		CacheResponse: true,
 	}
 
 	var searchData searchV2Results
@@ -1571,11 +1641,11 @@ type storeInfoAbbrev struct {
 
 var errUnexpectedConnCheckResponse = errors.New("unexpected response during connection check")
 
-func (s *Store) snapConnCheck() ([]string, error) {
+func (s *Store) snapConnCheck(base *url.URL) ([]string, error) {
 	var hosts []string
 	// NOTE: "core" is possibly the only snap that's sure to be in all stores
 	//       when we drop "core" in the move to snapd/core18/etc, change this
-	infoURL := s.endpointURL(path.Join(snapInfoEndpPath, "core"), url.Values{
+	infoURL := endpointURL(base, path.Join(snapInfoEndpPath, "core"), url.Values{
 		// we only want the download URL
 		"fields": {"download"},
 		// we only need *one* (but can't filter by channel ... yet)
@@ -1586,9 +1656,10 @@ func (s *Store) snapConnCheck() ([]string, error) {
 	var result storeInfoAbbrev
 	resp, err := httputil.RetryRequest(infoURL.String(), func() (*http.Response, error) {
 		return s.doRequest(context.TODO(), s.client, &requestOptions{
-			Method:   "GET",
-			URL:      infoURL,
-			APILevel: apiV2Endps,
+			Method:     "GET",
+			URL:        infoURL,
+			APILevel:   apiV2Endps,
+			NoFailover: true,
 		}, nil)
 	}, func(resp *http.Response) error {
 		return decodeJSONBody(resp, &result, nil)
@@ -1640,14 +1711,17 @@ func (s *Store) snapConnCheck() ([]string, error) {
 func (s *Store) ConnectivityCheck() (status map[string]bool, err error) {
 	status = make(map[string]bool)
 
-	checkers := []func() ([]string, error){
-		s.snapConnCheck,
-	}
-
-	for _, checker := range checkers {
-		hosts, err := checker()
+	// check every store endpoint, not just the one requests
+	// would currently fail over to
+	for _, base := range s.storeEndpoints() {
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+		hosts, err := s.snapConnCheck(u)
 		for _, host := range hosts {
-			status[host] = (err == nil)
+			// a host is reachable if reachable via any endpoint
+			status[host] = status[host] || (err == nil)
 		}
 	}
 
@@ -23,14 +23,15 @@ package store
 import (
 	"context"
 	"crypto"
-	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -57,15 +58,87 @@ var downloadRetryStrategy = retry.LimitCount(7, retry.LimitTime(90*time.Second,
 
 // Deltas enabled by default on classic, but allow opting in or out on both classic and core.
 func useDeltas() bool {
-	// only xdelta3 is supported for now, so check the binary exists here
-	// TODO: have a per-format checker instead
-	if _, err := getXdelta3Cmd(); err != nil {
+	if len(availableDeltaFormats(nil)) == 0 {
 		return false
 	}
 
 	return osutil.GetenvBool("SNAPD_USE_DELTAS_EXPERIMENTAL", true)
 }
 
+// deltaApplicator knows how to apply the deltas of one format.
+type deltaApplicator struct {
+	format string
+	// tool is the command applying the deltas
+	tool string
+	// args returns the arguments of tool to generate target from
+	// source and delta
+	args func(source, delta, target string) []string
+}
+
+// deltaApplicators lists the supported delta formats, in order of
+// preference when the store offers several formats for the same delta.
+var deltaApplicators = []deltaApplicator{
+	{
+		format: "xdelta3",
+		tool:   "xdelta3",
+		args: func(source, delta, target string) []string {
+			return []string{"-d", "-s", source, delta, target}
+		},
+	}, {
+		format: "zstd-patch",
+		tool:   "zstd",
+		args: func(source, delta, target string) []string {
+			return []string{"-d", "-q", "-f", "--long=31", "--patch-from=" + source, delta, "-o", target}
+		},
+	}, {
+		format: "bsdiff",
+		tool:   "bspatch",
+		args: func(source, delta, target string) []string {
+			return []string{source, target, delta}
+		},
+	},
+}
+
+func findDeltaApplicator(format string) *deltaApplicator {
+	for i := range deltaApplicators {
+		if deltaApplicators[i].format == format {
+			return &deltaApplicators[i]
+		}
+	}
+	return nil
+}
+
+// availableDeltaFormats returns the delta formats, out of the given ones
+// or all the supported ones if nil, whose tool is available.
+func availableDeltaFormats(formats []string) []string {
+	if formats == nil {
+		for _, da := range deltaApplicators {
+			formats = append(formats, da.format)
+		}
+	}
+	var available []string
+	for _, format := range formats {
+		da := findDeltaApplicator(format)
+		if da == nil {
+			continue
+		}
+		if _, err := getDeltaToolCmd(da.tool); err != nil {
+			continue
+		}
+		available = append(available, format)
+	}
+	return available
+}
+
+// acceptedDeltaFormats returns the delta formats to accept from the
+// store, in order of preference, or nil if deltas are not to be used.
+func (s *Store) acceptedDeltaFormats() []string {
+	if !useDeltas() {
+		return nil
+	}
+	return availableDeltaFormats(s.deltaFormats)
+}
+
 func (s *Store) cdnHeader() (string, error) {
 	if s.noCDN {
 		return "none", nil
@@ -114,9 +187,87 @@ func (e HashError) Error() string {
 }
 
 type DownloadOptions struct {
+	// RateLimit is the bandwidth budget, in bytes per second, shared
+	// by all the rate-limited downloads of the store.
 	RateLimit           int64
 	IsAutoRefresh       bool
 	LeavePartialOnError bool
+	// Concurrency bounds the number of downloads of the store running
+	// at the same time, no bound if <= 0.
+	Concurrency int
+	// Priority orders the downloads waiting to start, higher first.
+	Priority int
+	// Resume describes the partial download left behind by a previous
+	// attempt, e.g. before snapd restarted. It is validated and
+	// continued if it matches the snap being downloaded.
+	Resume *DownloadProgress
+	// Checkpoint is called regularly with the progress of the download
+	// once it is safely on disk. The partial download is also kept if
+	// the download is cancelled so that it can be resumed.
+	Checkpoint func(offset int64, partialSha3_384 string)
+}
+
+// DownloadProgress records how far a download got.
+type DownloadProgress struct {
+	// Sha3_384 and Size are those of the complete snap.
+	Sha3_384 string `json:"sha3-384"`
+	Size     int64  `json:"size"`
+	// Offset is how much of the snap was safely written to disk,
+	// PartialSha3_384 is the sha3-384 of that content.
+	Offset          int64  `json:"offset"`
+	PartialSha3_384 string `json:"partial-sha3-384"`
+}
+
+// the download progress is checkpointed every this many bytes
+var downloadCheckpointInterval int64 = 16 * 1024 * 1024
+
+// validatePartial checks the content of the partial download against
+// the recorded progress, returning from where to resume the download.
+// Anything written after the last checkpoint might not have made it to
+// disk intact and is downloaded again.
+func validatePartial(w *os.File, size int64, downloadInfo *snap.DownloadInfo, progress *DownloadProgress) (resume int64, err error) {
+	valid := progress.Sha3_384 == downloadInfo.Sha3_384 && progress.Size == downloadInfo.Size && progress.Offset <= size
+	if valid {
+		if _, err := w.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		h := crypto.SHA3_384.New()
+		if _, err := io.CopyN(h, w, progress.Offset); err != nil {
+			return 0, err
+		}
+		valid = fmt.Sprintf("%x", h.Sum(nil)) == progress.PartialSha3_384
+	}
+	if valid {
+		resume = progress.Offset
+	} else {
+		logger.Noticef("Cannot resume download of %q: partial download does not match its recorded progress.", w.Name())
+	}
+	if err := w.Truncate(resume); err != nil {
+		return 0, err
+	}
+	return w.Seek(resume, io.SeekStart)
+}
+
+// checkpointWriter reports the progress of a download every
+// downloadCheckpointInterval bytes, after syncing the written content.
+type checkpointWriter struct {
+	f          interface{ Sync() error }
+	h          hash.Hash
+	offset     int64
+	next       int64
+	checkpoint func(offset int64, partialSha3_384 string)
+}
+
+func (cw *checkpointWriter) Write(p []byte) (int, error) {
+	cw.offset += int64(len(p))
+	if cw.offset >= cw.next {
+		if err := cw.f.Sync(); err != nil {
+			return 0, err
+		}
+		cw.checkpoint(cw.offset, fmt.Sprintf("%x", cw.h.Sum(nil)))
+		cw.next = cw.offset + downloadCheckpointInterval
+	}
+	return len(p), nil
 }
 
 // Download downloads the snap addressed by download info and returns its
@@ -133,11 +284,21 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return nil
 	}
 
-	if useDeltas() {
+	var priority, concurrency int
+	if dlOpts != nil {
+		priority, concurrency = dlOpts.Priority, dlOpts.Concurrency
+	}
+	release, err := s.downloads.acquire(ctx, priority, concurrency)
+	if err != nil {
+		return fmt.Errorf("The download has been cancelled: %s", err)
+	}
+	defer release()
+
+	if formats := s.acceptedDeltaFormats(); len(formats) > 0 {
 		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
 
-		if len(downloadInfo.Deltas) == 1 {
-			err := s.downloadAndApplyDelta(name, targetPath, downloadInfo, pbar, user, dlOpts)
+		if len(downloadInfo.Deltas) > 0 {
+			err := s.downloadAndApplyDeltas(name, targetPath, downloadInfo, formats, pbar, user, dlOpts)
 			if err == nil {
 				return nil
 			}
@@ -155,6 +316,12 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	if err != nil {
 		return err
 	}
+	if resume > 0 && dlOpts != nil && dlOpts.Resume != nil {
+		resume, err = validatePartial(w, resume, downloadInfo, dlOpts.Resume)
+		if err != nil {
+			return err
+		}
+	}
 	defer func() {
 		fi, _ := w.Stat()
 		if cerr := w.Close(); cerr != nil && err == nil {
@@ -163,7 +330,8 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		if err == nil {
 			return
 		}
-		if dlOpts == nil || !dlOpts.LeavePartialOnError || fi == nil || fi.Size() == 0 {
+		leavePartial := dlOpts != nil && (dlOpts.LeavePartialOnError || (dlOpts.Checkpoint != nil && cancelled(ctx)))
+		if !leavePartial || fi == nil || fi.Size() == 0 {
 			os.Remove(w.Name())
 		}
 	}()
@@ -183,12 +351,15 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		url = downloadInfo.DownloadURL
 	}
 
-	if downloadInfo.Size == 0 || resume < downloadInfo.Size {
+	switch {
+	case resume == 0 && s.downloadFromPeers(ctx, name, downloadInfo, w, pbar):
+		// verified already
+	case downloadInfo.Size == 0 || resume < downloadInfo.Size:
 		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar, dlOpts)
 		if err != nil {
 			logger.Debugf("download of %q failed: %#v", url, err)
 		}
-	} else {
+	default:
 		// we're done! check the hash though
 		h := crypto.SHA3_384.New()
 		if _, err := w.Seek(0, os.SEEK_SET); err != nil {
@@ -344,11 +515,20 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 		dlSize = float64(resp.ContentLength)
 		pbar.Start(name, dlSize)
 		mw := io.MultiWriter(w, h, pbar)
+		if f, ok := w.(interface{ Sync() error }); ok && dlOpts.Checkpoint != nil {
+			// after the hash saw the content
+			mw = io.MultiWriter(mw, &checkpointWriter{
+				f:          f,
+				h:          h,
+				offset:     resume,
+				next:       resume + downloadCheckpointInterval,
+				checkpoint: dlOpts.Checkpoint,
+			})
+		}
 		var limiter io.Reader
 		limiter = resp.Body
 		if limit := dlOpts.RateLimit; limit > 0 {
-			bucket := ratelimit.NewBucketWithRate(float64(limit), 2*limit)
-			limiter = ratelimitReader(resp.Body, bucket)
+			limiter = s.downloads.limitReader(resp.Body, limit)
 		}
 		_, finalErr = io.Copy(mw, limiter)
 		pbar.Finished()
@@ -449,19 +629,8 @@ func doDownloadReqImpl(ctx context.Context, storeURL *url.URL, cdnHeader string,
 	return s.doRequest(ctx, cli, reqOptions, user)
 }
 
-// downloadDelta downloads the delta for the preferred format, returning the path.
-func (s *Store) downloadDelta(deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
-
-	if len(downloadInfo.Deltas) != 1 {
-		return errors.New("store returned more than one download delta")
-	}
-
-	deltaInfo := downloadInfo.Deltas[0]
-
-	if deltaInfo.Format != s.deltaFormat {
-		return fmt.Errorf("store returned unsupported delta format %q (only xdelta3 currently)", deltaInfo.Format)
-	}
-
+// downloadDelta downloads the given delta.
+func (s *Store) downloadDelta(deltaName string, deltaInfo *snap.DeltaInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
 	authAvail, err := s.authAvailable(user)
 	if err != nil {
 		return err
@@ -472,33 +641,114 @@ func (s *Store) downloadDelta(deltaName string, downloadInfo *snap.DownloadInfo,
 		url = deltaInfo.DownloadURL
 	}
 
+	if dlOpts != nil && (dlOpts.Resume != nil || dlOpts.Checkpoint != nil) {
+		// the progress is about the snap, not its delta
+		opts := *dlOpts
+		opts.Resume, opts.Checkpoint = nil, nil
+		dlOpts = &opts
+	}
+
 	return download(context.TODO(), deltaName, deltaInfo.Sha3_384, url, user, s, w, 0, pbar, dlOpts)
 }
 
-func getXdelta3Cmd(args ...string) (*exec.Cmd, error) {
-	if osutil.ExecutableExists("xdelta3") {
-		return exec.Command("xdelta3", args...), nil
+// deltaChain picks out of the deltas returned by the store, in the
+// given formats, the chain of deltas leading from the current revision
+// to the target one that is the smallest to download. The store
+// returns a single delta unless the client is several revisions behind.
+func deltaChain(deltas []snap.DeltaInfo, formats []string) ([]snap.DeltaInfo, error) {
+	preference := make(map[string]int, len(formats))
+	for i, format := range formats {
+		preference[format] = i
+	}
+
+	type step struct{ from, to int }
+	steps := make(map[step]snap.DeltaInfo)
+	from, to := -1, -1
+	for _, delta := range deltas {
+		pref, ok := preference[delta.Format]
+		if !ok {
+			continue
+		}
+		if delta.FromRevision >= delta.ToRevision {
+			return nil, fmt.Errorf("store returned invalid delta from revision %d to %d", delta.FromRevision, delta.ToRevision)
+		}
+		st := step{delta.FromRevision, delta.ToRevision}
+		if other, ok := steps[st]; ok {
+			if other.Format == delta.Format {
+				return nil, fmt.Errorf("store returned more than one %s delta from revision %d to %d", delta.Format, delta.FromRevision, delta.ToRevision)
+			}
+			if preference[other.Format] < pref {
+				continue
+			}
+		}
+		steps[st] = delta
+		if from == -1 || delta.FromRevision < from {
+			from = delta.FromRevision
+		}
+		if delta.ToRevision > to {
+			to = delta.ToRevision
+		}
 	}
-	return snapdtool.CommandFromSystemSnap("/usr/bin/xdelta3", args...)
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("store returned no delta in a supported format (%s)", strings.Join(formats, ", "))
+	}
+
+	sorted := make([]snap.DeltaInfo, 0, len(steps))
+	for _, delta := range steps {
+		sorted = append(sorted, delta)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].FromRevision != sorted[j].FromRevision {
+			return sorted[i].FromRevision < sorted[j].FromRevision
+		}
+		return sorted[i].ToRevision < sorted[j].ToRevision
+	})
+
+	// deltas only go forward, so by the time the deltas from a
+	// revision are considered the smallest chain to it is known
+	sizes := map[int]int64{from: 0}
+	chains := map[int][]snap.DeltaInfo{from: nil}
+	for _, delta := range sorted {
+		size, ok := sizes[delta.FromRevision]
+		if !ok {
+			continue
+		}
+		size += delta.Size
+		if known, ok := sizes[delta.ToRevision]; ok && known <= size {
+			continue
+		}
+		sizes[delta.ToRevision] = size
+		chain := append([]snap.DeltaInfo(nil), chains[delta.FromRevision]...)
+		chains[delta.ToRevision] = append(chain, delta)
+	}
+	chain, ok := chains[to]
+	if !ok {
+		return nil, fmt.Errorf("store returned no chain of deltas from revision %d to %d", from, to)
+	}
+	return chain, nil
 }
 
-// applyDelta generates a target snap from a previously downloaded snap and a downloaded delta.
-var applyDelta = func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
-	snapBase := fmt.Sprintf("%s_%d.snap", name, deltaInfo.FromRevision)
-	snapPath := filepath.Join(dirs.SnapBlobDir, snapBase)
+func getDeltaToolCmd(tool string, args ...string) (*exec.Cmd, error) {
+	if osutil.ExecutableExists(tool) {
+		return exec.Command(tool, args...), nil
+	}
+	return snapdtool.CommandFromSystemSnap(filepath.Join("/usr/bin", tool), args...)
+}
 
-	if !osutil.FileExists(snapPath) {
-		return fmt.Errorf("snap %q revision %d not found at %s", name, deltaInfo.FromRevision, snapPath)
+// applyDelta generates a target snap from a source snap and a downloaded delta.
+var applyDelta = func(name string, sourcePath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+	if !osutil.FileExists(sourcePath) {
+		return fmt.Errorf("snap %q revision %d not found at %s", name, deltaInfo.FromRevision, sourcePath)
 	}
 
-	if deltaInfo.Format != "xdelta3" {
-		return fmt.Errorf("cannot apply unsupported delta format %q (only xdelta3 currently)", deltaInfo.Format)
+	da := findDeltaApplicator(deltaInfo.Format)
+	if da == nil {
+		return fmt.Errorf("cannot apply unsupported delta format %q", deltaInfo.Format)
 	}
 
 	partialTargetPath := targetPath + ".partial"
 
-	xdelta3Args := []string{"-d", "-s", snapPath, deltaPath, partialTargetPath}
-	cmd, err := getXdelta3Cmd(xdelta3Args...)
+	cmd, err := getDeltaToolCmd(da.tool, da.args(sourcePath, deltaPath, partialTargetPath)...)
 	if err != nil {
 		return err
 	}
@@ -533,12 +783,54 @@ var applyDelta = func(name string, deltaPath string, deltaInfo *snap.DeltaInfo,
 	return nil
 }
 
-// downloadAndApplyDelta downloads and then applies the delta to the current snap.
-func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
-	deltaInfo := &downloadInfo.Deltas[0]
+// downloadAndApplyDeltas downloads and then applies, one after the
+// other, the chain of deltas from the current snap to the target one.
+func (s *Store) downloadAndApplyDeltas(name, targetPath string, downloadInfo *snap.DownloadInfo, formats []string, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
+	chain, err := deltaChain(downloadInfo.Deltas, formats)
+	if err != nil {
+		return err
+	}
+	var deltasSize int64
+	for _, deltaInfo := range chain {
+		deltasSize += deltaInfo.Size
+	}
+	if downloadInfo.Size > 0 && deltasSize >= downloadInfo.Size {
+		return fmt.Errorf("deltas are not smaller than the snap (%d >= %d bytes)", deltasSize, downloadInfo.Size)
+	}
+
+	sourcePath := filepath.Join(dirs.SnapBlobDir, fmt.Sprintf("%s_%d.snap", name, chain[0].FromRevision))
+	for i := range chain {
+		deltaInfo := &chain[i]
+		deltaName := fmt.Sprintf(i18n.G("%s (delta)"), name)
+		stepTargetPath := targetPath
+		sha3_384 := downloadInfo.Sha3_384
+		if len(chain) > 1 {
+			deltaName = fmt.Sprintf(i18n.G("%s (delta %d/%d)"), name, i+1, len(chain))
+		}
+		if i < len(chain)-1 {
+			// intermediate revisions cannot be verified
+			stepTargetPath = fmt.Sprintf("%s.delta-%d", targetPath, deltaInfo.ToRevision)
+			sha3_384 = ""
+		}
+
+		err := s.downloadAndApplyDelta(name, deltaName, sourcePath, stepTargetPath, deltaInfo, sha3_384, pbar, user, dlOpts)
+		if i > 0 {
+			// the intermediate snap is not needed anymore
+			os.Remove(sourcePath)
+		}
+		if err != nil {
+			return err
+		}
+		sourcePath = stepTargetPath
+	}
+
+	logger.Debugf("Successfully applied %d delta(s) for %q, saving %d bytes.", len(chain), name, downloadInfo.Size-deltasSize)
+	return nil
+}
 
+// downloadAndApplyDelta downloads and then applies one delta to the source snap.
+func (s *Store) downloadAndApplyDelta(name, deltaName, sourcePath, targetPath string, deltaInfo *snap.DeltaInfo, targetSha3_384 string, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) (err error) {
 	deltaPath := fmt.Sprintf("%s.%s-%d-to-%d.partial", targetPath, deltaInfo.Format, deltaInfo.FromRevision, deltaInfo.ToRevision)
-	deltaName := fmt.Sprintf(i18n.G("%s (delta)"), name)
 
 	w, err := os.OpenFile(deltaPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
@@ -551,18 +843,13 @@ func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *sna
 		os.Remove(deltaPath)
 	}()
 
-	err = s.downloadDelta(deltaName, downloadInfo, w, pbar, user, dlOpts)
+	err = s.downloadDelta(deltaName, deltaInfo, w, pbar, user, dlOpts)
 	if err != nil {
 		return err
 	}
 
 	logger.Debugf("Successfully downloaded delta for %q at %s", name, deltaPath)
-	if err := applyDelta(name, deltaPath, deltaInfo, targetPath, downloadInfo.Sha3_384); err != nil {
-		return err
-	}
-
-	logger.Debugf("Successfully applied delta for %q at %s, saving %d bytes.", name, deltaPath, downloadInfo.Size-deltaInfo.Size)
-	return nil
+	return applyDelta(name, sourcePath, deltaPath, deltaInfo, targetPath, targetSha3_384)
 }
 
 func (s *Store) CacheDownloads() int {
@@ -572,8 +859,21 @@ func (s *Store) CacheDownloads() int {
 func (s *Store) SetCacheDownloads(fileCount int) {
 	s.cfg.CacheDownloads = fileCount
 	if fileCount > 0 {
-		s.cacher = NewCacheManager(dirs.SnapDownloadCacheDir, fileCount)
+		s.cacher = newCacheManager(dirs.SnapDownloadCacheDir, fileCount, s.cacheMaxSize)
 	} else {
 		s.cacher = &nullCache{}
 	}
 }
+
+// cacheMaxSize returns the maximum size of the cached downloads.
+func (s *Store) cacheMaxSize() int64 {
+	if s.cfg.CacheMaxSize == nil {
+		return 0
+	}
+	maxSize, err := s.cfg.CacheMaxSize()
+	if err != nil {
+		logger.Noticef("cannot get maximum download cache size: %v", err)
+		return 0
+	}
+	return maxSize
+}
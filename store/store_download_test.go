@@ -156,6 +156,138 @@ func (s *storeDownloadSuite) TestResumeOfCompleted(c *C) {
 	c.Assert(targetFn, testutil.FileEquals, expectedContentStr)
 }
 
+func (s *storeDownloadSuite) TestDownloadResumeFromProgress(c *C) {
+	partialContentStr := "partial content "
+	missingContentStr := "was downloaded"
+	expectedContentStr := partialContentStr + missingContentStr
+	checkpointed := "partial "
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		// content written after the last checkpoint is downloaded again
+		c.Check(resume, Equals, int64(len(checkpointed)))
+		w.Write([]byte(expectedContentStr[resume:]))
+		return nil
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "abcdabcd"
+	snap.Size = int64(len(expectedContentStr))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := ioutil.WriteFile(targetFn+".partial", []byte(partialContentStr), 0644)
+	c.Assert(err, IsNil)
+
+	dlOpts := &store.DownloadOptions{
+		Resume: &store.DownloadProgress{
+			Sha3_384:        snap.Sha3_384,
+			Size:            snap.Size,
+			Offset:          int64(len(checkpointed)),
+			PartialSha3_384: fmt.Sprintf("%x", sha3.Sum384([]byte(checkpointed))),
+		},
+	}
+	err = s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+
+	c.Assert(targetFn, testutil.FileEquals, expectedContentStr)
+}
+
+func (s *storeDownloadSuite) TestDownloadResumeFromMismatchedProgress(c *C) {
+	partialContentStr := "corrupted content "
+	expectedContentStr := "partial content was downloaded"
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(resume, Equals, int64(0))
+		w.Write([]byte(expectedContentStr))
+		return nil
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "abcdabcd"
+	snap.Size = int64(len(expectedContentStr))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := ioutil.WriteFile(targetFn+".partial", []byte(partialContentStr), 0644)
+	c.Assert(err, IsNil)
+
+	dlOpts := &store.DownloadOptions{
+		Resume: &store.DownloadProgress{
+			Sha3_384:        snap.Sha3_384,
+			Size:            snap.Size,
+			Offset:          8,
+			PartialSha3_384: fmt.Sprintf("%x", sha3.Sum384([]byte("partial "))),
+		},
+	}
+	err = s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+
+	c.Assert(targetFn, testutil.FileEquals, expectedContentStr)
+	c.Check(s.logbuf.String(), Matches, `(?s).*Cannot resume download of ".*foo_1.0_all.snap.partial": partial download does not match its recorded progress.*`)
+}
+
+func (s *storeDownloadSuite) TestDownloadCheckpoints(c *C) {
+	restore := store.MockDownloadCheckpointInterval(10000)
+	defer restore()
+
+	buf := bytes.Repeat([]byte{'x'}, 50000)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf)
+	}))
+	defer mockServer.Close()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384(buf))
+	snap.Size = int64(len(buf))
+
+	var offsets []int64
+	dlOpts := &store.DownloadOptions{
+		Checkpoint: func(offset int64, partialSha3_384 string) {
+			c.Check(partialSha3_384, Equals, fmt.Sprintf("%x", sha3.Sum384(buf[:offset])))
+			offsets = append(offsets, offset)
+		},
+	}
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+	c.Assert(targetFn, testutil.FileEquals, buf)
+
+	c.Assert(offsets, Not(HasLen), 0)
+	for i, offset := range offsets {
+		c.Check(offset >= int64(10000*(i+1)), Equals, true)
+	}
+}
+
+func (s *storeDownloadSuite) TestDownloadCancelledLeavesPartialWithCheckpoint(c *C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	var tmpfile *os.File
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		tmpfile = w.(*os.File)
+		w.Write([]byte{'X'})
+		cancel()
+		return fmt.Errorf("The download has been cancelled: %s", ctx.Err())
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Size = 1
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	dlOpts := &store.DownloadOptions{Checkpoint: func(int64, string) {}}
+	err := s.store.Download(ctx, "foo", path, &snap.DownloadInfo, nil, nil, dlOpts)
+	c.Assert(err, ErrorMatches, "The download has been cancelled: context canceled")
+	// the partial download is kept to be resumed later
+	c.Assert(osutil.FileExists(tmpfile.Name()), Equals, true)
+	c.Assert(osutil.FileExists(path), Equals, false)
+}
+
 func (s *storeDownloadSuite) TestDownloadEOFHandlesResumeHashCorrectly(c *C) {
 	n := 0
 	var mockServer *httptest.Server
@@ -591,9 +723,7 @@ var downloadDeltaTests = []struct {
 	authenticated bool
 	deviceSession bool
 	useLocalUser  bool
-	format        string
 	expectedURL   string
-	expectError   bool
 }{{
 	// An unauthenticated request downloads the anonymous delta url.
 	info: snap.DownloadInfo{
@@ -604,9 +734,7 @@ var downloadDeltaTests = []struct {
 	},
 	authenticated: false,
 	deviceSession: false,
-	format:        "xdelta3",
 	expectedURL:   "anon-delta-url",
-	expectError:   false,
 }, {
 	// An authenticated request downloads the authenticated delta url.
 	info: snap.DownloadInfo{
@@ -618,9 +746,7 @@ var downloadDeltaTests = []struct {
 	authenticated: true,
 	deviceSession: false,
 	useLocalUser:  false,
-	format:        "xdelta3",
 	expectedURL:   "auth-delta-url",
-	expectError:   false,
 }, {
 	// A device-authenticated request downloads the authenticated delta url.
 	info: snap.DownloadInfo{
@@ -632,9 +758,7 @@ var downloadDeltaTests = []struct {
 	authenticated: false,
 	deviceSession: true,
 	useLocalUser:  false,
-	format:        "xdelta3",
 	expectedURL:   "auth-delta-url",
-	expectError:   false,
 }, {
 	// A local authenticated request downloads the anonymous delta url.
 	info: snap.DownloadInfo{
@@ -646,38 +770,7 @@ var downloadDeltaTests = []struct {
 	authenticated: true,
 	deviceSession: false,
 	useLocalUser:  true,
-	format:        "xdelta3",
 	expectedURL:   "anon-delta-url",
-	expectError:   false,
-}, {
-	// An error is returned if more than one matching delta is returned by the store,
-	// though this may be handled in the future.
-	info: snap.DownloadInfo{
-		Sha3_384: "sha3",
-		Deltas: []snap.DeltaInfo{
-			{DownloadURL: "xdelta3-delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 25},
-			{DownloadURL: "bsdiff-delta-url", Format: "xdelta3", FromRevision: 25, ToRevision: 26},
-		},
-	},
-	authenticated: false,
-	deviceSession: false,
-	format:        "xdelta3",
-	expectedURL:   "",
-	expectError:   true,
-}, {
-	// If the supported format isn't available, an error is returned.
-	info: snap.DownloadInfo{
-		Sha3_384: "sha3",
-		Deltas: []snap.DeltaInfo{
-			{DownloadURL: "xdelta3-delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
-			{DownloadURL: "ydelta-delta-url", Format: "ydelta", FromRevision: 24, ToRevision: 26},
-		},
-	},
-	authenticated: false,
-	deviceSession: false,
-	format:        "bsdiff",
-	expectedURL:   "",
-	expectError:   true,
 }}
 
 func (s *storeDownloadSuite) TestDownloadDelta(c *C) {
@@ -689,7 +782,6 @@ func (s *storeDownloadSuite) TestDownloadDelta(c *C) {
 	sto := store.New(nil, dauthCtx)
 
 	for _, testCase := range downloadDeltaTests {
-		sto.SetDeltaFormat(testCase.format)
 		restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, _ *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
 			c.Check(dlOpts, DeepEquals, &store.DownloadOptions{IsAutoRefresh: true})
 			expectedUser := s.user
@@ -723,14 +815,9 @@ func (s *storeDownloadSuite) TestDownloadDelta(c *C) {
 			authedUser = nil
 		}
 
-		err = sto.DownloadDelta("snapname", &testCase.info, w, nil, authedUser, &store.DownloadOptions{IsAutoRefresh: true})
-
-		if testCase.expectError {
-			c.Assert(err, NotNil)
-		} else {
-			c.Assert(err, IsNil)
-			c.Assert(w.Name(), testutil.FileEquals, "I was downloaded")
-		}
+		err = sto.DownloadDelta("snapname", &testCase.info.Deltas[0], w, nil, authedUser, &store.DownloadOptions{IsAutoRefresh: true})
+		c.Assert(err, IsNil)
+		c.Assert(w.Name(), testutil.FileEquals, "I was downloaded")
 	}
 }
 
@@ -743,6 +830,14 @@ var applyDeltaTests = []struct {
 	deltaInfo:       snap.DeltaInfo{Format: "xdelta3", FromRevision: 24, ToRevision: 26},
 	currentRevision: 24,
 	error:           "",
+}, {
+	deltaInfo:       snap.DeltaInfo{Format: "zstd-patch", FromRevision: 24, ToRevision: 26},
+	currentRevision: 24,
+	error:           "",
+}, {
+	deltaInfo:       snap.DeltaInfo{Format: "bsdiff", FromRevision: 24, ToRevision: 26},
+	currentRevision: 24,
+	error:           "",
 }, {
 	// An error is returned if the expected current snap does not exist on disk.
 	deltaInfo:       snap.DeltaInfo{Format: "xdelta3", FromRevision: 24, ToRevision: 26},
@@ -752,14 +847,20 @@ var applyDeltaTests = []struct {
 	// An error is returned if the format is not supported.
 	deltaInfo:       snap.DeltaInfo{Format: "nodelta", FromRevision: 24, ToRevision: 26},
 	currentRevision: 24,
-	error:           "cannot apply unsupported delta format \"nodelta\" (only xdelta3 currently)",
+	error:           "cannot apply unsupported delta format \"nodelta\"",
 }}
 
 func (s *storeDownloadSuite) TestApplyDelta(c *C) {
+	mockZstd := testutil.MockCommand(c, "zstd", "")
+	defer mockZstd.Restore()
+	mockBspatch := testutil.MockCommand(c, "bspatch", "")
+	defer mockBspatch.Restore()
+
 	for _, testCase := range applyDeltaTests {
 		name := "foo"
 		currentSnapName := fmt.Sprintf("%s_%d.snap", name, testCase.currentRevision)
 		currentSnapPath := filepath.Join(dirs.SnapBlobDir, currentSnapName)
+		sourceSnapPath := filepath.Join(dirs.SnapBlobDir, fmt.Sprintf("%s_%d.snap", name, testCase.deltaInfo.FromRevision))
 		targetSnapName := fmt.Sprintf("%s_%d.snap", name, testCase.deltaInfo.ToRevision)
 		targetSnapPath := filepath.Join(dirs.SnapBlobDir, targetSnapName)
 		err := os.MkdirAll(filepath.Dir(currentSnapPath), 0755)
@@ -770,20 +871,32 @@ func (s *storeDownloadSuite) TestApplyDelta(c *C) {
 		err = ioutil.WriteFile(deltaPath, nil, 0644)
 		c.Assert(err, IsNil)
 		// When testing a case where the call to the external
-		// xdelta3 is successful,
+		// delta tool is successful,
 		// simulate the resulting .partial.
 		if testCase.error == "" {
 			err = ioutil.WriteFile(targetSnapPath+".partial", nil, 0644)
 			c.Assert(err, IsNil)
 		}
 
-		err = store.ApplyDelta(name, deltaPath, &testCase.deltaInfo, targetSnapPath, "")
+		err = store.ApplyDelta(name, sourceSnapPath, deltaPath, &testCase.deltaInfo, targetSnapPath, "")
 
 		if testCase.error == "" {
 			c.Assert(err, IsNil)
-			c.Assert(s.mockXDelta.Calls(), DeepEquals, [][]string{
-				{"xdelta3", "-d", "-s", currentSnapPath, deltaPath, targetSnapPath + ".partial"},
-			})
+			partialPath := targetSnapPath + ".partial"
+			switch testCase.deltaInfo.Format {
+			case "xdelta3":
+				c.Assert(s.mockXDelta.Calls(), DeepEquals, [][]string{
+					{"xdelta3", "-d", "-s", currentSnapPath, deltaPath, partialPath},
+				})
+			case "zstd-patch":
+				c.Assert(mockZstd.Calls(), DeepEquals, [][]string{
+					{"zstd", "-d", "-q", "-f", "--long=31", "--patch-from=" + currentSnapPath, deltaPath, "-o", partialPath},
+				})
+			case "bsdiff":
+				c.Assert(mockBspatch.Calls(), DeepEquals, [][]string{
+					{"bspatch", currentSnapPath, partialPath, deltaPath},
+				})
+			}
 			c.Assert(osutil.FileExists(targetSnapPath+".partial"), Equals, false)
 			st, err := os.Stat(targetSnapPath)
 			c.Assert(err, IsNil)
@@ -797,6 +910,114 @@ func (s *storeDownloadSuite) TestApplyDelta(c *C) {
 		}
 		c.Assert(os.Remove(currentSnapPath), IsNil)
 		c.Assert(os.Remove(deltaPath), IsNil)
+		s.mockXDelta.ForgetCalls()
+		mockZstd.ForgetCalls()
+		mockBspatch.ForgetCalls()
+	}
+}
+
+func (s *storeDownloadSuite) TestAcceptedDeltaFormats(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+	// only the mocked tools are available
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", s.mockXDelta.BinDir())
+
+	c.Check(s.store.AcceptedDeltaFormats(), DeepEquals, []string{"xdelta3"})
+
+	mockBspatch := testutil.MockCommand(c, "bspatch", "")
+	defer mockBspatch.Restore()
+	os.Setenv("PATH", s.mockXDelta.BinDir()+":"+mockBspatch.BinDir())
+	c.Check(s.store.AcceptedDeltaFormats(), DeepEquals, []string{"xdelta3", "bsdiff"})
+
+	// the accepted formats can be restricted
+	sto := store.New(&store.Config{DeltaFormat: "bsdiff, zstd-patch, unknown"}, nil)
+	c.Check(sto.AcceptedDeltaFormats(), DeepEquals, []string{"bsdiff"})
+
+	// or deltas disabled entirely
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "0"), IsNil)
+	c.Check(s.store.AcceptedDeltaFormats(), HasLen, 0)
+}
+
+var deltaChainTests = []struct {
+	deltas []snap.DeltaInfo
+	chain  []snap.DeltaInfo
+	error  string
+}{{
+	// A single delta is its own chain.
+	deltas: []snap.DeltaInfo{
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+	},
+	chain: []snap.DeltaInfo{
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+	},
+}, {
+	// Deltas through intermediate revisions are chained in order.
+	deltas: []snap.DeltaInfo{
+		{Format: "xdelta3", FromRevision: 25, ToRevision: 26},
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 25},
+	},
+	chain: []snap.DeltaInfo{
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 25},
+		{Format: "xdelta3", FromRevision: 25, ToRevision: 26},
+	},
+}, {
+	// The smallest chain is picked.
+	deltas: []snap.DeltaInfo{
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 26, Size: 300},
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 25, Size: 100},
+		{Format: "xdelta3", FromRevision: 25, ToRevision: 26, Size: 100},
+	},
+	chain: []snap.DeltaInfo{
+		{Format: "xdelta3", FromRevision: 24, ToRevision: 25, Size: 100},
+		{Format: "xdelta3", FromRevision: 25, ToRevision: 26, Size: 100},
+	},
+}, {
+	// The preferred format is picked, unsupported ones are ignored.
+	deltas: []snap.DeltaInfo{
+		{Format: "ydelta", FromRevision: 24, ToRevision: 26},
+		{Format: "bsdiff", FromRevision: 24, ToRevision: 26},
+		{Format: "zstd-patch", FromRevision: 24, ToRevision: 26},
+	},
+	chain: []snap.DeltaInfo{
+		{Format: "zstd-patch", FromRevision: 24, ToRevision: 26},
+	},
+}, {
+	deltas: []snap.DeltaInfo{
+		{Format: "ydelta", FromRevision: 24, ToRevision: 26},
+	},
+	error: `store returned no delta in a supported format \(xdelta3, zstd-patch, bsdiff\)`,
+}, {
+	deltas: []snap.DeltaInfo{
+		{Format: "bsdiff", FromRevision: 24, ToRevision: 26},
+		{Format: "bsdiff", FromRevision: 24, ToRevision: 26},
+	},
+	error: `store returned more than one bsdiff delta from revision 24 to 26`,
+}, {
+	deltas: []snap.DeltaInfo{
+		{Format: "bsdiff", FromRevision: 26, ToRevision: 24},
+	},
+	error: `store returned invalid delta from revision 26 to 24`,
+}, {
+	// A gap in the chain.
+	deltas: []snap.DeltaInfo{
+		{Format: "bsdiff", FromRevision: 24, ToRevision: 25},
+		{Format: "bsdiff", FromRevision: 26, ToRevision: 27},
+	},
+	error: `store returned no chain of deltas from revision 24 to 27`,
+}}
+
+func (s *storeDownloadSuite) TestDeltaChain(c *C) {
+	for _, t := range deltaChainTests {
+		chain, err := store.DeltaChain(t.deltas, []string{"xdelta3", "zstd-patch", "bsdiff"})
+		if t.error != "" {
+			c.Check(err, ErrorMatches, t.error, Commentf("%v", t.deltas))
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Check(chain, DeepEquals, t.chain)
 	}
 }
 
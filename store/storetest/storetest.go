@@ -97,6 +97,10 @@ func (Store) ConnectivityCheck() (map[string]bool, error) {
 	panic("ConnectivityCheck not expected")
 }
 
+func (Store) EndpointsStatus() []store.EndpointStatus {
+	panic("EndpointsStatus not expected")
+}
+
 func (Store) CreateCohorts(context.Context, []string) (map[string]string, error) {
 	panic("CreateCohort not expected")
 }
@@ -88,6 +88,36 @@ defaults:
 	c.Check(sysctlArgs, DeepEquals, [][]string{{"--root", filepath.Join(boot.InstallHostWritableDir, "_writable_defaults"), "mask", "rsyslog.service"}})
 }
 
+func (s *sysconfigSuite) TestSystemOptionsOverGadgetDefaults(c *C) {
+	const gadgetDefaultsYaml = `
+defaults:
+  system:
+    service:
+      ssh.disable: false
+`
+	si := &snap.SideInfo{
+		RealName: "pc",
+		Revision: snap.R(1),
+		SnapID:   "idid",
+	}
+	snapInfo := snaptest.MockSnapWithFiles(c, "name: pc\ntype: gadget", si, [][]string{
+		{"meta/gadget.yaml", gadgetYaml + gadgetDefaultsYaml},
+	})
+
+	sshDontRunFile := filepath.Join(boot.InstallHostWritableDir, "_writable_defaults/etc/ssh/sshd_not_to_be_run")
+
+	err := sysconfig.ConfigureRunSystem(&sysconfig.Options{
+		TargetRootDir: boot.InstallHostWritableDir,
+		GadgetDir:     snapInfo.MountDir(),
+		SystemOptions: map[string]interface{}{
+			"service.ssh.disable": true,
+		},
+	})
+	c.Assert(err, IsNil)
+
+	c.Check(osutil.FileExists(sshDontRunFile), Equals, true)
+}
+
 func (s *sysconfigSuite) TestInstallModeEarlyDefaultsFromGadgetInvalid(c *C) {
 	const gadgetDefaultsYaml = `
 defaults:
@@ -42,6 +42,10 @@ type Options struct {
 
 	// GadgetDir is the path of the mounted gadget snap.
 	GadgetDir string
+
+	// SystemOptions are system configuration options applied on top of
+	// the defaults of the gadget, e.g. chosen by its install-device hook.
+	SystemOptions map[string]interface{}
 }
 
 type FilesystemOnlyApplyOptions struct {
@@ -76,6 +80,12 @@ func ConfigureRunSystem(opts *Options) error {
 			return err
 		}
 		defaults := gadget.SystemDefaults(ginf.Defaults)
+		if len(opts.SystemOptions) > 0 && defaults == nil {
+			defaults = make(map[string]interface{}, len(opts.SystemOptions))
+		}
+		for k, v := range opts.SystemOptions {
+			defaults[k] = v
+		}
 		if len(defaults) > 0 {
 			// options are nil which implies core system
 			var options *FilesystemOnlyApplyOptions
@@ -98,7 +98,11 @@ func (s *emulation) IsActive(service string) (bool, error) {
 	return false, errNotImplemented
 }
 
-func (s *emulation) LogReader(services []string, n int, follow bool) (io.ReadCloser, error) {
+func (s *emulation) FailureState(service string) (restarts int, failed bool, err error) {
+	return 0, false, errNotImplemented
+}
+
+func (s *emulation) LogReader(services []string, opts LogReaderOptions) (io.ReadCloser, error) {
 	return nil, errNotImplemented
 }
 
@@ -22,9 +22,11 @@ package systemd
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log/syslog"
 	"net"
 	"os"
+	"strconv"
 )
 
 var journalStdoutPath = "/run/systemd/journal/stdout"
@@ -70,3 +72,18 @@ func NewJournalStreamFile(identifier string, priority syslog.Priority, levelPref
 
 	return conn.File()
 }
+
+// KernelLogReader returns a reader for the JSON logs of the kernel and of the
+// audit subsystem since the current boot, limited to the last n entries
+// unless n is negative. This is where apparmor and seccomp log denials.
+func KernelLogReader(n int) (io.ReadCloser, error) {
+	args := []string{"-o", "json", "--no-pager", "-b"}
+	if n < 0 {
+		args = append(args, "--no-tail")
+	} else {
+		args = append(args, "-n", strconv.Itoa(n))
+	}
+	args = append(args, "_TRANSPORT=kernel", "+", "_TRANSPORT=audit")
+
+	return osutilStreamCommand("journalctl", args...)
+}
@@ -20,9 +20,12 @@
 package systemd_test
 
 import (
+	"io"
+	"io/ioutil"
 	"log/syslog"
 	"net"
 	"path"
+	"strings"
 
 	. "gopkg.in/check.v1"
 
@@ -87,3 +90,27 @@ func (j *journalTestSuite) TestStreamFileHeader(c *C) {
 
 	<-doneCh
 }
+
+func (j *journalTestSuite) TestKernelLogReader(c *C) {
+	var args [][]string
+	restore := MockOsutilStreamCommand(func(name string, myargs ...string) (io.ReadCloser, error) {
+		c.Check(name, Equals, "journalctl")
+		args = append(args, myargs)
+		return ioutil.NopCloser(strings.NewReader("{}\n")), nil
+	})
+	defer restore()
+
+	rc, err := KernelLogReader(100)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, "{}\n")
+
+	_, err = KernelLogReader(-1)
+	c.Assert(err, IsNil)
+
+	c.Check(args, DeepEquals, [][]string{
+		{"-o", "json", "--no-pager", "-b", "-n", "100", "_TRANSPORT=kernel", "+", "_TRANSPORT=audit"},
+		{"-o", "json", "--no-pager", "-b", "--no-tail", "_TRANSPORT=kernel", "+", "_TRANSPORT=audit"},
+	})
+}
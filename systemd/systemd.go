@@ -132,7 +132,7 @@ func Available() error {
 }
 
 // Version returns systemd version.
-func Version() (int, error) {
+var Version = func() (int, error) {
 	out, err := systemctlCmd("--version")
 	if err != nil {
 		return 0, err
@@ -168,22 +168,102 @@ func Version() (int, error) {
 	return ver, nil
 }
 
+// MockSystemdVersion makes Version return the given version and error.
+func MockSystemdVersion(version int, err error) (restore func()) {
+	oldVersion := Version
+	Version = func() (int, error) {
+		return version, err
+	}
+	return func() {
+		Version = oldVersion
+	}
+}
+
+// The systemd versions needed for the per-unit journal settings.
+const (
+	// LogRateLimitMinVersion is the version supporting the
+	// LogRateLimitIntervalSec= and LogRateLimitBurst= settings.
+	LogRateLimitMinVersion = 240
+	// LogNamespaceMinVersion is the version supporting the
+	// LogNamespace= setting, journald namespace instances and
+	// journalctl --namespace.
+	LogNamespaceMinVersion = 245
+)
+
+// RestartBackoffMinVersion is the version supporting the
+// RestartSteps= and RestartMaxDelaySec= settings escalating the delay
+// between restarts of a service.
+const RestartBackoffMinVersion = 254
+
+// EnsureAtLeast returns an error if the systemd version is lower than
+// the required one.
+func EnsureAtLeast(requiredVersion int) error {
+	ver, err := Version()
+	if err != nil {
+		return err
+	}
+	if ver < requiredVersion {
+		return fmt.Errorf("systemd version %d is too old (expected at least %d)", ver, requiredVersion)
+	}
+	return nil
+}
+
 var osutilStreamCommand = osutil.StreamCommand
 
+// LogReaderOptions carries the options of a LogReader call.
+type LogReaderOptions struct {
+	// N is the maximum number of entries to return initially, if
+	// negative there is no limit.
+	N int
+	// Follow makes the reader keep returning entries as they are logged.
+	Follow bool
+	// Since and Until, if set, restrict the entries to those logged at
+	// or after and at or before the given times respectively.
+	Since time.Time
+	Until time.Time
+	// Namespaces includes the entries logged into journal namespaces,
+	// which needs systemd 245 or later.
+	Namespaces bool
+}
+
+func journalTimestamp(t time.Time) string {
+	return "@" + strconv.FormatInt(t.Unix(), 10)
+}
+
 // jctl calls journalctl to get the JSON logs of the given services.
-var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+var jctl = func(svcs []string, opts LogReaderOptions) (io.ReadCloser, error) {
 	// args will need two entries per service, plus a fixed number (give or take
-	// one) for the initial options.
-	args := make([]string, 0, 2*len(svcs)+6)        // the fixed number is 6
+	// one) for the initial options, plus two per time bound and one for
+	// namespaces.
+	size := 2*len(svcs) + 6 // the fixed number is 6
+	if !opts.Since.IsZero() {
+		size += 2
+	}
+	if !opts.Until.IsZero() {
+		size += 2
+	}
+	if opts.Namespaces {
+		size++
+	}
+	args := make([]string, 0, size)
 	args = append(args, "-o", "json", "--no-pager") //   3...
-	if n < 0 {
+	if opts.N < 0 {
 		args = append(args, "--no-tail") // < 2
 	} else {
-		args = append(args, "-n", strconv.Itoa(n)) // ... + 2 ...
+		args = append(args, "-n", strconv.Itoa(opts.N)) // ... + 2 ...
 	}
-	if follow {
+	if opts.Follow {
 		args = append(args, "-f") // ... + 1 == 6
 	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since", journalTimestamp(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		args = append(args, "--until", journalTimestamp(opts.Until))
+	}
+	if opts.Namespaces {
+		args = append(args, "--namespace=*")
+	}
 
 	for i := range svcs {
 		args = append(args, "-u", svcs[i]) // this is why 2×
@@ -192,7 +272,7 @@ var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
 	return osutilStreamCommand("journalctl", args...)
 }
 
-func MockJournalctl(f func(svcs []string, n int, follow bool) (io.ReadCloser, error)) func() {
+func MockJournalctl(f func(svcs []string, opts LogReaderOptions) (io.ReadCloser, error)) func() {
 	oldJctl := jctl
 	jctl = f
 	return func() {
@@ -234,8 +314,12 @@ type Systemd interface {
 	IsEnabled(service string) (bool, error)
 	// IsActive checks whether the given service is Active
 	IsActive(service string) (bool, error)
+	// FailureState returns how many times the given service was
+	// restarted automatically since it was last started, and whether
+	// it ended up in the failed state.
+	FailureState(service string) (restarts int, failed bool, err error)
 	// LogReader returns a reader for the given services' log.
-	LogReader(services []string, n int, follow bool) (io.ReadCloser, error)
+	LogReader(services []string, opts LogReaderOptions) (io.ReadCloser, error)
 	// AddMountUnitFile adds/enables/starts a mount unit.
 	AddMountUnitFile(name, revision, what, where, fstype string) (string, error)
 	// RemoveMountUnitFile unmounts/stops/disables/removes a mount unit.
@@ -388,8 +472,8 @@ func (s *systemd) StartNoBlock(serviceNames ...string) error {
 	return err
 }
 
-func (*systemd) LogReader(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
-	return jctl(serviceNames, n, follow)
+func (*systemd) LogReader(serviceNames []string, opts LogReaderOptions) (io.ReadCloser, error) {
+	return jctl(serviceNames, opts)
 }
 
 var statusregex = regexp.MustCompile(`(?m)^(?:(.+?)=(.*)|(.*))?$`)
@@ -579,6 +663,37 @@ func (s *systemd) IsActive(serviceName string) (bool, error) {
 	return false, err
 }
 
+func (s *systemd) FailureState(serviceName string) (restarts int, failed bool, err error) {
+	if s.mode == GlobalUserMode {
+		panic("cannot call show with GlobalUserMode")
+	}
+	out, err := s.systemctl("show", "--property=NRestarts,ActiveState", serviceName)
+	if err != nil {
+		return 0, false, err
+	}
+	seenRestarts := false
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return 0, false, fmt.Errorf("cannot get failure state of %q: bad line %q in ‘systemctl show’ output", serviceName, line)
+		}
+		switch kv[0] {
+		case "NRestarts":
+			restarts, err = strconv.Atoi(kv[1])
+			if err != nil {
+				return 0, false, fmt.Errorf("cannot get failure state of %q: invalid NRestarts %q", serviceName, kv[1])
+			}
+			seenRestarts = true
+		case "ActiveState":
+			failed = kv[1] == "failed"
+		}
+	}
+	if !seenRestarts {
+		return 0, false, fmt.Errorf("cannot get failure state of %q: missing NRestarts in ‘systemctl show’ output", serviceName)
+	}
+	return restarts, failed, nil
+}
+
 func (s *systemd) Stop(serviceName string, timeout time.Duration) error {
 	if s.mode == GlobalUserMode {
 		panic("cannot call stop with GlobalUserMode")
@@ -121,13 +121,13 @@ func (s *SystemdTestSuite) myRun(args ...string) (out []byte, err error) {
 	return out, err
 }
 
-func (s *SystemdTestSuite) myJctl(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+func (s *SystemdTestSuite) myJctl(svcs []string, opts LogReaderOptions) (io.ReadCloser, error) {
 	var err error
 	var out []byte
 
-	s.jns = append(s.jns, strconv.Itoa(n))
+	s.jns = append(s.jns, strconv.Itoa(opts.N))
 	s.jsvcs = append(s.jsvcs, svcs)
-	s.jfollows = append(s.jfollows, follow)
+	s.jfollows = append(s.jfollows, opts.Follow)
 
 	if s.j < len(s.jouts) {
 		out = s.jouts[s.j]
@@ -397,6 +397,23 @@ func (s *SystemdTestSuite) TestAvailable(c *C) {
 	c.Check(s.argses, DeepEquals, [][]string{{"--version"}})
 }
 
+func (s *SystemdTestSuite) TestEnsureAtLeast(c *C) {
+	s.outs = [][]byte{
+		[]byte("systemd 239\n+PAM\n"),
+		[]byte("systemd 240\n+PAM\n"),
+		[]byte("foo 240\n+PAM\n"),
+	}
+
+	c.Check(EnsureAtLeast(LogRateLimitMinVersion), ErrorMatches, `systemd version 239 is too old \(expected at least 240\)`)
+	c.Check(EnsureAtLeast(LogRateLimitMinVersion), IsNil)
+	c.Check(EnsureAtLeast(LogRateLimitMinVersion), ErrorMatches, `cannot parse systemd version: .*`)
+
+	restore := MockSystemdVersion(245, nil)
+	defer restore()
+	c.Check(EnsureAtLeast(LogNamespaceMinVersion), IsNil)
+	c.Check(s.argses, HasLen, 3)
+}
+
 func (s *SystemdTestSuite) TestVersion(c *C) {
 	s.outs = [][]byte{
 		[]byte("systemd 223\n+PAM\n"),
@@ -481,7 +498,7 @@ func (s *SystemdTestSuite) TestIsTimeout(c *C) {
 func (s *SystemdTestSuite) TestLogErrJctl(c *C) {
 	s.jerrs = []error{&Timeout{}}
 
-	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, 24, false)
+	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, LogReaderOptions{N: 24})
 	c.Check(err, NotNil)
 	c.Check(reader, IsNil)
 	c.Check(s.jns, DeepEquals, []string{"24"})
@@ -496,7 +513,7 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 `
 	s.jouts = [][]byte{[]byte(expected)}
 
-	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, 24, false)
+	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, LogReaderOptions{N: 24})
 	c.Check(err, IsNil)
 	logs, err := ioutil.ReadAll(reader)
 	c.Assert(err, IsNil)
@@ -740,15 +757,23 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 		return nil, nil
 	})
 
-	_, err = Jctl([]string{"foo", "bar"}, 10, false)
+	_, err = Jctl([]string{"foo", "bar"}, LogReaderOptions{N: 10})
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "-u", "foo", "-u", "bar"})
-	_, err = Jctl([]string{"foo", "bar", "baz"}, 99, true)
+	_, err = Jctl([]string{"foo", "bar", "baz"}, LogReaderOptions{N: 99, Follow: true})
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "99", "-f", "-u", "foo", "-u", "bar", "-u", "baz"})
-	_, err = Jctl([]string{"foo", "bar"}, -1, false)
+	_, err = Jctl([]string{"foo", "bar"}, LogReaderOptions{N: -1})
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "-u", "foo", "-u", "bar"})
+	_, err = Jctl([]string{"foo"}, LogReaderOptions{
+		N:          -1,
+		Since:      time.Unix(1600000000, 0),
+		Until:      time.Unix(1600003600, 0),
+		Namespaces: true,
+	})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "--since", "@1600000000", "--until", "@1600003600", "--namespace=*", "-u", "foo"})
 }
 
 func (s *SystemdTestSuite) TestIsActiveIsInactive(c *C) {
@@ -798,6 +823,44 @@ func (s *SystemdTestSuite) TestIsActiveUnexpectedErr(c *C) {
 	c.Assert(err, ErrorMatches, ".* failed with exit status 1: random-failure\n")
 }
 
+func (s *SystemdTestSuite) TestFailureState(c *C) {
+	s.outs = [][]byte{
+		[]byte("NRestarts=4\nActiveState=activating\n"),
+		[]byte("NRestarts=5\nActiveState=failed\n"),
+	}
+
+	restarts, failed, err := New("xyzzy", SystemMode, s.rep).FailureState("foo.service")
+	c.Assert(err, IsNil)
+	c.Check(restarts, Equals, 4)
+	c.Check(failed, Equals, false)
+
+	restarts, failed, err = New("xyzzy", SystemMode, s.rep).FailureState("foo.service")
+	c.Assert(err, IsNil)
+	c.Check(restarts, Equals, 5)
+	c.Check(failed, Equals, true)
+
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"show", "--property=NRestarts,ActiveState", "foo.service"},
+		{"show", "--property=NRestarts,ActiveState", "foo.service"},
+	})
+}
+
+func (s *SystemdTestSuite) TestFailureStateBadOutput(c *C) {
+	s.outs = [][]byte{
+		[]byte("ActiveState=active\n"),
+		[]byte("NRestarts=lots\nActiveState=active\n"),
+		[]byte("NRestarts\n"),
+	}
+
+	sysd := New("xyzzy", SystemMode, s.rep)
+	_, _, err := sysd.FailureState("foo.service")
+	c.Check(err, ErrorMatches, `cannot get failure state of "foo.service": missing NRestarts in ‘systemctl show’ output`)
+	_, _, err = sysd.FailureState("foo.service")
+	c.Check(err, ErrorMatches, `cannot get failure state of "foo.service": invalid NRestarts "lots"`)
+	_, _, err = sysd.FailureState("foo.service")
+	c.Check(err, ErrorMatches, `cannot get failure state of "foo.service": bad line "NRestarts" in ‘systemctl show’ output`)
+}
+
 func makeMockMountUnit(c *C, mountDir string) string {
 	mountUnit := MountUnitPath(dirs.StripRootDir(mountDir))
 	err := ioutil.WriteFile(mountUnit, nil, 0644)
@@ -21,7 +21,9 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/jessevdk/go-flags"
 
@@ -29,7 +31,10 @@ import (
 	"github.com/snapcore/snapd/gadget/install"
 )
 
-var installRun = install.Run
+var (
+	installRun  = install.Run
+	installSeal = install.Seal
+)
 
 type cmdCreatePartitions struct {
 	Mount                bool   `short:"m" long:"mount" description:"Also mount filesystems after creation"`
@@ -94,8 +99,21 @@ func main() {
 		KernelPath:              args.KernelPath,
 		Model:                   model,
 	}
+	if args.Encrypt {
+		tmpdir, err := ioutil.TempDir("", "uc20-create-partitions")
+		if err != nil {
+			panic(err)
+		}
+		defer os.RemoveAll(tmpdir)
+		options.UnsealedKeyFile = filepath.Join(tmpdir, "unsealed-key")
+	}
 	err = installRun(args.Positional.GadgetRoot, args.Positional.Device, options)
 	if err != nil {
 		panic(err)
 	}
+	if args.Encrypt {
+		if err := installSeal(options); err != nil {
+			panic(err)
+		}
+	}
 }
@@ -27,6 +27,7 @@ import (
 var (
 	SessionInfoCmd    = sessionInfoCmd
 	ServiceControlCmd = serviceControlCmd
+	ServiceStatusCmd  = serviceStatusCmd
 )
 
 func MockStopTimeouts(stop, kill time.Duration) (restore func()) {
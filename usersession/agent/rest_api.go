@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timeout"
 )
@@ -35,6 +36,7 @@ var restApi = []*Command{
 	rootCmd,
 	sessionInfoCmd,
 	serviceControlCmd,
+	serviceStatusCmd,
 }
 
 var (
@@ -52,6 +54,11 @@ var (
 		Path: "/v1/service-control",
 		POST: postServiceControl,
 	}
+
+	serviceStatusCmd = &Command{
+		Path: "/v1/service-status",
+		GET:  serviceStatus,
+	}
 )
 
 func sessionInfo(c *Command, r *http.Request) Response {
@@ -186,3 +193,39 @@ func postServiceControl(c *Command, r *http.Request) Response {
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.UserMode, dummyReporter{})
 	return impl(&inst, sysd)
 }
+
+type serviceUnitStatus struct {
+	Unit    string `json:"unit"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+func serviceStatus(c *Command, r *http.Request) Response {
+	services := strutil.CommaSeparatedList(r.URL.Query().Get("services"))
+	if len(services) == 0 {
+		return BadRequest("no services specified")
+	}
+	// Refuse to report on non-snap services
+	for _, service := range services {
+		if !strings.HasPrefix(service, "snap.") {
+			return InternalError("cannot get status of non-snap service %v", service)
+		}
+	}
+
+	systemdLock.Lock()
+	defer systemdLock.Unlock()
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.UserMode, dummyReporter{})
+	sts, err := sysd.Status(services...)
+	if err != nil {
+		return InternalError("cannot get status of services: %v", err)
+	}
+	result := make([]serviceUnitStatus, 0, len(sts))
+	for _, st := range sts {
+		result = append(result, serviceUnitStatus{
+			Unit:    st.UnitName,
+			Enabled: st.Enabled,
+			Active:  st.Active,
+		})
+	}
+	return SyncResponse(result)
+}
@@ -357,3 +357,74 @@ func (s *restSuite) TestServicesStopReportsTimeout(c *C) {
 		{"--user", "stop", "snap.bar.service"},
 	})
 }
+
+func (s *restSuite) TestServiceStatus(c *C) {
+	// the agent.ServiceStatus end point only supports GET requests
+	c.Assert(agent.ServiceStatusCmd.GET, NotNil)
+	c.Check(agent.ServiceStatusCmd.PUT, IsNil)
+	c.Check(agent.ServiceStatusCmd.POST, IsNil)
+	c.Check(agent.ServiceStatusCmd.DELETE, IsNil)
+
+	c.Check(agent.ServiceStatusCmd.Path, Equals, "/v1/service-status")
+
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		switch cmd[len(cmd)-1] {
+		case "snap.foo.service":
+			return []byte("Type=simple\nId=snap.foo.service\nActiveState=active\nUnitFileState=disabled\n"), nil
+		case "snap.foo.socket":
+			return []byte("Id=snap.foo.socket\nActiveState=active\nUnitFileState=enabled\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected call %q", cmd)
+	})
+	defer restore()
+
+	_, err := agent.New()
+	c.Assert(err, IsNil)
+
+	req, err := http.NewRequest("GET", "/v1/service-status?services=snap.foo.service,snap.foo.socket", nil)
+	c.Assert(err, IsNil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, DeepEquals, []interface{}{
+		map[string]interface{}{"unit": "snap.foo.service", "enabled": false, "active": true},
+		map[string]interface{}{"unit": "snap.foo.socket", "enabled": true, "active": true},
+	})
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type", "snap.foo.service"},
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState", "snap.foo.socket"},
+	})
+}
+
+func (s *restSuite) TestServiceStatusNonSnap(c *C) {
+	_, err := agent.New()
+	c.Assert(err, IsNil)
+
+	req, err := http.NewRequest("GET", "/v1/service-status?services=snap.foo.service,dbus.service", nil)
+	c.Assert(err, IsNil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "cannot get status of non-snap service dbus.service",
+	})
+
+	// no services is a bad request
+	req, err = http.NewRequest("GET", "/v1/service-status", nil)
+	c.Assert(err, IsNil)
+	rec = httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+
+	// nothing was asked of systemd
+	c.Check(s.sysdLog, HasLen, 0)
+}
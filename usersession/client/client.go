@@ -31,6 +31,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/snapcore/snapd/dirs"
@@ -267,3 +268,39 @@ func (client *Client) ServicesStop(ctx context.Context, services []string) (stop
 	_, stopFailures, err = client.serviceControlCall(ctx, "stop", services)
 	return stopFailures, err
 }
+
+// ServiceUnitStatus is the status of a user service unit in a session.
+type ServiceUnitStatus struct {
+	Unit    string `json:"unit"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+// ServicesStatus returns the status of the given user service units in
+// each of the running user sessions, keyed by uid.
+func (client *Client) ServicesStatus(ctx context.Context, services []string) (status map[int][]ServiceUnitStatus, err error) {
+	query := url.Values{"services": []string{strings.Join(services, ",")}}
+	responses, err := client.doMany(ctx, "GET", "/v1/service-status", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status = make(map[int][]ServiceUnitStatus)
+	for _, resp := range responses {
+		if resp.err != nil {
+			if err == nil {
+				err = resp.err
+			}
+			continue
+		}
+		var sts []ServiceUnitStatus
+		if decodeErr := json.Unmarshal(resp.Result, &sts); decodeErr != nil {
+			if err == nil {
+				err = decodeErr
+			}
+			continue
+		}
+		status[resp.uid] = sts
+	}
+	return status, err
+}
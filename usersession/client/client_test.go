@@ -446,3 +446,46 @@ func (s *clientSuite) TestServicesStopFailure(c *C) {
 		Error:   "failed to stop",
 	})
 }
+
+func (s *clientSuite) TestServicesStatus(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v1/service-status")
+		c.Check(r.URL.Query().Get("services"), Equals, "snap.foo.service,snap.foo.socket")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{
+  "type": "sync",
+  "result": [
+    {"unit": "snap.foo.service", "enabled": false, "active": true},
+    {"unit": "snap.foo.socket", "enabled": true, "active": true}
+  ]
+}`))
+	})
+	status, err := s.cli.ServicesStatus(context.Background(), []string{"snap.foo.service", "snap.foo.socket"})
+	c.Assert(err, IsNil)
+	expected := []client.ServiceUnitStatus{
+		{Unit: "snap.foo.service", Enabled: false, Active: true},
+		{Unit: "snap.foo.socket", Enabled: true, Active: true},
+	}
+	c.Check(status, DeepEquals, map[int][]client.ServiceUnitStatus{
+		42:   expected,
+		1000: expected,
+	})
+}
+
+func (s *clientSuite) TestServicesStatusError(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(500)
+		w.Write([]byte(`{
+  "type": "error",
+  "result": {
+    "message": "cannot get status of services: boom"
+  }
+}`))
+	})
+	status, err := s.cli.ServicesStatus(context.Background(), []string{"snap.foo.service"})
+	c.Check(status, DeepEquals, map[int][]client.ServiceUnitStatus{})
+	c.Check(err, ErrorMatches, "cannot get status of services: boom")
+}
@@ -0,0 +1,167 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+const dbusSnapOwnerKey = "X-Snap="
+
+func genDBusServiceFile(app *snap.AppInfo, busName string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `[D-BUS Service]
+Name=%s
+Comment=Bus name for snap application %s.%s
+SystemdService=%s
+Exec=%s
+AssumedAppArmorLabel=%s
+`, busName, app.Snap.InstanceName(), app.Name, app.ServiceName(), app.LauncherCommand(), app.SecurityTag())
+	if app.DaemonScope == snap.SystemDaemon {
+		// system bus activation needs to know who to run the
+		// service as, even when systemd does the launching
+		buf.WriteString("User=root\n")
+	}
+	fmt.Fprintf(&buf, "%s%s\n", dbusSnapOwnerKey, app.Snap.InstanceName())
+	return buf.Bytes()
+}
+
+// snapNameFromDBusServiceFile returns the instance name of the snap that
+// generated the given D-Bus service file, or "" if it was not generated
+// by snapd.
+func snapNameFromDBusServiceFile(filename string) (owner string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := s.Text(); strings.HasPrefix(line, dbusSnapOwnerKey) {
+			return strings.TrimPrefix(line, dbusSnapOwnerKey), nil
+		}
+	}
+	return "", s.Err()
+}
+
+// removeSnapDBusServiceFiles removes the D-Bus service files of the given
+// snap in dir, except for the ones listed in keep.
+func removeSnapDBusServiceFiles(dir, instanceName string, keep map[string]bool) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.service"))
+	if err != nil {
+		return err
+	}
+	for _, path := range matches {
+		if keep[filepath.Base(path)] {
+			continue
+		}
+		owner, err := snapNameFromDBusServiceFile(path)
+		if err != nil {
+			return err
+		}
+		if owner != instanceName {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSnapDBusActivationFiles writes the D-Bus service files that let the
+// session and system buses start the services of the snap declaring
+// activates-on slots on demand, and removes the ones it no longer needs.
+func AddSnapDBusActivationFiles(s *snap.Info) (err error) {
+	sessionContent := make(map[string][]byte)
+	systemContent := make(map[string][]byte)
+	for _, app := range s.Apps {
+		for _, slot := range app.ActivatesOn {
+			busName, ok := slot.Attrs["name"].(string)
+			if !ok {
+				// cannot happen for a snap that passed validation
+				return fmt.Errorf("internal error: cannot get bus name of slot %q", slot.Name)
+			}
+			filename := busName + ".service"
+			switch app.DaemonScope {
+			case snap.SystemDaemon:
+				systemContent[filename] = genDBusServiceFile(app, busName)
+			case snap.UserDaemon:
+				sessionContent[filename] = genDBusServiceFile(app, busName)
+			}
+		}
+	}
+
+	var written []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, path := range written {
+			os.Remove(path)
+		}
+	}()
+
+	for _, set := range []struct {
+		dir     string
+		content map[string][]byte
+	}{
+		{dirs.SnapDBusSessionServicesDir, sessionContent},
+		{dirs.SnapDBusSystemServicesDir, systemContent},
+	} {
+		keep := make(map[string]bool, len(set.content))
+		for filename, content := range set.content {
+			if err := os.MkdirAll(set.dir, 0755); err != nil {
+				return err
+			}
+			path := filepath.Join(set.dir, filename)
+			if err := osutil.AtomicWriteFile(path, content, 0644, 0); err != nil {
+				return err
+			}
+			written = append(written, path)
+			keep[filename] = true
+		}
+		if err := removeSnapDBusServiceFiles(set.dir, s.InstanceName(), keep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveSnapDBusActivationFiles removes the D-Bus service files of the
+// snap.
+func RemoveSnapDBusActivationFiles(s *snap.Info) error {
+	for _, dir := range []string{dirs.SnapDBusSessionServicesDir, dirs.SnapDBusSystemServicesDir} {
+		if err := removeSnapDBusServiceFiles(dir, s.InstanceName(), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
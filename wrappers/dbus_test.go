@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+type dbusTestSuite struct {
+	testutil.BaseTest
+	tempdir string
+}
+
+var _ = Suite(&dbusTestSuite{})
+
+func (s *dbusTestSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.tempdir = c.MkDir()
+	dirs.SetRootDir(s.tempdir)
+}
+
+func (s *dbusTestSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+	s.BaseTest.TearDownTest(c)
+}
+
+const dbusActivatedYaml = `
+name: snapname
+version: 1.0
+slots:
+  dbus-session:
+    interface: dbus
+    bus: session
+    name: org.example.Session
+  dbus-system:
+    interface: dbus
+    bus: system
+    name: org.example.System
+apps:
+  user-svc:
+    command: bin/user-svc
+    daemon: simple
+    daemon-scope: user
+    activates-on: [dbus-session]
+  system-svc:
+    command: bin/system-svc
+    daemon: simple
+    activates-on: [dbus-system]
+`
+
+func (s *dbusTestSuite) TestAddSnapDBusActivationFiles(c *C) {
+	info := snaptest.MockSnap(c, dbusActivatedYaml, &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+
+	sessionService := filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Session.service")
+	c.Check(sessionService, testutil.FileEquals, `[D-BUS Service]
+Name=org.example.Session
+Comment=Bus name for snap application snapname.user-svc
+SystemdService=snap.snapname.user-svc.service
+Exec=/usr/bin/snap run snapname.user-svc
+AssumedAppArmorLabel=snap.snapname.user-svc
+X-Snap=snapname
+`)
+
+	systemService := filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.System.service")
+	c.Check(systemService, testutil.FileEquals, `[D-BUS Service]
+Name=org.example.System
+Comment=Bus name for snap application snapname.system-svc
+SystemdService=snap.snapname.system-svc.service
+Exec=/usr/bin/snap run snapname.system-svc
+AssumedAppArmorLabel=snap.snapname.system-svc
+User=root
+X-Snap=snapname
+`)
+
+	err = wrappers.RemoveSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(sessionService, testutil.FileAbsent)
+	c.Check(systemService, testutil.FileAbsent)
+}
+
+func (s *dbusTestSuite) TestAddSnapDBusActivationFilesRemovesStale(c *C) {
+	info := snaptest.MockSnap(c, dbusActivatedYaml, &snap.SideInfo{Revision: snap.R(12)})
+
+	c.Assert(os.MkdirAll(dirs.SnapDBusSessionServicesDir, 0755), IsNil)
+	// a bus name the snap no longer activates on
+	stale := filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Old.service")
+	c.Assert(ioutil.WriteFile(stale, []byte("[D-BUS Service]\nName=org.example.Old\nX-Snap=snapname\n"), 0644), IsNil)
+	// another snap's file, and one not generated by snapd
+	other := filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Other.service")
+	c.Assert(ioutil.WriteFile(other, []byte("[D-BUS Service]\nName=org.example.Other\nX-Snap=other-snap\n"), 0644), IsNil)
+	foreign := filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Foreign.service")
+	c.Assert(ioutil.WriteFile(foreign, []byte("[D-BUS Service]\nName=org.example.Foreign\n"), 0644), IsNil)
+
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+
+	c.Check(stale, testutil.FileAbsent)
+	c.Check(other, testutil.FilePresent)
+	c.Check(foreign, testutil.FilePresent)
+	c.Check(filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Session.service"), testutil.FilePresent)
+
+	err = wrappers.RemoveSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(other, testutil.FilePresent)
+	c.Check(foreign, testutil.FilePresent)
+}
+
+func (s *dbusTestSuite) TestAddSnapDBusActivationFilesNoActivatableServices(c *C) {
+	info := snaptest.MockSnap(c, "name: snapname\nversion: 1.0\napps:\n  svc:\n    daemon: simple\n", &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(dirs.SnapDBusSessionServicesDir, testutil.FileAbsent)
+	c.Check(dirs.SnapDBusSystemServicesDir, testutil.FileAbsent)
+}
@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+)
+
+// JournalSettings carries the journald settings applied to the services
+// of a snap.
+type JournalSettings struct {
+	// MaxSize, if set, makes the system services of the snap log into a
+	// journal namespace of their own whose disk usage is capped to
+	// MaxSize bytes, instead of logging into the system journal.
+	MaxSize int64
+	// RateLimitBurst, if set, is the number of messages each service
+	// can log within RateLimitInterval before further messages are
+	// dropped.
+	RateLimitBurst    int
+	RateLimitInterval time.Duration
+}
+
+// JournalNamespace returns the name of the journal namespace the system
+// services of the snap log into when their journal is size capped.
+func JournalNamespace(snapInstanceName string) string {
+	return "snap-" + snapInstanceName
+}
+
+func journaldConfFile(snapInstanceName string) string {
+	return filepath.Join(dirs.SnapJournaldConfDir, fmt.Sprintf("journald@%s.conf", JournalNamespace(snapInstanceName)))
+}
+
+func journaldServiceName(snapInstanceName string) string {
+	return fmt.Sprintf("systemd-journald@%s.service", JournalNamespace(snapInstanceName))
+}
+
+// supportedJournalSettings returns the journal settings the running
+// systemd supports. The configuration of the others is refused, so they
+// are only logged and dropped here.
+func supportedJournalSettings(s *snap.Info, settings *JournalSettings) *JournalSettings {
+	if settings == nil {
+		return nil
+	}
+	supported := *settings
+	if supported.MaxSize > 0 {
+		if err := systemd.EnsureAtLeast(systemd.LogNamespaceMinVersion); err != nil {
+			logger.Noticef("cannot cap the journal size of snap %q: %v", s.InstanceName(), err)
+			supported.MaxSize = 0
+		}
+	}
+	if supported.RateLimitBurst > 0 {
+		if err := systemd.EnsureAtLeast(systemd.LogRateLimitMinVersion); err != nil {
+			logger.Noticef("cannot rate limit the journal of snap %q: %v", s.InstanceName(), err)
+			supported.RateLimitBurst = 0
+			supported.RateLimitInterval = 0
+		}
+	}
+	if supported == (JournalSettings{}) {
+		return nil
+	}
+	return &supported
+}
+
+func hasSystemServices(s *snap.Info) bool {
+	for _, app := range s.Apps {
+		if app.IsService() && app.DaemonScope == snap.SystemDaemon {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureJournalNamespace writes the configuration of the journal namespace
+// of the snap when its journal is size capped and removes it otherwise.
+func ensureJournalNamespace(s *snap.Info, settings *JournalSettings) error {
+	path := journaldConfFile(s.InstanceName())
+	if settings == nil || settings.MaxSize == 0 || !hasSystemServices(s) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Auto-generated, DO NOT EDIT\n")
+	buf.WriteString("[Journal]\n")
+	fmt.Fprintf(&buf, "SystemMaxUse=%d\n", settings.MaxSize)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	err := osutil.EnsureFileState(path, &osutil.MemoryFileState{Content: buf.Bytes(), Mode: 0644})
+	if err == osutil.ErrSameState {
+		return nil
+	}
+	return err
+}
+
+// RestartJournalNamespace restarts the journald instance of the journal
+// namespace of the snap, if it has one, so that a changed size cap takes
+// effect.
+func RestartJournalNamespace(s *snap.Info, inter interacter) error {
+	if !osutil.FileExists(journaldConfFile(s.InstanceName())) {
+		return nil
+	}
+	// there are no journald namespace instances to restart
+	if err := systemd.EnsureAtLeast(systemd.LogNamespaceMinVersion); err != nil {
+		return nil
+	}
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+	return sysd.Restart(journaldServiceName(s.InstanceName()), 5*time.Second)
+}
@@ -0,0 +1,76 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/snap/snapenv"
+)
+
+// ServiceOverrides carries the environment and the extra arguments a
+// service of a snap is started with on top of what its snap.yaml says.
+type ServiceOverrides struct {
+	// Env maps the names of environment variables the snap declared as
+	// configurable for the service to the values they are set to
+	Env map[string]string
+	// Args are appended to the command line of the service
+	Args []string
+}
+
+// systemdQuote quotes s as a single word of a unit file setting,
+// escaping specifiers and, on command lines, variable expansion.
+func systemdQuote(s string, commandLine bool) string {
+	r := []string{`\`, `\\`, `"`, `\"`, "%", "%%"}
+	if commandLine {
+		r = append(r, "$", "$$")
+	}
+	return `"` + strings.NewReplacer(r...).Replace(s) + `"`
+}
+
+func (o *ServiceOverrides) environment() []string {
+	if o == nil || len(o.Env) == 0 {
+		return nil
+	}
+	env := make([]string, 0, len(o.Env)+1)
+	names := make([]string, 0, len(o.Env))
+	for k, v := range o.Env {
+		env = append(env, systemdQuote(k+"="+v, false))
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	// tell snap-exec which variables it must not take from snap.yaml,
+	// the others might just be inherited by the service
+	env = append(env, systemdQuote(snapenv.ConfiguredEnv+"="+strings.Join(names, ","), false))
+	sort.Strings(env)
+	return env
+}
+
+func (o *ServiceOverrides) args() []string {
+	if o == nil || len(o.Args) == 0 {
+		return nil
+	}
+	args := make([]string, len(o.Args))
+	for i, arg := range o.Args {
+		args[i] = systemdQuote(arg, true)
+	}
+	return args
+}
@@ -0,0 +1,226 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
+	"github.com/snapcore/snapd/timeutil"
+)
+
+// ScheduledTask is a task a snap set up at runtime, with snapctl schedule,
+// to run one of its apps to completion on a schedule.
+type ScheduledTask struct {
+	Name string
+	// App is the app run by the task, it is not a service
+	App *snap.AppInfo
+	// Schedule is in the format understood by timeutil.ParseSchedule
+	Schedule string
+}
+
+// scheduledTaskUnitName returns the name of a unit of the scheduled task,
+// app names cannot contain "_" so it does not collide with the units of
+// the apps of the snap.
+func scheduledTaskUnitName(instanceName, name, suffix string) string {
+	return fmt.Sprintf("snap.%s.schedule_%s.%s", instanceName, name, suffix)
+}
+
+func scheduledTaskUnitsGlob(instanceName string) string {
+	return filepath.Join(dirs.SnapServicesDir, fmt.Sprintf("snap.%s.schedule_*", instanceName))
+}
+
+func genScheduledTaskServiceFile(task *ScheduledTask) []byte {
+	serviceTemplate := `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Scheduled task {{.Task.Name}} of snap application {{.App.Snap.InstanceName}}.{{.App.Name}}
+Requires={{.MountUnit}}
+After={{.MountUnit}} snapd.apparmor.service
+X-Snappy=yes
+
+[Service]
+EnvironmentFile=-/etc/environment
+ExecStart={{.App.LauncherCommand}}
+SyslogIdentifier={{.App.Snap.InstanceName}}.{{.App.Name}}
+WorkingDirectory={{.App.Snap.DataDir}}
+Type=oneshot
+`
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("scheduled-task-service").Parse(serviceTemplate))
+	wrapperData := struct {
+		Task      *ScheduledTask
+		App       *snap.AppInfo
+		MountUnit string
+	}{
+		Task:      task,
+		App:       task.App,
+		MountUnit: filepath.Base(systemd.MountUnitPath(task.App.Snap.MountDir())),
+	}
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+	return templateOut.Bytes()
+}
+
+func genScheduledTaskTimerFile(task *ScheduledTask) ([]byte, error) {
+	timerTemplate := `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Timer of scheduled task {{.Task.Name}} of snap application {{.App.Snap.InstanceName}}.{{.App.Name}}
+Requires={{.MountUnit}}
+After={{.MountUnit}}
+X-Snappy=yes
+
+[Timer]
+Unit={{.ServiceFileName}}
+Persistent=true
+{{ range .Schedules }}OnCalendar={{ . }}
+{{ end }}
+[Install]
+WantedBy={{.TimersTarget}}
+`
+	schedule, err := timeutil.ParseSchedule(task.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("scheduled-task-timer").Parse(timerTemplate))
+	wrapperData := struct {
+		Task            *ScheduledTask
+		App             *snap.AppInfo
+		MountUnit       string
+		ServiceFileName string
+		TimersTarget    string
+		Schedules       []string
+	}{
+		Task:            task,
+		App:             task.App,
+		MountUnit:       filepath.Base(systemd.MountUnitPath(task.App.Snap.MountDir())),
+		ServiceFileName: scheduledTaskUnitName(task.App.Snap.InstanceName(), task.Name, "service"),
+		TimersTarget:    systemd.TimersTarget,
+		Schedules:       generateOnCalendarSchedules(schedule),
+	}
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+	return templateOut.Bytes(), nil
+}
+
+// ensureScheduledTasks writes the units of the given scheduled tasks of the
+// snap, removes the ones of tasks that are gone, and enables and starts or
+// stops and disables their timers accordingly.
+func ensureScheduledTasks(s *snap.Info, tasks []*ScheduledTask, preseeding bool, inter interacter) error {
+	instanceName := s.InstanceName()
+	content := make(map[string][]byte, 2*len(tasks))
+	var timers []string
+	for _, task := range tasks {
+		if task.App.Snap.InstanceName() != instanceName {
+			return fmt.Errorf("internal error: scheduled task %q runs an app of snap %q, not %q", task.Name, task.App.Snap.InstanceName(), instanceName)
+		}
+		timer, err := genScheduledTaskTimerFile(task)
+		if err != nil {
+			return fmt.Errorf("cannot generate timer of scheduled task %q: %v", task.Name, err)
+		}
+		timerName := scheduledTaskUnitName(instanceName, task.Name, "timer")
+		content[timerName] = timer
+		content[scheduledTaskUnitName(instanceName, task.Name, "service")] = genScheduledTaskServiceFile(task)
+		timers = append(timers, timerName)
+	}
+	sort.Strings(timers)
+
+	existing, err := filepath.Glob(scheduledTaskUnitsGlob(instanceName))
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for _, path := range existing {
+		if _, ok := content[filepath.Base(path)]; !ok {
+			stale = append(stale, path)
+		}
+	}
+
+	changed := len(stale) > 0
+	for name, data := range content {
+		path := filepath.Join(dirs.SnapServicesDir, name)
+		err := osutil.EnsureFileState(path, &osutil.MemoryFileState{Content: data, Mode: 0644})
+		if err == osutil.ErrSameState {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+	for _, path := range stale {
+		name := filepath.Base(path)
+		if filepath.Ext(name) == ".timer" && !preseeding {
+			if err := sysd.Disable(name); err != nil {
+				return err
+			}
+			if err := sysd.Stop(name, time.Duration(timeout.DefaultTimeout)); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("Failed to remove unit file %q of scheduled task: %v", path, err)
+		}
+	}
+	if preseeding {
+		return nil
+	}
+	if err := sysd.DaemonReload(); err != nil {
+		return err
+	}
+	for _, timer := range timers {
+		if err := sysd.Enable(timer); err != nil {
+			return err
+		}
+	}
+	if len(timers) > 0 {
+		if err := sysd.Start(timers...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveScheduledTasks stops and removes the units of all the scheduled
+// tasks of the snap.
+func RemoveScheduledTasks(s *snap.Info, inter interacter) error {
+	return ensureScheduledTasks(s, nil, false, inter)
+}
@@ -245,7 +245,7 @@ func StartServices(apps []*snap.AppInfo, disabledSvcs []string, flags *StartServ
 			}
 		}(app)
 
-		if len(app.Sockets) == 0 && app.Timer == nil {
+		if len(app.Sockets) == 0 && app.Timer == nil && len(app.ActivatesOn) == 0 {
 			// check if the service is disabled, if so don't start it up
 			// this could happen for example if the service was disabled in
 			// the install hook by snapctl or if the service was disabled in
@@ -346,6 +346,18 @@ func userDaemonReload() error {
 type AddSnapServicesOptions struct {
 	Preseeding   bool
 	VitalityRank int
+	// ServiceProviders maps the names of the services of the snap to
+	// the units of the services of other snaps they want and start
+	// after, as resolved from their after-plugs
+	ServiceProviders map[string][]string
+	// Journal carries the journald settings of the services of the snap
+	Journal *JournalSettings
+	// Overrides maps the names of the services of the snap to the
+	// environment and arguments they are configured to run with
+	Overrides map[string]*ServiceOverrides
+	// ScheduledTasks are the tasks the snap set up to run its apps on
+	// a schedule
+	ScheduledTasks []*ScheduledTask
 }
 
 // AddSnapServices adds service units for the applications from the snap which are services.
@@ -357,6 +369,11 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, opts *AddSnapServicesO
 	if opts == nil {
 		opts = &AddSnapServicesOptions{}
 	}
+	if opts.Journal != nil {
+		withSupportedJournal := *opts
+		withSupportedJournal.Journal = supportedJournalSettings(s, opts.Journal)
+		opts = &withSupportedJournal
+	}
 
 	// check if any previously disabled services are now no longer services and
 	// log messages about that
@@ -452,9 +469,9 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, opts *AddSnapServicesO
 			written = append(written, path)
 		}
 
-		if app.Timer != nil || len(app.Sockets) != 0 {
-			// service is socket or timer activated, not during the
-			// boot
+		if app.Timer != nil || len(app.Sockets) != 0 || len(app.ActivatesOn) != 0 {
+			// service is socket, timer or D-Bus activated, not
+			// during the boot
 			continue
 		}
 		// XXX: this may become quadratic, optimize.
@@ -467,6 +484,10 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, opts *AddSnapServicesO
 		toEnable = append(toEnable, app)
 	}
 
+	if err := ensureJournalNamespace(s, opts.Journal); err != nil {
+		return err
+	}
+
 	disableEnabledServices, err = enableServices(toEnable, inter)
 	if err != nil {
 		return err
@@ -485,7 +506,7 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, opts *AddSnapServicesO
 		}
 	}
 
-	return nil
+	return ensureScheduledTasks(s, opts.ScheduledTasks, preseeding, inter)
 }
 
 // EnableSnapServices enables all services of the snap; the main use case for this is
@@ -651,6 +672,14 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 
 	}
 
+	if err := ensureJournalNamespace(s, nil); err != nil {
+		logger.Noticef("Failed to remove journal namespace configuration of %q: %v", s.InstanceName(), err)
+	}
+
+	if err := RemoveScheduledTasks(s, inter); err != nil {
+		return err
+	}
+
 	// only reload if we actually had services
 	if removedSystem {
 		if err := systemSysd.DaemonReload(); err != nil {
@@ -691,22 +720,40 @@ Requires={{.MountUnit}}
 {{- if .PrerequisiteTarget}}
 Wants={{.PrerequisiteTarget}}
 {{- end}}
+{{- if .Wants}}
+Wants={{ stringsJoin .Wants " " }}
+{{- end}}
 {{- if .After}}
 After={{ stringsJoin .After " " }}
 {{- end}}
 {{- if .Before}}
 Before={{ stringsJoin .Before " "}}
 {{- end}}
+{{- if .App.StartLimitBurst}}
+StartLimitBurst={{.App.StartLimitBurst}}
+{{- end}}
+{{- if .App.StartLimitInterval}}
+StartLimitIntervalSec={{.App.StartLimitInterval.Seconds}}
+{{- end}}
 X-Snappy=yes
 
 [Service]
 EnvironmentFile=-/etc/environment
-ExecStart={{.App.LauncherCommand}}
+{{- range .Environment}}
+Environment={{.}}
+{{- end}}
+ExecStart={{.App.LauncherCommand}}{{range .Args}} {{.}}{{end}}
 SyslogIdentifier={{.App.Snap.InstanceName}}.{{.App.Name}}
 Restart={{.Restart}}
 {{- if .App.RestartDelay}}
 RestartSec={{.App.RestartDelay.Seconds}}
 {{- end}}
+{{- if .App.RestartSteps}}
+RestartSteps={{.App.RestartSteps}}
+{{- end}}
+{{- if .App.RestartMaxDelay}}
+RestartMaxDelaySec={{.App.RestartMaxDelay.Seconds}}
+{{- end}}
 WorkingDirectory={{.WorkingDir}}
 {{- if .App.StopCommand}}
 ExecStop={{.App.LauncherStopCommand}}
@@ -742,7 +789,14 @@ KillSignal={{.KillSignal}}
 {{- if .OOMAdjustScore }}
 OOMScoreAdjust={{.OOMAdjustScore}}
 {{- end}}
-{{- if not .App.Sockets}}
+{{- if .LogNamespace}}
+LogNamespace={{.LogNamespace}}
+{{- end}}
+{{- if .LogRateLimitBurst}}
+LogRateLimitIntervalSec={{.LogRateLimitInterval.Seconds}}
+LogRateLimitBurst={{.LogRateLimitBurst}}
+{{- end}}
+{{- if not (or .App.Sockets .App.ActivatesOn)}}
 
 [Install]
 WantedBy={{.ServicesTarget}}
@@ -797,8 +851,16 @@ WantedBy={{.ServicesTarget}}
 		KillMode           string
 		KillSignal         string
 		OOMAdjustScore     int
+		LogNamespace       string
 		Before             []string
 		After              []string
+		Wants              []string
+
+		LogRateLimitBurst    int
+		LogRateLimitInterval time.Duration
+
+		Environment []string
+		Args        []string
 
 		Home    string
 		EnvVars string
@@ -835,6 +897,27 @@ WantedBy={{.ServicesTarget}}
 		panic("unknown snap.DaemonScope")
 	}
 
+	if opts.Journal != nil {
+		// journal namespaces are only available to system services
+		if opts.Journal.MaxSize > 0 && appInfo.DaemonScope == snap.SystemDaemon {
+			wrapperData.LogNamespace = JournalNamespace(appInfo.Snap.InstanceName())
+		}
+		wrapperData.LogRateLimitBurst = opts.Journal.RateLimitBurst
+		wrapperData.LogRateLimitInterval = opts.Journal.RateLimitInterval
+	}
+
+	if overrides := opts.Overrides[appInfo.Name]; overrides != nil {
+		wrapperData.Environment = overrides.environment()
+		wrapperData.Args = overrides.args()
+	}
+
+	// Order after and want the services of other snaps this service
+	// depends on
+	if providers := opts.ServiceProviders[appInfo.Name]; len(providers) > 0 {
+		wrapperData.Wants = providers
+		wrapperData.After = append(wrapperData.After, providers...)
+	}
+
 	// Add extra "After" targets
 	if wrapperData.PrerequisiteTarget != "" {
 		wrapperData.After = append([]string{wrapperData.PrerequisiteTarget}, wrapperData.After...)
@@ -827,6 +827,56 @@ WantedBy=multi-user.target
 `, mountUnitPrefix, mountUnitPrefix))
 }
 
+func (s *servicesWrapperGenSuite) TestSupervision(c *C) {
+	service := &snap.AppInfo{
+		Snap: &snap.Info{
+			SuggestedName: "snap",
+			Version:       "0.3.4",
+			SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+		},
+		Name:               "app",
+		Command:            "bin/foo start",
+		Daemon:             "simple",
+		DaemonScope:        snap.SystemDaemon,
+		RestartDelay:       timeout.Timeout(time.Second),
+		RestartSteps:       5,
+		RestartMaxDelay:    timeout.Timeout(2 * time.Minute),
+		StartLimitBurst:    10,
+		StartLimitInterval: timeout.Timeout(10 * time.Minute),
+		// handled by snapd, not systemd
+		OnFailureAction: snap.FailureActionRevert,
+	}
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+Requires=%s-snap-44.mount
+Wants=network.target
+After=%s-snap-44.mount network.target snapd.apparmor.service
+StartLimitBurst=10
+StartLimitIntervalSec=600
+X-Snappy=yes
+
+[Service]
+EnvironmentFile=-/etc/environment
+ExecStart=/usr/bin/snap run snap.app
+SyslogIdentifier=snap.app
+Restart=on-failure
+RestartSec=1
+RestartSteps=5
+RestartMaxDelaySec=120
+WorkingDirectory=/var/snap/snap/44
+TimeoutStopSec=30
+Type=simple
+
+[Install]
+WantedBy=multi-user.target
+`, mountUnitPrefix, mountUnitPrefix))
+}
+
 func (s *servicesWrapperGenSuite) TestVitalityScore(c *C) {
 	service := &snap.AppInfo{
 		Snap: &snap.Info{
@@ -868,3 +918,164 @@ OOMScoreAdjust=-899
 WantedBy=multi-user.target
 `, mountUnitPrefix, mountUnitPrefix))
 }
+
+func (s *servicesWrapperGenSuite) TestServiceProviders(c *C) {
+	service := &snap.AppInfo{
+		Snap: &snap.Info{
+			SuggestedName: "snap",
+			Version:       "0.3.4",
+			SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+		},
+		Name:        "app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.SystemDaemon,
+	}
+
+	opts := &wrappers.AddSnapServicesOptions{
+		ServiceProviders: map[string][]string{
+			"app":   {"snap.db.server.service", "snap.db.backup.service"},
+			"other": {"snap.other.service"},
+		},
+	}
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, opts)
+	c.Assert(err, IsNil)
+
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+Requires=%s-snap-44.mount
+Wants=network.target
+Wants=snap.db.server.service snap.db.backup.service
+After=%s-snap-44.mount network.target snapd.apparmor.service snap.db.server.service snap.db.backup.service
+X-Snappy=yes
+
+[Service]
+EnvironmentFile=-/etc/environment
+ExecStart=/usr/bin/snap run snap.app
+SyslogIdentifier=snap.app
+Restart=on-failure
+WorkingDirectory=/var/snap/snap/44
+TimeoutStopSec=30
+Type=simple
+
+[Install]
+WantedBy=multi-user.target
+`, mountUnitPrefix, mountUnitPrefix))
+}
+
+func (s *servicesWrapperGenSuite) TestJournalSettings(c *C) {
+	info := &snap.Info{
+		SuggestedName: "snap",
+		Version:       "0.3.4",
+		SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+	}
+	service := &snap.AppInfo{
+		Snap:        info,
+		Name:        "app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.SystemDaemon,
+	}
+	userService := &snap.AppInfo{
+		Snap:        info,
+		Name:        "user-app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.UserDaemon,
+	}
+
+	opts := &wrappers.AddSnapServicesOptions{
+		Journal: &wrappers.JournalSettings{
+			MaxSize:           64 * 1000 * 1000,
+			RateLimitBurst:    1000,
+			RateLimitInterval: 30 * time.Second,
+		},
+	}
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, opts)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+Requires=%s-snap-44.mount
+Wants=network.target
+After=%s-snap-44.mount network.target snapd.apparmor.service
+X-Snappy=yes
+
+[Service]
+EnvironmentFile=-/etc/environment
+ExecStart=/usr/bin/snap run snap.app
+SyslogIdentifier=snap.app
+Restart=on-failure
+WorkingDirectory=/var/snap/snap/44
+TimeoutStopSec=30
+Type=simple
+LogNamespace=snap-snap
+LogRateLimitIntervalSec=30
+LogRateLimitBurst=1000
+
+[Install]
+WantedBy=multi-user.target
+`, mountUnitPrefix, mountUnitPrefix))
+
+	// user services cannot log into a namespace
+	generatedWrapper, err = wrappers.GenerateSnapServiceFile(userService, opts)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Not(testutil.Contains), "LogNamespace=")
+	c.Check(string(generatedWrapper), testutil.Contains, "\nLogRateLimitIntervalSec=30\nLogRateLimitBurst=1000\n")
+}
+
+func (s *servicesWrapperGenSuite) TestServiceOverrides(c *C) {
+	info := &snap.Info{
+		SuggestedName: "snap",
+		Version:       "0.3.4",
+		SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+	}
+	service := &snap.AppInfo{
+		Snap:        info,
+		Name:        "app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.SystemDaemon,
+	}
+
+	opts := &wrappers.AddSnapServicesOptions{
+		Overrides: map[string]*wrappers.ServiceOverrides{
+			"app": {
+				Env: map[string]string{
+					"LOG_LEVEL": "debug",
+					"GREETING":  `say "100%"`,
+				},
+				Args: []string{"--verbose", "--prefix=$HOME"},
+			},
+			"other-app": {
+				Args: []string{"--other"},
+			},
+		},
+	}
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, opts)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+Requires=%s-snap-44.mount
+Wants=network.target
+After=%s-snap-44.mount network.target snapd.apparmor.service
+X-Snappy=yes
+
+[Service]
+EnvironmentFile=-/etc/environment
+Environment="GREETING=say \"100%%%%\""
+Environment="LOG_LEVEL=debug"
+Environment="SNAP_CONFIGURED_ENV=GREETING,LOG_LEVEL"
+ExecStart=/usr/bin/snap run snap.app "--verbose" "--prefix=$$HOME"
+SyslogIdentifier=snap.app
+Restart=on-failure
+WorkingDirectory=/var/snap/snap/44
+TimeoutStopSec=30
+Type=simple
+
+[Install]
+WantedBy=multi-user.target
+`, mountUnitPrefix, mountUnitPrefix))
+}
@@ -128,6 +128,170 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 	c.Check(s.sysdLog[1], DeepEquals, []string{"daemon-reload"})
 }
 
+func (s *servicesTestSuite) TestAddSnapServicesScheduledTasksAndRemove(c *C) {
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+	taskSvcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.schedule_backup.service")
+	taskTimerFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.schedule_backup.timer")
+
+	opts := &wrappers.AddSnapServicesOptions{
+		ScheduledTasks: []*wrappers.ScheduledTask{
+			{Name: "backup", App: info.Apps["hello"], Schedule: "mon,10:00"},
+		},
+	}
+	err := wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)},
+		{"daemon-reload"},
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.schedule_backup.timer"},
+		{"start", "snap.hello-snap.schedule_backup.timer"},
+	})
+
+	c.Check(taskSvcFile, testutil.FileContains, "\nExecStart=/usr/bin/snap run hello-snap.hello\n")
+	c.Check(taskSvcFile, testutil.FileContains, "\nType=oneshot\n")
+	c.Check(taskTimerFile, testutil.FileContains, "\nUnit=snap.hello-snap.schedule_backup.service\nPersistent=true\nOnCalendar=Mon *-*-* 10:00\n")
+
+	// nothing to do when the tasks did not change
+	s.sysdLog = nil
+	err = wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)},
+		{"daemon-reload"},
+	})
+
+	s.sysdLog = nil
+	err = wrappers.RemoveSnapServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(taskSvcFile), Equals, false)
+	c.Check(osutil.FileExists(taskTimerFile), Equals, false)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "disable", filepath.Base(svcFile)},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.schedule_backup.timer"},
+		{"stop", "snap.hello-snap.schedule_backup.timer"},
+		{"show", "--property=ActiveState", "snap.hello-snap.schedule_backup.timer"},
+		{"daemon-reload"},
+		{"daemon-reload"},
+	})
+}
+
+func (s *servicesTestSuite) TestAddSnapServicesScheduledTasksAppNamedSchedule(c *C) {
+	info := snaptest.MockSnap(c, `name: hello-snap
+version: 1.0
+apps:
+ hello:
+   command: bin/hello
+ schedule:
+   command: bin/schedule
+   daemon: simple
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.schedule.service")
+	taskTimerFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.schedule_backup.timer")
+
+	opts := &wrappers.AddSnapServicesOptions{
+		ScheduledTasks: []*wrappers.ScheduledTask{
+			{Name: "backup", App: info.Apps["hello"], Schedule: "mon,10:00"},
+		},
+	}
+	err := wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	// the units of the app are not taken for the ones of a scheduled task
+	c.Check(svcFile, testutil.FilePresent)
+	c.Check(taskTimerFile, testutil.FilePresent)
+
+	// nor removed with them
+	opts.ScheduledTasks = nil
+	err = wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(svcFile, testutil.FilePresent)
+	c.Check(taskTimerFile, testutil.FileAbsent)
+}
+
+func (s *servicesTestSuite) TestAddSnapServicesJournalNamespace(c *C) {
+	restore := systemd.MockSystemdVersion(245, nil)
+	defer restore()
+
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+	confFile := filepath.Join(s.tempdir, "/etc/systemd/journald@snap-hello-snap.conf")
+
+	opts := &wrappers.AddSnapServicesOptions{
+		Journal: &wrappers.JournalSettings{MaxSize: 10 * 1000 * 1000},
+	}
+	err := wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)},
+		{"daemon-reload"},
+	})
+	c.Check(svcFile, testutil.FileContains, "\nLogNamespace=snap-hello-snap\n")
+	c.Check(confFile, testutil.FileEquals, `# Auto-generated, DO NOT EDIT
+[Journal]
+SystemMaxUse=10000000
+`)
+
+	s.sysdLog = nil
+	err = wrappers.RestartJournalNamespace(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"stop", "systemd-journald@snap-hello-snap.service"},
+		{"show", "--property=ActiveState", "systemd-journald@snap-hello-snap.service"},
+		{"start", "systemd-journald@snap-hello-snap.service"},
+	})
+
+	// dropping the size cap removes the namespace
+	s.sysdLog = nil
+	err = wrappers.AddSnapServices(info, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(svcFile, Not(testutil.FileContains), "LogNamespace=")
+	c.Check(confFile, testutil.FileAbsent)
+
+	s.sysdLog = nil
+	err = wrappers.RestartJournalNamespace(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+
+	err = wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(confFile, testutil.FilePresent)
+	err = wrappers.RemoveSnapServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(confFile, testutil.FileAbsent)
+}
+
+func (s *servicesTestSuite) TestAddSnapServicesJournalSettingsOldSystemd(c *C) {
+	restore := systemd.MockSystemdVersion(239, nil)
+	defer restore()
+
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+	confFile := filepath.Join(s.tempdir, "/etc/systemd/journald@snap-hello-snap.conf")
+
+	opts := &wrappers.AddSnapServicesOptions{
+		Journal: &wrappers.JournalSettings{
+			MaxSize:           10 * 1000 * 1000,
+			RateLimitBurst:    100,
+			RateLimitInterval: 10 * time.Second,
+		},
+	}
+	err := wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(svcFile, Not(testutil.FileContains), "LogNamespace=")
+	c.Check(svcFile, Not(testutil.FileContains), "LogRateLimit")
+	c.Check(confFile, testutil.FileAbsent)
+
+	// rate limits are supported since systemd 240
+	restore = systemd.MockSystemdVersion(240, nil)
+	defer restore()
+	err = wrappers.AddSnapServices(info, nil, opts, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(svcFile, Not(testutil.FileContains), "LogNamespace=")
+	c.Check(svcFile, testutil.FileContains, "\nLogRateLimitIntervalSec=10\nLogRateLimitBurst=100\n")
+	c.Check(confFile, testutil.FileAbsent)
+}
+
 func (s *servicesTestSuite) TestAddSnapServicesAndRemoveUserDaemons(c *C) {
 	info := snaptest.MockSnap(c, packageHello+`
  svc1:
@@ -169,6 +333,39 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemoveUserDaemons(c *C) {
 	})
 }
 
+func (s *servicesTestSuite) TestAddSnapServicesDBusActivatedUserDaemon(c *C) {
+	info := snaptest.MockSnap(c, `
+name: hello-snap
+version: 1.0
+slots:
+  dbus-slot:
+    interface: dbus
+    bus: session
+    name: org.example.Hello
+apps:
+  svc1:
+    daemon: simple
+    daemon-scope: user
+    activates-on: [dbus-slot]
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/user/snap.hello-snap.svc1.service")
+
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	// the service is started on demand by the session bus, not at login
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "daemon-reload"},
+	})
+	content, err := ioutil.ReadFile(svcFile)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Not(testutil.Contains), "[Install]")
+
+	s.sysdLog = nil
+	err = wrappers.StartServices(info.Services(), nil, nil, &progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
 var snapdYaml = `name: snapd
 version: 1.0
 type: snapd